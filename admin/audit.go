@@ -0,0 +1,69 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+type AuditLogEntry struct {
+	ID         string    `json:"id"`
+	UserID     *string   `json:"user_id"`
+	Username   *string   `json:"username"`
+	Action     string    `json:"action"`
+	StatusCode *int      `json:"status_code"`
+	IPAddress  *string   `json:"ip_address"`
+	Created    time.Time `json:"created"`
+}
+
+// RecordAction adds an action to the admin audit log. userID is empty and
+// statusCode is nil for actions taken from the command line.
+func RecordAction(ctx context.Context, dbtx db.DBTX, userID string, action string, statusCode *int, ipAddress string) error {
+	params := db.AdminAuditLogInsertParams{
+		Action: action,
+	}
+	if userID != "" {
+		userUUID, err := uuid.Parse(userID)
+		if err != nil {
+			return fmt.Errorf("parse user UUID: %w", err)
+		}
+		params.UserID = &userUUID
+	}
+	if statusCode != nil {
+		params.StatusCode = lo.ToPtr(int32(*statusCode))
+	}
+	if ipAddress != "" {
+		params.IpAddress = &ipAddress
+	}
+
+	return db.New(dbtx).AdminAuditLogInsert(ctx, params)
+}
+
+// GetAuditLog returns the most recent admin actions, newest first
+func GetAuditLog(ctx context.Context, dbtx db.DBTX, limit int) ([]AuditLogEntry, error) {
+	rows, err := db.New(dbtx).AdminAuditLogGet(ctx, int32(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.Map(rows, func(row *db.AdminAuditLogGetRow, _ int) AuditLogEntry {
+		entry := AuditLogEntry{
+			ID:        row.ID.String(),
+			Username:  row.Username,
+			Action:    row.Action,
+			IPAddress: row.IpAddress,
+			Created:   row.Created,
+		}
+		if row.UserID != nil {
+			entry.UserID = lo.ToPtr(row.UserID.String())
+		}
+		if row.StatusCode != nil {
+			entry.StatusCode = lo.ToPtr(int(*row.StatusCode))
+		}
+		return entry
+	}), nil
+}
@@ -61,3 +61,31 @@ GROUP BY
 ORDER BY
   COUNT DESC;
 
+
+-- name: AdminAuditLogInsert :exec
+INSERT INTO admin_audit_log(
+  user_id,
+  action,
+  status_code,
+  ip_address)
+VALUES (
+  sqlc.narg(user_id),
+  @action,
+  sqlc.narg(status_code),
+  sqlc.narg(ip_address));
+
+-- name: AdminAuditLogGet :many
+SELECT
+  l.id,
+  l.user_id,
+  u.username,
+  l.action,
+  l.status_code,
+  l.ip_address,
+  l.created
+FROM
+  admin_audit_log l
+  LEFT JOIN users u ON u.id = l.user_id
+ORDER BY
+  l.created DESC
+LIMIT @max_entries;
@@ -0,0 +1,337 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/auth/authtest"
+	"github.com/andrewbenington/queue-share-api/client"
+	"github.com/andrewbenington/queue-share-api/controller"
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/db/dbtest"
+	"github.com/andrewbenington/queue-share-api/service"
+	"github.com/andrewbenington/queue-share-api/service/spotifytest"
+	"github.com/andrewbenington/queue-share-api/user"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata with the responses received")
+
+// January 2024, which is when the fixture history was played
+const january = "timeframe=month&start_unix=1704067200&end_unix=1706745600"
+
+func TestMain(m *testing.M) {
+	// pgx returns timestamps in the local time zone, which would otherwise
+	// change the golden files from machine to machine
+	time.Local = time.UTC
+	os.Exit(m.Run())
+}
+
+// testApp serves the full router, with middleware, from a seeded test database
+// and a fake Spotify API
+type testApp struct {
+	handler http.Handler
+	spotify *spotifytest.Server
+	pool    *pgxpool.Pool
+}
+
+func newTestApp(t *testing.T) *testApp {
+	t.Helper()
+
+	pool := dbtest.New(t)
+	dbtest.Seed(t, pool)
+	authtest.UseKeys(t)
+
+	spotifyServer := spotifytest.NewServer(t)
+	newProvider := client.NewProvider
+	client.NewProvider = func(httpClient *http.Client) service.MusicProvider {
+		return spotifyServer.SpotifyClient(httpClient)
+	}
+	t.Cleanup(func() { client.NewProvider = newProvider })
+
+	for _, userID := range []uuid.UUID{dbtest.AliceID, dbtest.BobID, dbtest.CarolID} {
+		err := user.UpdateSpotifyToken(context.Background(), pool, userID.String(), &oauth2.Token{
+			AccessToken:  "access-" + userID.String(),
+			RefreshToken: "refresh-" + userID.String(),
+			Expiry:       time.Now().Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("seed spotify token: %s", err)
+		}
+	}
+
+	a := &App{}
+	a.Initialize()
+
+	return &testApp{
+		handler: withMiddleware(a.Router),
+		spotify: spotifyServer,
+		pool:    pool,
+	}
+}
+
+func (a *testApp) login(t *testing.T, username string) string {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", "/auth/token", nil)
+	req.SetBasicAuth(username, dbtest.Password)
+	w := httptest.NewRecorder()
+	a.handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("log in as %s: %d %s", username, w.Code, w.Body.String())
+	}
+
+	resp := controller.TokenResponse{}
+	err := json.NewDecoder(w.Body).Decode(&resp)
+	if err != nil {
+		t.Fatalf("decode token response: %s", err)
+	}
+	return resp.Token
+}
+
+func (a *testApp) get(token string, path string) *httptest.ResponseRecorder {
+	return a.do(token, "GET", path, nil)
+}
+
+// do makes a request with body encoded as JSON, if it isn't nil
+func (a *testApp) do(token string, method string, path string, body any) *httptest.ResponseRecorder {
+	encoded := bytes.Buffer{}
+	if body != nil {
+		json.NewEncoder(&encoded).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &encoded)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	a.handler.ServeHTTP(w, req)
+	return w
+}
+
+// assertGolden compares a JSON response body with testdata/<name>.golden.json.
+// Both are reformatted first, so object keys are compared in sorted order.
+func assertGolden(t *testing.T, name string, body []byte) {
+	t.Helper()
+
+	got, err := indentJSON(body)
+	if err != nil {
+		t.Fatalf("response is not JSON: %s\n%s", err, body)
+	}
+
+	path := filepath.Join("testdata", name+".golden.json")
+	if *update {
+		err = os.WriteFile(path, got, 0o644)
+		if err != nil {
+			t.Fatalf("write golden file: %s", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (run with -update to create it): %s", err)
+	}
+	assert.Equal(t, string(want), string(got))
+}
+
+func indentJSON(body []byte) ([]byte, error) {
+	var value any
+	err := json.Unmarshal(body, &value)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.Buffer{}
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(value)
+	return buf.Bytes(), err
+}
+
+func TestAuth(t *testing.T) {
+	a := newTestApp(t)
+
+	t.Run("wrong password", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/auth/token", nil)
+		req.SetBasicAuth("alice", "not the password")
+		w := httptest.NewRecorder()
+		a.handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("token identifies the user", func(t *testing.T) {
+		token := a.login(t, "alice")
+
+		w := a.get(token, "/user")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), dbtest.AliceID.String())
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		w := a.get("", "/rankings/track?"+january)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestTrackRankings(t *testing.T) {
+	a := newTestApp(t)
+	token := a.login(t, "alice")
+
+	t.Run("own history", func(t *testing.T) {
+		w := a.get(token, "/rankings/track?"+january)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assertGolden(t, "rankings_track", w.Body.Bytes())
+	})
+
+	t.Run("friend's history", func(t *testing.T) {
+		w := a.get(token, "/rankings/track?"+january+"&friend_id="+dbtest.BobID.String())
+		assert.Equal(t, http.StatusOK, w.Code)
+		assertGolden(t, "rankings_track_friend", w.Body.Bytes())
+	})
+}
+
+func TestCompareTracks(t *testing.T) {
+	a := newTestApp(t)
+
+	t.Run("shared with friends", func(t *testing.T) {
+		w := a.get(a.login(t, "alice"), "/stats/compare-tracks?shared_only=true&"+january)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assertGolden(t, "compare_tracks_shared", w.Body.Bytes())
+	})
+
+	t.Run("no friends", func(t *testing.T) {
+		w := a.get(a.login(t, "carol"), "/stats/compare-tracks?"+january)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assertGolden(t, "compare_tracks_no_friends", w.Body.Bytes())
+	})
+}
+
+// readArchive returns the contents of each file in a zip archive
+func readArchive(t *testing.T, body []byte) map[string][]byte {
+	t.Helper()
+
+	reader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("response is not a zip archive: %s", err)
+	}
+	files := map[string][]byte{}
+	for _, file := range reader.File {
+		f, err := file.Open()
+		assert.NoError(t, err)
+		files[file.Name], err = io.ReadAll(f)
+		assert.NoError(t, err)
+		f.Close()
+	}
+	return files
+}
+
+func TestUpdateUser(t *testing.T) {
+	a := newTestApp(t)
+	token := a.login(t, "alice")
+
+	t.Run("username in use", func(t *testing.T) {
+		// usernames are unique regardless of case
+		w := a.do(token, "PUT", "/user", controller.UpdateUserBody{Username: "Bob"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("renamed", func(t *testing.T) {
+		w := a.do(token, "PUT", "/user", controller.UpdateUserBody{Username: "alicia", DisplayName: "Alicia"})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		u := user.User{}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&u))
+		assert.Equal(t, "alicia", u.Username)
+		assert.Equal(t, "Alicia", u.DisplayName)
+	})
+}
+
+func TestExportUserData(t *testing.T) {
+	a := newTestApp(t)
+
+	w := a.get(a.login(t, "alice"), "/user/export")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+
+	files := readArchive(t, w.Body.Bytes())
+	assert.ElementsMatch(t, []string{"profile.json", "history.json", "episode_history.json", "friends.json", "hosted_rooms.json"}, lo.Keys(files))
+
+	profile := user.User{}
+	assert.NoError(t, json.Unmarshal(files["profile.json"], &profile))
+	assert.Equal(t, dbtest.AliceID.String(), profile.ID)
+	assert.Equal(t, "alice", profile.Username)
+
+	history := []db.SpotifyHistory{}
+	assert.NoError(t, json.Unmarshal(files["history.json"], &history))
+	assert.NotEmpty(t, history)
+	for _, entry := range history {
+		assert.Equal(t, dbtest.AliceID, entry.UserID)
+	}
+
+	friends := []user.User{}
+	assert.NoError(t, json.Unmarshal(files["friends.json"], &friends))
+	assert.Equal(t, []string{"bob"}, lo.Map(friends, func(u user.User, _ int) string { return u.Username }))
+
+	rooms := []user.HostedRoom{}
+	assert.NoError(t, json.Unmarshal(files["hosted_rooms.json"], &rooms))
+	assert.Equal(t, []string{dbtest.RoomCode}, lo.Map(rooms, func(r user.HostedRoom, _ int) string { return r.Code }))
+}
+
+func TestDeleteUser(t *testing.T) {
+	a := newTestApp(t)
+	ctx := context.Background()
+	token := a.login(t, "alice")
+
+	count := func(t *testing.T, query string) int {
+		var count int
+		err := a.pool.QueryRow(ctx, query, dbtest.AliceID).Scan(&count)
+		assert.NoError(t, err)
+		return count
+	}
+
+	t.Run("wrong password", func(t *testing.T) {
+		w := a.do(token, "DELETE", "/user", controller.DeleteUserBody{Password: "not the password"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, 1, count(t, "SELECT COUNT(*) FROM users WHERE id = $1"))
+	})
+
+	t.Run("not exported", func(t *testing.T) {
+		w := a.do(token, "DELETE", "/user", controller.DeleteUserBody{Password: dbtest.Password})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, 1, count(t, "SELECT COUNT(*) FROM users WHERE id = $1"))
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		assert.NotZero(t, count(t, "SELECT COUNT(*) FROM spotify_history WHERE user_id = $1"))
+
+		w := a.get(token, "/user/export")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = a.do(token, "DELETE", "/user", controller.DeleteUserBody{Password: dbtest.Password})
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		assert.Zero(t, count(t, "SELECT COUNT(*) FROM users WHERE id = $1"))
+		assert.Zero(t, count(t, "SELECT COUNT(*) FROM spotify_history WHERE user_id = $1"))
+		assert.Zero(t, count(t, "SELECT COUNT(*) FROM user_sessions WHERE user_id = $1"))
+		assert.Zero(t, count(t, "SELECT COUNT(*) FROM spotify_tokens WHERE user_id = $1"))
+		assert.Zero(t, count(t, "SELECT COUNT(*) FROM user_passwords WHERE user_id = $1"))
+
+		w = a.get(token, "/user")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -2,13 +2,18 @@ package app
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/andrewbenington/queue-share-api/admin"
 	"github.com/andrewbenington/queue-share-api/auth"
+	"github.com/andrewbenington/queue-share-api/config"
+	"github.com/andrewbenington/queue-share-api/db"
 	"github.com/andrewbenington/queue-share-api/requests"
 	"github.com/andrewbenington/queue-share-api/user"
 	"github.com/andrewbenington/queue-share-api/util"
@@ -34,6 +39,12 @@ func withMiddleware(handler http.Handler) http.Handler {
 
 func authMW(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the access token may have expired by the time it is refreshed
+		if r.URL.Path == "/auth/refresh" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		reqToken := r.Header.Get("Authorization")
 		splitToken := strings.Split(reqToken, "Bearer ")
 		if len(splitToken) < 2 {
@@ -42,12 +53,25 @@ func authMW(next http.Handler) http.Handler {
 		}
 		reqToken = splitToken[1]
 
-		id, err := user.GetTokenID(reqToken)
+		claims, err := user.ParseToken(reqToken)
 		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
 			json.NewEncoder(w).Encode(requests.ErrorResponse{Error: err.Error()})
 			return
 		}
+		id := claims.UserID
+
+		active, err := user.SessionIsActive(r.Context(), db.Service().Pool, id, claims.SessionID)
+		if err != nil {
+			log.Printf("check session: %s", err)
+			requests.RespondInternalError(w)
+			return
+		}
+		if !active {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(requests.ErrorResponse{Error: "Session has been revoked"})
+			return
+		}
 
 		friendID := r.URL.Query().Get("friend_id")
 		if r.Method != "GET" || !(strings.HasPrefix(r.RequestURI, "/user") || strings.HasPrefix(r.RequestURI, "/admin")) {
@@ -59,10 +83,68 @@ func authMW(next http.Handler) http.Handler {
 			})
 		}
 		ctx := context.WithValue(r.Context(), auth.UserContextKey, id)
+		ctx = context.WithValue(ctx, auth.SessionContextKey, claims.SessionID)
+		ctx = context.WithValue(ctx, auth.RoleContextKey, claims.Role)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// statusRecorder keeps the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// adminMW only lets admins through, and records each request they make in the
+// admin audit log. The role in the token is only used to turn away non-admins
+// early, since it stays the same until the token expires; admin access is
+// checked against the user's current role.
+func adminMW(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(auth.UserContextKey).(string)
+		if !ok {
+			requests.RespondAuthError(w)
+			return
+		}
+		role, _ := r.Context().Value(auth.RoleContextKey).(string)
+		if role != user.RoleAdmin {
+			requests.RespondWithError(w, http.StatusForbidden, "Admin access required")
+			return
+		}
+
+		u, err := user.GetByID(r.Context(), db.Service().Pool, userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			requests.RespondAuthError(w)
+			return
+		}
+		if err != nil {
+			log.Printf("get user role: %s", err)
+			requests.RespondInternalError(w)
+			return
+		}
+		if u.Role != user.RoleAdmin {
+			requests.RespondWithError(w, http.StatusForbidden, "Admin access required")
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		// the request context may already be done
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err = admin.RecordAction(ctx, db.Service().Pool, userID, r.Method+" "+r.RequestURI, &recorder.status, requests.ClientIP(r))
+		if err != nil {
+			log.Printf("record admin action: %s", err)
+		}
+	})
+}
+
 func contentMW(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "OPTIONS" {
@@ -76,7 +158,10 @@ func contentMW(next http.Handler) http.Handler {
 
 func corsMW(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if origin := allowedOrigin(r.Header.Get("Origin"), config.GetCORSOrigins()); origin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		w.Header().Add("Vary", "Origin")
 		w.Header().Set("Access-Control-Allow-Methods", "*")
 		w.Header().Set("Access-Control-Allow-Headers", "Authorization, *")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
@@ -88,6 +173,20 @@ func corsMW(next http.Handler) http.Handler {
 	})
 }
 
+// allowedOrigin returns the Access-Control-Allow-Origin value for a request
+// from origin, or "" if the origin isn't allowed
+func allowedOrigin(origin string, allowed []string) string {
+	for _, a := range allowed {
+		if a == "*" {
+			return "*"
+		}
+		if origin != "" && strings.EqualFold(a, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
 func logMW(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/health" {
@@ -100,8 +199,24 @@ func logMW(next http.Handler) http.Handler {
 
 func timeoutMW(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx, cancel := context.WithTimeout(r.Context(), time.Second*300)
+		// streams end when they're done or the client disconnects
+		if isStreamingRequest(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), config.GetRequestTimeout())
 		defer cancel()
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// isStreamingRequest returns whether path is a server-sent event stream or a
+// download that can take longer than the request timeout
+func isStreamingRequest(path string) bool {
+	if path == "/stats/history/export" || path == "/user/export" {
+		return true
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	return len(parts) == 3 && parts[0] == "room" && parts[2] == "events"
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andrewbenington/queue-share-api/auth"
+	"github.com/andrewbenington/queue-share-api/db/dbtest"
+	"github.com/andrewbenington/queue-share-api/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminMW(t *testing.T) {
+	handler := adminMW(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be reached")
+	}))
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/admin/logs", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("not an admin", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserContextKey, "4b9c7a55-3f7e-4d52-9a3e-8c1b9f2d6e10")
+		ctx = context.WithValue(ctx, auth.RoleContextKey, user.RoleUser)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/admin/logs", nil).WithContext(ctx))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestAdminMWRole(t *testing.T) {
+	pool := dbtest.New(t)
+	dbtest.Seed(t, pool)
+
+	reached := false
+	handler := adminMW(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	// a token issued while alice was an admin
+	ctx := context.WithValue(context.Background(), auth.UserContextKey, dbtest.AliceID.String())
+	ctx = context.WithValue(ctx, auth.RoleContextKey, user.RoleAdmin)
+
+	t.Run("no longer an admin", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/admin/logs", nil).WithContext(ctx))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.False(t, reached)
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		assert.NoError(t, user.SetRole(context.Background(), pool, "alice", user.RoleAdmin))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/admin/logs", nil).WithContext(ctx))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, reached)
+	})
+}
+
+func TestAllowedOrigin(t *testing.T) {
+	t.Run("any origin", func(t *testing.T) {
+		assert.Equal(t, "*", allowedOrigin("https://queueshare.example", []string{"*"}))
+		assert.Equal(t, "*", allowedOrigin("", []string{"*"}))
+	})
+
+	t.Run("listed origins", func(t *testing.T) {
+		allowed := []string{"https://queueshare.example", "http://localhost:3000"}
+		assert.Equal(t, "http://localhost:3000", allowedOrigin("http://localhost:3000", allowed))
+		assert.Equal(t, "", allowedOrigin("https://elsewhere.example", allowed))
+		assert.Equal(t, "", allowedOrigin("", allowed))
+	})
+}
+
+func TestTimeoutMW(t *testing.T) {
+	handler := timeoutMW(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			w.WriteHeader(http.StatusRequestTimeout)
+		}
+	}))
+
+	for path, streaming := range map[string]bool{
+		"/room/TEST/events":     true,
+		"/stats/history/export": true,
+		"/user/export":          true,
+		"/room/TEST/queue":      false,
+		"/stats/history":        false,
+		"/room/events":          false,
+	} {
+		t.Run(path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			if streaming {
+				assert.Equal(t, http.StatusOK, w.Code)
+			} else {
+				assert.Equal(t, http.StatusRequestTimeout, w.Code)
+			}
+		})
+	}
+}
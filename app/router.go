@@ -14,13 +14,35 @@ func (a *App) initRouter() {
 	a.Router.HandleFunc("/room/{code}", a.Controller.DeleteRoom).Methods("DELETE", "OPTIONS")
 	a.Router.HandleFunc("/room/{code}/queue", a.Controller.GetQueue).Methods("GET", "OPTIONS")
 	a.Router.HandleFunc("/room/{code}/queue/{song}", a.Controller.PushToRoomQueue).Methods("POST", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/queue", a.Controller.ReorderRoomQueue).Methods("PUT", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/queue/{queue_track_id}", a.Controller.RemoveFromRoomQueue).Methods("DELETE", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/queue/{queue_track_id}/vote", a.Controller.VoteOnQueueTrack).Methods("PUT", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/policy", a.Controller.GetQueuePolicy).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/policy", a.Controller.SetQueuePolicy).Methods("PUT", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/schedule", a.Controller.GetSchedule).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/schedule", a.Controller.SetSchedule).Methods("PUT", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/schedule", a.Controller.DeleteSchedule).Methods("DELETE", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/blocklist", a.Controller.GetBlocklist).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/blocklist", a.Controller.AddToBlocklist).Methods("POST", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/blocklist/{id}", a.Controller.RemoveFromBlocklist).Methods("DELETE", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/invites", a.Controller.GetInvites).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/invites", a.Controller.CreateInvite).Methods("POST", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/invites/redeem", a.Controller.RedeemInvite).Methods("POST", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/invites/{id}", a.Controller.RevokeInvite).Methods("DELETE", "OPTIONS")
 
 	a.Router.HandleFunc("/room/{code}/play", a.Controller.Play).Methods("POST", "OPTIONS")
 	a.Router.HandleFunc("/room/{code}/pause", a.Controller.Pause).Methods("POST", "OPTIONS")
 	a.Router.HandleFunc("/room/{code}/next", a.Controller.Next).Methods("POST", "OPTIONS")
 	a.Router.HandleFunc("/room/{code}/previous", a.Controller.Previous).Methods("POST", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/skip-vote", a.Controller.GetSkipVotes).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/skip-vote", a.Controller.VoteToSkip).Methods("POST", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/skip-vote", a.Controller.RemoveSkipVote).Methods("DELETE", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/skips", a.Controller.GetSkipHistory).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/session-history", a.Controller.GetSessionHistory).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/export-playlist", a.Controller.ExportPlaylist).Methods("POST", "OPTIONS")
 	a.Router.HandleFunc("/room/{code}/volume", a.Controller.SetVolume).Methods("PUT", "OPTIONS")
 	a.Router.HandleFunc("/room/{code}/player", a.Controller.GetPlayback).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/room/{code}/events", a.Controller.RoomEvents).Methods("GET", "OPTIONS")
 
 	a.Router.HandleFunc("/room/{code}/devices", a.Controller.Devices).Methods("GET", "OPTIONS")
 	a.Router.HandleFunc("/room/{code}/playlists", a.Controller.RoomPlaylists).Methods("GET", "OPTIONS")
@@ -42,11 +64,17 @@ func (a *App) initRouter() {
 
 	a.Router.HandleFunc("/user", a.Controller.CreateUser).Methods("POST", "OPTIONS")
 	a.Router.HandleFunc("/user", a.Controller.CurrentUser).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/user", a.Controller.UpdateUser).Methods("PUT", "OPTIONS")
+	a.Router.HandleFunc("/user", a.Controller.DeleteUser).Methods("DELETE", "OPTIONS")
+	a.Router.HandleFunc("/user/password", a.Controller.ChangePassword).Methods("PUT", "OPTIONS")
+	a.Router.HandleFunc("/user/export", a.Controller.ExportUserData).Methods("GET", "OPTIONS")
 	a.Router.HandleFunc("/user/rooms/hosted", a.Controller.GetUserHostedRooms).Methods("GET", "OPTIONS")
 	a.Router.HandleFunc("/user/rooms/joined", a.Controller.GetUserJoinedRooms).Methods("GET", "OPTIONS")
 	a.Router.HandleFunc("/user/spotify", a.Controller.UnlinkSpotify).Methods("DELETE", "OPTIONS")
 	a.Router.HandleFunc("/user/has-spotify-history", a.Controller.UserHasSpotifyHistory).Methods("GET", "OPTIONS")
 	a.Router.HandleFunc("/user/playlists", a.Controller.UserPlaylists).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/user/sessions", a.Controller.GetSessions).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/user/sessions/{id}", a.Controller.RevokeSession).Methods("DELETE", "OPTIONS")
 
 	a.Router.HandleFunc("/user/friend-suggestions", a.Controller.UserFriendRequestData).Methods("GET", "OPTIONS")
 	a.Router.HandleFunc("/user/friend-request", a.Controller.UserSendFriendRequest).Methods("POST", "OPTIONS")
@@ -59,6 +87,15 @@ func (a *App) initRouter() {
 	a.Router.HandleFunc("/user/build-queue", a.StatsController.AddMixToQueue).Methods("POST", "OPTIONS")
 
 	a.Router.HandleFunc("/stats/upload", a.StatsController.UploadHistory).Methods("POST", "OPTIONS")
+	a.Router.HandleFunc("/stats/upload/{job_id}", a.StatsController.GetHistoryUpload).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/stats/upload/{job_id}/resume", a.StatsController.ResumeHistoryUpload).Methods("POST", "OPTIONS")
+	a.Router.HandleFunc("/stats/import/review", a.StatsController.GetImportReview).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/stats/import/review/{review_id}", a.StatsController.ResolveImportReview).Methods("POST", "OPTIONS")
+	a.Router.HandleFunc("/stats/import/review/{review_id}", a.StatsController.DismissImportReview).Methods("DELETE", "OPTIONS")
+	a.Router.HandleFunc("/stats/import/{source}", a.StatsController.ImportHistory).Methods("POST", "OPTIONS")
+	a.Router.HandleFunc("/stats/history/export", a.StatsController.ExportHistory).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/stats/history/merges", a.StatsController.GetHistoryMerges).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/stats/history/reconcile", a.StatsController.ReconcileHistory).Methods("POST", "OPTIONS")
 	a.Router.HandleFunc("/stats/history", a.StatsController.GetAllHistory).Methods("GET", "OPTIONS")
 
 	a.Router.HandleFunc("/stats/all-track-streams", a.StatsController.GetAllStreamsByURI).Methods("GET", "OPTIONS")
@@ -67,6 +104,7 @@ func (a *App) initRouter() {
 	a.Router.HandleFunc("/stats/track", a.StatsController.GetTrackStatsByURI).Methods("GET", "OPTIONS")
 	a.Router.HandleFunc("/stats/artist", a.StatsController.GetArtistStatsByURI).Methods("GET", "OPTIONS")
 	a.Router.HandleFunc("/stats/album", a.StatsController.GetAlbumStatsByURI).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/stats/show", a.StatsController.GetShowStats).Methods("GET", "OPTIONS")
 
 	a.Router.HandleFunc("/stats/compare-tracks", a.StatsController.UserCompareFriendTopTracks).Methods("GET", "OPTIONS")
 	a.Router.HandleFunc("/stats/compare-artists", a.StatsController.UserCompareFriendTopArtists).Methods("GET", "OPTIONS")
@@ -83,6 +121,7 @@ func (a *App) initRouter() {
 	a.Router.HandleFunc("/rankings/track", a.StatsController.GetTopTracksByTimeframe).Methods("GET", "OPTIONS")
 	a.Router.HandleFunc("/rankings/artist", a.StatsController.GetTopArtistsByTimeframe).Methods("GET", "OPTIONS")
 	a.Router.HandleFunc("/rankings/album", a.StatsController.GetTopAlbumsByTimeframe).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/rankings/show", a.StatsController.GetTopShowsByTimeframe).Methods("GET", "OPTIONS")
 
 	a.Router.HandleFunc("/spotify/search-tracks", a.Controller.SearchTracksByUser).Methods("GET", "OPTIONS")
 	a.Router.HandleFunc("/spotify/search-artists", a.Controller.SearchArtistsByUser).Methods("GET", "OPTIONS")
@@ -95,17 +134,23 @@ func (a *App) initRouter() {
 	a.Router.HandleFunc("/spotify/history", a.StatsController.GetSpotifyHistory).Methods("GET", "OPTIONS")
 
 	a.Router.HandleFunc("/auth/token", a.Controller.GetToken).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/auth/refresh", a.Controller.RefreshToken).Methods("POST", "OPTIONS")
+	a.Router.HandleFunc("/auth/logout", a.Controller.Logout).Methods("POST", "OPTIONS")
 	a.Router.HandleFunc("/auth/spotify-url", a.Controller.GetSpotifyLoginURL).Methods("GET", "OPTIONS")
 	a.Router.HandleFunc("/auth/spotify-redirect", a.Controller.SpotifyAuthRedirect).Methods("GET", "OPTIONS")
+	a.Router.HandleFunc("/.well-known/jwks.json", a.Controller.GetJWKS).Methods("GET", "OPTIONS")
 
 	a.Router.HandleFunc("/version", a.Controller.GetVersion).Methods("GET", "OPTIONS")
 
-	a.Router.HandleFunc("/admin/tables", a.Controller.GetTableData).Methods("GET", "OPTIONS")
-	a.Router.HandleFunc("/admin/uncached-tracks", a.Controller.GetUncachedTracks).Methods("GET", "OPTIONS")
-	a.Router.HandleFunc("/admin/missing-isrcs", a.Controller.GetMissingISRCNumbers).Methods("GET", "OPTIONS")
-	a.Router.HandleFunc("/admin/missing-artist-uris", a.Controller.GetMissingArtistURIs).Methods("GET", "OPTIONS")
-	a.Router.HandleFunc("/admin/missing-artist-uris-by-user", a.Controller.GetMissingArtistURIsByUser).Methods("GET", "OPTIONS")
-	a.Router.HandleFunc("/admin/logs", a.Controller.GetLogsByDate).Methods("GET", "OPTIONS")
-	a.Router.HandleFunc("/admin/general", a.Controller.GetGeneralInfo).Methods("GET", "OPTIONS")
+	adminRouter := a.Router.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(adminMW)
+	adminRouter.HandleFunc("/tables", a.Controller.GetTableData).Methods("GET", "OPTIONS")
+	adminRouter.HandleFunc("/uncached-tracks", a.Controller.GetUncachedTracks).Methods("GET", "OPTIONS")
+	adminRouter.HandleFunc("/missing-isrcs", a.Controller.GetMissingISRCNumbers).Methods("GET", "OPTIONS")
+	adminRouter.HandleFunc("/missing-artist-uris", a.Controller.GetMissingArtistURIs).Methods("GET", "OPTIONS")
+	adminRouter.HandleFunc("/missing-artist-uris-by-user", a.Controller.GetMissingArtistURIsByUser).Methods("GET", "OPTIONS")
+	adminRouter.HandleFunc("/logs", a.Controller.GetLogsByDate).Methods("GET", "OPTIONS")
+	adminRouter.HandleFunc("/general", a.Controller.GetGeneralInfo).Methods("GET", "OPTIONS")
+	adminRouter.HandleFunc("/audit-log", a.Controller.GetAuditLog).Methods("GET", "OPTIONS")
 
 }
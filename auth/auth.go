@@ -1,18 +1,22 @@
 package auth
 
 import (
-	"sync"
-
+	"github.com/andrewbenington/queue-share-api/config"
 	spotifyauth "github.com/zmb3/spotify/v2/auth"
 )
 
-type SpotifyLoginState struct {
-	UserID      string
-	RedirectURI string
-}
-
 type UserContextKeyT struct{}
 
+type SessionContextKeyT struct{}
+
+type RoleContextKeyT struct{}
+
+const (
+	// spotify_permissions_versions entry for tokens granted with SpotifyScopes
+	// including the playlist-modify scopes
+	PlaylistPermissionsVersion = 4
+)
+
 var (
 	SpotifyScopes = []string{
 		spotifyauth.ScopeUserReadPrivate,
@@ -20,8 +24,21 @@ var (
 		spotifyauth.ScopeUserModifyPlaybackState,
 		spotifyauth.ScopeUserTopRead,
 		spotifyauth.ScopeUserReadRecentlyPlayed,
+		spotifyauth.ScopePlaylistModifyPublic,
+		spotifyauth.ScopePlaylistModifyPrivate,
 	}
-	SpotifyStates     = map[string]SpotifyLoginState{}
-	SpotifyStatesLock = sync.Mutex{}
 	UserContextKey    UserContextKeyT
+	SessionContextKey SessionContextKeyT
+	RoleContextKey    RoleContextKeyT
 )
+
+// SpotifyAuthenticator returns an authenticator with the configured Spotify
+// client credentials and SpotifyScopes
+func SpotifyAuthenticator(opts ...spotifyauth.AuthenticatorOption) *spotifyauth.Authenticator {
+	opts = append([]spotifyauth.AuthenticatorOption{
+		spotifyauth.WithClientID(config.GetSpotifyClientID()),
+		spotifyauth.WithClientSecret(config.GetSpotifyClientSecret()),
+		spotifyauth.WithScopes(SpotifyScopes...),
+	}, opts...)
+	return spotifyauth.New(opts...)
+}
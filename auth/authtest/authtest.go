@@ -0,0 +1,34 @@
+// Package authtest sets up the keys tokens are signed and Spotify tokens are
+// encrypted with for tests, which run without a config
+package authtest
+
+import (
+	"testing"
+
+	"github.com/andrewbenington/queue-share-api/auth"
+)
+
+var (
+	// SigningSecret is the legacy secret test tokens are signed with
+	SigningSecret = []byte("queue share test signing secret")
+	// EncryptionKey is the legacy key test Spotify tokens are encrypted with
+	EncryptionKey = "queue share test encryption key"
+)
+
+// UseKeys signs tokens with SigningSecret and encrypts Spotify tokens with
+// EncryptionKey for the rest of the test binary
+func UseKeys(t testing.TB) {
+	t.Helper()
+
+	keyring, err := auth.LoadKeyring(SigningSecret, "", "", "")
+	if err != nil {
+		t.Fatalf("load signing keys: %s", err)
+	}
+	auth.UseSigningKeys(keyring)
+
+	encryptionKeyring, err := auth.LoadEncryptionKeyring(EncryptionKey, "", "")
+	if err != nil {
+		t.Fatalf("load encryption keys: %s", err)
+	}
+	auth.UseEncryptionKeys(encryptionKeyring)
+}
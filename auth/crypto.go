@@ -1,27 +1,243 @@
 package auth
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
+	"sync"
 
 	"github.com/andrewbenington/queue-share-api/config"
 )
 
+const (
+	dataKeySize = 32
+)
+
+var (
+	// envelopeMagic starts every ciphertext encrypted with a versioned key.
+	// Ciphertexts without it were encrypted directly with the legacy key.
+	envelopeMagic = []byte("QSE1")
+
+	encryptionKeyring     *EncryptionKeyring
+	encryptionKeyringErr  error
+	encryptionKeyringOnce sync.Once
+
+	ErrUnknownEncryptionKey = errors.New("unknown encryption key")
+)
+
+// EncryptionKeyring holds the versioned keys Spotify tokens are encrypted with.
+// Each token is encrypted with its own random data key, and the data key is
+// encrypted with a versioned key whose ID is stored at the start of the
+// ciphertext. Rotating a key only requires rewrapping the data keys.
+type EncryptionKeyring struct {
+	activeID string
+	keys     map[string][]byte
+}
+
 func hashTo64(value string) []byte {
 	hasher := sha256.New()
 	hasher.Write([]byte(value))
 	return hasher.Sum(nil)
 }
 
-func AESGCMEncrypt(token string) ([]byte, error) {
-	keyBytes := hashTo64(config.GetEncryptionKey())
-	tokenBytes := []byte(token)
+// EncryptionKeys returns the keyring loaded from the encryption key settings. It
+// panics if they are invalid, since no tokens could be encrypted or decrypted.
+func EncryptionKeys() *EncryptionKeyring {
+	encryptionKeyringOnce.Do(func() {
+		encryptionKeyring, encryptionKeyringErr = LoadEncryptionKeyring(
+			config.GetEncryptionKey(),
+			config.GetEncryptionKeys(),
+			config.GetEncryptionKeyID(),
+		)
+	})
+	if encryptionKeyringErr != nil {
+		panic(fmt.Sprintf("load encryption keys: %s", encryptionKeyringErr))
+	}
+	return encryptionKeyring
+}
+
+// UseEncryptionKeys replaces the keyring loaded from the encryption key
+// settings, so that tests can encrypt tokens without a config
+func UseEncryptionKeys(k *EncryptionKeyring) {
+	encryptionKeyringOnce.Do(func() {})
+	encryptionKeyring, encryptionKeyringErr = k, nil
+}
+
+// LoadEncryptionKeyring builds a keyring from the legacy encryption key, if it
+// is set, which has the ID "", and AES-256 keys formatted as comma-separated
+// id:base64-key pairs. activeID picks the key new tokens are encrypted with.
+func LoadEncryptionKeyring(legacyKey string, envKeys string, activeID string) (*EncryptionKeyring, error) {
+	k := &EncryptionKeyring{
+		activeID: activeID,
+		keys:     map[string][]byte{},
+	}
+	// an empty legacy key would hash to a key anyone could encrypt with
+	if legacyKey != "" {
+		k.keys[""] = hashTo64(legacyKey)
+	}
+
+	for _, pair := range strings.Split(envKeys, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(pair, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("encryption key '%s' should be formatted as id:base64-key", pair)
+		}
+		if len(id) > 255 {
+			return nil, fmt.Errorf("encryption key ID '%s' is too long", id)
+		}
+		if _, exists := k.keys[id]; exists {
+			return nil, fmt.Errorf("duplicate encryption key '%s'", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("decode encryption key '%s': %w", id, err)
+		}
+		if len(key) != dataKeySize {
+			return nil, fmt.Errorf("encryption key '%s' must be %d bytes", id, dataKeySize)
+		}
+		k.keys[id] = key
+	}
+
+	if _, ok := k.keys[activeID]; !ok {
+		return nil, fmt.Errorf("active encryption key '%s' not found", activeID)
+	}
+
+	return k, nil
+}
+
+// ActivePrefix returns the bytes every ciphertext encrypted with the active key
+// starts with
+func (k *EncryptionKeyring) ActivePrefix() []byte {
+	return envelopeHeader(k.activeID)
+}
+
+func envelopeHeader(keyID string) []byte {
+	header := append([]byte{}, envelopeMagic...)
+	header = append(header, byte(len(keyID)))
+	return append(header, keyID...)
+}
+
+// Encrypt encrypts the plaintext with a new data key, wrapped with the active key
+func (k *EncryptionKeyring) Encrypt(plaintext string) ([]byte, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
 
-	block, err := aes.NewCipher(keyBytes)
+	header := envelopeHeader(k.activeID)
+	wrappedKey, err := sealAESGCM(k.keys[k.activeID], dataKey, header)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := sealAESGCM(dataKey, []byte(plaintext), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := append(header, wrappedKey...)
+	return append(envelope, ciphertext...), nil
+}
+
+// Decrypt decrypts a ciphertext encrypted with any key in the keyring, including
+// ones encrypted with the legacy key before keys were versioned
+func (k *EncryptionKeyring) Decrypt(encrypted []byte) (string, error) {
+	if !bytes.HasPrefix(encrypted, envelopeMagic) {
+		plaintext, err := k.openLegacy(encrypted)
+		return string(plaintext), err
+	}
+
+	dataKey, ciphertext, err := k.unwrap(encrypted)
+	if err != nil {
+		// a legacy ciphertext's random nonce can start with the magic bytes
+		if plaintext, legacyErr := k.openLegacy(encrypted); legacyErr == nil {
+			return string(plaintext), nil
+		}
+		return "", err
+	}
+
+	plaintext, err := openAESGCM(dataKey, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// openLegacy decrypts a ciphertext encrypted with the legacy key before keys
+// were versioned
+func (k *EncryptionKeyring) openLegacy(encrypted []byte) ([]byte, error) {
+	key, ok := k.keys[""]
+	if !ok {
+		return nil, fmt.Errorf("%w ''", ErrUnknownEncryptionKey)
+	}
+	return openAESGCM(key, encrypted, nil)
+}
+
+// Rewrap returns the ciphertext with its data key wrapped by the active key,
+// without decrypting the ciphertext itself. Legacy ciphertexts are re-encrypted.
+func (k *EncryptionKeyring) Rewrap(encrypted []byte) ([]byte, error) {
+	if bytes.HasPrefix(encrypted, k.ActivePrefix()) {
+		return encrypted, nil
+	}
+	if !bytes.HasPrefix(encrypted, envelopeMagic) {
+		plaintext, err := k.Decrypt(encrypted)
+		if err != nil {
+			return nil, err
+		}
+		return k.Encrypt(plaintext)
+	}
+
+	dataKey, ciphertext, err := k.unwrap(encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	header := envelopeHeader(k.activeID)
+	wrappedKey, err := sealAESGCM(k.keys[k.activeID], dataKey, header)
+	if err != nil {
+		return nil, err
+	}
+	envelope := append(header, wrappedKey...)
+	return append(envelope, ciphertext...), nil
+}
+
+// unwrap decrypts an envelope's data key and returns it with the ciphertext
+func (k *EncryptionKeyring) unwrap(encrypted []byte) (dataKey []byte, ciphertext []byte, err error) {
+	rest := encrypted[len(envelopeMagic):]
+	if len(rest) < 1 || len(rest) < 1+int(rest[0]) {
+		return nil, nil, fmt.Errorf("invalid token")
+	}
+	keyID := string(rest[1 : 1+int(rest[0])])
+	rest = rest[1+int(rest[0]):]
+
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w '%s'", ErrUnknownEncryptionKey, keyID)
+	}
+
+	// nonce, data key and GCM tag
+	wrappedKeySize := 12 + dataKeySize + 16
+	if len(rest) < wrappedKeySize {
+		return nil, nil, fmt.Errorf("invalid token")
+	}
+	dataKey, err = openAESGCM(key, rest[:wrappedKeySize], envelopeHeader(keyID))
+	if err != nil {
+		return nil, nil, err
+	}
+	return dataKey, rest[wrappedKeySize:], nil
+}
+
+func sealAESGCM(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
@@ -36,31 +252,32 @@ func AESGCMEncrypt(token string) ([]byte, error) {
 		return nil, err
 	}
 
-	ciphertext := aesGCM.Seal(nonce, nonce, tokenBytes, nil)
-	return ciphertext, nil
+	return aesGCM.Seal(nonce, nonce, plaintext, additionalData), nil
 }
 
-func AESGCMDecrypt(encrypted []byte) (string, error) {
-	key := hashTo64(config.GetEncryptionKey())
-
+func openAESGCM(key []byte, encrypted []byte, additionalData []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	aesGCM, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	nonceSize := aesGCM.NonceSize()
 	if len(encrypted) < nonceSize {
-		return "", fmt.Errorf("invalid token")
+		return nil, fmt.Errorf("invalid token")
 	}
 	nonce, ciphertext := encrypted[:nonceSize], encrypted[nonceSize:]
-	plaintext, err := aesGCM.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		return "", err
-	}
-	return string(plaintext), nil
+	return aesGCM.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func AESGCMEncrypt(token string) ([]byte, error) {
+	return EncryptionKeys().Encrypt(token)
+}
+
+func AESGCMDecrypt(encrypted []byte) (string, error) {
+	return EncryptionKeys().Decrypt(encrypted)
 }
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"bytes"
+	"encoding/base64"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -8,7 +10,10 @@ import (
 
 func TestEncryptDecrypt(t *testing.T) {
 	t.Run("encrypt -> decrypt", func(t *testing.T) {
-		t.Setenv("ENCRYPTION_KEY", "password")
+		keyring, err := LoadEncryptionKeyring("password", "", "")
+		assert.NoError(t, err)
+		UseEncryptionKeys(keyring)
+
 		encrypted, err := AESGCMEncrypt("this is my token")
 		assert.NoError(t, err)
 
@@ -18,3 +23,105 @@ func TestEncryptDecrypt(t *testing.T) {
 		assert.Equal(t, "this is my token", decrypted)
 	})
 }
+
+func TestEncryptionKeyring(t *testing.T) {
+	oldKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	newKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))
+	envKeys := "2026-09:" + oldKey + ",2026-10:" + newKey
+
+	t.Run("legacy ciphertext decrypts after rotation", func(t *testing.T) {
+		// encrypted with the legacy key before keys were versioned
+		legacy, err := sealAESGCM(hashTo64("password"), []byte("this is my token"), nil)
+		assert.NoError(t, err)
+
+		keyring, err := LoadEncryptionKeyring("password", envKeys, "2026-10")
+		assert.NoError(t, err)
+
+		decrypted, err := keyring.Decrypt(legacy)
+		assert.NoError(t, err)
+		assert.Equal(t, "this is my token", decrypted)
+	})
+
+	t.Run("ciphertext is tagged with key ID", func(t *testing.T) {
+		before, err := LoadEncryptionKeyring("password", envKeys, "2026-09")
+		assert.NoError(t, err)
+		encrypted, err := before.Encrypt("this is my token")
+		assert.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(encrypted, before.ActivePrefix()))
+
+		after, err := LoadEncryptionKeyring("password", envKeys, "2026-10")
+		assert.NoError(t, err)
+		assert.False(t, bytes.HasPrefix(encrypted, after.ActivePrefix()))
+
+		decrypted, err := after.Decrypt(encrypted)
+		assert.NoError(t, err)
+		assert.Equal(t, "this is my token", decrypted)
+	})
+
+	t.Run("rewrap moves ciphertext to active key", func(t *testing.T) {
+		before, err := LoadEncryptionKeyring("password", envKeys, "2026-09")
+		assert.NoError(t, err)
+		encrypted, err := before.Encrypt("this is my token")
+		assert.NoError(t, err)
+		legacy, err := sealAESGCM(hashTo64("password"), []byte("this is my token"), nil)
+		assert.NoError(t, err)
+
+		after, err := LoadEncryptionKeyring("password", envKeys, "2026-10")
+		assert.NoError(t, err)
+
+		for _, ciphertext := range [][]byte{encrypted, legacy} {
+			rewrapped, err := after.Rewrap(ciphertext)
+			assert.NoError(t, err)
+			assert.True(t, bytes.HasPrefix(rewrapped, after.ActivePrefix()))
+
+			// the old key is no longer needed
+			newOnly, err := LoadEncryptionKeyring("", "2026-10:"+newKey, "2026-10")
+			assert.NoError(t, err)
+			decrypted, err := newOnly.Decrypt(rewrapped)
+			assert.NoError(t, err)
+			assert.Equal(t, "this is my token", decrypted)
+
+			unchanged, err := after.Rewrap(rewrapped)
+			assert.NoError(t, err)
+			assert.Equal(t, rewrapped, unchanged)
+		}
+	})
+
+	t.Run("removed key is rejected", func(t *testing.T) {
+		before, err := LoadEncryptionKeyring("password", envKeys, "2026-09")
+		assert.NoError(t, err)
+		encrypted, err := before.Encrypt("this is my token")
+		assert.NoError(t, err)
+
+		after, err := LoadEncryptionKeyring("password", "2026-10:"+newKey, "2026-10")
+		assert.NoError(t, err)
+		_, err = after.Decrypt(encrypted)
+		assert.ErrorIs(t, err, ErrUnknownEncryptionKey)
+	})
+
+	t.Run("no legacy key", func(t *testing.T) {
+		keyring, err := LoadEncryptionKeyring("", envKeys, "2026-10")
+		assert.NoError(t, err)
+
+		// encrypted with the hash of an empty key
+		legacy, err := sealAESGCM(hashTo64(""), []byte("this is my token"), nil)
+		assert.NoError(t, err)
+		_, err = keyring.Decrypt(legacy)
+		assert.ErrorIs(t, err, ErrUnknownEncryptionKey)
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		_, err := LoadEncryptionKeyring("password", envKeys, "2026-11")
+		assert.Error(t, err)
+
+		// there is no legacy key to encrypt with
+		_, err = LoadEncryptionKeyring("", envKeys, "")
+		assert.Error(t, err)
+
+		_, err = LoadEncryptionKeyring("password", "short:"+base64.StdEncoding.EncodeToString([]byte("short")), "")
+		assert.Error(t, err)
+
+		_, err = LoadEncryptionKeyring("password", envKeys+",2026-10:"+newKey, "")
+		assert.Error(t, err)
+	})
+}
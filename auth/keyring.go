@@ -0,0 +1,309 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/andrewbenington/queue-share-api/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is a key tokens are signed or verified with. Keys without a private
+// half can only verify tokens, which lets a retired key keep accepting the
+// tokens it signed until they expire.
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+func (k *SigningKey) CanSign() bool {
+	return k.signKey != nil
+}
+
+// Keyring holds the keys tokens are verified with, identified by the kid in the
+// token header, and the one key new tokens are signed with
+type Keyring struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// JSONWebKey is the public half of an asymmetric signing key, as published in a
+// JWKS document
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+const (
+	// HMAC secrets shorter than the SHA-256 output are easier to guess than
+	// the signature they produce
+	minHMACSecretLength = 32
+)
+
+var (
+	keyring     *Keyring
+	keyringErr  error
+	keyringOnce sync.Once
+
+	ErrUnknownKeyID = errors.New("unknown signing key")
+)
+
+// SigningKeys returns the keyring loaded from the signing key settings. It
+// panics if they are invalid, since no tokens could be signed or verified.
+func SigningKeys() *Keyring {
+	keyringOnce.Do(func() {
+		keyring, keyringErr = LoadKeyring(
+			config.GetSigningSecret(),
+			config.GetSigningKeys(),
+			config.GetSigningKeysDir(),
+			config.GetSigningKeyID(),
+		)
+	})
+	if keyringErr != nil {
+		panic(fmt.Sprintf("load signing keys: %s", keyringErr))
+	}
+	return keyring
+}
+
+// UseSigningKeys replaces the keyring loaded from the signing key settings, so
+// that tests can sign tokens without a config
+func UseSigningKeys(k *Keyring) {
+	keyringOnce.Do(func() {})
+	keyring, keyringErr = k, nil
+}
+
+// LoadKeyring builds a keyring from:
+//   - the legacy signing secret, if it is set, which verifies tokens without a kid
+//   - HMAC keys formatted as comma-separated kid:base64-secret pairs
+//   - a directory of key files named after their kid. <kid>.hmac files hold a
+//     base64 HMAC secret, and <kid>.pem files hold an Ed25519 or RSA private key,
+//     or a public key to verify with only.
+//
+// activeID picks the key new tokens are signed with. If it is empty, tokens are
+// signed with the legacy secret.
+func LoadKeyring(legacySecret []byte, envKeys string, dir string, activeID string) (*Keyring, error) {
+	k := &Keyring{keys: map[string]*SigningKey{}}
+
+	// without a legacy secret, tokens without a kid are rejected rather than
+	// verified with an empty key anyone could sign with
+	if len(legacySecret) > 0 {
+		k.keys[""] = &SigningKey{
+			Method:    jwt.SigningMethodHS256,
+			signKey:   legacySecret,
+			verifyKey: legacySecret,
+		}
+	}
+
+	for _, pair := range strings.Split(envKeys, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kid, encoded, ok := strings.Cut(pair, ":")
+		if !ok || kid == "" {
+			return nil, fmt.Errorf("signing key '%s' should be formatted as kid:base64-secret", pair)
+		}
+		key, err := hmacKey(kid, encoded)
+		if err != nil {
+			return nil, err
+		}
+		if err = k.add(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("read signing keys directory: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			key, err := keyFromFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			if key == nil {
+				continue
+			}
+			if err = k.add(key); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	active, ok := k.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active signing key '%s' not found", activeID)
+	}
+	if !active.CanSign() {
+		return nil, fmt.Errorf("active signing key '%s' has no private key", activeID)
+	}
+	k.active = active
+
+	return k, nil
+}
+
+func (k *Keyring) add(key *SigningKey) error {
+	if _, exists := k.keys[key.ID]; exists {
+		return fmt.Errorf("duplicate signing key '%s'", key.ID)
+	}
+	k.keys[key.ID] = key
+	return nil
+}
+
+func hmacKey(kid string, encoded string) (*SigningKey, error) {
+	secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("decode signing key '%s': %w", kid, err)
+	}
+	if len(secret) < minHMACSecretLength {
+		return nil, fmt.Errorf("signing key '%s' must be at least %d bytes", kid, minHMACSecretLength)
+	}
+	return &SigningKey{
+		ID:        kid,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}, nil
+}
+
+// keyFromFile loads a .hmac or .pem key file, returning nil for other files
+func keyFromFile(path string) (*SigningKey, error) {
+	ext := filepath.Ext(path)
+	kid := strings.TrimSuffix(filepath.Base(path), ext)
+	if ext != ".hmac" && ext != ".pem" {
+		return nil, nil
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read signing key '%s': %w", kid, err)
+	}
+	if ext == ".hmac" {
+		return hmacKey(kid, string(contents))
+	}
+
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, fmt.Errorf("signing key '%s' is not PEM encoded", kid)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("signing key '%s' has unsupported PEM type %s", kid, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse signing key '%s': %w", kid, err)
+	}
+
+	key := &SigningKey{ID: kid}
+	switch typed := parsed.(type) {
+	case ed25519.PrivateKey:
+		key.Method = jwt.SigningMethodEdDSA
+		key.signKey = typed
+		key.verifyKey = typed.Public()
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+		key.verifyKey = typed
+	case *rsa.PrivateKey:
+		key.Method = jwt.SigningMethodRS256
+		key.signKey = typed
+		key.verifyKey = &typed.PublicKey
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+		key.verifyKey = typed
+	default:
+		return nil, fmt.Errorf("signing key '%s' must be an Ed25519 or RSA key", kid)
+	}
+	return key, nil
+}
+
+// Sign signs the claims with the active key, setting the kid header so the
+// token can still be verified after the active key changes
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.Method, claims)
+	if k.active.ID != "" {
+		token.Header["kid"] = k.active.ID
+	}
+	return token.SignedString(k.active.signKey)
+}
+
+// Keyfunc picks the key to verify a token with from its kid header, for use with
+// jwt.Parse. Tokens without a kid were signed with the legacy secret.
+func (k *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w '%s'", ErrUnknownKeyID, kid)
+	}
+	// the algorithm is part of the key, so a token can't pick a weaker one
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.verifyKey, nil
+}
+
+// JWKS returns the public halves of the keyring's asymmetric keys, so other
+// services can verify tokens. HMAC keys are secret and never included.
+func (k *Keyring) JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, key := range k.keys {
+		switch public := key.verifyKey.(type) {
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JSONWebKey{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Algorithm: key.Method.Alg(),
+				Use:       "sig",
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(public),
+			})
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JSONWebKey{
+				KeyType:   "RSA",
+				KeyID:     key.ID,
+				Algorithm: key.Method.Alg(),
+				Use:       "sig",
+				N:         base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].KeyID < set.Keys[j].KeyID
+	})
+	return set
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"id":  "4b9c7a55-3f7e-4d52-9a3e-8c1b9f2d6e10",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func writePEM(t *testing.T, path string, blockType string, der []byte) {
+	t.Helper()
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600)
+	assert.NoError(t, err)
+}
+
+func TestKeyring(t *testing.T) {
+	legacy := []byte("legacy secret")
+	oldSecret := base64.StdEncoding.EncodeToString([]byte("old secret, at least thirty-two bytes"))
+	newSecret := base64.StdEncoding.EncodeToString([]byte("new secret, at least thirty-two bytes"))
+	envKeys := "2026-09:" + oldSecret + ",2026-10:" + newSecret
+
+	t.Run("legacy secret signs without kid", func(t *testing.T) {
+		keyring, err := LoadKeyring(legacy, "", "", "")
+		assert.NoError(t, err)
+
+		signed, err := keyring.Sign(testClaims())
+		assert.NoError(t, err)
+
+		token, err := jwt.Parse(signed, keyring.Keyfunc)
+		assert.NoError(t, err)
+		assert.NotContains(t, token.Header, "kid")
+	})
+
+	t.Run("tokens survive rotation", func(t *testing.T) {
+		before, err := LoadKeyring(legacy, envKeys, "", "2026-09")
+		assert.NoError(t, err)
+		signed, err := before.Sign(testClaims())
+		assert.NoError(t, err)
+		legacySigned, err := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims()).SignedString(legacy)
+		assert.NoError(t, err)
+
+		after, err := LoadKeyring(legacy, envKeys, "", "2026-10")
+		assert.NoError(t, err)
+
+		token, err := jwt.Parse(signed, after.Keyfunc)
+		assert.NoError(t, err)
+		assert.Equal(t, "2026-09", token.Header["kid"])
+
+		_, err = jwt.Parse(legacySigned, after.Keyfunc)
+		assert.NoError(t, err)
+
+		newSigned, err := after.Sign(testClaims())
+		assert.NoError(t, err)
+		token, err = jwt.Parse(newSigned, after.Keyfunc)
+		assert.NoError(t, err)
+		assert.Equal(t, "2026-10", token.Header["kid"])
+	})
+
+	t.Run("removed key is rejected", func(t *testing.T) {
+		before, err := LoadKeyring(legacy, envKeys, "", "2026-09")
+		assert.NoError(t, err)
+		signed, err := before.Sign(testClaims())
+		assert.NoError(t, err)
+
+		after, err := LoadKeyring(legacy, "2026-10:"+newSecret, "", "2026-10")
+		assert.NoError(t, err)
+		_, err = jwt.Parse(signed, after.Keyfunc)
+		assert.ErrorIs(t, err, ErrUnknownKeyID)
+	})
+
+	t.Run("algorithm must match key", func(t *testing.T) {
+		keyring, err := LoadKeyring(legacy, envKeys, "", "2026-10")
+		assert.NoError(t, err)
+
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		assert.NoError(t, err)
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, testClaims())
+		token.Header["kid"] = "2026-10"
+		signed, err := token.SignedString(private)
+		assert.NoError(t, err)
+
+		_, err = jwt.Parse(signed, keyring.Keyfunc)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		_, err := LoadKeyring(legacy, envKeys, "", "2026-11")
+		assert.Error(t, err)
+
+		_, err = LoadKeyring(legacy, "no-secret", "", "")
+		assert.Error(t, err)
+
+		_, err = LoadKeyring(legacy, envKeys+",2026-10:"+newSecret, "", "")
+		assert.Error(t, err)
+
+		_, err = LoadKeyring(legacy, "2026-11:", "", "2026-11")
+		assert.Error(t, err)
+
+		_, err = LoadKeyring(legacy, "2026-11:"+base64.StdEncoding.EncodeToString([]byte("short")), "", "2026-11")
+		assert.Error(t, err)
+
+		dir := t.TempDir()
+		err = os.WriteFile(filepath.Join(dir, "hs-2026-11.hmac"), []byte("\n"), 0600)
+		assert.NoError(t, err)
+		_, err = LoadKeyring(legacy, "", dir, "")
+		assert.Error(t, err)
+
+		// there is no legacy secret to sign with
+		_, err = LoadKeyring(nil, envKeys, "", "")
+		assert.Error(t, err)
+	})
+
+	t.Run("empty key is rejected", func(t *testing.T) {
+		keyring, err := LoadKeyring(nil, envKeys, "", "2026-10")
+		assert.NoError(t, err)
+
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims()).SignedString([]byte{})
+		assert.NoError(t, err)
+		_, err = jwt.Parse(signed, keyring.Keyfunc)
+		assert.ErrorIs(t, err, ErrUnknownKeyID)
+
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+		token.Header["kid"] = ""
+		signed, err = token.SignedString([]byte{})
+		assert.NoError(t, err)
+		_, err = jwt.Parse(signed, keyring.Keyfunc)
+		assert.ErrorIs(t, err, ErrUnknownKeyID)
+	})
+
+	t.Run("keys directory", func(t *testing.T) {
+		dir := t.TempDir()
+
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		assert.NoError(t, err)
+		privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+		assert.NoError(t, err)
+		writePEM(t, filepath.Join(dir, "ed-2026-10.pem"), "PRIVATE KEY", privateDER)
+
+		retiredPublic, retiredPrivate, err := ed25519.GenerateKey(rand.Reader)
+		assert.NoError(t, err)
+		retiredDER, err := x509.MarshalPKIXPublicKey(retiredPublic)
+		assert.NoError(t, err)
+		writePEM(t, filepath.Join(dir, "ed-2026-09.pem"), "PUBLIC KEY", retiredDER)
+
+		err = os.WriteFile(filepath.Join(dir, "hs-2026-08.hmac"), []byte(oldSecret+"\n"), 0600)
+		assert.NoError(t, err)
+		err = os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a key"), 0600)
+		assert.NoError(t, err)
+
+		keyring, err := LoadKeyring(legacy, "", dir, "ed-2026-10")
+		assert.NoError(t, err)
+
+		signed, err := keyring.Sign(testClaims())
+		assert.NoError(t, err)
+		_, err = jwt.Parse(signed, keyring.Keyfunc)
+		assert.NoError(t, err)
+
+		retired := jwt.NewWithClaims(jwt.SigningMethodEdDSA, testClaims())
+		retired.Header["kid"] = "ed-2026-09"
+		retiredSigned, err := retired.SignedString(retiredPrivate)
+		assert.NoError(t, err)
+		_, err = jwt.Parse(retiredSigned, keyring.Keyfunc)
+		assert.NoError(t, err)
+
+		// a public key can't be the active key
+		_, err = LoadKeyring(legacy, "", dir, "ed-2026-09")
+		assert.Error(t, err)
+
+		jwks := keyring.JWKS()
+		assert.Len(t, jwks.Keys, 2)
+		assert.Equal(t, "ed-2026-09", jwks.Keys[0].KeyID)
+		assert.Equal(t, "ed-2026-10", jwks.Keys[1].KeyID)
+		assert.Equal(t, "OKP", jwks.Keys[1].KeyType)
+		assert.Equal(t, "EdDSA", jwks.Keys[1].Algorithm)
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString(public), jwks.Keys[1].X)
+	})
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPassword(t *testing.T) {
+	t.Run("hash -> verify", func(t *testing.T) {
+		hash, err := HashPassword("hunter2")
+		assert.NoError(t, err)
+		assert.Regexp(t, `^\$argon2id\$v=19\$m=19456,t=2,p=1\$`, hash)
+
+		matches, needsRehash, err := VerifyPassword(hash, "hunter2")
+		assert.NoError(t, err)
+		assert.True(t, matches)
+		assert.False(t, needsRehash)
+
+		matches, _, err = VerifyPassword(hash, "hunter3")
+		assert.NoError(t, err)
+		assert.False(t, matches)
+	})
+
+	t.Run("same password hashes differently", func(t *testing.T) {
+		first, err := HashPassword("hunter2")
+		assert.NoError(t, err)
+		second, err := HashPassword("hunter2")
+		assert.NoError(t, err)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("outdated parameters need rehash", func(t *testing.T) {
+		hash, err := hashPasswordWithParams("hunter2", Argon2Params{Memory: 8 * 1024, Time: 1, Threads: 1})
+		assert.NoError(t, err)
+
+		matches, needsRehash, err := VerifyPassword(hash, "hunter2")
+		assert.NoError(t, err)
+		assert.True(t, matches)
+		assert.True(t, needsRehash)
+	})
+
+	t.Run("pgcrypto bcrypt hash", func(t *testing.T) {
+		// crypt('hunter2', gen_salt('bf'))
+		hash := "$2a$06$/4u0w/oq4Kwx57ODvh5t4.ai1pt96W5872MIdWoDjfCZ3d/kVwemS"
+
+		matches, needsRehash, err := VerifyPassword(hash, "hunter2")
+		assert.NoError(t, err)
+		assert.True(t, matches)
+		assert.True(t, needsRehash)
+
+		matches, needsRehash, err = VerifyPassword(hash, "hunter3")
+		assert.NoError(t, err)
+		assert.False(t, matches)
+		assert.False(t, needsRehash)
+	})
+
+	t.Run("invalid hash", func(t *testing.T) {
+		_, _, err := VerifyPassword("not a hash", "hunter2")
+		assert.ErrorIs(t, err, ErrInvalidPasswordHash)
+	})
+}
@@ -0,0 +1,26 @@
+-- name: SpotifyLoginStateInsert :exec
+INSERT INTO spotify_login_states(
+  state,
+  user_id,
+  redirect_uri,
+  code_verifier,
+  expires_at)
+VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5);
+
+-- name: SpotifyLoginStateConsume :one
+DELETE FROM spotify_login_states
+WHERE state = $1
+  AND expires_at > NOW()
+RETURNING
+  user_id,
+  redirect_uri,
+  code_verifier;
+
+-- name: SpotifyLoginStateDeleteExpired :execrows
+DELETE FROM spotify_login_states
+WHERE expires_at <= NOW();
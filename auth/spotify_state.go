@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// how long a user has to finish logging in to Spotify
+	SpotifyLoginStateLifetime = 10 * time.Minute
+)
+
+type SpotifyLoginState struct {
+	UserID       string
+	RedirectURI  string
+	CodeVerifier string
+}
+
+// CreateSpotifyLoginState stores a login state for the user, so any instance
+// Spotify redirects back to can finish the login. It returns the state to send
+// to Spotify and the PKCE code verifier its challenge is derived from.
+func CreateSpotifyLoginState(ctx context.Context, dbtx db.DBTX, userID string, redirectURI string) (state string, codeVerifier string, err error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return "", "", fmt.Errorf("parse user UUID: %w", err)
+	}
+
+	state = uuid.New().String()
+	codeVerifier = oauth2.GenerateVerifier()
+	err = db.New(dbtx).SpotifyLoginStateInsert(ctx, db.SpotifyLoginStateInsertParams{
+		State:        state,
+		UserID:       userUUID,
+		RedirectUri:  redirectURI,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(SpotifyLoginStateLifetime),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return state, codeVerifier, nil
+}
+
+// ConsumeSpotifyLoginState returns the login state and deletes it so it can't be
+// used again. It returns sql.ErrNoRows if the state is unknown or has expired.
+func ConsumeSpotifyLoginState(ctx context.Context, dbtx db.DBTX, state string) (*SpotifyLoginState, error) {
+	row, err := db.New(dbtx).SpotifyLoginStateConsume(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	return &SpotifyLoginState{
+		UserID:       row.UserID.String(),
+		RedirectURI:  row.RedirectUri,
+		CodeVerifier: row.CodeVerifier,
+	}, nil
+}
+
+// DeleteExpiredSpotifyLoginStates removes logins that were never finished
+func DeleteExpiredSpotifyLoginStates(ctx context.Context, dbtx db.DBTX) (int64, error) {
+	return db.New(dbtx).SpotifyLoginStateDeleteExpired(ctx)
+}
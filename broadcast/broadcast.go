@@ -0,0 +1,171 @@
+package broadcast
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	EventNowPlaying       = "now_playing"
+	EventQueue            = "queue"
+	EventGuestsAndMembers = "guests_and_members"
+
+	poll_period = time.Second * 5
+	// give writes that triggered a refresh time to commit and reach Spotify
+	refresh_delay     = time.Millisecond * 500
+	subscriber_buffer = 16
+)
+
+type Event struct {
+	Type string
+	Data json.RawMessage
+}
+
+// SnapshotFunc returns the current state of a room, keyed by event type
+type SnapshotFunc func(ctx context.Context) (map[string]any, error)
+
+type roomFeed struct {
+	snapshot    SnapshotFunc
+	subscribers map[chan Event]struct{}
+	last        map[string]json.RawMessage
+	refresh     chan struct{}
+	cancel      context.CancelFunc
+}
+
+var (
+	feeds     = make(map[string]*roomFeed)
+	feedsLock sync.Mutex
+)
+
+// Subscribe returns a channel of events for a room, starting with the most recent
+// snapshot if the room is already being polled. Every subscriber to a room shares
+// one poller, which is created with the given SnapshotFunc and stops once the last
+// subscriber unsubscribes.
+func Subscribe(roomID string, snapshot SnapshotFunc) (<-chan Event, func()) {
+	feedsLock.Lock()
+	defer feedsLock.Unlock()
+
+	feed, ok := feeds[roomID]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		feed = &roomFeed{
+			snapshot:    snapshot,
+			subscribers: make(map[chan Event]struct{}),
+			last:        make(map[string]json.RawMessage),
+			refresh:     make(chan struct{}, 1),
+			cancel:      cancel,
+		}
+		feeds[roomID] = feed
+		go feed.run(ctx)
+	}
+
+	events := make(chan Event, subscriber_buffer)
+	for _, eventType := range sortedKeys(feed.last) {
+		events <- Event{Type: eventType, Data: feed.last[eventType]}
+	}
+	feed.subscribers[events] = struct{}{}
+
+	unsubscribe := func() {
+		feedsLock.Lock()
+		defer feedsLock.Unlock()
+
+		delete(feed.subscribers, events)
+		if len(feed.subscribers) == 0 && feeds[roomID] == feed {
+			feed.cancel()
+			delete(feeds, roomID)
+		}
+	}
+
+	return events, unsubscribe
+}
+
+// Refresh makes the room's poller take a new snapshot without waiting for the next
+// poll, if anyone is subscribed to the room
+func Refresh(roomID string) {
+	feedsLock.Lock()
+	defer feedsLock.Unlock()
+
+	feed, ok := feeds[roomID]
+	if !ok {
+		return
+	}
+
+	select {
+	case feed.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// HasSubscribers returns true if anyone connected to this server is subscribed to
+// the room, in which case its poller keeps the room's state up to date
+func HasSubscribers(roomID string) bool {
+	feedsLock.Lock()
+	defer feedsLock.Unlock()
+
+	_, ok := feeds[roomID]
+	return ok
+}
+
+func (feed *roomFeed) run(ctx context.Context) {
+	ticker := time.NewTicker(poll_period)
+	defer ticker.Stop()
+
+	feed.poll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-feed.refresh:
+			time.Sleep(refresh_delay)
+			feed.poll(ctx)
+		case <-ticker.C:
+			feed.poll(ctx)
+		}
+	}
+}
+
+func (feed *roomFeed) poll(ctx context.Context) {
+	snapshot, err := feed.snapshot(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Error getting room snapshot: %s", err)
+		}
+		return
+	}
+
+	feedsLock.Lock()
+	defer feedsLock.Unlock()
+
+	for _, eventType := range sortedKeys(snapshot) {
+		data, err := json.Marshal(snapshot[eventType])
+		if err != nil {
+			log.Printf("Error marshalling %s event: %s", eventType, err)
+			continue
+		}
+		if string(data) == string(feed.last[eventType]) {
+			continue
+		}
+
+		feed.last[eventType] = data
+		for subscriber := range feed.subscribers {
+			select {
+			case subscriber <- Event{Type: eventType, Data: data}:
+			default:
+				// slow subscribers miss this event and catch up on the next change
+			}
+		}
+	}
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
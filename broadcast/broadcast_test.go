@@ -0,0 +1,41 @@
+package broadcast
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribe(t *testing.T) {
+	t.Run("subscribers share one snapshot", func(t *testing.T) {
+		polls := make(chan struct{}, 10)
+		snapshot := func(ctx context.Context) (map[string]any, error) {
+			polls <- struct{}{}
+			return map[string]any{EventNowPlaying: "track"}, nil
+		}
+
+		first, unsubscribeFirst := Subscribe("room", snapshot)
+		event := <-first
+		assert.Equal(t, EventNowPlaying, event.Type)
+		assert.Equal(t, `"track"`, string(event.Data))
+
+		assert.True(t, HasSubscribers("room"))
+
+		second, unsubscribeSecond := Subscribe("room", snapshot)
+		event = <-second
+		assert.Equal(t, `"track"`, string(event.Data))
+		assert.Len(t, polls, 1)
+
+		Refresh("room")
+		time.Sleep(refresh_delay * 2)
+		assert.Len(t, polls, 2)
+		assert.Len(t, first, 0, "unchanged snapshots should not be sent")
+
+		unsubscribeFirst()
+		unsubscribeSecond()
+		assert.NotContains(t, feeds, "room")
+		assert.False(t, HasSubscribers("room"))
+	})
+}
@@ -12,14 +12,20 @@ import (
 	"github.com/andrewbenington/queue-share-api/auth"
 	"github.com/andrewbenington/queue-share-api/db"
 	"github.com/andrewbenington/queue-share-api/room"
+	"github.com/andrewbenington/queue-share-api/service"
 	"github.com/andrewbenington/queue-share-api/user"
 	"github.com/google/uuid"
 	"github.com/zmb3/spotify/v2"
-	spotifyauth "github.com/zmb3/spotify/v2/auth"
 	"golang.org/x/oauth2"
 )
 
-func ForRoom(ctx context.Context, code string) (statusCode int, client *spotify.Client, err error) {
+// NewProvider returns the MusicProvider Spotify requests are made with. Tests
+// replace it to send requests to a spotifytest.Server instead.
+var NewProvider = func(httpClient *http.Client) service.MusicProvider {
+	return spotify.New(httpClient)
+}
+
+func ForRoom(ctx context.Context, code string) (statusCode int, client service.MusicProvider, err error) {
 	tx, err := db.Service().BeginTx(ctx)
 	if err != nil {
 		return http.StatusInternalServerError, nil, err
@@ -40,9 +46,9 @@ func ForRoom(ctx context.Context, code string) (statusCode int, client *spotify.
 		return http.StatusInternalServerError, nil, err
 	}
 
-	authenticator := spotifyauth.New(spotifyauth.WithScopes(auth.SpotifyScopes...))
+	authenticator := auth.SpotifyAuthenticator()
 	httpClient := authenticator.Client(ctx, token)
-	spotifyClient := spotify.New(httpClient)
+	spotifyClient := NewProvider(httpClient)
 
 	// log.Println(token.AccessToken)
 
@@ -70,7 +76,7 @@ func ForRoom(ctx context.Context, code string) (statusCode int, client *spotify.
 	return http.StatusOK, spotifyClient, nil
 }
 
-func ForUser(ctx context.Context, userID uuid.UUID) (statusCode int, client *spotify.Client, err error) {
+func ForUser(ctx context.Context, userID uuid.UUID) (statusCode int, client service.MusicProvider, err error) {
 	tx, err := db.Service().BeginTx(ctx)
 	if err != nil {
 		return http.StatusInternalServerError, nil, err
@@ -91,9 +97,9 @@ func ForUser(ctx context.Context, userID uuid.UUID) (statusCode int, client *spo
 		return http.StatusInternalServerError, nil, err
 	}
 
-	authenticator := spotifyauth.New(spotifyauth.WithScopes(auth.SpotifyScopes...))
+	authenticator := auth.SpotifyAuthenticator()
 	httpClient := authenticator.Client(ctx, token)
-	spotifyClient := spotify.New(httpClient)
+	spotifyClient := NewProvider(httpClient)
 
 	// refresh token if stale
 	if token.Expiry.Before(time.Now()) {
@@ -1,14 +1,22 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"os"
-	"strings"
 	"time"
 
+	"github.com/andrewbenington/queue-share-api/admin"
 	"github.com/andrewbenington/queue-share-api/app"
+	"github.com/andrewbenington/queue-share-api/auth"
+	"github.com/andrewbenington/queue-share-api/config"
 	"github.com/andrewbenington/queue-share-api/db"
 	"github.com/andrewbenington/queue-share-api/engine"
+	"github.com/andrewbenington/queue-share-api/user"
 	"github.com/andrewbenington/queue-share-api/version"
 	"gopkg.in/yaml.v3"
 )
@@ -21,6 +29,18 @@ func main() {
 	}
 	log.Println("version:\n" + string(bytes))
 
+	args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("config:\n%s", err)
+	}
+
+	// fail on startup if the signing or encryption keys are misconfigured
+	auth.SigningKeys()
+	auth.EncryptionKeys()
+
 	a := app.App{}
 	a.Initialize()
 	err = db.Service().Initialize()
@@ -28,23 +48,34 @@ func main() {
 		log.Println(err)
 		// log.Fatal(err)
 	}
+	dbAvailable := err == nil
 
-	addr := "0.0.0.0:8080"
-
-	shouldRunEngine := true
-
-	for _, arg := range os.Args {
-		if arg == "--no-engine" {
-			shouldRunEngine = false
-			continue
+	if len(args) > 0 && args[0] == "promote-admin" {
+		if len(args) != 2 {
+			log.Fatal("usage: queue-share promote-admin <username>")
+		}
+		if err != nil {
+			log.Fatal("promote admin: database unavailable")
 		}
+		err = promoteAdmin(args[1])
+		if err != nil {
+			log.Fatalf("promote admin: %s", err)
+		}
+		log.Printf("%s is now an admin", args[1])
+		return
+	}
 
-		if specifiedAddr, ok := strings.CutPrefix(arg, "--addr="); ok {
-			addr = specifiedAddr
+	if config.GetMigrate() && !dbAvailable {
+		log.Fatal("migrate: database unavailable")
+	}
+	if dbAvailable {
+		err = prepareSchema(config.GetMigrate())
+		if err != nil {
+			log.Fatalf("database schema: %s", err)
 		}
 	}
 
-	if shouldRunEngine {
+	if config.GetEngine().Enabled {
 		now := time.Now()
 		engine.LastFetch = &now
 
@@ -52,5 +83,64 @@ func main() {
 
 	}
 
-	a.Run(addr)
+	a.Run(config.GetAddr())
+}
+
+// prepareSchema applies pending migrations if migrate is set, and refuses to
+// start the server on a database that is dirty or newer than it
+func prepareSchema(migrate bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	if migrate {
+		applied, err := db.Migrate(ctx, db.Service().Pool)
+		for _, migration := range applied {
+			log.Printf("applied migration %d", migration)
+		}
+		if err != nil {
+			return fmt.Errorf("migrate: %w", err)
+		}
+	}
+
+	current, err := db.CheckSchemaVersion(ctx, db.Service().Pool)
+	if err != nil {
+		return err
+	}
+
+	expected, err := db.LatestMigrationVersion()
+	if err != nil {
+		return err
+	}
+	if current.Version < expected {
+		log.Printf("database schema is at version %d, but this server expects %d. Start with --migrate to apply the pending migrations", current.Version, expected)
+	}
+
+	return nil
+}
+
+// promoteAdmin gives the user access to the /admin endpoints. Admins can only be
+// added from the command line, so this is how the first admin is set up.
+func promoteAdmin(username string) error {
+	ctx := context.Background()
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = user.SetRole(ctx, tx, username, user.RoleAdmin)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no user with username '%s'", username)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = admin.RecordAction(ctx, tx, "", "promote-admin "+username, nil, "")
+	if err != nil {
+		return fmt.Errorf("record admin action: %w", err)
+	}
+
+	return tx.Commit(ctx)
 }
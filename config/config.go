@@ -1,80 +1,243 @@
 package config
 
 import (
-	"encoding/base64"
 	"fmt"
 	"os"
+	"path"
 	"strings"
+	"time"
 )
 
+// Config holds the settings the server reads at startup. They are loaded from,
+// in increasing precedence: defaults, a YAML file, environment variables and
+// command-line flags.
 type Config struct {
-	postgresHost string
-	postgresPort string
-	postgresUser string
-	postgresPass string
-	postgresDB   string
+	Env  string `yaml:"env"`
+	Addr string `yaml:"addr"`
+	// Migrate applies pending database migrations on startup
+	Migrate bool `yaml:"migrate"`
 
-	spotifyClientID     string
-	spotifyClientSecret string
-	spotifyRedirectURL  string
+	Postgres   PostgresConfig   `yaml:"postgres"`
+	Spotify    SpotifyConfig    `yaml:"spotify"`
+	Encryption EncryptionConfig `yaml:"encryption"`
+	Signing    SigningConfig    `yaml:"signing"`
+	HTTP       HTTPConfig       `yaml:"http"`
+	Uploads    UploadsConfig    `yaml:"uploads"`
+	Engine     EngineConfig     `yaml:"engine"`
 
-	encryptionKey string
+	// decoded from Signing.Secret
 	signingSecret []byte
-	env           string
+}
+
+type PostgresConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	DB       string `yaml:"db"`
+
+	MaxConns        int32         `yaml:"max_conns"`
+	MaxConnIdleTime time.Duration `yaml:"max_conn_idle_time"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout"`
+}
+
+type SpotifyConfig struct {
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	RedirectURL  string `yaml:"redirect_url"`
+}
+
+type EncryptionConfig struct {
+	// Key is the legacy encryption key. It is only needed to decrypt tokens
+	// encrypted before keys were versioned, or when KeyID is empty.
+	Key   string `yaml:"key"`
+	KeyID string `yaml:"key_id"`
+	// Keys are additional AES-256 keys, formatted as comma-separated
+	// id:base64-key pairs
+	Keys string `yaml:"keys"`
+}
+
+type SigningConfig struct {
+	// Secret is the base64 legacy HMAC secret. It is only needed to verify
+	// tokens without a kid, or when KeyID is empty.
+	Secret string `yaml:"secret"`
+	KeyID  string `yaml:"key_id"`
+	// Keys are additional HMAC keys, formatted as comma-separated
+	// kid:base64-secret pairs
+	Keys    string `yaml:"keys"`
+	KeysDir string `yaml:"keys_dir"`
+}
+
+type HTTPConfig struct {
+	// RequestTimeout cancels the context of requests that take longer
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// CORSOrigins may call the API from a browser. "*" allows any origin.
+	CORSOrigins []string `yaml:"cors_origins"`
+}
+
+type UploadsConfig struct {
+	// Dir holds uploaded streaming history archives until they are imported.
+	// It should persist across restarts so interrupted uploads can be resumed.
+	Dir string `yaml:"dir"`
+}
+
+// EngineConfig controls the background jobs. Each period is the minimum time
+// between two runs of a job, which are checked every CyclePeriod.
+type EngineConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	CyclePeriod          time.Duration `yaml:"cycle_period"`
+	HistoryPeriod        time.Duration `yaml:"history_period"`
+	SpotifyProfilePeriod time.Duration `yaml:"spotify_profile_period"`
+	SaveLogsPeriod       time.Duration `yaml:"save_logs_period"`
+	RoomQueuePeriod      time.Duration `yaml:"room_queue_period"`
+	RoomSchedulePeriod   time.Duration `yaml:"room_schedule_period"`
+	LoginStatesPeriod    time.Duration `yaml:"login_states_period"`
+	ReencryptPeriod      time.Duration `yaml:"reencrypt_period"`
+	// LoadURIsPeriod is used when the last run found nothing to load, and
+	// LoadURIsBusyPeriod when it did
+	LoadURIsPeriod     time.Duration `yaml:"load_uris_period"`
+	LoadURIsBusyPeriod time.Duration `yaml:"load_uris_busy_period"`
 }
 
 var (
-	config Config
+	config = Default()
 )
 
-func init() {
-	signingSecret, err := base64.StdEncoding.DecodeString(os.Getenv("SIGNING_SECRET"))
-	if err != nil {
-		panic("can't decode signing secret")
-	}
-	config = Config{
-		postgresHost: os.Getenv("POSTGRES_HOST"),
-		postgresPort: os.Getenv("POSTGRES_PORT"),
-		postgresUser: os.Getenv("POSTGRES_USER"),
-		postgresPass: os.Getenv("POSTGRES_PASS"),
-		postgresDB:   os.Getenv("POSTGRES_DB"),
-
-		spotifyClientID:     os.Getenv("SPOTIFY_ID"),
-		spotifyClientSecret: os.Getenv("SPOTIFY_SECRET"),
-		spotifyRedirectURL:  os.Getenv("SPOTIFY_REDIRECT"),
-
-		encryptionKey: os.Getenv("ENCRYPTION_KEY"),
-		signingSecret: signingSecret,
-		env:           os.Getenv("ENV"),
-	}
-	if config.env == "" {
-		config.env = "LOCAL"
+// Default returns the settings used when nothing else sets them
+func Default() Config {
+	return Config{
+		Env:  "LOCAL",
+		Addr: "0.0.0.0:8080",
+		Postgres: PostgresConfig{
+			MaxConns:        30,
+			MaxConnIdleTime: 5 * time.Minute,
+			ConnectTimeout:  5 * time.Second,
+		},
+		HTTP: HTTPConfig{
+			RequestTimeout: 300 * time.Second,
+			CORSOrigins:    []string{"*"},
+		},
+		Uploads: UploadsConfig{
+			Dir: path.Join("temp", "uploads"),
+		},
+		Engine: EngineConfig{
+			Enabled:              true,
+			CyclePeriod:          10 * time.Second,
+			HistoryPeriod:        30 * time.Minute,
+			SpotifyProfilePeriod: 24 * time.Hour,
+			SaveLogsPeriod:       10 * time.Second,
+			RoomQueuePeriod:      10 * time.Second,
+			RoomSchedulePeriod:   time.Minute,
+			LoginStatesPeriod:    time.Hour,
+			ReencryptPeriod:      10 * time.Minute,
+			LoadURIsPeriod:       30 * time.Minute,
+			LoadURIsBusyPeriod:   10 * time.Second,
+		},
 	}
 }
 
+func init() {
+	// packages may read the config before main loads it, like in tests, so
+	// start from the environment. Errors are reported by Load.
+	_ = config.applyEnv(os.Getenv)
+	_ = config.resolve()
+}
+
 func GetEncryptionKey() string {
-	return config.encryptionKey
+	return config.Encryption.Key
+}
+
+// GetEncryptionKeyID returns the ID of the key new Spotify tokens are encrypted
+// with. If it is empty, they are encrypted with the encryption key.
+func GetEncryptionKeyID() string {
+	return config.Encryption.KeyID
+}
+
+// GetEncryptionKeys returns additional AES-256 encryption keys, formatted as
+// comma-separated id:base64-key pairs
+func GetEncryptionKeys() string {
+	return config.Encryption.Keys
 }
 
 func GetSigningSecret() []byte {
 	return config.signingSecret
 }
 
+// GetSigningKeyID returns the kid of the key new tokens are signed with. If it
+// is empty, tokens are signed with the signing secret and have no kid.
+func GetSigningKeyID() string {
+	return config.Signing.KeyID
+}
+
+// GetSigningKeys returns additional HMAC signing keys, formatted as
+// comma-separated kid:base64-secret pairs
+func GetSigningKeys() string {
+	return config.Signing.Keys
+}
+
+// GetSigningKeysDir returns the directory signing keys are loaded from
+func GetSigningKeysDir() string {
+	return config.Signing.KeysDir
+}
+
+func GetSpotifyClientID() string {
+	return config.Spotify.ClientID
+}
+
+func GetSpotifyClientSecret() string {
+	return config.Spotify.ClientSecret
+}
+
 func GetSpotifyRedirect() string {
-	return config.spotifyRedirectURL
+	return config.Spotify.RedirectURL
 }
 
 func GetIsProd() bool {
-	return strings.EqualFold(config.env, "PROD")
+	return strings.EqualFold(config.Env, "PROD")
+}
+
+// GetAddr returns the address the server listens on
+func GetAddr() string {
+	return config.Addr
+}
+
+// GetMigrate returns whether pending migrations are applied on startup
+func GetMigrate() bool {
+	return config.Migrate
 }
 
 func GetDBString() string {
-	switch strings.ToUpper(config.env) {
+	p := config.Postgres
+	connectTimeout := int(p.ConnectTimeout.Seconds())
+	switch strings.ToUpper(config.Env) {
 	case "LOCAL":
-		return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s connect_timeout=5 sslmode=disable",
-			config.postgresHost, config.postgresPort, config.postgresUser, config.postgresPass, config.postgresDB)
+		return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s connect_timeout=%d sslmode=disable",
+			p.Host, p.Port, p.User, p.Password, p.DB, connectTimeout)
 	default:
-		return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s connect_timeout=5",
-			config.postgresHost, config.postgresPort, config.postgresUser, config.postgresPass, config.postgresDB)
+		return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s connect_timeout=%d",
+			p.Host, p.Port, p.User, p.Password, p.DB, connectTimeout)
 	}
 }
+
+// GetDBPool returns the connection pool settings
+func GetDBPool() (maxConns int32, maxConnIdleTime time.Duration, connectTimeout time.Duration) {
+	return config.Postgres.MaxConns, config.Postgres.MaxConnIdleTime, config.Postgres.ConnectTimeout
+}
+
+func GetRequestTimeout() time.Duration {
+	return config.HTTP.RequestTimeout
+}
+
+func GetCORSOrigins() []string {
+	return config.HTTP.CORSOrigins
+}
+
+// GetUploadsDir returns the directory uploaded history archives are kept in
+func GetUploadsDir() string {
+	return config.Uploads.Dir
+}
+
+func GetEngine() EngineConfig {
+	return config.Engine
+}
@@ -0,0 +1,295 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Load builds the config from the YAML file named by --config or CONFIG_FILE,
+// environment variables and the flags in args, and validates it. args are the
+// command-line arguments without the program name. The arguments left after
+// the flags are returned.
+func Load(args []string) ([]string, error) {
+	cfg, rest, err := load(args, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	config = *cfg
+	return rest, nil
+}
+
+func load(args []string, getenv func(string) string) (*Config, []string, error) {
+	cfg := Default()
+
+	flags := flag.NewFlagSet("queue-share", flag.ContinueOnError)
+	configFile := flags.String("config", getenv("CONFIG_FILE"), "YAML config file")
+	addr := flags.String("addr", "", "address to listen on")
+	noEngine := flags.Bool("no-engine", false, "don't run the background engine")
+	migrate := flags.Bool("migrate", false, "apply pending database migrations on startup")
+	maxConns := flags.Int("db-max-conns", 0, "maximum database connections")
+	requestTimeout := flags.Duration("request-timeout", 0, "cancel requests that take longer than this")
+	corsOrigins := flags.String("cors-origins", "", "comma-separated origins allowed to call the API, or *")
+	err := flags.Parse(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if *configFile != "" {
+		err = cfg.applyFile(*configFile)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	err = cfg.applyEnv(getenv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// only flags that were passed override the file and environment
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.Addr = *addr
+		case "no-engine":
+			cfg.Engine.Enabled = !*noEngine
+		case "migrate":
+			cfg.Migrate = *migrate
+		case "db-max-conns":
+			cfg.Postgres.MaxConns = int32(*maxConns)
+		case "request-timeout":
+			cfg.HTTP.RequestTimeout = *requestTimeout
+		case "cors-origins":
+			cfg.HTTP.CORSOrigins = splitList(*corsOrigins)
+		}
+	})
+
+	err = cfg.resolve()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = cfg.validate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &cfg, flags.Args(), nil
+}
+
+func (c *Config) applyFile(path string) error {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	// catch misspelled settings instead of ignoring them
+	decoder.KnownFields(true)
+	err = decoder.Decode(c)
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// applyEnv overrides settings with the environment variables that are set
+func (c *Config) applyEnv(getenv func(string) string) error {
+	e := envReader{getenv: getenv}
+
+	e.string(&c.Env, "ENV")
+	e.string(&c.Addr, "ADDR")
+	e.bool(&c.Migrate, "MIGRATE")
+
+	e.string(&c.Postgres.Host, "POSTGRES_HOST")
+	e.string(&c.Postgres.Port, "POSTGRES_PORT")
+	e.string(&c.Postgres.User, "POSTGRES_USER")
+	e.string(&c.Postgres.Password, "POSTGRES_PASS")
+	e.string(&c.Postgres.DB, "POSTGRES_DB")
+	e.int32(&c.Postgres.MaxConns, "DB_MAX_CONNS")
+	e.duration(&c.Postgres.MaxConnIdleTime, "DB_MAX_CONN_IDLE_TIME")
+	e.duration(&c.Postgres.ConnectTimeout, "DB_CONNECT_TIMEOUT")
+
+	e.string(&c.Spotify.ClientID, "SPOTIFY_ID")
+	e.string(&c.Spotify.ClientSecret, "SPOTIFY_SECRET")
+	e.string(&c.Spotify.RedirectURL, "SPOTIFY_REDIRECT")
+
+	e.string(&c.Encryption.Key, "ENCRYPTION_KEY")
+	e.string(&c.Encryption.KeyID, "ENCRYPTION_KEY_ID")
+	e.string(&c.Encryption.Keys, "ENCRYPTION_KEYS")
+
+	e.string(&c.Signing.Secret, "SIGNING_SECRET")
+	e.string(&c.Signing.KeyID, "SIGNING_KEY_ID")
+	e.string(&c.Signing.Keys, "SIGNING_KEYS")
+	e.string(&c.Signing.KeysDir, "SIGNING_KEYS_DIR")
+
+	e.duration(&c.HTTP.RequestTimeout, "REQUEST_TIMEOUT")
+	e.list(&c.HTTP.CORSOrigins, "CORS_ORIGINS")
+
+	e.string(&c.Uploads.Dir, "UPLOADS_DIR")
+
+	e.bool(&c.Engine.Enabled, "ENGINE_ENABLED")
+	e.duration(&c.Engine.CyclePeriod, "ENGINE_CYCLE_PERIOD")
+	e.duration(&c.Engine.HistoryPeriod, "ENGINE_HISTORY_PERIOD")
+	e.duration(&c.Engine.SpotifyProfilePeriod, "ENGINE_SPOTIFY_PROFILE_PERIOD")
+	e.duration(&c.Engine.SaveLogsPeriod, "ENGINE_SAVE_LOGS_PERIOD")
+	e.duration(&c.Engine.RoomQueuePeriod, "ENGINE_ROOM_QUEUE_PERIOD")
+	e.duration(&c.Engine.RoomSchedulePeriod, "ENGINE_ROOM_SCHEDULE_PERIOD")
+	e.duration(&c.Engine.LoginStatesPeriod, "ENGINE_LOGIN_STATES_PERIOD")
+	e.duration(&c.Engine.ReencryptPeriod, "ENGINE_REENCRYPT_PERIOD")
+	e.duration(&c.Engine.LoadURIsPeriod, "ENGINE_LOAD_URIS_PERIOD")
+	e.duration(&c.Engine.LoadURIsBusyPeriod, "ENGINE_LOAD_URIS_BUSY_PERIOD")
+
+	return errors.Join(e.errs...)
+}
+
+// resolve decodes the settings that are stored encoded
+func (c *Config) resolve() error {
+	signingSecret, err := base64.StdEncoding.DecodeString(c.Signing.Secret)
+	if err != nil {
+		return fmt.Errorf("signing.secret (SIGNING_SECRET) is not valid base64: %w", err)
+	}
+	c.signingSecret = signingSecret
+	return nil
+}
+
+// validate checks that every required setting is present and every number is
+// usable, and reports all of the problems at once
+func (c *Config) validate() error {
+	errs := []error{}
+	required := func(value string, name string, env string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s (%s) is required", name, env))
+		}
+	}
+	positive := func(value time.Duration, name string, env string) {
+		if value <= 0 {
+			errs = append(errs, fmt.Errorf("%s (%s) must be positive, not %s", name, env, value))
+		}
+	}
+
+	required(c.Addr, "addr", "ADDR")
+
+	required(c.Postgres.Host, "postgres.host", "POSTGRES_HOST")
+	required(c.Postgres.Port, "postgres.port", "POSTGRES_PORT")
+	required(c.Postgres.User, "postgres.user", "POSTGRES_USER")
+	required(c.Postgres.DB, "postgres.db", "POSTGRES_DB")
+	if c.Postgres.MaxConns <= 0 {
+		errs = append(errs, fmt.Errorf("postgres.max_conns (DB_MAX_CONNS) must be positive, not %d", c.Postgres.MaxConns))
+	}
+	positive(c.Postgres.MaxConnIdleTime, "postgres.max_conn_idle_time", "DB_MAX_CONN_IDLE_TIME")
+	if c.Postgres.ConnectTimeout < time.Second {
+		errs = append(errs, fmt.Errorf("postgres.connect_timeout (DB_CONNECT_TIMEOUT) must be at least 1s, not %s", c.Postgres.ConnectTimeout))
+	}
+
+	required(c.Spotify.ClientID, "spotify.client_id", "SPOTIFY_ID")
+	required(c.Spotify.ClientSecret, "spotify.client_secret", "SPOTIFY_SECRET")
+	required(c.Spotify.RedirectURL, "spotify.redirect_url", "SPOTIFY_REDIRECT")
+
+	if c.Encryption.Key == "" && c.Encryption.KeyID == "" {
+		errs = append(errs, errors.New("encryption.key (ENCRYPTION_KEY) is required unless encryption.key_id (ENCRYPTION_KEY_ID) is set"))
+	}
+	if len(c.signingSecret) == 0 && c.Signing.KeyID == "" {
+		errs = append(errs, errors.New("signing.secret (SIGNING_SECRET) is required unless signing.key_id (SIGNING_KEY_ID) is set"))
+	}
+
+	positive(c.HTTP.RequestTimeout, "http.request_timeout", "REQUEST_TIMEOUT")
+	if len(c.HTTP.CORSOrigins) == 0 {
+		errs = append(errs, errors.New("http.cors_origins (CORS_ORIGINS) needs at least one origin, or *"))
+	}
+
+	required(c.Uploads.Dir, "uploads.dir", "UPLOADS_DIR")
+
+	positive(c.Engine.CyclePeriod, "engine.cycle_period", "ENGINE_CYCLE_PERIOD")
+	positive(c.Engine.HistoryPeriod, "engine.history_period", "ENGINE_HISTORY_PERIOD")
+	positive(c.Engine.SpotifyProfilePeriod, "engine.spotify_profile_period", "ENGINE_SPOTIFY_PROFILE_PERIOD")
+	positive(c.Engine.SaveLogsPeriod, "engine.save_logs_period", "ENGINE_SAVE_LOGS_PERIOD")
+	positive(c.Engine.RoomQueuePeriod, "engine.room_queue_period", "ENGINE_ROOM_QUEUE_PERIOD")
+	positive(c.Engine.RoomSchedulePeriod, "engine.room_schedule_period", "ENGINE_ROOM_SCHEDULE_PERIOD")
+	positive(c.Engine.LoginStatesPeriod, "engine.login_states_period", "ENGINE_LOGIN_STATES_PERIOD")
+	positive(c.Engine.ReencryptPeriod, "engine.reencrypt_period", "ENGINE_REENCRYPT_PERIOD")
+	positive(c.Engine.LoadURIsPeriod, "engine.load_uris_period", "ENGINE_LOAD_URIS_PERIOD")
+	positive(c.Engine.LoadURIsBusyPeriod, "engine.load_uris_busy_period", "ENGINE_LOAD_URIS_BUSY_PERIOD")
+
+	return errors.Join(errs...)
+}
+
+// envReader parses environment variables into settings, skipping the ones
+// that are unset or empty and collecting parse errors
+type envReader struct {
+	getenv func(string) string
+	errs   []error
+}
+
+func (e *envReader) string(target *string, name string) {
+	if value := e.getenv(name); value != "" {
+		*target = value
+	}
+}
+
+func (e *envReader) list(target *[]string, name string) {
+	if value := e.getenv(name); value != "" {
+		*target = splitList(value)
+	}
+}
+
+func (e *envReader) bool(target *bool, name string) {
+	value := e.getenv(name)
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s should be true or false, not '%s'", name, value))
+		return
+	}
+	*target = parsed
+}
+
+func (e *envReader) int32(target *int32, name string) {
+	value := e.getenv(name)
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s should be a whole number, not '%s'", name, value))
+		return
+	}
+	*target = int32(parsed)
+}
+
+func (e *envReader) duration(target *time.Duration, name string) {
+	value := e.getenv(name)
+	if value == "" {
+		return
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s should be a duration like 30s or 5m, not '%s'", name, value))
+		return
+	}
+	*target = parsed
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
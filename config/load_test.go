@@ -0,0 +1,154 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testEnv(env map[string]string) func(string) string {
+	return func(name string) string {
+		return env[name]
+	}
+}
+
+func requiredEnv() map[string]string {
+	return map[string]string{
+		"POSTGRES_HOST":    "localhost",
+		"POSTGRES_PORT":    "5432",
+		"POSTGRES_USER":    "queue_share",
+		"POSTGRES_DB":      "queue_share",
+		"SPOTIFY_ID":       "client-id",
+		"SPOTIFY_SECRET":   "client-secret",
+		"SPOTIFY_REDIRECT": "http://localhost:3000/spotify-redirect",
+		"ENCRYPTION_KEY":   "encryption key",
+		"SIGNING_SECRET":   "c2lnbmluZyBzZWNyZXQ=",
+	}
+}
+
+func writeFile(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(contents), 0o600)
+	assert.NoError(t, err)
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cfg, rest, err := load(nil, testEnv(requiredEnv()))
+		assert.NoError(t, err)
+		assert.Empty(t, rest)
+		assert.Equal(t, int32(30), cfg.Postgres.MaxConns)
+		assert.Equal(t, 300*time.Second, cfg.HTTP.RequestTimeout)
+		assert.Equal(t, []string{"*"}, cfg.HTTP.CORSOrigins)
+		assert.True(t, cfg.Engine.Enabled)
+		assert.Equal(t, []byte("signing secret"), cfg.signingSecret)
+	})
+
+	t.Run("file, then env, then flags", func(t *testing.T) {
+		path := writeFile(t, `
+addr: 0.0.0.0:9000
+postgres:
+  max_conns: 10
+  max_conn_idle_time: 1m
+http:
+  request_timeout: 20s
+  cors_origins: [https://file.example]
+engine:
+  history_period: 1h
+`)
+		env := requiredEnv()
+		env["CONFIG_FILE"] = path
+		env["DB_MAX_CONNS"] = "15"
+		env["REQUEST_TIMEOUT"] = "40s"
+
+		cfg, rest, err := load([]string{"--request-timeout=1m", "--no-engine", "promote-admin", "bob"}, testEnv(env))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"promote-admin", "bob"}, rest)
+
+		// only in the file
+		assert.Equal(t, "0.0.0.0:9000", cfg.Addr)
+		assert.Equal(t, time.Minute, cfg.Postgres.MaxConnIdleTime)
+		assert.Equal(t, []string{"https://file.example"}, cfg.HTTP.CORSOrigins)
+		assert.Equal(t, time.Hour, cfg.Engine.HistoryPeriod)
+		// the environment overrides the file
+		assert.Equal(t, int32(15), cfg.Postgres.MaxConns)
+		// flags override both
+		assert.Equal(t, time.Minute, cfg.HTTP.RequestTimeout)
+		assert.False(t, cfg.Engine.Enabled)
+		// untouched defaults
+		assert.Equal(t, 10*time.Second, cfg.Engine.CyclePeriod)
+	})
+
+	t.Run("list flag", func(t *testing.T) {
+		cfg, _, err := load([]string{"--cors-origins", "https://a.example, https://b.example"}, testEnv(requiredEnv()))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"https://a.example", "https://b.example"}, cfg.HTTP.CORSOrigins)
+	})
+
+	t.Run("missing required settings", func(t *testing.T) {
+		_, _, err := load(nil, testEnv(map[string]string{}))
+		assert.ErrorContains(t, err, "postgres.host (POSTGRES_HOST) is required")
+		assert.ErrorContains(t, err, "spotify.client_id (SPOTIFY_ID) is required")
+		assert.ErrorContains(t, err, "signing.secret (SIGNING_SECRET) is required")
+		assert.ErrorContains(t, err, "encryption.key (ENCRYPTION_KEY) is required")
+	})
+
+	t.Run("encryption key is optional with a key ID", func(t *testing.T) {
+		env := requiredEnv()
+		delete(env, "ENCRYPTION_KEY")
+		env["ENCRYPTION_KEY_ID"] = "2026-10"
+		cfg, _, err := load(nil, testEnv(env))
+		assert.NoError(t, err)
+		assert.Empty(t, cfg.Encryption.Key)
+	})
+
+	t.Run("signing secret is optional with a key ID", func(t *testing.T) {
+		env := requiredEnv()
+		delete(env, "SIGNING_SECRET")
+		env["SIGNING_KEY_ID"] = "2026-10"
+		cfg, _, err := load(nil, testEnv(env))
+		assert.NoError(t, err)
+		assert.Empty(t, cfg.signingSecret)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		env := requiredEnv()
+		env["SIGNING_SECRET"] = "not base64!"
+		_, _, err := load(nil, testEnv(env))
+		assert.ErrorContains(t, err, "SIGNING_SECRET")
+
+		env = requiredEnv()
+		env["REQUEST_TIMEOUT"] = "soon"
+		env["DB_MAX_CONNS"] = "lots"
+		_, _, err = load(nil, testEnv(env))
+		assert.ErrorContains(t, err, "REQUEST_TIMEOUT should be a duration")
+		assert.ErrorContains(t, err, "DB_MAX_CONNS should be a whole number")
+
+		_, _, err = load([]string{"--db-max-conns=0"}, testEnv(requiredEnv()))
+		assert.ErrorContains(t, err, "postgres.max_conns (DB_MAX_CONNS) must be positive")
+	})
+
+	t.Run("unknown file setting", func(t *testing.T) {
+		path := writeFile(t, "http:\n  request_timeot: 5s\n")
+		_, _, err := load([]string{"--config", path}, testEnv(requiredEnv()))
+		assert.ErrorContains(t, err, "request_timeot")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, _, err := load([]string{"--config", filepath.Join(t.TempDir(), "missing.yaml")}, testEnv(requiredEnv()))
+		assert.ErrorContains(t, err, "read config file")
+	})
+}
+
+func TestExampleConfig(t *testing.T) {
+	cfg := Default()
+	err := cfg.applyFile("../config.example.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, Default().Engine, cfg.Engine)
+	assert.Equal(t, Default().HTTP, cfg.HTTP)
+	assert.Equal(t, Default().Uploads, cfg.Uploads)
+}
@@ -1,11 +1,13 @@
 package constants
 
 const (
-	ErrorBadRequest       = "Bad Request"
-	ErrorInternal         = "Internal Service Error"
-	ErrorPassword         = "Invalid room password"
-	ErrorNotAuthenticated = "Not Authenticated"
-	ErrorUsernameInUse    = "Username in use"
-	ErrorNotFound         = "Not found"
-	ErrorForbidden        = "Forbidden"
+	ErrorBadRequest        = "Bad Request"
+	ErrorInternal          = "Internal Service Error"
+	ErrorPassword          = "Invalid room password"
+	ErrorNotAuthenticated  = "Not Authenticated"
+	ErrorUsernameInUse     = "Username in use"
+	ErrorNotFound          = "Not found"
+	ErrorForbidden         = "Forbidden"
+	ErrorIncorrectPassword = "Incorrect password"
+	ErrorExportRequired    = "Export your data before deleting your account"
 )
@@ -0,0 +1,265 @@
+package controller
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/auth"
+	"github.com/andrewbenington/queue-share-api/constants"
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/requests"
+	"github.com/andrewbenington/queue-share-api/user"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// how recently a user must have exported their data to delete their account
+	user_delete_export_window = time.Hour * 24
+)
+
+type UpdateUserBody struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+}
+
+type ChangePasswordBody struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type DeleteUserBody struct {
+	Password string `json:"password"`
+}
+
+// UpdateUser changes the user's username and display name. Fields left empty
+// are unchanged.
+func (c *Controller) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(auth.UserContextKey).(string)
+	if !ok {
+		requests.RespondAuthError(w)
+		return
+	}
+
+	var req UpdateUserBody
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		requests.RespondBadRequest(w)
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	u, err := user.GetByID(ctx, tx, userID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if username := strings.TrimSpace(req.Username); username != "" {
+		u.Username = username
+	}
+	if displayName := strings.TrimSpace(req.DisplayName); displayName != "" {
+		u.DisplayName = displayName
+	}
+
+	err = user.UpdateNames(ctx, tx, userID, u.Username, u.DisplayName)
+	if err != nil {
+		// checked against username_case_insensitive
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			requests.RespondWithError(w, http.StatusConflict, constants.ErrorUsernameInUse)
+			return
+		}
+		log.Printf("update user names: %s", err)
+		requests.RespondInternalError(w)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(u)
+}
+
+// ChangePassword changes the user's password after checking their current one,
+// and logs them out of their other sessions
+func (c *Controller) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(auth.UserContextKey).(string)
+	if !ok {
+		requests.RespondAuthError(w)
+		return
+	}
+	sessionID, _ := ctx.Value(auth.SessionContextKey).(string)
+
+	var req ChangePasswordBody
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil || req.NewPassword == "" {
+		requests.RespondBadRequest(w)
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if !reauthenticate(w, r, tx, userID, req.CurrentPassword) {
+		return
+	}
+
+	err = user.UpdatePassword(ctx, tx, userID, req.NewPassword)
+	if err != nil {
+		log.Printf("update password: %s", err)
+		requests.RespondInternalError(w)
+		return
+	}
+
+	err = user.RevokeOtherSessions(ctx, tx, userID, sessionID)
+	if err != nil {
+		log.Printf("revoke other sessions: %s", err)
+		requests.RespondInternalError(w)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ExportUserData downloads an archive of the user's data. The archive is
+// written as it's read, so an error partway through ends the download early.
+func (c *Controller) ExportUserData(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(auth.UserContextKey).(string)
+	if !ok {
+		requests.RespondAuthError(w)
+		return
+	}
+
+	// checked before the response starts, since errors can't be sent after
+	_, err := user.GetByID(ctx, db.Service().Pool, userID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	filename := fmt.Sprintf("queue-share-export-%s.zip", time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+
+	err = user.WriteDataArchive(ctx, db.Service().Pool, userID, w)
+	if err != nil {
+		log.Printf("export user data: %s", err)
+		return
+	}
+
+	err = user.SetDataExported(ctx, db.Service().Pool, userID)
+	if err != nil {
+		log.Printf("record user data export: %s", err)
+	}
+}
+
+// DeleteUser deletes the user's account after checking their password. The
+// account's data can't be exported afterwards, so it must have been exported
+// within user_delete_export_window first.
+func (c *Controller) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(auth.UserContextKey).(string)
+	if !ok {
+		requests.RespondAuthError(w)
+		return
+	}
+
+	var req DeleteUserBody
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		requests.RespondBadRequest(w)
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if !reauthenticate(w, r, tx, userID, req.Password) {
+		return
+	}
+
+	exported, err := user.ExportedDataSince(ctx, tx, userID, time.Now().Add(-user_delete_export_window))
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+	if !exported {
+		requests.RespondWithError(w, http.StatusConflict, constants.ErrorExportRequired)
+		return
+	}
+
+	err = user.Delete(ctx, tx, userID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reauthenticate checks the password of the logged in user before a sensitive
+// change, responding with an error if it doesn't match
+func reauthenticate(w http.ResponseWriter, r *http.Request, dbtx db.DBTX, userID string, password string) bool {
+	ctx := r.Context()
+
+	u, err := user.GetByID(ctx, dbtx, userID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return false
+	}
+
+	authenticated, err := user.Authenticate(ctx, dbtx, u.Username, password)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("authenticate: %s", err)
+		requests.RespondInternalError(w)
+		return false
+	}
+	if !authenticated || err == sql.ErrNoRows {
+		requests.RespondWithError(w, http.StatusUnauthorized, constants.ErrorIncorrectPassword)
+		return false
+	}
+	return true
+}
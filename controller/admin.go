@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/andrewbenington/queue-share-api/admin"
@@ -185,6 +186,32 @@ func (c *Controller) GetGeneralInfo(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+const auditLogMaxEntries = 500
+
+func (c *Controller) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > auditLogMaxEntries {
+		limit = auditLogMaxEntries
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	entries, err := admin.GetAuditLog(ctx, tx, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(entries)
+}
+
 func userFromDBOrMap(ctx context.Context, userID string, userMap map[string]user.User, tx db.DBTX) (*user.User, error) {
 	if userData, ok := userMap[userID]; ok {
 		return &userData, nil
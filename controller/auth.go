@@ -3,6 +3,7 @@ package controller
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -15,15 +16,27 @@ import (
 	"github.com/andrewbenington/queue-share-api/requests"
 	"github.com/andrewbenington/queue-share-api/service"
 	"github.com/andrewbenington/queue-share-api/user"
-	"github.com/google/uuid"
 	"github.com/zmb3/spotify/v2"
 	spotifyauth "github.com/zmb3/spotify/v2/auth"
+	"golang.org/x/oauth2"
 )
 
 type TokenResponse struct {
-	Token     string     `json:"token"`
-	ExpiresAt time.Time  `json:"expires_at"`
-	User      *user.User `json:"user"`
+	Token            string     `json:"token"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	RefreshToken     string     `json:"refresh_token"`
+	RefreshExpiresAt time.Time  `json:"refresh_expires_at"`
+	User             *user.User `json:"user"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// sessionClientInfo returns the user agent and IP address shown in the user's
+// list of sessions
+func sessionClientInfo(r *http.Request) (userAgent string, ipAddress string) {
+	return r.UserAgent(), requests.ClientIP(r)
 }
 
 func (c *Controller) GetToken(w http.ResponseWriter, r *http.Request) {
@@ -59,22 +72,138 @@ func (c *Controller) GetToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, expiry, err := u.GetJWT()
+	userAgent, ipAddress := sessionClientInfo(r)
+	sessionID, refreshToken, refreshExpiry, err := user.CreateSession(ctx, tx, u.ID, userAgent, ipAddress)
+	if err != nil {
+		log.Printf("create session: %s", err)
+		requests.RespondInternalError(w)
+		return
+	}
+
+	token, expiry, err := u.GetJWT(sessionID)
 	if err != nil {
 		log.Printf("generate jwt: %s", err)
 		requests.RespondInternalError(w)
 		return
 	}
 
+	// also saves the password's new hash if it was rehashed
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
 	resp := TokenResponse{
-		Token:     token,
-		ExpiresAt: expiry,
-		User:      u,
+		Token:            token,
+		ExpiresAt:        expiry,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiry,
+		User:             u,
 	}
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(resp)
 }
 
+// RefreshToken exchanges a refresh token for a new access token and refresh
+// token. Each refresh token can only be used once.
+func (c *Controller) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var body RefreshTokenRequest
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil || body.RefreshToken == "" {
+		requests.RespondBadRequest(w)
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	session, err := user.RefreshSession(ctx, tx, body.RefreshToken)
+	if err == sql.ErrNoRows {
+		// reusing a refresh token revokes its session, which needs to be saved
+		_ = tx.Commit(ctx)
+		requests.RespondAuthError(w)
+		return
+	}
+	if err != nil {
+		log.Printf("refresh session: %s", err)
+		requests.RespondInternalError(w)
+		return
+	}
+
+	u, err := user.GetByID(ctx, tx, session.UserID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	token, expiry, err := u.GetJWT(session.ID)
+	if err != nil {
+		log.Printf("generate jwt: %s", err)
+		requests.RespondInternalError(w)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(TokenResponse{
+		Token:            token,
+		ExpiresAt:        expiry,
+		RefreshToken:     session.RefreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+		User:             u,
+	})
+}
+
+// Logout revokes the session the request was made with
+func (c *Controller) Logout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(auth.UserContextKey).(string)
+	if !ok {
+		requests.RespondAuthError(w)
+		return
+	}
+	sessionID, _ := ctx.Value(auth.SessionContextKey).(string)
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	err = user.RevokeSession(ctx, tx, userID, sessionID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetJWKS publishes the public keys tokens can be signed with, so other services
+// can verify them
+func (c *Controller) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(auth.SigningKeys().JWKS())
+}
+
 type GetSpotifyLoginURLResponse struct {
 	URL string `json:"url"`
 }
@@ -87,16 +216,15 @@ func (c *Controller) GetSpotifyLoginURL(w http.ResponseWriter, r *http.Request)
 	}
 	redirect := r.URL.Query().Get("redirect")
 
-	state := uuid.New().String()
-	auth.SpotifyStatesLock.Lock()
-	auth.SpotifyStates[state] = auth.SpotifyLoginState{
-		UserID:      userID,
-		RedirectURI: redirect,
+	state, codeVerifier, err := auth.CreateSpotifyLoginState(r.Context(), db.Service().Pool, userID, redirect)
+	if err != nil {
+		log.Printf("create spotify login state: %s", err)
+		requests.RespondInternalError(w)
+		return
 	}
-	auth.SpotifyStatesLock.Unlock()
 
-	authenticator := spotifyauth.New(spotifyauth.WithRedirectURL(config.GetSpotifyRedirect()), spotifyauth.WithScopes(auth.SpotifyScopes...))
-	url := authenticator.AuthURL(state)
+	authenticator := auth.SpotifyAuthenticator(spotifyauth.WithRedirectURL(config.GetSpotifyRedirect()))
+	url := authenticator.AuthURL(state, oauth2.S256ChallengeOption(codeVerifier))
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(GetSpotifyLoginURLResponse{URL: url})
@@ -113,12 +241,20 @@ func (c *Controller) SpotifyAuthRedirect(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	auth.SpotifyStatesLock.Lock()
-	loginState, ok := auth.SpotifyStates[state]
-	if ok {
-		delete(auth.SpotifyStates, state)
+	// consumed outside of the transaction so the state can't be reused even if
+	// the login fails
+	loginState, err := auth.ConsumeSpotifyLoginState(ctx, db.Service().Pool, state)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("unknown spotify state")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("unknown spotify state"))
+		return
+	}
+	if err != nil {
+		log.Printf("get spotify login state: %s", err)
+		requests.RespondInternalError(w)
+		return
 	}
-	auth.SpotifyStatesLock.Unlock()
 
 	tx, err := db.Service().BeginTx(ctx)
 	if err != nil {
@@ -127,13 +263,6 @@ func (c *Controller) SpotifyAuthRedirect(w http.ResponseWriter, r *http.Request)
 	}
 	defer tx.Rollback(ctx)
 
-	if !ok {
-		log.Printf("unknown spotify state")
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("unknown spotify state"))
-		return
-	}
-
 	redirectURI, err := url.Parse(loginState.RedirectURI)
 	if err != nil {
 		log.Printf("bad redirect uri: %s", err)
@@ -163,8 +292,8 @@ func (c *Controller) SpotifyAuthRedirect(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	authenticator := spotifyauth.New(spotifyauth.WithRedirectURL(config.GetSpotifyRedirect()), spotifyauth.WithScopes(auth.SpotifyScopes...))
-	token, err := authenticator.Token(ctx, state, r)
+	authenticator := auth.SpotifyAuthenticator(spotifyauth.WithRedirectURL(config.GetSpotifyRedirect()))
+	token, err := authenticator.Token(ctx, state, r, oauth2.VerifierOption(loginState.CodeVerifier))
 	if err != nil {
 		log.Printf("get spotify token: %s\n", err)
 		redirectQuery.Add("error", fmt.Sprintf("Error getting Spotify token: %s", err))
@@ -178,6 +307,14 @@ func (c *Controller) SpotifyAuthRedirect(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// the user has just granted every scope in auth.SpotifyScopes
+	err = user.SetLatestSpotifyPermissions(ctx, tx, loginState.UserID)
+	if err != nil {
+		log.Printf("update user spotify permissions: %s\n", err)
+		redirectQuery.Add("error", fmt.Sprintf("Error updating user Spotify permissions: %s", err))
+		return
+	}
+
 	spotifyClient := spotify.New(authenticator.Client(ctx, token))
 	userData, err := service.GetUser(ctx, spotifyClient)
 	if err != nil {
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/andrewbenington/queue-share-api/broadcast"
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/requests"
+	"github.com/andrewbenington/queue-share-api/room"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type BlocklistRequest struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+func (c *Controller) GetBlocklist(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Moderator {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	blocklist, err := room.GetBlocklist(ctx, tx, reqCtx.Room.ID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(blocklist)
+}
+
+func (c *Controller) AddToBlocklist(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Moderator {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	var body BlocklistRequest
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		requests.RespondBadRequest(w)
+		return
+	}
+
+	value, err := room.NormalizeBlockedValue(body.Kind, body.Value)
+	if err != nil {
+		requests.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	item, err := room.AddToBlocklist(ctx, tx, reqCtx.Room.ID, body.Kind, value, reqCtx.UserID)
+	if err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+			requests.RespondWithError(w, http.StatusConflict, "Already blocked")
+		} else {
+			requests.RespondWithDBError(w, err)
+		}
+		return
+	}
+
+	removed, err := room.RemoveBlockedPendingTracks(ctx, tx, reqCtx.Room.ID, room.Blocklist{*item})
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
+	if removed > 0 {
+		broadcast.Refresh(reqCtx.Room.ID)
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(item)
+}
+
+func (c *Controller) RemoveFromBlocklist(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Moderator {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	itemID := mux.Vars(r)["id"]
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	err = room.RemoveFromBlocklist(ctx, tx, reqCtx.Room.ID, itemID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/andrewbenington/queue-share-api/broadcast"
 	"github.com/andrewbenington/queue-share-api/db"
 	"github.com/andrewbenington/queue-share-api/requests"
 	"github.com/andrewbenington/queue-share-api/room"
@@ -47,6 +48,8 @@ func (*Controller) JoinRoomAsMember(w http.ResponseWriter, r *http.Request) {
 		Room: *reqCtx.Room,
 	}
 
+	broadcast.Refresh(reqCtx.Room.ID)
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(resp)
 }
@@ -98,6 +101,8 @@ func (*Controller) AddMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	broadcast.Refresh(reqCtx.Room.ID)
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(resp)
 }
@@ -145,6 +150,8 @@ func (*Controller) SetModerator(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	broadcast.Refresh(reqCtx.Room.ID)
+
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(resp)
 }
@@ -182,6 +189,8 @@ func (*Controller) DeleteMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	broadcast.Refresh(reqCtx.Room.ID)
+
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(resp)
 }
@@ -230,6 +239,8 @@ func (*Controller) AddGuest(w http.ResponseWriter, r *http.Request) {
 		requests.RespondInternalError(w)
 		return
 	}
+	broadcast.Refresh(reqCtx.Room.ID)
+
 	w.WriteHeader(http.StatusCreated)
 	w.Write(body)
 }
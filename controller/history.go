@@ -1,23 +1,26 @@
 package controller
 
 import (
-	"archive/zip"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
-	"path"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/andrewbenington/queue-share-api/config"
 	"github.com/andrewbenington/queue-share-api/db"
 	"github.com/andrewbenington/queue-share-api/engine"
 	"github.com/andrewbenington/queue-share-api/history"
 	"github.com/andrewbenington/queue-share-api/requests"
 	"github.com/andrewbenington/queue-share-api/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 )
 
 const (
@@ -126,6 +129,36 @@ func (c *StatsController) GetAllHistory(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// HistoryUploadJob reports the progress of importing an uploaded streaming
+// history archive
+type HistoryUploadJob struct {
+	ID          uuid.UUID `json:"id"`
+	Source      string    `json:"source"`
+	Status      string    `json:"status"`
+	FilesTotal  int32     `json:"files_total"`
+	FilesDone   int32     `json:"files_done"`
+	EntriesDone int32     `json:"entries_done"`
+	Error       *string   `json:"error"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+}
+
+func historyUploadJobFromRow(job *db.HistoryUploadJob) HistoryUploadJob {
+	return HistoryUploadJob{
+		ID:          job.ID,
+		Source:      job.Source,
+		Status:      job.Status,
+		FilesTotal:  job.FilesTotal,
+		FilesDone:   job.FilesDone,
+		EntriesDone: job.EntriesDone,
+		Error:       job.Error,
+		Created:     job.Created,
+		Updated:     job.Updated,
+	}
+}
+
+// UploadHistory saves a Spotify Extended Streaming History zip and imports it
+// in the background. The response has the ID of the job to poll for progress.
 func (c *StatsController) UploadHistory(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -135,81 +168,122 @@ func (c *StatsController) UploadHistory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	zipData, err := io.ReadAll(r.Body)
+	archivePath, err := saveUpload(r.Body, "history-*.zip")
 	if err != nil {
-		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		log.Printf("save history upload: %s", err)
+		http.Error(w, "Error saving uploaded file", http.StatusInternalServerError)
 		return
 	}
 
-	tempFile, err := os.CreateTemp("", "history-zip-*.zip")
+	job, err := history.CreateUploadJob(ctx, db.Service().Pool, userUUID, archivePath)
 	if err != nil {
-		http.Error(w, "Error creating temporary file", http.StatusInternalServerError)
+		os.Remove(archivePath)
+	}
+	if history.IsUploadRejected(err) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer tempFile.Close()
-
-	_, err = tempFile.Write(zipData)
 	if err != nil {
-		http.Error(w, "Error writing temp file", http.StatusInternalServerError)
+		requests.RespondWithDBError(w, err)
 		return
 	}
 
-	zipReader, err := zip.OpenReader(tempFile.Name())
+	// the import outlives the request
+	go history.RunUploadJob(context.Background(), job)
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(historyUploadJobFromRow(job))
+}
+
+// saveUpload streams an uploaded file to the uploads directory, where it stays
+// until it is imported so a failed import can be resumed
+func saveUpload(body io.Reader, pattern string) (string, error) {
+	err := os.MkdirAll(config.GetUploadsDir(), 0755)
 	if err != nil {
-		http.Error(w, "Error opening zip file", http.StatusInternalServerError)
+		return "", err
+	}
+
+	file, err := os.CreateTemp(config.GetUploadsDir(), pattern)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, body)
+	if err == nil {
+		err = file.Sync()
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
+func (c *StatsController) GetHistoryUpload(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userUUID, err := userUUIDFromRequest(r)
+	if err != nil {
+		requests.RespondWithError(w, 401, err.Error())
 		return
 	}
-	defer zipReader.Close()
 
-	tx, err := db.Service().BeginTx(ctx)
+	jobID, err := uuid.Parse(mux.Vars(r)["job_id"])
 	if err != nil {
-		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		requests.RespondBadRequest(w)
 		return
 	}
-	defer tx.Rollback(ctx)
 
-	for _, file := range zipReader.File {
-		if strings.EqualFold(path.Base(file.Name), "Userdata.json") {
-			http.Error(w, "This is the wrong file. Please upload your \"Extended Streaming History\", NOT your \"Account Data\".", http.StatusBadRequest)
-			return
-		}
-		if !strings.HasPrefix(path.Base(file.Name), "Streaming_History_Audio") {
-			continue
-		}
+	job, err := history.GetUploadJob(ctx, db.Service().Pool, userUUID, jobID)
+	if errors.Is(err, history.ErrUploadNotFound) {
+		requests.RespondNotFound(w)
+		return
+	}
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
 
-		// Open the file from the zip archive
-		zippedFile, err := file.Open()
-		if err != nil {
-			http.Error(w, "Error opening file from zip", http.StatusInternalServerError)
-			return
-		}
-		defer zippedFile.Close()
+	json.NewEncoder(w).Encode(historyUploadJobFromRow(job))
+}
 
-		entryData := []history.StreamingEntry{}
-		err = json.NewDecoder(zippedFile).Decode(&entryData)
-		if err != nil {
-			log.Printf("Error decoding Spotify JSON: %s", err)
-			http.Error(w, "Error decoding JSON", http.StatusInternalServerError)
-			return
-		}
+// ResumeHistoryUpload restarts a failed or interrupted upload, skipping the
+// files that were already imported
+func (c *StatsController) ResumeHistoryUpload(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
-		err = history.InsertEntriesFromHistory(ctx, tx, userUUID, entryData)
-		if err != nil {
-			fmt.Println(err)
-			http.Error(w, "Error uploading history", http.StatusInternalServerError)
-			return
-		}
+	userUUID, err := userUUIDFromRequest(r)
+	if err != nil {
+		requests.RespondWithError(w, 401, err.Error())
+		return
+	}
 
-		fmt.Printf("Uploaded file %s\n", file.Name)
+	jobID, err := uuid.Parse(mux.Vars(r)["job_id"])
+	if err != nil {
+		requests.RespondBadRequest(w)
+		return
 	}
 
-	err = tx.Commit(ctx)
+	job, err := history.ResumeUploadJob(ctx, db.Service().Pool, userUUID, jobID)
+	if errors.Is(err, history.ErrUploadNotFound) {
+		requests.RespondNotFound(w)
+		return
+	}
+	if errors.Is(err, history.ErrUploadNotResumable) {
+		requests.RespondWithError(w, http.StatusConflict, "Only failed or interrupted uploads can be resumed")
+		return
+	}
 	if err != nil {
-		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		requests.RespondWithDBError(w, err)
 		return
 	}
 
+	go history.RunUploadJob(context.Background(), job)
+
 	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(historyUploadJobFromRow(job))
 }
 
 func (c *StatsController) GetAllStreamsByURI(w http.ResponseWriter, r *http.Request) {
@@ -277,6 +351,12 @@ func getFilterParams(r *http.Request) history.FilterParams {
 		albumURI = &albumURIParam
 	}
 
+	sourcesParam := r.URL.Query().Get("sources")
+	var sources []string
+	if sourcesParam != "" {
+		sources = strings.Split(sourcesParam, ",")
+	}
+
 	timeframeParam := r.URL.Query().Get("timeframe")
 	var timeframe history.Timeframe = "month"
 	if timeframeParam == "day" || timeframeParam == "week" || timeframeParam == "year" || timeframeParam == "all_time" {
@@ -303,6 +383,7 @@ func getFilterParams(r *http.Request) history.FilterParams {
 		Max:         int32(max),
 		ArtistURIs:  artistURIs,
 		AlbumURI:    albumURI,
+		Sources:     sources,
 		Start:       &start,
 		End:         &end,
 		Timeframe:   timeframe,
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/history"
+	"github.com/andrewbenington/queue-share-api/requests"
+)
+
+// ExportHistory streams every stream in the user's history as CSV, JSON Lines
+// or Parquet. It accepts the same filters as the stats endpoints, except that
+// short streams are included unless minimum_milliseconds is set.
+func (c *StatsController) ExportHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userUUID, err := userUUIDFromRequest(r)
+	if err != nil {
+		requests.RespondWithError(w, 401, err.Error())
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = history.ExportFormatCSV
+	}
+	contentType, ok := history.ExportContentType[format]
+	if !ok {
+		requests.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("unsupported export format %s", format))
+		return
+	}
+
+	filter := getFilterParams(r)
+	if r.URL.Query().Get("minimum_milliseconds") == "" {
+		filter.MinMSPlayed = 0
+	}
+
+	filename := fmt.Sprintf("queue-share-history-%s.%s", time.Now().Format("2006-01-02"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+
+	// the status has already been sent, so a failure can only cut the file short
+	err = history.ExportHistory(ctx, db.Service().Pool, userUUID, filter, format, w)
+	if err != nil {
+		log.Printf("export history for %s: %s", userUUID, err)
+	}
+}
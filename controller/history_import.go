@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/andrewbenington/queue-share-api/client"
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/history"
+	"github.com/andrewbenington/queue-share-api/requests"
+	"github.com/andrewbenington/queue-share-api/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const DEFAULT_REVIEW_LIMIT = 100
+
+// max_history_import_size is the largest Last.fm or ListenBrainz export that
+// can be imported
+const max_history_import_size = 512 << 20
+
+// ImportHistory saves a Last.fm or ListenBrainz export and imports it into the
+// user's history in the background. Scrobbles that can't be matched to a
+// Spotify track are queued for review. The response has the ID of the job to
+// poll for progress.
+func (c *StatsController) ImportHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userUUID, err := userUUIDFromRequest(r)
+	if err != nil {
+		requests.RespondWithError(w, 401, err.Error())
+		return
+	}
+
+	source := mux.Vars(r)["source"]
+	if source != history.SourceLastFM && source != history.SourceListenBrainz {
+		requests.RespondNotFound(w)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, max_history_import_size)
+	exportPath, err := saveUpload(body, "import-*")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		requests.RespondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Export is larger than %d MB", max_history_import_size>>20))
+		return
+	}
+	if err != nil {
+		log.Printf("save history import: %s", err)
+		http.Error(w, "Error saving uploaded file", http.StatusInternalServerError)
+		return
+	}
+
+	job, err := history.CreateImportJob(ctx, db.Service().Pool, userUUID, source, exportPath)
+	if err != nil {
+		os.Remove(exportPath)
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	// the import outlives the request
+	go history.RunUploadJob(context.Background(), job)
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(historyUploadJobFromRow(job))
+}
+
+type ImportReviewResponse struct {
+	Review []*db.HistoryImportReview `json:"review"`
+}
+
+func (c *StatsController) GetImportReview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userUUID, err := userUUIDFromRequest(r)
+	if err != nil {
+		requests.RespondWithError(w, 401, err.Error())
+		return
+	}
+
+	limitParam := r.URL.Query().Get("limit")
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil || limit > DEFAULT_REVIEW_LIMIT {
+		limit = DEFAULT_REVIEW_LIMIT
+	}
+
+	review, err := history.GetImportReview(ctx, db.Service().Pool, userUUID, int32(limit))
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(ImportReviewResponse{Review: review})
+}
+
+type ResolveImportReviewRequest struct {
+	SpotifyTrackURI string `json:"spotify_track_uri"`
+}
+
+// ResolveImportReview imports a scrobble waiting for review as a stream of the
+// Spotify track the user picked
+func (c *StatsController) ResolveImportReview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userUUID, err := userUUIDFromRequest(r)
+	if err != nil {
+		requests.RespondWithError(w, 401, err.Error())
+		return
+	}
+
+	reviewID, err := uuid.Parse(mux.Vars(r)["review_id"])
+	if err != nil {
+		requests.RespondBadRequest(w)
+		return
+	}
+
+	var req ResolveImportReviewRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		requests.RespondBadRequest(w)
+		return
+	}
+	trackID, err := service.IDFromURI(req.SpotifyTrackURI)
+	if err != nil {
+		requests.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	code, spClient, err := client.ForUser(ctx, userUUID)
+	if err != nil {
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	track, err := service.GetTrack(ctx, spClient, trackID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	err = history.ResolveImportReview(ctx, tx, userUUID, reviewID, *track)
+	if errors.Is(err, history.ErrReviewNotFound) {
+		requests.RespondNotFound(w)
+		return
+	}
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DismissImportReview removes a scrobble from the review queue without
+// importing it
+func (c *StatsController) DismissImportReview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userUUID, err := userUUIDFromRequest(r)
+	if err != nil {
+		requests.RespondWithError(w, 401, err.Error())
+		return
+	}
+
+	reviewID, err := uuid.Parse(mux.Vars(r)["review_id"])
+	if err != nil {
+		requests.RespondBadRequest(w)
+		return
+	}
+
+	err = history.DismissImportReview(ctx, db.Service().Pool, userUUID, reviewID)
+	if errors.Is(err, history.ErrReviewNotFound) {
+		requests.RespondNotFound(w)
+		return
+	}
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
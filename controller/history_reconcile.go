@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/history"
+	"github.com/andrewbenington/queue-share-api/requests"
+)
+
+const DEFAULT_MERGES_LIMIT = 100
+
+type HistoryMergesResponse struct {
+	Merges []*db.HistoryMerge `json:"merges"`
+}
+
+// GetHistoryMerges lists the streams recorded by the recently played poller
+// that were merged into uploaded streams, most recent first
+func (c *StatsController) GetHistoryMerges(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userUUID, err := userUUIDFromRequest(r)
+	if err != nil {
+		requests.RespondWithError(w, 401, err.Error())
+		return
+	}
+
+	limitParam := r.URL.Query().Get("limit")
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil || limit > DEFAULT_MERGES_LIMIT {
+		limit = DEFAULT_MERGES_LIMIT
+	}
+
+	merges, err := history.GetHistoryMerges(ctx, db.Service().Pool, userUUID, int32(limit))
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(HistoryMergesResponse{Merges: merges})
+}
+
+// ReconcileHistory merges the user's duplicate streams now, rather than after
+// their next upload, and responds with what was merged
+func (c *StatsController) ReconcileHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userUUID, err := userUUIDFromRequest(r)
+	if err != nil {
+		requests.RespondWithError(w, 401, err.Error())
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	merges, err := history.ReconcileHistory(ctx, tx, userUUID, nil)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(HistoryMergesResponse{Merges: merges})
+}
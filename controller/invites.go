@@ -0,0 +1,263 @@
+package controller
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/broadcast"
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/requests"
+	"github.com/andrewbenington/queue-share-api/room"
+	"github.com/gorilla/mux"
+)
+
+type CreateInviteRequest struct {
+	Role      string     `json:"role"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxUses   *int       `json:"max_uses"`
+}
+
+type RedeemInviteRequest struct {
+	Token string `json:"token"`
+	Name  string `json:"name"`
+}
+
+func (c *Controller) GetInvites(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Host {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	invites, err := room.GetActiveInvites(ctx, tx, reqCtx.Room.ID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(invites)
+}
+
+// CreateInvite mints an invite token that grants a role in the room without the
+// room password
+func (c *Controller) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Host {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	var body CreateInviteRequest
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		requests.RespondBadRequest(w)
+		return
+	}
+
+	if body.Role == "" {
+		body.Role = room.InviteRoleGuest
+	}
+	if !room.IsInviteRole(body.Role) {
+		requests.RespondWithError(w, http.StatusBadRequest, "Role must be guest, member or moderator")
+		return
+	}
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		requests.RespondWithError(w, http.StatusBadRequest, "Expiry must be in the future")
+		return
+	}
+	if body.MaxUses != nil && *body.MaxUses <= 0 {
+		requests.RespondWithError(w, http.StatusBadRequest, "Use limit must be positive")
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	invite, err := room.CreateInvite(ctx, tx, reqCtx.Room.ID, reqCtx.UserID, body.Role, body.ExpiresAt, body.MaxUses)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invite)
+}
+
+func (c *Controller) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Host {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	inviteID := mux.Vars(r)["id"]
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	err = room.RevokeInvite(ctx, tx, reqCtx.Room.ID, inviteID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
+	broadcast.Refresh(reqCtx.Room.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RedeemInvite grants the role in an invite token. Guests are added to the room
+// under the given name and send the token with later requests in place of the
+// room password. Members and moderators must be logged in, and are added to the
+// room's members.
+func (c *Controller) RedeemInvite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	var body RedeemInviteRequest
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		requests.RespondBadRequest(w)
+		return
+	}
+
+	claims, err := room.ParseInviteToken(body.Token)
+	if err != nil || claims.RoomID != reqCtx.Room.ID {
+		requests.RespondWithError(w, http.StatusForbidden, "This invite is not valid")
+		return
+	}
+
+	roleLevel := Guest
+	switch claims.Role {
+	case room.InviteRoleMember:
+		roleLevel = Member
+	case room.InviteRoleModerator:
+		roleLevel = Moderator
+	}
+
+	if roleLevel >= Member && reqCtx.UserID == "" {
+		requests.RespondAuthError(w)
+		return
+	}
+	if roleLevel == Guest && body.Name == "" {
+		requests.RespondWithError(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+	if reqCtx.PermissionLevel >= roleLevel && reqCtx.PermissionLevel >= Member {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	err = room.UseInvite(ctx, tx, reqCtx.Room.ID, claims.InviteID)
+	if errors.Is(err, sql.ErrNoRows) {
+		requests.RespondWithError(w, http.StatusForbidden, "This invite has been revoked, has expired or has been used up")
+		return
+	}
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	resp := room.RoomResponse{
+		Room: *reqCtx.Room,
+	}
+
+	if roleLevel == Guest {
+		var guest *room.Guest
+		if reqCtx.GuestID != "" {
+			guest, err = room.InsertGuestWithID(ctx, tx, reqCtx.Room.Code, body.Name, reqCtx.GuestID)
+		} else {
+			guest, err = room.InsertGuest(ctx, tx, reqCtx.Room.Code, body.Name)
+		}
+		if err != nil {
+			requests.RespondWithDBError(w, err)
+			return
+		}
+
+		err = room.SetGuestInvite(ctx, tx, reqCtx.Room.ID, guest.ID, claims.InviteID)
+		if err != nil {
+			requests.RespondWithDBError(w, err)
+			return
+		}
+		resp.Guest = guest
+	} else {
+		err = room.AddInvitedMember(ctx, tx, reqCtx.Room.ID, reqCtx.UserID, roleLevel == Moderator)
+		if err != nil {
+			requests.RespondWithDBError(w, err)
+			return
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
+	broadcast.Refresh(reqCtx.Room.ID)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/andrewbenington/queue-share-api/broadcast"
 	"github.com/andrewbenington/queue-share-api/client"
 	"github.com/andrewbenington/queue-share-api/requests"
 	"github.com/zmb3/spotify/v2"
@@ -39,6 +40,8 @@ func (c *Controller) Pause(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	broadcast.Refresh(reqCtx.Room.ID)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -80,6 +83,8 @@ func (c *Controller) Play(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	broadcast.Refresh(reqCtx.Room.ID)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -110,6 +115,8 @@ func (c *Controller) Next(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	broadcast.Refresh(reqCtx.Room.ID)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -140,6 +147,8 @@ func (c *Controller) Previous(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	broadcast.Refresh(reqCtx.Room.ID)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/requests"
+	"github.com/andrewbenington/queue-share-api/room"
+)
+
+func (c *Controller) GetQueuePolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Guest {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	policy, err := room.GetQueuePolicy(ctx, tx, reqCtx.Room.ID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(policy)
+}
+
+func (c *Controller) SetQueuePolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Host {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	var policy room.QueuePolicy
+	err = json.NewDecoder(r.Body).Decode(&policy)
+	if err != nil {
+		requests.RespondBadRequest(w)
+		return
+	}
+
+	for _, limit := range []*int{
+		policy.MaxPendingPerParticipant,
+		policy.CooldownSeconds,
+		policy.ReplayBlockMinutes,
+		policy.MaxDurationMS,
+		policy.SkipVoteCount,
+	} {
+		if limit != nil && *limit < 0 {
+			requests.RespondWithError(w, http.StatusBadRequest, "Queue policy limits cannot be negative")
+			return
+		}
+	}
+
+	if policy.SkipVotePercent != nil && (*policy.SkipVotePercent < 1 || *policy.SkipVotePercent > 100) {
+		requests.RespondWithError(w, http.StatusBadRequest, "Skip vote percent must be between 1 and 100")
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	err = room.SetQueuePolicy(ctx, tx, reqCtx.Room.ID, policy)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(policy)
+}
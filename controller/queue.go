@@ -7,16 +7,19 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
-	"time"
 
+	"github.com/andrewbenington/queue-share-api/broadcast"
 	"github.com/andrewbenington/queue-share-api/client"
 	"github.com/andrewbenington/queue-share-api/constants"
 	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/engine"
 	"github.com/andrewbenington/queue-share-api/requests"
 	"github.com/andrewbenington/queue-share-api/room"
 	"github.com/andrewbenington/queue-share-api/service"
 	"github.com/gorilla/mux"
+	"github.com/samber/lo"
 )
 
 var (
@@ -25,6 +28,28 @@ var (
 	}, "", " ")
 )
 
+type RoomQueueResponse struct {
+	*service.CurrentQueue
+	Pending []PendingQueueTrack `json:"pending"`
+}
+
+type PendingQueueTrack struct {
+	QueueTrackID string       `json:"queue_track_id"`
+	Track        db.TrackData `json:"track"`
+	AddedBy      string       `json:"added_by"`
+	Priority     int          `json:"priority"`
+	Score        int          `json:"score"`
+	Vote         int          `json:"vote"`
+}
+
+type VoteRequest struct {
+	Vote int `json:"vote"`
+}
+
+type ReorderQueueRequest struct {
+	QueueTrackIDs []string `json:"queue_track_ids"`
+}
+
 func (c *Controller) GetQueue(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -44,27 +69,14 @@ func (c *Controller) GetQueue(w http.ResponseWriter, r *http.Request) {
 		requests.RespondWithError(w, status, err.Error())
 		return
 	}
-	currentQueue, err := service.GetUserQueue(r.Context(), spClient)
-	if err != nil {
-		log.Printf("Error getting user queue: %s", err)
-		requests.RespondInternalError(w)
-		return
-	}
-
-	err = service.UpdateUserPlayback(r.Context(), spClient, currentQueue)
-	if err != nil {
-		log.Printf("Error updating user playback: %s", err)
-		requests.RespondInternalError(w)
-		return
-	}
 
-	status, errMessage := addGuestsAndMembersToTracks(r.Context(), reqCtx.Room.ID, currentQueue)
+	status, errMessage, roomQueue := getRoomQueue(ctx, reqCtx, spClient)
 	if status != http.StatusOK {
 		requests.RespondWithError(w, status, errMessage)
 		return
 	}
 
-	responseBytes, err := json.MarshalIndent(currentQueue, "", " ")
+	responseBytes, err := json.MarshalIndent(roomQueue, "", " ")
 	if err != nil {
 		requests.RespondInternalError(w)
 		return
@@ -86,6 +98,11 @@ func (c *Controller) PushToRoomQueue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if reqCtx.ParticipantID() == "" {
+		requests.RespondWithError(w, http.StatusBadRequest, "Guest ID is required to add to the queue")
+		return
+	}
+
 	vars := mux.Vars(r)
 	songID, ok := vars["song"]
 	if !ok || songID == "" {
@@ -98,14 +115,11 @@ func (c *Controller) PushToRoomQueue(w http.ResponseWriter, r *http.Request) {
 		requests.RespondWithError(w, status, err.Error())
 		return
 	}
-	err = service.PushToUserQueue(r.Context(), spClient, songID)
-	if err != nil && strings.Contains(err.Error(), "No active device found") {
-		requests.RespondWithError(w, http.StatusBadRequest, "Host is not playing music")
-		return
-	}
+
+	track, err := service.GetTrack(ctx, spClient, songID)
 	if err != nil {
-		log.Printf("Error pushing to user queue: %s", err)
-		requests.RespondInternalError(w)
+		log.Printf("Error getting track %s: %s", songID, err)
+		requests.RespondWithError(w, http.StatusBadRequest, "Track not found")
 		return
 	}
 
@@ -116,53 +130,215 @@ func (c *Controller) PushToRoomQueue(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback(ctx)
 
-	if reqCtx.UserID != "" {
-		err = room.SetQueueTrackUser(ctx, tx, reqCtx.Room.Code, songID, reqCtx.UserID)
+	// tracks are added one request at a time so that the queue policy can't be
+	// broken by adding several at once
+	err = room.LockRoom(ctx, tx, reqCtx.Room.ID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	blocklist, err := room.GetBlocklist(ctx, tx, reqCtx.Room.ID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+	if blocked := blocklist.Blocks(*track); blocked != nil {
+		requests.RespondWithRuleError(w, http.StatusForbidden, room.RuleBlocked, fmt.Sprintf("This %s has been blocked in this room", blocked.Kind))
+		return
+	}
+
+	pending, err := room.GetPendingQueue(ctx, tx, reqCtx.Room.ID, "")
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	// a track that is already waiting in the room queue gets an upvote instead of a duplicate entry
+	existing, alreadyPending := room.FindPendingTrack(pending, songID)
+
+	// moderators and the host aren't limited by the room's queue policy
+	if !alreadyPending && reqCtx.PermissionLevel < Moderator {
+		violation, err := room.CheckQueuePolicy(ctx, tx, reqCtx.Room.ID, reqCtx.ParticipantID(), *track)
 		if err != nil {
 			requests.RespondWithDBError(w, err)
 			return
 		}
-	} else if reqCtx.GuestID != "" {
-		err = room.SetQueueTrackGuest(ctx, tx, reqCtx.Room.Code, songID, reqCtx.GuestID)
-		if err != nil {
-			requests.RespondWithDBError(w, err)
+		if violation != nil {
+			respondWithPolicyViolation(w, violation)
 			return
 		}
 	}
 
-	time.Sleep(500 * time.Millisecond)
-
-	currentQueue, err := service.GetUserQueue(r.Context(), spClient)
+	if alreadyPending {
+		err = room.VoteOnQueueTrack(ctx, tx, reqCtx.Room.ID, existing.ID, reqCtx.ParticipantID(), 1)
+	} else if reqCtx.UserID != "" {
+		err = room.SetQueueTrackUser(ctx, tx, reqCtx.Room.Code, songID, reqCtx.UserID)
+	} else {
+		err = room.SetQueueTrackGuest(ctx, tx, reqCtx.Room.Code, songID, reqCtx.GuestID)
+	}
 	if err != nil {
-		requests.RespondInternalError(w)
+		requests.RespondWithDBError(w, err)
 		return
 	}
 
-	err = service.UpdateUserPlayback(r.Context(), spClient, currentQueue)
+	err = tx.Commit(ctx)
 	if err != nil {
-		requests.RespondInternalError(w)
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
 		return
 	}
 
-	status, errMessage := addGuestsAndMembersToTracks(r.Context(), reqCtx.Room.ID, currentQueue)
+	// the track is pushed to Spotify in the background, which refreshes the room
+	engine.RequestRoomQueueFill(ctx, reqCtx.Room.ID, reqCtx.Room.Code)
+
+	broadcast.Refresh(reqCtx.Room.ID)
+
+	status, errMessage, roomQueue := getRoomQueue(ctx, reqCtx, spClient)
 	if status != http.StatusOK {
 		requests.RespondWithError(w, status, errMessage)
 		return
 	}
 
-	responseBytes, err := json.MarshalIndent(currentQueue, "", " ")
+	responseBytes, err := json.MarshalIndent(roomQueue, "", " ")
 	if err != nil {
 		requests.RespondInternalError(w)
 		return
 	}
 
+	_, _ = w.Write(responseBytes)
+}
+
+func (c *Controller) VoteOnQueueTrack(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Guest {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	if reqCtx.ParticipantID() == "" {
+		requests.RespondWithError(w, http.StatusBadRequest, "Guest ID is required to vote")
+		return
+	}
+
+	var body VoteRequest
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil || body.Vote < -1 || body.Vote > 1 {
+		requests.RespondBadRequest(w)
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	err = room.VoteOnQueueTrack(ctx, tx, reqCtx.Room.ID, mux.Vars(r)["queue_track_id"], reqCtx.ParticipantID(), body.Vote)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
 	err = tx.Commit(ctx)
 	if err != nil {
 		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
 		return
 	}
 
-	_, _ = w.Write(responseBytes)
+	broadcast.Refresh(reqCtx.Room.ID)
+
+	respondWithPendingTracks(w, r, reqCtx)
+}
+
+func (c *Controller) ReorderRoomQueue(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Moderator {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	var body ReorderQueueRequest
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		requests.RespondBadRequest(w)
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	err = room.ReorderPendingQueue(ctx, tx, reqCtx.Room.ID, body.QueueTrackIDs)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
+	broadcast.Refresh(reqCtx.Room.ID)
+
+	respondWithPendingTracks(w, r, reqCtx)
+}
+
+func (c *Controller) RemoveFromRoomQueue(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Moderator {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	err = room.RemovePendingTrack(ctx, tx, reqCtx.Room.ID, mux.Vars(r)["queue_track_id"])
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
+	broadcast.Refresh(reqCtx.Room.ID)
+
+	respondWithPendingTracks(w, r, reqCtx)
 }
 
 func (c *Controller) PushToUserQueue(w http.ResponseWriter, r *http.Request) {
@@ -202,13 +378,7 @@ func (c *Controller) PushToUserQueue(w http.ResponseWriter, r *http.Request) {
 }
 
 func addGuestsAndMembersToTracks(ctx context.Context, roomID string, q *service.CurrentQueue) (statusCode int, errMessage string) {
-	tx, err := db.Service().BeginTx(ctx)
-	if err != nil {
-		return http.StatusInternalServerError, err.Error()
-	}
-	defer tx.Rollback(ctx)
-
-	guestTracks, err := room.GetQueueTrackAddedBy(ctx, tx, roomID)
+	guestTracks, err := room.GetQueueTrackAddedBy(ctx, db.Service().Pool, roomID)
 	if err == sql.ErrNoRows {
 		return http.StatusNotFound, constants.ErrorNotFound
 	}
@@ -225,21 +395,107 @@ func addGuestsAndMembersToTracks(ctx context.Context, roomID string, q *service.
 		}
 	}
 
+	// the engine marks tracks as played once they start playing
 	for _, gt := range guestTracks {
 		queueTrack, ok := tracks[gt.TrackID]
 		if ok && !gt.Played && gt.Timestamp.After(queueTrack.AddedAt) {
 			queueTrack.AddedBy = gt.AddedBy
 			queueTrack.AddedAt = gt.Timestamp
-			if queueTrack.ID == q.CurrentlyPlaying.ID {
-				room.MarkTracksAsPlayedSince(ctx, tx, roomID, gt.Timestamp)
-			}
 		}
 	}
 
-	err = tx.Commit(ctx)
+	return http.StatusOK, ""
+}
+
+func respondWithPolicyViolation(w http.ResponseWriter, violation *room.PolicyViolation) {
+	status := http.StatusForbidden
+	if violation.RetryAfter > 0 {
+		status = http.StatusTooManyRequests
+		w.Header().Set("Retry-After", strconv.Itoa(int(violation.RetryAfter.Seconds())))
+	}
+	requests.RespondWithRuleError(w, status, violation.Rule, violation.Message)
+}
+
+func getRoomQueue(ctx context.Context, reqCtx RequestContext, spClient service.MusicProvider) (statusCode int, errMessage string, roomQueue *RoomQueueResponse) {
+	currentQueue, err := service.GetUserQueue(ctx, spClient)
+	if err != nil {
+		log.Printf("Error getting user queue: %s", err)
+		return http.StatusInternalServerError, constants.ErrorInternal, nil
+	}
+
+	err = service.UpdateUserPlayback(ctx, spClient, currentQueue)
 	if err != nil {
-		return http.StatusInternalServerError, fmt.Sprintf("commit transaction: %s", err)
+		log.Printf("Error updating user playback: %s", err)
+		return http.StatusInternalServerError, constants.ErrorInternal, nil
 	}
 
-	return http.StatusOK, ""
+	statusCode, errMessage = addGuestsAndMembersToTracks(ctx, reqCtx.Room.ID, currentQueue)
+	if statusCode != http.StatusOK {
+		return statusCode, errMessage, nil
+	}
+
+	pending, err := getPendingTracks(ctx, reqCtx, spClient)
+	if err != nil {
+		log.Printf("Error getting pending tracks: %s", err)
+		return http.StatusInternalServerError, constants.ErrorInternal, nil
+	}
+
+	return http.StatusOK, "", &RoomQueueResponse{
+		CurrentQueue: currentQueue,
+		Pending:      pending,
+	}
+}
+
+func getPendingTracks(ctx context.Context, reqCtx RequestContext, spClient service.MusicProvider) ([]PendingQueueTrack, error) {
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	pending, err := room.GetPendingQueue(ctx, tx, reqCtx.Room.ID, reqCtx.ParticipantID())
+	if err != nil {
+		return nil, err
+	}
+
+	trackIDs := lo.Map(pending, func(track room.PendingTrack, _ int) string {
+		return track.TrackID
+	})
+	trackData, err := service.GetTracks(ctx, spClient, trackIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := make([]PendingQueueTrack, 0, len(pending))
+	for _, track := range pending {
+		tracks = append(tracks, PendingQueueTrack{
+			QueueTrackID: track.ID,
+			Track:        trackData[track.TrackID],
+			AddedBy:      track.AddedBy,
+			Priority:     track.Priority,
+			Score:        track.Score,
+			Vote:         track.Vote,
+		})
+	}
+
+	return tracks, nil
+}
+
+func respondWithPendingTracks(w http.ResponseWriter, r *http.Request, reqCtx RequestContext) {
+	ctx := r.Context()
+
+	status, spClient, err := client.ForRoom(ctx, reqCtx.Room.Code)
+	if err != nil {
+		requests.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	pending, err := getPendingTracks(ctx, reqCtx, spClient)
+	if err != nil {
+		log.Printf("Error getting pending tracks: %s", err)
+		requests.RespondInternalError(w)
+		return
+	}
+
+	json.NewEncoder(w).Encode(pending)
 }
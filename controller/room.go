@@ -34,6 +34,14 @@ const (
 	Host
 )
 
+// ParticipantID returns the ID of the member or guest making the request
+func (reqCtx RequestContext) ParticipantID() string {
+	if reqCtx.UserID != "" {
+		return reqCtx.UserID
+	}
+	return reqCtx.GuestID
+}
+
 func (c *Controller) CreateRoom(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	userID, ok := r.Context().Value(auth.UserContextKey).(string)
@@ -206,6 +214,7 @@ func (*Controller) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
 		return
 	}
+	defer tx.Rollback(ctx)
 
 	reqCtx, err := getRoomRequestContext(ctx, r)
 	if err != nil {
@@ -231,6 +240,12 @@ func (*Controller) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -239,6 +254,7 @@ func getRoomRequestContext(ctx context.Context, r *http.Request) (as RequestCont
 	if err != nil {
 		return as, nil
 	}
+	defer tx.Rollback(ctx)
 
 	code, guestID, password := room.ParametersFromRequest(r)
 	rm, err := room.GetByCode(ctx, tx, code)
@@ -272,9 +288,21 @@ func getRoomRequestContext(ctx context.Context, r *http.Request) (as RequestCont
 		}
 	}
 
-	// Request has been made by a guest; authenticate with room password
+	// Request has been made by a guest; authenticate with an invite or the room password
 	as.GuestID = guestID
 
+	inviteToken := room.InviteTokenFromRequest(r)
+	if inviteToken != "" && guestID != "" {
+		inviteValid, err := room.GuestHasValidInvite(ctx, tx, rm.ID, guestID, inviteToken)
+		if err != nil {
+			return as, err
+		}
+		if inviteValid {
+			as.PermissionLevel = Guest
+			return as, nil
+		}
+	}
+
 	passwordValid, err := room.ValidatePassword(ctx, tx, code, password)
 	if err != nil && err != sql.ErrNoRows {
 		return as, err
@@ -285,5 +313,7 @@ func getRoomRequestContext(ctx context.Context, r *http.Request) (as RequestCont
 	}
 	as.PermissionLevel = Guest
 
-	return as, nil
+	// save the password's new hash if it was rehashed
+	err = tx.Commit(ctx)
+	return as, err
 }
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/broadcast"
+	"github.com/andrewbenington/queue-share-api/client"
+	"github.com/andrewbenington/queue-share-api/engine"
+	"github.com/andrewbenington/queue-share-api/requests"
+	"github.com/andrewbenington/queue-share-api/room"
+	"github.com/andrewbenington/queue-share-api/service"
+)
+
+const (
+	room_events_keepalive = time.Second * 30
+)
+
+type QueueEvent struct {
+	Queue   []service.TrackInfo `json:"queue"`
+	Pending []PendingQueueTrack `json:"pending"`
+}
+
+// RoomEvents streams changes to the room's queue, playback, guests and members as
+// server-sent events
+func (c *Controller) RoomEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Guest {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		requests.RespondWithError(w, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	events, unsubscribe := broadcast.Subscribe(reqCtx.Room.ID, roomSnapshot(*reqCtx.Room))
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(room_events_keepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			if !canReceiveRoomEvents(ctx, r) {
+				return
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, event.Data)
+			// the rest of the snapshot is sent without checking again
+			for len(events) > 0 {
+				event = <-events
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, event.Data)
+			}
+			flusher.Flush()
+		case <-keepalive.C:
+			if !canReceiveRoomEvents(ctx, r) {
+				return
+			}
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// canReceiveRoomEvents checks the subscriber's access to the room again, since
+// a guest or member can be removed or their invite revoked after the stream was
+// opened. The stream is closed if access can't be checked, and the client
+// reconnects.
+func canReceiveRoomEvents(ctx context.Context, r *http.Request) bool {
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Error checking room events access: %s", err)
+		}
+		return false
+	}
+	return reqCtx.PermissionLevel >= Guest
+}
+
+// roomSnapshot returns the state shared by every subscriber to the room, so it
+// doesn't include anything specific to a guest or member, like their votes
+func roomSnapshot(rm room.Room) broadcast.SnapshotFunc {
+	return func(ctx context.Context) (map[string]any, error) {
+		status, spClient, err := client.ForRoom(ctx, rm.Code)
+		if err != nil {
+			return nil, fmt.Errorf("get room client (%d): %w", status, err)
+		}
+
+		status, errMessage, roomQueue := getRoomQueue(ctx, RequestContext{Room: &rm}, spClient)
+		if status != http.StatusOK {
+			return nil, errors.New(errMessage)
+		}
+
+		// subscribed rooms aren't polled by the engine, so the play log is kept
+		// here
+		err = engine.RecordNowPlaying(ctx, rm.ID, roomQueue.CurrentQueue)
+		if err != nil {
+			log.Printf("Error logging now playing for room %s: %s", rm.Code, err)
+		}
+
+		// the start time is recalculated from the playback progress every poll
+		nowPlaying := roomQueue.CurrentlyPlaying
+		if nowPlaying.StartedPlayingEpochMilis != nil {
+			startedPlaying := time.UnixMilli(*nowPlaying.StartedPlayingEpochMilis).Round(time.Second).UnixMilli()
+			nowPlaying.StartedPlayingEpochMilis = &startedPlaying
+		}
+
+		guestsAndMembers, err := getRoomGuestsAndMembers(ctx, rm.ID)
+		if err != nil {
+			return nil, fmt.Errorf("get guests and members: %w", err)
+		}
+
+		return map[string]any{
+			broadcast.EventNowPlaying: nowPlaying,
+			broadcast.EventQueue: QueueEvent{
+				Queue:   roomQueue.Queue,
+				Pending: roomQueue.Pending,
+			},
+			broadcast.EventGuestsAndMembers: guestsAndMembers,
+		}, nil
+	}
+}
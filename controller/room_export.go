@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/auth"
+	"github.com/andrewbenington/queue-share-api/client"
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/requests"
+	"github.com/andrewbenington/queue-share-api/room"
+	"github.com/andrewbenington/queue-share-api/service"
+	"github.com/andrewbenington/queue-share-api/user"
+)
+
+type ExportPlaylistRequest struct {
+	Name           string     `json:"name"`
+	Description    string     `json:"description"`
+	Public         bool       `json:"public"`
+	Start          *time.Time `json:"start"`
+	End            *time.Time `json:"end"`
+	ExcludeSkipped bool       `json:"exclude_skipped"`
+	OrderBy        string     `json:"order_by"`
+}
+
+type ExportPlaylistResponse struct {
+	ID         string `json:"id"`
+	URL        string `json:"url"`
+	TrackCount int    `json:"track_count"`
+}
+
+// ExportPlaylist creates a playlist on the host's Spotify account from the tracks
+// played in the room
+func (c *Controller) ExportPlaylist(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Host {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	var body ExportPlaylistRequest
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		requests.RespondBadRequest(w)
+		return
+	}
+
+	if body.OrderBy == "" {
+		body.OrderBy = room.OrderByPlayed
+	}
+	if body.OrderBy != room.OrderByPlayed && body.OrderBy != room.OrderByVotes {
+		requests.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("order_by must be '%s' or '%s'", room.OrderByPlayed, room.OrderByVotes))
+		return
+	}
+	if body.Start != nil && body.End != nil && !body.End.After(*body.Start) {
+		requests.RespondWithError(w, http.StatusBadRequest, "End must be after start")
+		return
+	}
+	if body.Name == "" {
+		body.Name = fmt.Sprintf("%s (Queue Share)", reqCtx.Room.Name)
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// hosts who connected Spotify before playlist scopes were requested need to
+	// connect again
+	hasPermissions, err := user.HasSpotifyPermissions(ctx, tx, reqCtx.Room.Host.ID, auth.PlaylistPermissionsVersion)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+	if !hasPermissions {
+		requests.RespondWithError(w, http.StatusForbidden, "Reconnect your Spotify account to allow Queue Share to create playlists")
+		return
+	}
+
+	played, err := room.GetPlayedTracks(ctx, tx, reqCtx.Room.ID, body.Start, body.End, body.ExcludeSkipped)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	trackIDs := room.PlaylistTrackIDs(played, body.OrderBy)
+	if len(trackIDs) == 0 {
+		requests.RespondWithError(w, http.StatusBadRequest, "No tracks were played in this time window")
+		return
+	}
+
+	status, spClient, err := client.ForRoom(ctx, reqCtx.Room.Code)
+	if err != nil {
+		requests.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	playlist, err := service.CreatePlaylist(ctx, spClient, body.Name, body.Description, body.Public, trackIDs)
+	if err != nil {
+		requests.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("create playlist: %s", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ExportPlaylistResponse{
+		ID:         playlist.ID,
+		URL:        playlist.URL,
+		TrackCount: len(trackIDs),
+	})
+}
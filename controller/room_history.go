@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/client"
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/requests"
+	"github.com/andrewbenington/queue-share-api/room"
+	"github.com/andrewbenington/queue-share-api/service"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+type SessionHistoryEntry struct {
+	ID         string       `json:"id"`
+	Track      db.TrackData `json:"track"`
+	StartedAt  time.Time    `json:"started_at"`
+	DurationMS int          `json:"duration_ms"`
+	AddedBy    string       `json:"added_by,omitempty"`
+	AddedByID  string       `json:"added_by_id,omitempty"`
+	Score      int          `json:"score"`
+	Skipped    bool         `json:"skipped"`
+	SkipVotes  *int         `json:"skip_votes"`
+}
+
+type SessionHistoryResponse struct {
+	History []SessionHistoryEntry `json:"history"`
+	Total   int                   `json:"total"`
+	Limit   int                   `json:"limit"`
+	Offset  int                   `json:"offset"`
+}
+
+// GetSessionHistory lists the tracks that have played in the room, most recent
+// first. It can be filtered to the tracks queued by one guest or member with the
+// participant_id parameter.
+func (c *Controller) GetSessionHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Guest {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	limitParam := r.URL.Query().Get("limit")
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil || limit <= 0 || limit > DEFAULT_LIMIT {
+		limit = DEFAULT_LIMIT
+	}
+
+	offsetParam := r.URL.Query().Get("offset")
+	offset, err := strconv.Atoi(offsetParam)
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	participantID := r.URL.Query().Get("participant_id")
+	if participantID != "" {
+		if _, err := uuid.Parse(participantID); err != nil {
+			requests.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("parse participant ID: %s", err))
+			return
+		}
+	}
+
+	status, spClient, err := client.ForRoom(ctx, reqCtx.Room.Code)
+	if err != nil {
+		requests.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	entries, total, err := room.GetSessionHistory(ctx, tx, reqCtx.Room.ID, participantID, limit, offset)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	trackIDs := lo.Uniq(lo.Map(entries, func(entry room.PlayLogEntry, _ int) string {
+		return entry.TrackID
+	}))
+	tracks, err := service.GetTracks(ctx, spClient, trackIDs)
+	if err != nil {
+		requests.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("get tracks: %s", err))
+		return
+	}
+
+	history := lo.Map(entries, func(entry room.PlayLogEntry, _ int) SessionHistoryEntry {
+		return SessionHistoryEntry{
+			ID:         entry.ID,
+			Track:      tracks[entry.TrackID],
+			StartedAt:  entry.StartedAt,
+			DurationMS: entry.DurationMS,
+			AddedBy:    entry.AddedBy,
+			AddedByID:  entry.AddedByID,
+			Score:      entry.Score,
+			Skipped:    entry.Skipped,
+			SkipVotes:  entry.SkipVotes,
+		}
+	})
+
+	json.NewEncoder(w).Encode(SessionHistoryResponse{
+		History: history,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+	})
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/broadcast"
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/requests"
+	"github.com/andrewbenington/queue-share-api/room"
+)
+
+func (c *Controller) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Guest {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	schedule, err := room.GetSchedule(ctx, tx, reqCtx.Room.ID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// SetSchedule replaces the room's schedule. A room whose start time is in the
+// future is closed until it starts.
+func (c *Controller) SetSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Host {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	var schedule room.Schedule
+	err = json.NewDecoder(r.Body).Decode(&schedule)
+	if err != nil {
+		requests.RespondBadRequest(w)
+		return
+	}
+
+	err = schedule.Validate()
+	if err != nil {
+		requests.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid schedule: %s", err))
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	err = room.SetSchedule(ctx, tx, reqCtx.Room.ID, schedule, time.Now())
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	saved, err := room.GetSchedule(ctx, tx, reqCtx.Room.ID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
+	broadcast.Refresh(reqCtx.Room.ID)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// DeleteSchedule removes the room's schedule and reopens the room if it was
+// closed
+func (c *Controller) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Host {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	err = room.DeleteSchedule(ctx, tx, reqCtx.Room.ID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
+	broadcast.Refresh(reqCtx.Room.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
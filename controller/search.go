@@ -8,12 +8,19 @@ import (
 
 	"github.com/andrewbenington/queue-share-api/auth"
 	"github.com/andrewbenington/queue-share-api/client"
+	"github.com/andrewbenington/queue-share-api/db"
 	"github.com/andrewbenington/queue-share-api/requests"
+	"github.com/andrewbenington/queue-share-api/room"
 	"github.com/andrewbenington/queue-share-api/service"
 	"github.com/google/uuid"
 	"github.com/samber/lo"
 )
 
+type RoomSearchResult struct {
+	db.TrackData
+	Blocked bool `json:"blocked"`
+}
+
 var (
 	SearchMissingError, _ = json.MarshalIndent(requests.ErrorResponse{
 		Error: "No search term present",
@@ -51,7 +58,27 @@ func (c *Controller) SearchTracksFromRoom(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	responseBytes, err := json.MarshalIndent(results, "", " ")
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	blocklist, err := room.GetBlocklist(ctx, tx, reqCtx.Room.ID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	roomResults := lo.Map(results, func(track db.TrackData, _ int) RoomSearchResult {
+		return RoomSearchResult{
+			TrackData: track,
+			Blocked:   blocklist.Blocks(track) != nil,
+		}
+	})
+
+	responseBytes, err := json.MarshalIndent(roomResults, "", " ")
 	if err != nil {
 		requests.RespondInternalError(w)
 		return
@@ -0,0 +1,292 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/broadcast"
+	"github.com/andrewbenington/queue-share-api/client"
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/requests"
+	"github.com/andrewbenington/queue-share-api/room"
+	"github.com/andrewbenington/queue-share-api/service"
+	"github.com/samber/lo"
+)
+
+type SkipResponse struct {
+	ID            string       `json:"id"`
+	Track         db.TrackData `json:"track"`
+	VoteCount     int          `json:"vote_count"`
+	RequiredVotes int          `json:"required_votes"`
+	Timestamp     time.Time    `json:"timestamp"`
+	Voters        []string     `json:"voters"`
+}
+
+func (c *Controller) GetSkipVotes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Guest {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	status, spClient, err := client.ForRoom(ctx, reqCtx.Room.Code)
+	if err != nil {
+		requests.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	nowPlaying, err := currentlyPlaying(ctx, spClient)
+	if err != nil {
+		requests.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	skipStatus, err := room.GetSkipVoteStatus(ctx, tx, reqCtx.Room.ID, nowPlaying.TrackID, reqCtx.ParticipantID())
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(skipStatus)
+}
+
+// VoteToSkip records the guest or member's vote to skip the current track, and
+// skips it once enough of the room has voted
+func (c *Controller) VoteToSkip(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Guest {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	if reqCtx.ParticipantID() == "" {
+		requests.RespondWithError(w, http.StatusBadRequest, "Guest ID is required to vote")
+		return
+	}
+
+	status, spClient, err := client.ForRoom(ctx, reqCtx.Room.Code)
+	if err != nil {
+		requests.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	nowPlaying, err := currentlyPlaying(ctx, spClient)
+	if err != nil {
+		requests.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if nowPlaying.TrackID == "" {
+		requests.RespondWithError(w, http.StatusConflict, "Nothing is playing")
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// votes are counted one request at a time so that the track is only
+	// skipped once
+	err = room.LockRoom(ctx, tx, reqCtx.Room.ID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	err = room.AddSkipVote(ctx, tx, reqCtx.Room.ID, nowPlaying.TrackID, reqCtx.ParticipantID())
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	skipStatus, err := room.GetSkipVoteStatus(ctx, tx, reqCtx.Room.ID, nowPlaying.TrackID, reqCtx.ParticipantID())
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if !skipStatus.Enabled {
+		requests.RespondWithError(w, http.StatusForbidden, "Voting to skip is not enabled in this room")
+		return
+	}
+
+	if skipStatus.Votes >= skipStatus.Required {
+		// the play is logged before it's skipped, in case nothing else has
+		// logged it yet
+		err = room.RecordNowPlaying(ctx, tx, reqCtx.Room.ID, nowPlaying)
+		if err != nil {
+			requests.RespondWithDBError(w, err)
+			return
+		}
+
+		recorded, err := room.RecordSkip(ctx, tx, reqCtx.Room.ID, *skipStatus, nowPlaying.StartedAt)
+		if err != nil {
+			requests.RespondWithDBError(w, err)
+			return
+		}
+
+		if recorded {
+			err = spClient.Next(ctx)
+			if err != nil {
+				log.Printf("error playing next song: %s", err)
+				requests.RespondWithError(w, http.StatusBadGateway, err.Error())
+				return
+			}
+		}
+		skipStatus.Skipped = true
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
+	broadcast.Refresh(reqCtx.Room.ID)
+
+	json.NewEncoder(w).Encode(skipStatus)
+}
+
+func (c *Controller) RemoveSkipVote(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Guest {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	if reqCtx.ParticipantID() == "" {
+		requests.RespondWithError(w, http.StatusBadRequest, "Guest ID is required to vote")
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	err = room.RemoveSkipVote(ctx, tx, reqCtx.Room.ID, reqCtx.ParticipantID())
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		http.Error(w, "Error committing DB transaction", http.StatusInternalServerError)
+		return
+	}
+
+	broadcast.Refresh(reqCtx.Room.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSkipHistory lists the tracks the room voted to skip
+func (c *Controller) GetSkipHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reqCtx, err := getRoomRequestContext(ctx, r)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	if reqCtx.PermissionLevel < Host {
+		requests.RespondWithRoomAuthError(w, int(reqCtx.PermissionLevel))
+		return
+	}
+
+	status, spClient, err := client.ForRoom(ctx, reqCtx.Room.Code)
+	if err != nil {
+		requests.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	skips, err := room.GetSkips(ctx, tx, reqCtx.Room.ID)
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+
+	trackIDs := lo.Uniq(lo.Map(skips, func(skip room.Skip, _ int) string {
+		return skip.TrackID
+	}))
+	tracks, err := service.GetTracks(ctx, spClient, trackIDs)
+	if err != nil {
+		requests.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("get tracks: %s", err))
+		return
+	}
+
+	response := lo.Map(skips, func(skip room.Skip, _ int) SkipResponse {
+		return SkipResponse{
+			ID:            skip.ID,
+			Track:         tracks[skip.TrackID],
+			VoteCount:     skip.VoteCount,
+			RequiredVotes: skip.RequiredVotes,
+			Timestamp:     skip.Timestamp,
+			Voters:        skip.Voters,
+		}
+	})
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// currentlyPlaying returns the track playing on the host's account and when it
+// started playing. The track ID is empty if nothing is playing.
+func currentlyPlaying(ctx context.Context, spClient service.MusicProvider) (room.NowPlaying, error) {
+	playing, err := spClient.PlayerCurrentlyPlaying(ctx)
+	if err != nil {
+		return room.NowPlaying{}, fmt.Errorf("get currently playing: %w", err)
+	}
+	if playing == nil || playing.Item == nil {
+		return room.NowPlaying{}, nil
+	}
+	return room.NowPlaying{
+		TrackID:    playing.Item.ID.String(),
+		StartedAt:  time.Now().Add(-time.Duration(playing.Progress) * time.Millisecond),
+		DurationMS: int(playing.Item.Duration),
+	}, nil
+}
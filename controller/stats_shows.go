@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/history"
+	"github.com/andrewbenington/queue-share-api/requests"
+)
+
+type TopShowsResponse struct {
+	Rankings []*history.ShowRankings `json:"rankings"`
+}
+
+// GetTopShowsByTimeframe ranks the podcasts and audiobooks in uploaded history
+// by listening time. These are never included in the music rankings.
+func (c *StatsController) GetTopShowsByTimeframe(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userUUID, err := userOrFriendUUIDFromRequest(ctx, r)
+	if err != nil {
+		requests.RespondWithError(w, 401, fmt.Sprintf("parse user UUID: %s", err))
+		return
+	}
+
+	var kind *string
+	switch kindParam := r.URL.Query().Get("kind"); kindParam {
+	case "":
+	case history.EpisodeKindPodcast, history.EpisodeKindAudiobook:
+		kind = &kindParam
+	default:
+		requests.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("kind should be %s or %s", history.EpisodeKindPodcast, history.EpisodeKindAudiobook))
+		return
+	}
+
+	transaction, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer transaction.Commit(ctx)
+
+	rankingResults, code, err := history.ShowRankingsByTimeframe(ctx, transaction, userUUID, getFilterParams(r), kind)
+	if err != nil {
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	json.NewEncoder(w).Encode(TopShowsResponse{Rankings: rankingResults})
+}
+
+type ShowStatsResponse struct {
+	Name     string                    `json:"name"`
+	Episodes []*history.EpisodeStreams `json:"episodes"`
+}
+
+// GetShowStats returns how much of each episode of a podcast, or chapter of an
+// audiobook, was listened to
+func (c *StatsController) GetShowStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userUUID, err := userOrFriendUUIDFromRequest(ctx, r)
+	if err != nil {
+		requests.RespondWithError(w, 401, fmt.Sprintf("parse user UUID: %s", err))
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		requests.RespondWithError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	transaction, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		http.Error(w, "Error connecting to database", http.StatusInternalServerError)
+		return
+	}
+	defer transaction.Commit(ctx)
+
+	episodes, err := history.ShowEpisodeStreams(ctx, transaction, userUUID, name, getFilterParams(r))
+	if err != nil {
+		requests.RespondWithDBError(w, err)
+		return
+	}
+	if len(episodes) == 0 {
+		requests.RespondNotFound(w)
+		return
+	}
+
+	json.NewEncoder(w).Encode(ShowStatsResponse{
+		Name:     name,
+		Episodes: episodes,
+	})
+}
@@ -24,9 +24,11 @@ type CreateUserBody struct {
 }
 
 type CreateUserResponse struct {
-	User      user.User `json:"user"`
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
+	User             user.User `json:"user"`
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
 }
 
 func (c *Controller) CreateUser(w http.ResponseWriter, r *http.Request) {
@@ -58,13 +60,23 @@ func (c *Controller) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user := user.User{
+	newUser := user.User{
 		ID:          userID,
 		Username:    req.Username,
 		DisplayName: req.DisplayName,
+		Role:        user.RoleUser,
 	}
 
-	token, expiry, err := user.GetJWT()
+	userAgent, ipAddress := sessionClientInfo(r)
+	sessionID, refreshToken, refreshExpiry, err := user.CreateSession(ctx, tx, userID, userAgent, ipAddress)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		log.Printf("create session: %s", err)
+		requests.RespondInternalError(w)
+		return
+	}
+
+	token, expiry, err := newUser.GetJWT(sessionID)
 	if err != nil {
 		_ = tx.Rollback(ctx)
 		log.Printf("generate jwt: %s", err)
@@ -73,9 +85,11 @@ func (c *Controller) CreateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	respBody := CreateUserResponse{
-		User:      user,
-		Token:     token,
-		ExpiresAt: expiry,
+		User:             newUser,
+		Token:            token,
+		ExpiresAt:        expiry,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiry,
 	}
 
 	err = tx.Commit(ctx)
@@ -2,14 +2,39 @@ package controller
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 
+	"github.com/andrewbenington/queue-share-api/db"
 	"github.com/andrewbenington/queue-share-api/version"
 )
 
 func (c *Controller) GetVersion(w http.ResponseWriter, r *http.Request) {
 	v := version.Get()
+	v.Schema = schemaVersion(r)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(v)
 }
+
+func schemaVersion(r *http.Request) *version.SchemaVersion {
+	expected, err := db.LatestMigrationVersion()
+	if err != nil {
+		log.Printf("get latest migration version: %s", err)
+		return nil
+	}
+	schema := &version.SchemaVersion{Expected: expected}
+
+	if db.Service().Pool == nil {
+		return schema
+	}
+	actual, err := db.GetSchemaVersion(r.Context(), db.Service().Pool)
+	if err != nil {
+		log.Printf("get schema version: %s", err)
+		return schema
+	}
+	schema.Actual = &actual.Version
+	schema.Dirty = actual.Dirty
+
+	return schema
+}
@@ -0,0 +1,16 @@
+package dbtest
+
+import (
+	"testing"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPortableSchema(t *testing.T) {
+	schema := portableSchema(db.Schema)
+
+	assert.NotContains(t, schema, " OWNER TO ")
+	assert.NotContains(t, schema, "transaction_timeout")
+	assert.Contains(t, schema, "CREATE TABLE public.spotify_history")
+}
@@ -0,0 +1,123 @@
+-- Fixtures loaded by dbtest.Seed. Track, album and artist rows match
+-- spotifytest.SeedCatalog, so the fake Spotify server is never needed for them.
+--
+-- Every user's password is 'password'.
+INSERT INTO users(id, username, display_name, created)
+VALUES
+  ('a11ce000-0000-4000-8000-000000000001', 'alice', 'Alice', '2024-01-01 00:00:00+00'),
+  ('b0b00000-0000-4000-8000-000000000002', 'bob', 'Bob', '2024-01-01 00:00:00+00'),
+  ('ca201000-0000-4000-8000-000000000003', 'carol', 'Carol', '2024-01-01 00:00:00+00');
+
+INSERT INTO user_passwords(user_id, encrypted_password)
+SELECT
+  id,
+  '$argon2id$v=19$m=19456,t=2,p=1$NRnUqKNtoL+RRCjySlRXBw$KGS+ikfitF/a94aGHwvoa/fYwVyiK5APk5d/mFeuSMs'
+FROM
+  users;
+
+-- alice and bob are friends, carol has no friends
+INSERT INTO user_friends(user_id, friend_id, added_timestamp)
+VALUES
+  ('a11ce000-0000-4000-8000-000000000001', 'b0b00000-0000-4000-8000-000000000002', '2024-01-01 00:00:00'),
+  ('b0b00000-0000-4000-8000-000000000002', 'a11ce000-0000-4000-8000-000000000001', '2024-01-01 00:00:00');
+
+INSERT INTO rooms(id, name, code, created, host_id, password_protected, updated)
+VALUES
+  ('2000e000-0000-4000-8000-000000000001', 'Test Room', 'TEST', '2024-01-01 00:00:00+00', 'a11ce000-0000-4000-8000-000000000001', false, '2024-01-01 00:00:00+00');
+
+INSERT INTO room_members(room_id, user_id, is_moderator)
+VALUES
+  ('2000e000-0000-4000-8000-000000000001', 'b0b00000-0000-4000-8000-000000000002', false);
+
+INSERT INTO spotify_artist_cache(id, uri, name, image_url, genres, popularity, follower_count)
+VALUES
+  ('qsartist00000000000000', 'spotify:artist:qsartist00000000000000', 'The Paper Lanterns', 'https://i.scdn.test/artist/qsartist00000000000000/640', '["indie pop"]', 60, 10000),
+  ('qsartist00000000000001', 'spotify:artist:qsartist00000000000001', 'Marisol Vega', 'https://i.scdn.test/artist/qsartist00000000000001/640', '["latin pop","dance pop"]', 50, 20000),
+  ('qsartist00000000000002', 'spotify:artist:qsartist00000000000002', 'Northbound Static', 'https://i.scdn.test/artist/qsartist00000000000002/640', '["post-rock"]', 40, 30000);
+
+INSERT INTO spotify_album_cache(id, uri, name, artist_id, artist_uri, artist_name, album_group, album_type, image_url, release_date, release_date_precision, genres, popularity, upc, spotify_track_ids, track_isrcs)
+VALUES
+  ('qsalbum000000000000000', 'spotify:album:qsalbum000000000000000', 'Lights Out Early', 'qsartist00000000000000', 'spotify:artist:qsartist00000000000000', 'The Paper Lanterns', NULL, 'album', 'https://i.scdn.test/album/qsalbum000000000000000/300', '2019-04-12', 'day', '["indie pop"]', 50, NULL, '["qstrack000000000000000","qstrack000000000000001","qstrack000000000000002"]', '["QSTEST000000","QSTEST000001","QSTEST000002"]'),
+  ('qsalbum000000000000001', 'spotify:album:qsalbum000000000000001', 'Second Story', 'qsartist00000000000000', 'spotify:artist:qsartist00000000000000', 'The Paper Lanterns', NULL, 'album', 'https://i.scdn.test/album/qsalbum000000000000001/300', '2022-09-30', 'day', '["indie pop"]', 51, NULL, '["qstrack000000000000003","qstrack000000000000004","qstrack000000000000005"]', '["QSTEST000003","QSTEST000004","QSTEST000005"]'),
+  ('qsalbum000000000000002', 'spotify:album:qsalbum000000000000002', 'Calor', 'qsartist00000000000001', 'spotify:artist:qsartist00000000000001', 'Marisol Vega', NULL, 'album', 'https://i.scdn.test/album/qsalbum000000000000002/300', '2021-06-18', 'day', '["latin pop","dance pop"]', 52, NULL, '["qstrack000000000000006","qstrack000000000000007","qstrack000000000000008"]', '["QSTEST000006","QSTEST000007","QSTEST000008"]'),
+  ('qsalbum000000000000003', 'spotify:album:qsalbum000000000000003', 'Signal Loss', 'qsartist00000000000002', 'spotify:artist:qsartist00000000000002', 'Northbound Static', NULL, 'album', 'https://i.scdn.test/album/qsalbum000000000000003/300', '2017-11-03', 'day', '["post-rock"]', 53, NULL, '["qstrack000000000000009","qstrack000000000000010","qstrack000000000000011"]', '["QSTEST000009","QSTEST000010","QSTEST000011"]');
+
+INSERT INTO spotify_track_cache(id, uri, name, album_id, album_uri, album_name, artist_id, artist_uri, artist_name, image_url, other_artists, duration_ms, popularity, explicit, preview_url, disc_number, track_number, type, external_ids, isrc)
+VALUES
+  ('qstrack000000000000000', 'spotify:track:qstrack000000000000000', 'Porch Light', 'qsalbum000000000000000', 'spotify:album:qsalbum000000000000000', 'Lights Out Early', 'qsartist00000000000000', 'spotify:artist:qsartist00000000000000', 'The Paper Lanterns', 'https://i.scdn.test/album/qsalbum000000000000000/640', '[]', 180000, 70, false, '', 1, 1, 'track', '{"isrc":"QSTEST000000"}', 'QSTEST000000'),
+  ('qstrack000000000000001', 'spotify:track:qstrack000000000000001', 'Kite Season', 'qsalbum000000000000000', 'spotify:album:qsalbum000000000000000', 'Lights Out Early', 'qsartist00000000000000', 'spotify:artist:qsartist00000000000000', 'The Paper Lanterns', 'https://i.scdn.test/album/qsalbum000000000000000/640', '[]', 195000, 69, false, '', 1, 2, 'track', '{"isrc":"QSTEST000001"}', 'QSTEST000001'),
+  ('qstrack000000000000002', 'spotify:track:qstrack000000000000002', 'Last Ferry Home', 'qsalbum000000000000000', 'spotify:album:qsalbum000000000000000', 'Lights Out Early', 'qsartist00000000000000', 'spotify:artist:qsartist00000000000000', 'The Paper Lanterns', 'https://i.scdn.test/album/qsalbum000000000000000/640', '[]', 210000, 68, false, '', 1, 3, 'track', '{"isrc":"QSTEST000002"}', 'QSTEST000002'),
+  ('qstrack000000000000003', 'spotify:track:qstrack000000000000003', 'Stairwell', 'qsalbum000000000000001', 'spotify:album:qsalbum000000000000001', 'Second Story', 'qsartist00000000000000', 'spotify:artist:qsartist00000000000000', 'The Paper Lanterns', 'https://i.scdn.test/album/qsalbum000000000000001/640', '[]', 180000, 67, false, '', 1, 1, 'track', '{"isrc":"QSTEST000003"}', 'QSTEST000003'),
+  ('qstrack000000000000004', 'spotify:track:qstrack000000000000004', 'Open Window', 'qsalbum000000000000001', 'spotify:album:qsalbum000000000000001', 'Second Story', 'qsartist00000000000000', 'spotify:artist:qsartist00000000000000', 'The Paper Lanterns', 'https://i.scdn.test/album/qsalbum000000000000001/640', '[]', 195000, 66, false, '', 1, 2, 'track', '{"isrc":"QSTEST000004"}', 'QSTEST000004'),
+  ('qstrack000000000000005', 'spotify:track:qstrack000000000000005', 'Paper Moon', 'qsalbum000000000000001', 'spotify:album:qsalbum000000000000001', 'Second Story', 'qsartist00000000000000', 'spotify:artist:qsartist00000000000000', 'The Paper Lanterns', 'https://i.scdn.test/album/qsalbum000000000000001/640', '[]', 210000, 65, false, '', 1, 3, 'track', '{"isrc":"QSTEST000005"}', 'QSTEST000005'),
+  ('qstrack000000000000006', 'spotify:track:qstrack000000000000006', 'Calor', 'qsalbum000000000000002', 'spotify:album:qsalbum000000000000002', 'Calor', 'qsartist00000000000001', 'spotify:artist:qsartist00000000000001', 'Marisol Vega', 'https://i.scdn.test/album/qsalbum000000000000002/640', '[]', 180000, 64, false, '', 1, 1, 'track', '{"isrc":"QSTEST000006"}', 'QSTEST000006'),
+  ('qstrack000000000000007', 'spotify:track:qstrack000000000000007', 'Medianoche', 'qsalbum000000000000002', 'spotify:album:qsalbum000000000000002', 'Calor', 'qsartist00000000000001', 'spotify:artist:qsartist00000000000001', 'Marisol Vega', 'https://i.scdn.test/album/qsalbum000000000000002/640', '[]', 195000, 63, false, '', 1, 2, 'track', '{"isrc":"QSTEST000007"}', 'QSTEST000007'),
+  ('qstrack000000000000008', 'spotify:track:qstrack000000000000008', 'Brisa', 'qsalbum000000000000002', 'spotify:album:qsalbum000000000000002', 'Calor', 'qsartist00000000000001', 'spotify:artist:qsartist00000000000001', 'Marisol Vega', 'https://i.scdn.test/album/qsalbum000000000000002/640', '[]', 210000, 62, false, '', 1, 3, 'track', '{"isrc":"QSTEST000008"}', 'QSTEST000008'),
+  ('qstrack000000000000009', 'spotify:track:qstrack000000000000009', 'Carrier Wave', 'qsalbum000000000000003', 'spotify:album:qsalbum000000000000003', 'Signal Loss', 'qsartist00000000000002', 'spotify:artist:qsartist00000000000002', 'Northbound Static', 'https://i.scdn.test/album/qsalbum000000000000003/640', '[]', 180000, 61, false, '', 1, 1, 'track', '{"isrc":"QSTEST000009"}', 'QSTEST000009'),
+  ('qstrack000000000000010', 'spotify:track:qstrack000000000000010', 'Dead Air', 'qsalbum000000000000003', 'spotify:album:qsalbum000000000000003', 'Signal Loss', 'qsartist00000000000002', 'spotify:artist:qsartist00000000000002', 'Northbound Static', 'https://i.scdn.test/album/qsalbum000000000000003/640', '[]', 195000, 60, false, '', 1, 2, 'track', '{"isrc":"QSTEST000010"}', 'QSTEST000010'),
+  ('qstrack000000000000011', 'spotify:track:qstrack000000000000011', 'Static Bloom', 'qsalbum000000000000003', 'spotify:album:qsalbum000000000000003', 'Signal Loss', 'qsartist00000000000002', 'spotify:artist:qsartist00000000000002', 'Northbound Static', 'https://i.scdn.test/album/qsalbum000000000000003/640', '[]', 210000, 59, false, '', 1, 3, 'track', '{"isrc":"QSTEST000011"}', 'QSTEST000011');
+
+-- January 2024 listening history. Each user plays their tracks a different
+-- number of times, so rankings have no ties:
+--   alice: tracks 0 (5), 3 (4), 6 (3), 9 (2), 1 (1)
+--   bob:   tracks 3 (3), 0 (2), 7 (1)
+INSERT INTO spotify_history(user_id, "timestamp", platform, ms_played, conn_country, track_name, artist_name, album_name, spotify_track_uri, reason_start, reason_end, shuffle, skipped, offline, incognito_mode, spotify_artist_uri, spotify_album_uri, from_history, isrc)
+SELECT
+  plays.user_id,
+  '2024-01-02 08:00:00'::timestamp + n * interval '1 day' + plays.track * interval '1 hour',
+  'ios',
+  tc.duration_ms,
+  'US',
+  tc.name,
+  tc.artist_name,
+  tc.album_name,
+  tc.uri,
+  'trackdone',
+  'trackdone',
+  false,
+  false,
+  false,
+  false,
+  tc.artist_uri,
+  tc.album_uri,
+  true,
+  tc.isrc
+FROM (
+  VALUES
+    ('a11ce000-0000-4000-8000-000000000001'::uuid, 0, 5),
+    ('a11ce000-0000-4000-8000-000000000001'::uuid, 3, 4),
+    ('a11ce000-0000-4000-8000-000000000001'::uuid, 6, 3),
+    ('a11ce000-0000-4000-8000-000000000001'::uuid, 9, 2),
+    ('a11ce000-0000-4000-8000-000000000001'::uuid, 1, 1),
+    ('b0b00000-0000-4000-8000-000000000002'::uuid, 3, 3),
+    ('b0b00000-0000-4000-8000-000000000002'::uuid, 0, 2),
+    ('b0b00000-0000-4000-8000-000000000002'::uuid, 7, 1)) AS plays(user_id, track, count)
+  JOIN spotify_track_cache tc ON tc.id = 'qstrack' || lpad(plays.track::text, 15, '0')
+  CROSS JOIN generate_series(1, plays.count) AS n;
+
+-- a skipped play, which is shorter than the default minimum and never ranked
+INSERT INTO spotify_history(user_id, "timestamp", platform, ms_played, conn_country, track_name, artist_name, album_name, spotify_track_uri, reason_start, reason_end, shuffle, skipped, offline, incognito_mode, spotify_artist_uri, spotify_album_uri, from_history, isrc)
+SELECT
+  'a11ce000-0000-4000-8000-000000000001',
+  '2024-01-20 18:30:00',
+  'ios',
+  10000,
+  'US',
+  name,
+  artist_name,
+  album_name,
+  uri,
+  'clickrow',
+  'fwdbtn',
+  false,
+  true,
+  false,
+  false,
+  artist_uri,
+  album_uri,
+  true,
+  isrc
+FROM
+  spotify_track_cache
+WHERE
+  id = 'qstrack000000000000002';
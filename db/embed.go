@@ -0,0 +1,81 @@
+package db
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Schema is the pg_dump of the database after every migration has run
+	//
+	//go:embed schema.sql
+	Schema string
+
+	// PostCreate seeds the rows every database needs, after Schema is loaded
+	//
+	//go:embed post-create.sql
+	PostCreate string
+
+	// Migrations holds the golang-migrate files in db/migrations
+	//
+	//go:embed migrations/*.sql
+	Migrations embed.FS
+)
+
+type migration struct {
+	Version int64
+	Name    string
+	// Path of the up migration in Migrations
+	Path string
+}
+
+// upMigrations lists the up migrations in Migrations, oldest first
+func upMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(Migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := []migration{}
+	for _, entry := range entries {
+		base, ok := strings.CutSuffix(entry.Name(), ".up.sql")
+		if !ok {
+			continue
+		}
+		prefix, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration '%s' has no version", entry.Name())
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration '%s' has no version: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, migration{
+			Version: version,
+			Name:    name,
+			Path:    path.Join("migrations", entry.Name()),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// LatestMigrationVersion returns the version of the newest migration, which is
+// the version a database loaded from Schema is at
+func LatestMigrationVersion() (int64, error) {
+	migrations, err := upMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, fmt.Errorf("no migrations found")
+	}
+
+	return migrations[len(migrations)-1].Version, nil
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbeddedFiles(t *testing.T) {
+	t.Run("latest migration", func(t *testing.T) {
+		version, err := LatestMigrationVersion()
+		assert.NoError(t, err)
+
+		matches, err := fs.Glob(Migrations, fmt.Sprintf("migrations/%d_*.up.sql", version))
+		assert.NoError(t, err)
+		assert.Len(t, matches, 1)
+	})
+
+	t.Run("up migrations", func(t *testing.T) {
+		migrations, err := upMigrations()
+		assert.NoError(t, err)
+		assert.NotEmpty(t, migrations)
+
+		for i, m := range migrations {
+			if i > 0 {
+				assert.Greater(t, m.Version, migrations[i-1].Version)
+			}
+			_, err := fs.Stat(Migrations, strings.TrimSuffix(m.Path, ".up.sql")+".down.sql")
+			assert.NoError(t, err, "%s has no down migration", m.Path)
+		}
+	})
+
+	t.Run("schema", func(t *testing.T) {
+		assert.Contains(t, Schema, "CREATE TABLE public.schema_migrations")
+		assert.Contains(t, PostCreate, "spotify_permissions_versions")
+	})
+}
@@ -0,0 +1,174 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationLockID is the Postgres advisory lock held while migrating, so only
+// one server applies migrations at a time
+const migrationLockID int64 = 0x71735f6d69677261
+
+var (
+	// ErrSchemaDirty means a migration failed partway through. The schema has to
+	// be fixed by hand, then marked clean with `make migrate-force`.
+	ErrSchemaDirty = errors.New("database schema is dirty")
+	// ErrSchemaAhead means the database has migrations this server doesn't know
+	// about, so it was probably migrated by a newer version
+	ErrSchemaAhead = errors.New("database schema is newer than this server")
+)
+
+// SchemaVersion is the row golang-migrate keeps in schema_migrations
+type SchemaVersion struct {
+	// Version is the last migration applied, or 0 if none have been
+	Version int64 `json:"version"`
+	Dirty   bool  `json:"dirty"`
+}
+
+const createSchemaMigrations = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version bigint NOT NULL PRIMARY KEY,
+    dirty boolean NOT NULL
+)`
+
+const getSchemaVersion = `
+SELECT
+    version,
+    dirty
+FROM
+    schema_migrations
+LIMIT 1
+`
+
+// GetSchemaVersion returns the migration version of the database. A database
+// that has never been migrated is at version 0.
+func GetSchemaVersion(ctx context.Context, dbtx DBTX) (*SchemaVersion, error) {
+	v := SchemaVersion{}
+	err := dbtx.QueryRow(ctx, getSchemaVersion).Scan(&v.Version, &v.Dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &v, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// CheckSchemaVersion returns the migration version of the database, or an
+// error if it is dirty or newer than the embedded migrations
+func CheckSchemaVersion(ctx context.Context, dbtx DBTX) (*SchemaVersion, error) {
+	latest, err := LatestMigrationVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := GetSchemaVersion(ctx, dbtx)
+	if err != nil {
+		return nil, fmt.Errorf("get schema version: %w", err)
+	}
+	if current.Dirty {
+		return current, fmt.Errorf("%w at version %d", ErrSchemaDirty, current.Version)
+	}
+	if current.Version > latest {
+		return current, fmt.Errorf("%w (database is at version %d, latest migration is %d)", ErrSchemaAhead, current.Version, latest)
+	}
+
+	return current, nil
+}
+
+// Migrate applies the embedded migrations the database hasn't run yet, and
+// returns the versions applied. It records progress in schema_migrations the
+// same way the migrate CLI does, so the two can be used on the same database.
+func Migrate(ctx context.Context, pool *pgxpool.Pool) ([]int64, error) {
+	migrations, err := upMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	// advisory locks belong to a session, so everything has to run on one
+	// connection
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID)
+	if err != nil {
+		return nil, fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		// the lock would otherwise stay with the connection when it goes back
+		// into the pool
+		_, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+		if err != nil {
+			conn.Conn().Close(context.Background())
+		}
+	}()
+
+	_, err = conn.Exec(ctx, createSchemaMigrations)
+	if err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	// another server may have migrated while this one waited for the lock
+	current, err := CheckSchemaVersion(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []int64{}
+	for _, m := range migrations {
+		if m.Version <= current.Version {
+			continue
+		}
+
+		sql, err := Migrations.ReadFile(m.Path)
+		if err != nil {
+			return applied, err
+		}
+
+		err = setSchemaVersion(ctx, conn, m.Version, true)
+		if err != nil {
+			return applied, err
+		}
+
+		// without arguments this uses the simple protocol, which runs every
+		// statement in the file
+		_, err = conn.Exec(ctx, string(sql))
+		if err != nil {
+			return applied, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+
+		err = setSchemaVersion(ctx, conn, m.Version, false)
+		if err != nil {
+			return applied, err
+		}
+		applied = append(applied, m.Version)
+	}
+
+	return applied, nil
+}
+
+func setSchemaVersion(ctx context.Context, conn *pgxpool.Conn, version int64, dirty bool) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "TRUNCATE schema_migrations")
+	if err != nil {
+		return fmt.Errorf("set schema version: %w", err)
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO schema_migrations(version, dirty) VALUES ($1, $2)", version, dirty)
+	if err != nil {
+		return fmt.Errorf("set schema version: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,79 @@
+package db_test
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/db/dbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("up to date", func(t *testing.T) {
+		pool := dbtest.New(t)
+
+		applied, err := db.Migrate(ctx, pool)
+		assert.NoError(t, err)
+		assert.Empty(t, applied)
+
+		latest, err := db.LatestMigrationVersion()
+		assert.NoError(t, err)
+		current, err := db.CheckSchemaVersion(ctx, pool)
+		assert.NoError(t, err)
+		assert.Equal(t, latest, current.Version)
+	})
+
+	t.Run("dirty", func(t *testing.T) {
+		pool := dbtest.New(t)
+		_, err := pool.Exec(ctx, "UPDATE schema_migrations SET dirty = true")
+		assert.NoError(t, err)
+
+		_, err = db.Migrate(ctx, pool)
+		assert.ErrorIs(t, err, db.ErrSchemaDirty)
+		_, err = db.CheckSchemaVersion(ctx, pool)
+		assert.ErrorIs(t, err, db.ErrSchemaDirty)
+	})
+
+	t.Run("ahead of the server", func(t *testing.T) {
+		pool := dbtest.New(t)
+		_, err := pool.Exec(ctx, "UPDATE schema_migrations SET version = 99991231235959")
+		assert.NoError(t, err)
+
+		_, err = db.Migrate(ctx, pool)
+		assert.ErrorIs(t, err, db.ErrSchemaAhead)
+	})
+
+	t.Run("applies pending migrations", func(t *testing.T) {
+		pool := dbtest.New(t)
+
+		// undo the newest migration, as `make migrate-down` would
+		downs, err := fs.Glob(db.Migrations, "migrations/*.down.sql")
+		assert.NoError(t, err)
+		sort.Strings(downs)
+		down, err := db.Migrations.ReadFile(downs[len(downs)-1])
+		assert.NoError(t, err)
+		previous, _, _ := strings.Cut(path.Base(downs[len(downs)-2]), "_")
+
+		_, err = pool.Exec(ctx, string(down))
+		assert.NoError(t, err)
+		_, err = pool.Exec(ctx, "UPDATE schema_migrations SET version = "+previous)
+		assert.NoError(t, err)
+
+		latest, err := db.LatestMigrationVersion()
+		assert.NoError(t, err)
+		applied, err := db.Migrate(ctx, pool)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{latest}, applied)
+
+		current, err := db.GetSchemaVersion(ctx, pool)
+		assert.NoError(t, err)
+		assert.Equal(t, &db.SchemaVersion{Version: latest}, current)
+	})
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS room_queue_votes;
+
+ALTER TABLE room_queue_tracks
+  DROP COLUMN priority,
+  DROP COLUMN pushed_at;
@@ -0,0 +1,14 @@
+ALTER TABLE room_queue_tracks
+  ADD COLUMN priority INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN pushed_at TIMESTAMPTZ;
+
+-- Tracks added before the room queue existed were pushed to Spotify immediately
+UPDATE room_queue_tracks SET pushed_at = timestamp;
+
+CREATE TABLE room_queue_votes(
+  queue_track_id uuid NOT NULL REFERENCES room_queue_tracks(id) ON DELETE CASCADE,
+  voter_id uuid NOT NULL,
+  vote INTEGER NOT NULL CHECK (vote IN (-1, 1)),
+  timestamp TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT room_queue_votes_pkey PRIMARY KEY (queue_track_id, voter_id)
+);
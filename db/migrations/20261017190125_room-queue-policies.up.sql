@@ -0,0 +1,9 @@
+CREATE TABLE room_queue_policies(
+  room_id uuid NOT NULL PRIMARY KEY REFERENCES rooms(id) ON DELETE CASCADE,
+  max_pending_per_participant INTEGER,
+  cooldown_seconds INTEGER,
+  replay_block_minutes INTEGER,
+  block_explicit BOOLEAN NOT NULL DEFAULT FALSE,
+  max_duration_ms INTEGER,
+  updated TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,9 @@
+CREATE TABLE room_blocklist(
+  id uuid NOT NULL PRIMARY KEY DEFAULT uuid_generate_v4(),
+  room_id uuid NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL CHECK (kind IN ('track', 'artist', 'isrc')),
+  value TEXT NOT NULL,
+  added_by uuid REFERENCES users(id) ON DELETE SET NULL,
+  created TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT no_duplicate_room_blocklist UNIQUE (room_id, kind, value)
+);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS room_skip_voters;
+
+DROP TABLE IF EXISTS room_skips;
+
+DROP TABLE IF EXISTS room_skip_votes;
+
+ALTER TABLE room_queue_policies
+  DROP COLUMN skip_vote_count,
+  DROP COLUMN skip_vote_percent;
@@ -0,0 +1,26 @@
+ALTER TABLE room_queue_policies
+  ADD COLUMN skip_vote_count INTEGER,
+  ADD COLUMN skip_vote_percent INTEGER CHECK (skip_vote_percent BETWEEN 1 AND 100);
+
+CREATE TABLE room_skip_votes(
+  room_id uuid NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+  voter_id uuid NOT NULL,
+  track_id TEXT NOT NULL,
+  timestamp TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (room_id, voter_id)
+);
+
+CREATE TABLE room_skips(
+  id uuid NOT NULL PRIMARY KEY DEFAULT uuid_generate_v4(),
+  room_id uuid NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+  track_id TEXT NOT NULL,
+  vote_count INTEGER NOT NULL,
+  required_votes INTEGER NOT NULL,
+  timestamp TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE room_skip_voters(
+  skip_id uuid NOT NULL REFERENCES room_skips(id) ON DELETE CASCADE,
+  voter_id uuid NOT NULL,
+  PRIMARY KEY (skip_id, voter_id)
+);
@@ -0,0 +1,15 @@
+CREATE TABLE room_play_log(
+  id uuid NOT NULL PRIMARY KEY DEFAULT uuid_generate_v4(),
+  room_id uuid NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+  track_id TEXT NOT NULL,
+  started_at TIMESTAMPTZ NOT NULL,
+  duration_ms INTEGER NOT NULL,
+  queue_track_id uuid REFERENCES room_queue_tracks(id) ON DELETE SET NULL,
+  guest_id uuid,
+  user_id uuid,
+  score INTEGER NOT NULL DEFAULT 0,
+  skipped BOOLEAN NOT NULL DEFAULT FALSE,
+  skip_votes INTEGER
+);
+
+CREATE INDEX room_play_log_room_started_idx ON room_play_log(room_id, started_at);
@@ -0,0 +1,15 @@
+UPDATE
+    spotify_tokens
+SET
+    permissions_version = 3
+WHERE
+    permissions_version = 4;
+
+DELETE FROM spotify_permissions_versions
+WHERE id = 4;
+
+SELECT
+    setval('spotify_permissions_versions_id_seq', (
+            SELECT
+                MAX(id)
+            FROM spotify_permissions_versions));
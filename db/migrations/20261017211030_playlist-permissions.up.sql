@@ -0,0 +1,11 @@
+INSERT INTO spotify_permissions_versions(id, description)
+    VALUES (3, 'Permission to read user top items and recently played tracks'),
+(4, 'Permission to create and modify playlists')
+ON CONFLICT (id)
+    DO NOTHING;
+
+SELECT
+    setval('spotify_permissions_versions_id_seq', (
+            SELECT
+                MAX(id)
+            FROM spotify_permissions_versions));
@@ -0,0 +1,11 @@
+CREATE TABLE room_schedules(
+  room_id uuid NOT NULL PRIMARY KEY REFERENCES rooms(id) ON DELETE CASCADE,
+  starts_at TIMESTAMPTZ,
+  ends_at TIMESTAMPTZ,
+  idle_timeout_minutes INTEGER CHECK (idle_timeout_minutes > 0),
+  recurrence TEXT CHECK (recurrence IN ('daily', 'weekly')),
+  last_opened TIMESTAMPTZ,
+  updated TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CHECK (starts_at IS NULL OR ends_at IS NULL OR ends_at > starts_at),
+  CHECK (recurrence IS NULL OR (starts_at IS NOT NULL AND ends_at IS NOT NULL))
+);
@@ -0,0 +1,4 @@
+ALTER TABLE room_guests
+  DROP COLUMN invite_id;
+
+DROP TABLE IF EXISTS room_invites;
@@ -0,0 +1,14 @@
+CREATE TABLE room_invites(
+  id uuid NOT NULL PRIMARY KEY DEFAULT uuid_generate_v4(),
+  room_id uuid NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+  role TEXT NOT NULL CHECK (role IN ('guest', 'member', 'moderator')),
+  created_by uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ,
+  max_uses INTEGER CHECK (max_uses > 0),
+  uses INTEGER NOT NULL DEFAULT 0,
+  revoked_at TIMESTAMPTZ
+);
+
+ALTER TABLE room_guests
+  ADD COLUMN invite_id uuid REFERENCES room_invites(id) ON DELETE SET NULL;
@@ -0,0 +1,16 @@
+CREATE TABLE user_sessions(
+  id uuid NOT NULL PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  refresh_token_hash bytea NOT NULL UNIQUE,
+  previous_refresh_token_hash bytea,
+  user_agent TEXT,
+  ip_address TEXT,
+  created TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_used TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX user_sessions_user_id_idx ON user_sessions(user_id);
+
+CREATE INDEX user_sessions_previous_refresh_token_hash_idx ON user_sessions(previous_refresh_token_hash);
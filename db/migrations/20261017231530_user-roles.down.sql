@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS admin_audit_log;
+
+ALTER TABLE users
+  DROP COLUMN role;
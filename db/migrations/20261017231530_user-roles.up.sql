@@ -0,0 +1,13 @@
+ALTER TABLE users
+  ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin'));
+
+CREATE TABLE admin_audit_log(
+  id uuid NOT NULL PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id uuid REFERENCES users(id) ON DELETE SET NULL,
+  action TEXT NOT NULL,
+  status_code INTEGER,
+  ip_address TEXT,
+  created TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX admin_audit_log_created_idx ON admin_audit_log(created);
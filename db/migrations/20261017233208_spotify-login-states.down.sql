@@ -0,0 +1 @@
+DROP TABLE IF EXISTS spotify_login_states;
@@ -0,0 +1,10 @@
+CREATE TABLE spotify_login_states(
+  state TEXT NOT NULL PRIMARY KEY,
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  redirect_uri TEXT NOT NULL,
+  code_verifier TEXT NOT NULL,
+  created TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX spotify_login_states_expires_at_idx ON spotify_login_states(expires_at);
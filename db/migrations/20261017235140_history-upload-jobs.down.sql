@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS history_upload_job_files;
+DROP TABLE IF EXISTS history_upload_jobs;
@@ -0,0 +1,23 @@
+CREATE TABLE history_upload_jobs(
+  id uuid NOT NULL PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  status TEXT NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'failed', 'complete')),
+  archive_path TEXT NOT NULL,
+  files_total INTEGER NOT NULL,
+  files_done INTEGER NOT NULL DEFAULT 0,
+  entries_done INTEGER NOT NULL DEFAULT 0,
+  error TEXT,
+  created TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX history_upload_jobs_user_id_idx ON history_upload_jobs(user_id);
+
+-- files of a job whose entries have been committed, so resuming skips them
+CREATE TABLE history_upload_job_files(
+  job_id uuid NOT NULL REFERENCES history_upload_jobs(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  entries INTEGER NOT NULL,
+  created TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (job_id, name)
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS spotify_episode_history;
@@ -0,0 +1,27 @@
+-- podcast episodes and audiobook chapters from uploaded streaming history.
+-- Music stays in spotify_history, so the music stats never include them.
+CREATE TABLE spotify_episode_history(
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  timestamp TIMESTAMP NOT NULL,
+  kind TEXT NOT NULL CHECK (kind IN ('podcast', 'audiobook')),
+  platform TEXT NOT NULL,
+  ms_played INTEGER NOT NULL,
+  conn_country TEXT NOT NULL,
+  -- the podcast or audiobook
+  show_name TEXT NOT NULL,
+  -- only audiobooks have one in the export
+  show_uri TEXT,
+  -- the episode or chapter
+  episode_name TEXT NOT NULL,
+  episode_uri TEXT NOT NULL,
+  video BOOLEAN NOT NULL DEFAULT FALSE,
+  reason_start TEXT,
+  reason_end TEXT,
+  shuffle BOOLEAN NOT NULL,
+  skipped BOOLEAN,
+  offline BOOLEAN NOT NULL,
+  incognito_mode BOOLEAN NOT NULL,
+  PRIMARY KEY (user_id, timestamp)
+);
+
+CREATE INDEX spotify_episode_history_show_idx ON spotify_episode_history(user_id, show_name);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS history_import_review;
+
+ALTER TABLE spotify_history
+  DROP COLUMN IF EXISTS source;
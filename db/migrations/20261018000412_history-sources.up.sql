@@ -0,0 +1,20 @@
+-- where each stream was imported from. Scrobbles from Last.fm and ListenBrainz
+-- are matched to Spotify tracks, but rankings can still include or exclude them.
+ALTER TABLE spotify_history
+  ADD COLUMN source TEXT NOT NULL DEFAULT 'spotify' CHECK (source IN ('spotify', 'lastfm', 'listenbrainz'));
+
+-- imported scrobbles that couldn't be matched to a cached Spotify track, kept
+-- until the user picks the track or dismisses them
+CREATE TABLE history_import_review(
+  id uuid NOT NULL PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  source TEXT NOT NULL CHECK (source IN ('lastfm', 'listenbrainz')),
+  timestamp TIMESTAMP NOT NULL,
+  track_name TEXT NOT NULL,
+  artist_name TEXT NOT NULL,
+  album_name TEXT NOT NULL,
+  isrc TEXT,
+  duration_ms INTEGER,
+  created TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (user_id, source, timestamp)
+);
@@ -0,0 +1,15 @@
+-- streams recorded by the recently played poller that were removed because an
+-- uploaded history has the same play, so users can see what was merged
+CREATE TABLE history_merges(
+  id uuid NOT NULL PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  upload_job_id uuid REFERENCES history_upload_jobs(id) ON DELETE SET NULL,
+  spotify_track_uri TEXT NOT NULL,
+  kept_timestamp TIMESTAMP NOT NULL,
+  kept_ms_played INTEGER NOT NULL,
+  removed_timestamp TIMESTAMP NOT NULL,
+  removed_ms_played INTEGER NOT NULL,
+  created TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX history_merges_user_id_idx ON history_merges(user_id);
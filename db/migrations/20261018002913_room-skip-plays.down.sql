@@ -0,0 +1,3 @@
+ALTER TABLE room_skips
+  DROP CONSTRAINT IF EXISTS room_skips_room_id_track_id_played_at_key,
+  DROP COLUMN IF EXISTS played_at;
@@ -0,0 +1,13 @@
+-- when the skipped play started, so each play is only skipped once even if its
+-- final votes arrive together
+ALTER TABLE room_skips
+  ADD COLUMN played_at TIMESTAMPTZ;
+
+UPDATE
+  room_skips
+SET
+  played_at = timestamp;
+
+ALTER TABLE room_skips
+  ALTER COLUMN played_at SET NOT NULL,
+  ADD CONSTRAINT room_skips_room_id_track_id_played_at_key UNIQUE (room_id, track_id, played_at);
@@ -0,0 +1,20 @@
+ALTER TABLE user_sessions
+  ADD COLUMN previous_refresh_token_hash bytea;
+
+CREATE INDEX user_sessions_previous_refresh_token_hash_idx ON user_sessions(previous_refresh_token_hash);
+
+UPDATE
+  user_sessions s
+SET
+  previous_refresh_token_hash = (
+    SELECT
+      t.refresh_token_hash
+    FROM
+      user_session_used_tokens t
+    WHERE
+      t.session_id = s.id
+    ORDER BY
+      t.used_at DESC
+    LIMIT 1);
+
+DROP TABLE IF EXISTS user_session_used_tokens;
@@ -0,0 +1,22 @@
+CREATE TABLE user_session_used_tokens(
+  refresh_token_hash bytea NOT NULL PRIMARY KEY,
+  session_id uuid NOT NULL REFERENCES user_sessions(id) ON DELETE CASCADE,
+  used_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX user_session_used_tokens_session_id_idx ON user_session_used_tokens(session_id);
+
+INSERT INTO user_session_used_tokens(refresh_token_hash, session_id, used_at)
+SELECT
+  previous_refresh_token_hash,
+  id,
+  last_used
+FROM
+  user_sessions
+WHERE
+  previous_refresh_token_hash IS NOT NULL;
+
+DROP INDEX IF EXISTS user_sessions_previous_refresh_token_hash_idx;
+
+ALTER TABLE user_sessions
+  DROP COLUMN previous_refresh_token_hash;
@@ -0,0 +1,4 @@
+CREATE TABLE user_data_exports(
+  user_id uuid NOT NULL PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  exported_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,5 @@
+DELETE FROM history_upload_jobs
+WHERE source <> 'spotify';
+
+ALTER TABLE history_upload_jobs
+  DROP COLUMN source;
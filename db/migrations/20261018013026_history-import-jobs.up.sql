@@ -0,0 +1,2 @@
+ALTER TABLE history_upload_jobs
+  ADD COLUMN source text NOT NULL DEFAULT 'spotify' CHECK (source IN ('spotify', 'lastfm', 'listenbrainz'));
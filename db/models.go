@@ -10,6 +10,15 @@ import (
 	uuid "github.com/google/uuid"
 )
 
+type AdminAuditLog struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     *uuid.UUID `json:"user_id"`
+	Action     string     `json:"action"`
+	StatusCode *int32     `json:"status_code"`
+	IpAddress  *string    `json:"ip_address"`
+	Created    time.Time  `json:"created"`
+}
+
 type AlbumData struct {
 	ID                   string     `json:"id"`
 	URI                  string     `json:"uri"`
@@ -39,6 +48,52 @@ type ArtistData struct {
 	FollowerCount *int32   `json:"follower_count"`
 }
 
+type HistoryImportReview struct {
+	ID         uuid.UUID `json:"id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Source     string    `json:"source"`
+	Timestamp  time.Time `json:"timestamp"`
+	TrackName  string    `json:"track_name"`
+	ArtistName string    `json:"artist_name"`
+	AlbumName  string    `json:"album_name"`
+	Isrc       *string   `json:"isrc"`
+	DurationMs *int32    `json:"duration_ms"`
+	Created    time.Time `json:"created"`
+}
+
+type HistoryMerge struct {
+	ID               uuid.UUID  `json:"id"`
+	UserID           uuid.UUID  `json:"user_id"`
+	UploadJobID      *uuid.UUID `json:"upload_job_id"`
+	SpotifyTrackUri  string     `json:"spotify_track_uri"`
+	KeptTimestamp    time.Time  `json:"kept_timestamp"`
+	KeptMsPlayed     int32      `json:"kept_ms_played"`
+	RemovedTimestamp time.Time  `json:"removed_timestamp"`
+	RemovedMsPlayed  int32      `json:"removed_ms_played"`
+	Created          time.Time  `json:"created"`
+}
+
+type HistoryUploadJob struct {
+	ID          uuid.UUID `json:"id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Status      string    `json:"status"`
+	ArchivePath string    `json:"archive_path"`
+	FilesTotal  int32     `json:"files_total"`
+	FilesDone   int32     `json:"files_done"`
+	EntriesDone int32     `json:"entries_done"`
+	Error       *string   `json:"error"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+	Source      string    `json:"source"`
+}
+
+type HistoryUploadJobFile struct {
+	JobID   uuid.UUID `json:"job_id"`
+	Name    string    `json:"name"`
+	Entries int32     `json:"entries"`
+	Created time.Time `json:"created"`
+}
+
 type Room struct {
 	ID                uuid.UUID  `json:"id"`
 	Name              string     `json:"name"`
@@ -50,10 +105,32 @@ type Room struct {
 	IsOpen            bool       `json:"is_open"`
 }
 
+type RoomBlocklist struct {
+	ID      uuid.UUID  `json:"id"`
+	RoomID  uuid.UUID  `json:"room_id"`
+	Kind    string     `json:"kind"`
+	Value   string     `json:"value"`
+	AddedBy *uuid.UUID `json:"added_by"`
+	Created time.Time  `json:"created"`
+}
+
 type RoomGuest struct {
-	ID     uuid.UUID `json:"id"`
-	RoomID uuid.UUID `json:"room_id"`
-	Name   string    `json:"name"`
+	ID       uuid.UUID  `json:"id"`
+	RoomID   uuid.UUID  `json:"room_id"`
+	Name     string     `json:"name"`
+	InviteID *uuid.UUID `json:"invite_id"`
+}
+
+type RoomInvite struct {
+	ID        uuid.UUID  `json:"id"`
+	RoomID    uuid.UUID  `json:"room_id"`
+	Role      string     `json:"role"`
+	CreatedBy uuid.UUID  `json:"created_by"`
+	Created   time.Time  `json:"created"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxUses   *int32     `json:"max_uses"`
+	Uses      int32      `json:"uses"`
+	RevokedAt *time.Time `json:"revoked_at"`
 }
 
 type RoomMember struct {
@@ -69,6 +146,32 @@ type RoomPassword struct {
 	EncryptedPassword *string   `json:"encrypted_password"`
 }
 
+type RoomPlayLog struct {
+	ID           uuid.UUID  `json:"id"`
+	RoomID       uuid.UUID  `json:"room_id"`
+	TrackID      string     `json:"track_id"`
+	StartedAt    time.Time  `json:"started_at"`
+	DurationMs   int32      `json:"duration_ms"`
+	QueueTrackID *uuid.UUID `json:"queue_track_id"`
+	GuestID      *uuid.UUID `json:"guest_id"`
+	UserID       *uuid.UUID `json:"user_id"`
+	Score        int32      `json:"score"`
+	Skipped      bool       `json:"skipped"`
+	SkipVotes    *int32     `json:"skip_votes"`
+}
+
+type RoomQueuePolicy struct {
+	RoomID                   uuid.UUID `json:"room_id"`
+	MaxPendingPerParticipant *int32    `json:"max_pending_per_participant"`
+	CooldownSeconds          *int32    `json:"cooldown_seconds"`
+	ReplayBlockMinutes       *int32    `json:"replay_block_minutes"`
+	BlockExplicit            bool      `json:"block_explicit"`
+	MaxDurationMs            *int32    `json:"max_duration_ms"`
+	Updated                  time.Time `json:"updated"`
+	SkipVoteCount            *int32    `json:"skip_vote_count"`
+	SkipVotePercent          *int32    `json:"skip_vote_percent"`
+}
+
 type RoomQueueTrack struct {
 	ID        uuid.UUID  `json:"id"`
 	TrackID   string     `json:"track_id"`
@@ -77,6 +180,47 @@ type RoomQueueTrack struct {
 	Timestamp time.Time  `json:"timestamp"`
 	UserID    *uuid.UUID `json:"user_id"`
 	Played    bool       `json:"played"`
+	Priority  int32      `json:"priority"`
+	PushedAt  *time.Time `json:"pushed_at"`
+}
+
+type RoomQueueVote struct {
+	QueueTrackID uuid.UUID `json:"queue_track_id"`
+	VoterID      uuid.UUID `json:"voter_id"`
+	Vote         int32     `json:"vote"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+type RoomSchedule struct {
+	RoomID             uuid.UUID  `json:"room_id"`
+	StartsAt           *time.Time `json:"starts_at"`
+	EndsAt             *time.Time `json:"ends_at"`
+	IdleTimeoutMinutes *int32     `json:"idle_timeout_minutes"`
+	Recurrence         *string    `json:"recurrence"`
+	LastOpened         *time.Time `json:"last_opened"`
+	Updated            time.Time  `json:"updated"`
+}
+
+type RoomSkipVoter struct {
+	SkipID  uuid.UUID `json:"skip_id"`
+	VoterID uuid.UUID `json:"voter_id"`
+}
+
+type RoomSkipVote struct {
+	RoomID    uuid.UUID `json:"room_id"`
+	VoterID   uuid.UUID `json:"voter_id"`
+	TrackID   string    `json:"track_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type RoomSkip struct {
+	ID            uuid.UUID `json:"id"`
+	RoomID        uuid.UUID `json:"room_id"`
+	TrackID       string    `json:"track_id"`
+	VoteCount     int32     `json:"vote_count"`
+	RequiredVotes int32     `json:"required_votes"`
+	Timestamp     time.Time `json:"timestamp"`
+	PlayedAt      time.Time `json:"played_at"`
 }
 
 type SchemaMigration struct {
@@ -84,6 +228,26 @@ type SchemaMigration struct {
 	Dirty   bool  `json:"dirty"`
 }
 
+type SpotifyEpisodeHistory struct {
+	UserID        uuid.UUID `json:"user_id"`
+	Timestamp     time.Time `json:"timestamp"`
+	Kind          string    `json:"kind"`
+	Platform      string    `json:"platform"`
+	MsPlayed      int32     `json:"ms_played"`
+	ConnCountry   string    `json:"conn_country"`
+	ShowName      string    `json:"show_name"`
+	ShowUri       *string   `json:"show_uri"`
+	EpisodeName   string    `json:"episode_name"`
+	EpisodeUri    string    `json:"episode_uri"`
+	Video         bool      `json:"video"`
+	ReasonStart   *string   `json:"reason_start"`
+	ReasonEnd     *string   `json:"reason_end"`
+	Shuffle       bool      `json:"shuffle"`
+	Skipped       *bool     `json:"skipped"`
+	Offline       bool      `json:"offline"`
+	IncognitoMode bool      `json:"incognito_mode"`
+}
+
 type SpotifyHistory struct {
 	UserID           uuid.UUID  `json:"user_id"`
 	Timestamp        time.Time  `json:"timestamp"`
@@ -107,6 +271,16 @@ type SpotifyHistory struct {
 	SpotifyAlbumUri  *string    `json:"spotify_album_uri"`
 	FromHistory      bool       `json:"from_history"`
 	Isrc             *string    `json:"isrc"`
+	Source           string     `json:"source"`
+}
+
+type SpotifyLoginState struct {
+	State        string    `json:"state"`
+	UserID       uuid.UUID `json:"user_id"`
+	RedirectUri  string    `json:"redirect_uri"`
+	CodeVerifier string    `json:"code_verifier"`
+	Created      time.Time `json:"created"`
+	ExpiresAt    time.Time `json:"expires_at"`
 }
 
 type SpotifyPermissionsVersion struct {
@@ -154,6 +328,12 @@ type User struct {
 	SpotifyName     *string   `json:"spotify_name"`
 	SpotifyImageUrl *string   `json:"spotify_image_url"`
 	Created         time.Time `json:"created"`
+	Role            string    `json:"role"`
+}
+
+type UserDataExport struct {
+	UserID     uuid.UUID `json:"user_id"`
+	ExportedAt time.Time `json:"exported_at"`
 }
 
 type UserFriend struct {
@@ -173,3 +353,21 @@ type UserPassword struct {
 	UserID            *uuid.UUID `json:"user_id"`
 	EncryptedPassword string     `json:"encrypted_password"`
 }
+
+type UserSession struct {
+	ID               uuid.UUID  `json:"id"`
+	UserID           uuid.UUID  `json:"user_id"`
+	RefreshTokenHash []byte     `json:"refresh_token_hash"`
+	UserAgent        *string    `json:"user_agent"`
+	IpAddress        *string    `json:"ip_address"`
+	Created          time.Time  `json:"created"`
+	LastUsed         time.Time  `json:"last_used"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+}
+
+type UserSessionUsedToken struct {
+	RefreshTokenHash []byte    `json:"refresh_token_hash"`
+	SessionID        uuid.UUID `json:"session_id"`
+	UsedAt           time.Time `json:"used_at"`
+}
@@ -114,6 +114,7 @@ type HistoryInsertBulkNullableParams struct {
 	IncognitoMode    []bool      `json:"incognito_mode"`
 	FromHistory      []bool      `json:"from_history"`
 	ISRC             []*string   `json:"isrc"`
+	Source           []string    `json:"source"`
 }
 
 func (q *Queries) HistoryInsertBulkNullable(ctx context.Context, arg HistoryInsertBulkNullableParams) error {
@@ -139,6 +140,75 @@ func (q *Queries) HistoryInsertBulkNullable(ctx context.Context, arg HistoryInse
 		pq.Array(arg.IncognitoMode),
 		pq.Array(arg.FromHistory),
 		pq.Array(arg.ISRC),
+		pq.Array(arg.Source),
+	)
+	return err
+}
+
+type HistoryEpisodeInsertBulkNullableParams struct {
+	UserIds       []uuid.UUID `json:"user_ids"`
+	Timestamp     []time.Time `json:"timestamp"`
+	Kind          []string    `json:"kind"`
+	Platform      []string    `json:"platform"`
+	MsPlayed      []int32     `json:"ms_played"`
+	ConnCountry   []string    `json:"conn_country"`
+	ShowName      []string    `json:"show_name"`
+	ShowUri       []*string   `json:"show_uri"`
+	EpisodeName   []string    `json:"episode_name"`
+	EpisodeUri    []string    `json:"episode_uri"`
+	Video         []bool      `json:"video"`
+	ReasonStart   []*string   `json:"reason_start"`
+	ReasonEnd     []*string   `json:"reason_end"`
+	Shuffle       []bool      `json:"shuffle"`
+	Skipped       []*bool     `json:"skipped"`
+	Offline       []bool      `json:"offline"`
+	IncognitoMode []bool      `json:"incognito_mode"`
+}
+
+func (q *Queries) HistoryEpisodeInsertBulkNullable(ctx context.Context, arg HistoryEpisodeInsertBulkNullableParams) error {
+	_, err := q.db.Exec(ctx, historyEpisodeInsertBulk,
+		pq.Array(arg.UserIds),
+		pq.Array(arg.Timestamp),
+		pq.Array(arg.Kind),
+		pq.Array(arg.Platform),
+		pq.Array(arg.MsPlayed),
+		pq.Array(arg.ConnCountry),
+		pq.Array(arg.ShowName),
+		pq.Array(arg.ShowUri),
+		pq.Array(arg.EpisodeName),
+		pq.Array(arg.EpisodeUri),
+		pq.Array(arg.Video),
+		pq.Array(arg.ReasonStart),
+		pq.Array(arg.ReasonEnd),
+		pq.Array(arg.Shuffle),
+		pq.Array(arg.Skipped),
+		pq.Array(arg.Offline),
+		pq.Array(arg.IncognitoMode),
+	)
+	return err
+}
+
+type HistoryImportReviewInsertBulkNullableParams struct {
+	UserIds    []uuid.UUID `json:"user_ids"`
+	Source     []string    `json:"source"`
+	Timestamp  []time.Time `json:"timestamp"`
+	TrackName  []string    `json:"track_name"`
+	ArtistName []string    `json:"artist_name"`
+	AlbumName  []string    `json:"album_name"`
+	Isrc       []*string   `json:"isrc"`
+	DurationMs []*int32    `json:"duration_ms"`
+}
+
+func (q *Queries) HistoryImportReviewInsertBulkNullable(ctx context.Context, arg HistoryImportReviewInsertBulkNullableParams) error {
+	_, err := q.db.Exec(ctx, historyImportReviewInsertBulk,
+		pq.Array(arg.UserIds),
+		pq.Array(arg.Source),
+		pq.Array(arg.Timestamp),
+		pq.Array(arg.TrackName),
+		pq.Array(arg.ArtistName),
+		pq.Array(arg.AlbumName),
+		pq.Array(arg.Isrc),
+		pq.Array(arg.DurationMs),
 	)
 	return err
 }
@@ -1,4 +1,6 @@
 INSERT INTO spotify_permissions_versions(id, description)
 VALUES 
   (1, 'Permission to read user subscription details and read/update playback state'),
-  (2, 'Permission to read user suggested tracks');
\ No newline at end of file
+  (2, 'Permission to read user suggested tracks'),
+  (3, 'Permission to read user top items and recently played tracks'),
+  (4, 'Permission to create and modify playlists');
\ No newline at end of file
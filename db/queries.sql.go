@@ -13,6 +13,91 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const adminAuditLogGet = `-- name: AdminAuditLogGet :many
+SELECT
+  l.id,
+  l.user_id,
+  u.username,
+  l.action,
+  l.status_code,
+  l.ip_address,
+  l.created
+FROM
+  admin_audit_log l
+  LEFT JOIN users u ON u.id = l.user_id
+ORDER BY
+  l.created DESC
+LIMIT $1
+`
+
+type AdminAuditLogGetRow struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     *uuid.UUID `json:"user_id"`
+	Username   *string    `json:"username"`
+	Action     string     `json:"action"`
+	StatusCode *int32     `json:"status_code"`
+	IpAddress  *string    `json:"ip_address"`
+	Created    time.Time  `json:"created"`
+}
+
+func (q *Queries) AdminAuditLogGet(ctx context.Context, maxEntries int32) ([]*AdminAuditLogGetRow, error) {
+	rows, err := q.db.Query(ctx, adminAuditLogGet, maxEntries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*AdminAuditLogGetRow
+	for rows.Next() {
+		var i AdminAuditLogGetRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Username,
+			&i.Action,
+			&i.StatusCode,
+			&i.IpAddress,
+			&i.Created,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const adminAuditLogInsert = `-- name: AdminAuditLogInsert :exec
+INSERT INTO admin_audit_log(
+  user_id,
+  action,
+  status_code,
+  ip_address)
+VALUES (
+  $1,
+  $2,
+  $3,
+  $4)
+`
+
+type AdminAuditLogInsertParams struct {
+	UserID     *uuid.UUID `json:"user_id"`
+	Action     string     `json:"action"`
+	StatusCode *int32     `json:"status_code"`
+	IpAddress  *string    `json:"ip_address"`
+}
+
+func (q *Queries) AdminAuditLogInsert(ctx context.Context, arg AdminAuditLogInsertParams) error {
+	_, err := q.db.Exec(ctx, adminAuditLogInsert,
+		arg.UserID,
+		arg.Action,
+		arg.StatusCode,
+		arg.IpAddress,
+	)
+	return err
+}
+
 const albumCacheGetByID = `-- name: AlbumCacheGetByID :many
 SELECT
     id, uri, name, artist_id, artist_uri, artist_name, album_group, album_type, image_url, release_date, release_date_precision, genres, popularity, upc, spotify_track_ids, track_isrcs
@@ -434,6 +519,296 @@ func (q *Queries) GetSpotifyTokensByRoomCode(ctx context.Context, code string) (
 	return &i, err
 }
 
+const historyDeleteStreams = `-- name: HistoryDeleteStreams :execrows
+DELETE FROM spotify_history
+WHERE user_id = $1
+    AND NOT from_history
+    AND timestamp = ANY ($2::timestamp[])
+`
+
+type HistoryDeleteStreamsParams struct {
+	UserID     uuid.UUID   `json:"user_id"`
+	Timestamps []time.Time `json:"timestamps"`
+}
+
+func (q *Queries) HistoryDeleteStreams(ctx context.Context, arg HistoryDeleteStreamsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, historyDeleteStreams, arg.UserID, arg.Timestamps)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const historyEpisodeGetTimestampRange = `-- name: HistoryEpisodeGetTimestampRange :one
+SELECT
+    MIN(timestamp)::timestamp AS first,
+    MAX(timestamp)::timestamp AS last
+FROM
+    spotify_episode_history
+WHERE
+    user_id = $1
+`
+
+type HistoryEpisodeGetTimestampRangeRow struct {
+	First time.Time `json:"first"`
+	Last  time.Time `json:"last"`
+}
+
+func (q *Queries) HistoryEpisodeGetTimestampRange(ctx context.Context, userID uuid.UUID) (*HistoryEpisodeGetTimestampRangeRow, error) {
+	row := q.db.QueryRow(ctx, historyEpisodeGetTimestampRange, userID)
+	var i HistoryEpisodeGetTimestampRangeRow
+	err := row.Scan(&i.First, &i.Last)
+	return &i, err
+}
+
+const historyEpisodeInsertBulk = `-- name: HistoryEpisodeInsertBulk :exec
+INSERT INTO spotify_episode_history(
+    user_id,
+    timestamp,
+    kind,
+    platform,
+    ms_played,
+    conn_country,
+    show_name,
+    show_uri,
+    episode_name,
+    episode_uri,
+    video,
+    reason_start,
+    reason_end,
+    shuffle,
+    skipped,
+    offline,
+    incognito_mode)
+VALUES (
+    unnest(
+        $1::uuid[]),
+    unnest(
+        $2::timestamp[]),
+    unnest(
+        $3::text[]),
+    unnest(
+        $4::text[]),
+    unnest(
+        $5::integer[]),
+    unnest(
+        $6::text[]),
+    unnest(
+        $7::text[]),
+    unnest(
+        $8::text[]),
+    unnest(
+        $9::text[]),
+    unnest(
+        $10::text[]),
+    unnest(
+        $11::boolean[]),
+    unnest(
+        $12::text[]),
+    unnest(
+        $13::text[]),
+    unnest(
+        $14::boolean[]),
+    unnest(
+        $15::boolean[]),
+    unnest(
+        $16::boolean[]),
+    unnest(
+        $17::boolean[]))
+ON CONFLICT
+    DO NOTHING
+`
+
+type HistoryEpisodeInsertBulkParams struct {
+	UserIds       []uuid.UUID `json:"user_ids"`
+	Timestamp     []time.Time `json:"timestamp"`
+	Kind          []string    `json:"kind"`
+	Platform      []string    `json:"platform"`
+	MsPlayed      []int32     `json:"ms_played"`
+	ConnCountry   []string    `json:"conn_country"`
+	ShowName      []string    `json:"show_name"`
+	ShowUri       []string    `json:"show_uri"`
+	EpisodeName   []string    `json:"episode_name"`
+	EpisodeUri    []string    `json:"episode_uri"`
+	Video         []bool      `json:"video"`
+	ReasonStart   []string    `json:"reason_start"`
+	ReasonEnd     []string    `json:"reason_end"`
+	Shuffle       []bool      `json:"shuffle"`
+	Skipped       []bool      `json:"skipped"`
+	Offline       []bool      `json:"offline"`
+	IncognitoMode []bool      `json:"incognito_mode"`
+}
+
+func (q *Queries) HistoryEpisodeInsertBulk(ctx context.Context, arg HistoryEpisodeInsertBulkParams) error {
+	_, err := q.db.Exec(ctx, historyEpisodeInsertBulk,
+		arg.UserIds,
+		arg.Timestamp,
+		arg.Kind,
+		arg.Platform,
+		arg.MsPlayed,
+		arg.ConnCountry,
+		arg.ShowName,
+		arg.ShowUri,
+		arg.EpisodeName,
+		arg.EpisodeUri,
+		arg.Video,
+		arg.ReasonStart,
+		arg.ReasonEnd,
+		arg.Shuffle,
+		arg.Skipped,
+		arg.Offline,
+		arg.IncognitoMode,
+	)
+	return err
+}
+
+const historyExportPage = `-- name: HistoryExportPage :many
+SELECT
+    h.timestamp,
+    h.track_name,
+    h.artist_name,
+    h.album_name,
+    h.ms_played,
+    h.platform,
+    h.conn_country,
+    h.spotify_track_uri,
+    h.spotify_artist_uri,
+    h.spotify_album_uri,
+    h.isrc,
+    h.reason_start,
+    h.reason_end,
+    h.shuffle,
+    h.skipped,
+    h.offline,
+    h.incognito_mode,
+    h.from_history,
+    h.source,
+    tc.duration_ms AS track_duration_ms,
+    tc.popularity AS track_popularity,
+    tc.explicit,
+    al.album_type,
+    al.release_date AS album_release_date,
+    ar.genres AS artist_genres,
+    ar.popularity AS artist_popularity
+FROM
+    spotify_history h
+    LEFT JOIN spotify_track_cache tc ON tc.uri = h.spotify_track_uri
+    LEFT JOIN spotify_album_cache al ON al.uri = h.spotify_album_uri
+    LEFT JOIN spotify_artist_cache ar ON ar.uri = h.spotify_artist_uri
+WHERE
+    h.user_id = $1
+    AND h.timestamp > $2::timestamp
+    AND h.timestamp BETWEEN $3::timestamp AND $4::timestamp
+    AND h.ms_played >= $5
+    AND ($6::text[] IS NULL
+        OR h.spotify_artist_uri = ANY ($6::text[]))
+    AND ($7::text IS NULL
+        OR h.spotify_album_uri = $7::text)
+    AND ($8::text[] IS NULL
+        OR h.source = ANY ($8::text[]))
+ORDER BY
+    h.timestamp
+LIMIT $9
+`
+
+type HistoryExportPageParams struct {
+	UserID      uuid.UUID `json:"user_id"`
+	After       time.Time `json:"after"`
+	StartDate   time.Time `json:"start_date"`
+	EndDate     time.Time `json:"end_date"`
+	MinMsPlayed int32     `json:"min_ms_played"`
+	ArtistUris  []string  `json:"artist_uris"`
+	AlbumURI    *string   `json:"album_uri"`
+	Sources     []string  `json:"sources"`
+	PageSize    int32     `json:"page_size"`
+}
+
+type HistoryExportPageRow struct {
+	Timestamp        time.Time  `json:"timestamp"`
+	TrackName        string     `json:"track_name"`
+	ArtistName       string     `json:"artist_name"`
+	AlbumName        string     `json:"album_name"`
+	MsPlayed         int32      `json:"ms_played"`
+	Platform         string     `json:"platform"`
+	ConnCountry      string     `json:"conn_country"`
+	SpotifyTrackUri  string     `json:"spotify_track_uri"`
+	SpotifyArtistUri *string    `json:"spotify_artist_uri"`
+	SpotifyAlbumUri  *string    `json:"spotify_album_uri"`
+	Isrc             *string    `json:"isrc"`
+	ReasonStart      *string    `json:"reason_start"`
+	ReasonEnd        *string    `json:"reason_end"`
+	Shuffle          bool       `json:"shuffle"`
+	Skipped          *bool      `json:"skipped"`
+	Offline          bool       `json:"offline"`
+	IncognitoMode    bool       `json:"incognito_mode"`
+	FromHistory      bool       `json:"from_history"`
+	Source           string     `json:"source"`
+	TrackDurationMs  *int32     `json:"track_duration_ms"`
+	TrackPopularity  *int32     `json:"track_popularity"`
+	Explicit         *bool      `json:"explicit"`
+	AlbumType        *string    `json:"album_type"`
+	AlbumReleaseDate *time.Time `json:"album_release_date"`
+	ArtistGenres     []string   `json:"artist_genres"`
+	ArtistPopularity *int32     `json:"artist_popularity"`
+}
+
+func (q *Queries) HistoryExportPage(ctx context.Context, arg HistoryExportPageParams) ([]*HistoryExportPageRow, error) {
+	rows, err := q.db.Query(ctx, historyExportPage,
+		arg.UserID,
+		arg.After,
+		arg.StartDate,
+		arg.EndDate,
+		arg.MinMsPlayed,
+		arg.ArtistUris,
+		arg.AlbumURI,
+		arg.Sources,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*HistoryExportPageRow
+	for rows.Next() {
+		var i HistoryExportPageRow
+		if err := rows.Scan(
+			&i.Timestamp,
+			&i.TrackName,
+			&i.ArtistName,
+			&i.AlbumName,
+			&i.MsPlayed,
+			&i.Platform,
+			&i.ConnCountry,
+			&i.SpotifyTrackUri,
+			&i.SpotifyArtistUri,
+			&i.SpotifyAlbumUri,
+			&i.Isrc,
+			&i.ReasonStart,
+			&i.ReasonEnd,
+			&i.Shuffle,
+			&i.Skipped,
+			&i.Offline,
+			&i.IncognitoMode,
+			&i.FromHistory,
+			&i.Source,
+			&i.TrackDurationMs,
+			&i.TrackPopularity,
+			&i.Explicit,
+			&i.AlbumType,
+			&i.AlbumReleaseDate,
+			&i.ArtistGenres,
+			&i.ArtistPopularity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const historyGetAlbumStreamCountByYear = `-- name: HistoryGetAlbumStreamCountByYear :many
 SELECT
     album_name,
@@ -962,6 +1337,69 @@ func (q *Queries) HistoryGetRecentArtistStreams(ctx context.Context, arg History
 	return items, nil
 }
 
+const historyGetShowEpisodes = `-- name: HistoryGetShowEpisodes :many
+SELECT
+    episode_name,
+    episode_uri,
+    COUNT(*) AS occurrences,
+    SUM(ms_played)::bigint AS listened_ms,
+    COUNT(*) FILTER (WHERE reason_end = 'trackdone') AS completed,
+    MAX(timestamp)::timestamp AS last_played
+FROM
+    spotify_episode_history
+WHERE
+    user_id = $1
+    AND show_name = $2
+    AND ms_played >= $3
+GROUP BY
+    episode_name,
+    episode_uri
+ORDER BY
+    MAX(timestamp) DESC
+`
+
+type HistoryGetShowEpisodesParams struct {
+	UserID      uuid.UUID `json:"user_id"`
+	ShowName    string    `json:"show_name"`
+	MinMsPlayed int32     `json:"min_ms_played"`
+}
+
+type HistoryGetShowEpisodesRow struct {
+	EpisodeName string    `json:"episode_name"`
+	EpisodeUri  string    `json:"episode_uri"`
+	Occurrences int64     `json:"occurrences"`
+	ListenedMs  int64     `json:"listened_ms"`
+	Completed   int64     `json:"completed"`
+	LastPlayed  time.Time `json:"last_played"`
+}
+
+func (q *Queries) HistoryGetShowEpisodes(ctx context.Context, arg HistoryGetShowEpisodesParams) ([]*HistoryGetShowEpisodesRow, error) {
+	rows, err := q.db.Query(ctx, historyGetShowEpisodes, arg.UserID, arg.ShowName, arg.MinMsPlayed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*HistoryGetShowEpisodesRow
+	for rows.Next() {
+		var i HistoryGetShowEpisodesRow
+		if err := rows.Scan(
+			&i.EpisodeName,
+			&i.EpisodeUri,
+			&i.Occurrences,
+			&i.ListenedMs,
+			&i.Completed,
+			&i.LastPlayed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const historyGetTimestampRange = `-- name: HistoryGetTimestampRange :one
 SELECT
     MIN(timestamp)::timestamp AS first,
@@ -1073,11 +1511,13 @@ WHERE
     AND timestamp BETWEEN $3::timestamp AND $4::timestamp
     AND ($5::text IS NULL
         OR spotify_artist_uri = $5::text)
+    AND ($6::text[] IS NULL
+        OR source = ANY ($6::text[]))
 GROUP BY
     spotify_album_uri
 ORDER BY
     COUNT(*) DESC
-LIMIT $6
+LIMIT $7
 `
 
 type HistoryGetTopAlbumsInTimeframeParams struct {
@@ -1086,6 +1526,7 @@ type HistoryGetTopAlbumsInTimeframeParams struct {
 	StartDate   time.Time `json:"start_date"`
 	EndDate     time.Time `json:"end_date"`
 	ArtistURI   *string   `json:"artist_uri"`
+	Sources     []string  `json:"sources"`
 	Max         int32     `json:"max"`
 }
 
@@ -1102,6 +1543,7 @@ func (q *Queries) HistoryGetTopAlbumsInTimeframe(ctx context.Context, arg Histor
 		arg.StartDate,
 		arg.EndDate,
 		arg.ArtistURI,
+		arg.Sources,
 		arg.Max,
 	)
 	if err != nil {
@@ -1174,11 +1616,13 @@ WHERE
     user_id = $1
     AND ms_played >= $2
     AND timestamp BETWEEN $3::timestamp AND $4::timestamp
+    AND ($5::text[] IS NULL
+        OR source = ANY ($5::text[]))
 GROUP BY
     spotify_artist_uri
 ORDER BY
     COUNT(*) DESC
-LIMIT $5
+LIMIT $6
 `
 
 type HistoryGetTopArtistsInTimeframeParams struct {
@@ -1186,6 +1630,7 @@ type HistoryGetTopArtistsInTimeframeParams struct {
 	MinMsPlayed int32     `json:"min_ms_played"`
 	StartDate   time.Time `json:"start_date"`
 	EndDate     time.Time `json:"end_date"`
+	Sources     []string  `json:"sources"`
 	Max         int32     `json:"max"`
 }
 
@@ -1201,6 +1646,7 @@ func (q *Queries) HistoryGetTopArtistsInTimeframe(ctx context.Context, arg Histo
 		arg.MinMsPlayed,
 		arg.StartDate,
 		arg.EndDate,
+		arg.Sources,
 		arg.Max,
 	)
 	if err != nil {
@@ -1221,33 +1667,109 @@ func (q *Queries) HistoryGetTopArtistsInTimeframe(ctx context.Context, arg Histo
 	return items, nil
 }
 
-const historyGetTopTracksInTimeframe = `-- name: HistoryGetTopTracksInTimeframe :many
+const historyGetTopShowsInTimeframe = `-- name: HistoryGetTopShowsInTimeframe :many
 SELECT
-    spotify_track_uri,
-    COUNT(*) AS occurrences
+    show_name,
+    kind,
+    COUNT(*) AS occurrences,
+    SUM(ms_played)::bigint AS listened_ms,
+    COUNT(DISTINCT episode_uri) AS episode_count,
+    COUNT(*) FILTER (WHERE reason_end = 'trackdone') AS completed
 FROM
-    spotify_history
+    spotify_episode_history
 WHERE
     user_id = $1
     AND ms_played >= $2
     AND timestamp BETWEEN $3::timestamp AND $4::timestamp
-    AND ($5::text[] IS NULL
-        OR spotify_artist_uri = ANY ($5::text[]))
-    AND ($6::text IS NULL
-        OR spotify_album_uri = $6::text)
+    AND ($5::text IS NULL
+        OR kind = $5::text)
 GROUP BY
-    spotify_track_uri
+    show_name,
+    kind
 ORDER BY
-    COUNT(*) DESC
-LIMIT $7
+    SUM(ms_played) DESC
+LIMIT $6
 `
 
-type HistoryGetTopTracksInTimeframeParams struct {
+type HistoryGetTopShowsInTimeframeParams struct {
 	UserID      uuid.UUID `json:"user_id"`
 	MinMsPlayed int32     `json:"min_ms_played"`
 	StartDate   time.Time `json:"start_date"`
 	EndDate     time.Time `json:"end_date"`
-	ArtistUris  []string  `json:"artist_uris"`
+	Kind        *string   `json:"kind"`
+	Max         int32     `json:"max"`
+}
+
+type HistoryGetTopShowsInTimeframeRow struct {
+	ShowName     string `json:"show_name"`
+	Kind         string `json:"kind"`
+	Occurrences  int64  `json:"occurrences"`
+	ListenedMs   int64  `json:"listened_ms"`
+	EpisodeCount int64  `json:"episode_count"`
+	Completed    int64  `json:"completed"`
+}
+
+func (q *Queries) HistoryGetTopShowsInTimeframe(ctx context.Context, arg HistoryGetTopShowsInTimeframeParams) ([]*HistoryGetTopShowsInTimeframeRow, error) {
+	rows, err := q.db.Query(ctx, historyGetTopShowsInTimeframe,
+		arg.UserID,
+		arg.MinMsPlayed,
+		arg.StartDate,
+		arg.EndDate,
+		arg.Kind,
+		arg.Max,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*HistoryGetTopShowsInTimeframeRow
+	for rows.Next() {
+		var i HistoryGetTopShowsInTimeframeRow
+		if err := rows.Scan(
+			&i.ShowName,
+			&i.Kind,
+			&i.Occurrences,
+			&i.ListenedMs,
+			&i.EpisodeCount,
+			&i.Completed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const historyGetTopTracksInTimeframe = `-- name: HistoryGetTopTracksInTimeframe :many
+SELECT
+    spotify_track_uri,
+    COUNT(*) AS occurrences
+FROM
+    spotify_history
+WHERE
+    user_id = $1
+    AND ms_played >= $2
+    AND timestamp BETWEEN $3::timestamp AND $4::timestamp
+    AND ($5::text[] IS NULL
+        OR spotify_artist_uri = ANY ($5::text[]))
+    AND ($6::text IS NULL
+        OR spotify_album_uri = $6::text)
+GROUP BY
+    spotify_track_uri
+ORDER BY
+    COUNT(*) DESC
+LIMIT $7
+`
+
+type HistoryGetTopTracksInTimeframeParams struct {
+	UserID      uuid.UUID `json:"user_id"`
+	MinMsPlayed int32     `json:"min_ms_played"`
+	StartDate   time.Time `json:"start_date"`
+	EndDate     time.Time `json:"end_date"`
+	ArtistUris  []string  `json:"artist_uris"`
 	AlbumURI    *string   `json:"album_uri"`
 	MaxTracks   int32     `json:"max_tracks"`
 }
@@ -1302,11 +1824,13 @@ WITH top_isrcs AS (
             OR spotify_artist_uri = ANY ($5::text[]))
         AND ($6::text IS NULL
             OR h.spotify_album_uri = $6::text)
+        AND ($7::text[] IS NULL
+            OR h.source = ANY ($7::text[]))
     GROUP BY
         tc.isrc
     ORDER BY
         COUNT(*) DESC
-    LIMIT $7
+    LIMIT $8
 ),
 pref_albums AS (
     SELECT DISTINCT ON (top_isrcs.isrc)
@@ -1344,6 +1868,7 @@ type HistoryGetTopTracksInTimeframeDedupParams struct {
 	EndDate     time.Time `json:"end_date"`
 	ArtistUris  []string  `json:"artist_uris"`
 	AlbumURI    *string   `json:"album_uri"`
+	Sources     []string  `json:"sources"`
 	MaxTracks   int32     `json:"max_tracks"`
 }
 
@@ -1362,6 +1887,7 @@ func (q *Queries) HistoryGetTopTracksInTimeframeDedup(ctx context.Context, arg H
 		arg.EndDate,
 		arg.ArtistUris,
 		arg.AlbumURI,
+		arg.Sources,
 		arg.MaxTracks,
 	)
 	if err != nil {
@@ -1539,6 +2065,162 @@ func (q *Queries) HistoryGetTrackURIForAlbum(ctx context.Context, arg HistoryGet
 	return spotify_track_uri, err
 }
 
+const historyImportReviewDelete = `-- name: HistoryImportReviewDelete :execrows
+DELETE FROM history_import_review
+WHERE id = $1
+    AND user_id = $2
+`
+
+type HistoryImportReviewDeleteParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) HistoryImportReviewDelete(ctx context.Context, arg HistoryImportReviewDeleteParams) (int64, error) {
+	result, err := q.db.Exec(ctx, historyImportReviewDelete, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const historyImportReviewGet = `-- name: HistoryImportReviewGet :one
+SELECT
+    id, user_id, source, timestamp, track_name, artist_name, album_name, isrc, duration_ms, created
+FROM
+    history_import_review
+WHERE
+    id = $1
+    AND user_id = $2
+`
+
+type HistoryImportReviewGetParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) HistoryImportReviewGet(ctx context.Context, arg HistoryImportReviewGetParams) (*HistoryImportReview, error) {
+	row := q.db.QueryRow(ctx, historyImportReviewGet, arg.ID, arg.UserID)
+	var i HistoryImportReview
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Source,
+		&i.Timestamp,
+		&i.TrackName,
+		&i.ArtistName,
+		&i.AlbumName,
+		&i.Isrc,
+		&i.DurationMs,
+		&i.Created,
+	)
+	return &i, err
+}
+
+const historyImportReviewGetAll = `-- name: HistoryImportReviewGetAll :many
+SELECT
+    id, user_id, source, timestamp, track_name, artist_name, album_name, isrc, duration_ms, created
+FROM
+    history_import_review
+WHERE
+    user_id = $1
+ORDER BY
+    timestamp
+LIMIT $2
+`
+
+type HistoryImportReviewGetAllParams struct {
+	UserID uuid.UUID `json:"user_id"`
+	Max    int32     `json:"max"`
+}
+
+func (q *Queries) HistoryImportReviewGetAll(ctx context.Context, arg HistoryImportReviewGetAllParams) ([]*HistoryImportReview, error) {
+	rows, err := q.db.Query(ctx, historyImportReviewGetAll, arg.UserID, arg.Max)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*HistoryImportReview
+	for rows.Next() {
+		var i HistoryImportReview
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Source,
+			&i.Timestamp,
+			&i.TrackName,
+			&i.ArtistName,
+			&i.AlbumName,
+			&i.Isrc,
+			&i.DurationMs,
+			&i.Created,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const historyImportReviewInsertBulk = `-- name: HistoryImportReviewInsertBulk :exec
+INSERT INTO history_import_review(
+    user_id,
+    source,
+    timestamp,
+    track_name,
+    artist_name,
+    album_name,
+    isrc,
+    duration_ms)
+VALUES (
+    unnest(
+        $1::uuid[]),
+    unnest(
+        $2::text[]),
+    unnest(
+        $3::timestamp[]),
+    unnest(
+        $4::text[]),
+    unnest(
+        $5::text[]),
+    unnest(
+        $6::text[]),
+    unnest(
+        $7::text[]),
+    unnest(
+        $8::integer[]))
+ON CONFLICT
+    DO NOTHING
+`
+
+type HistoryImportReviewInsertBulkParams struct {
+	UserIds    []uuid.UUID `json:"user_ids"`
+	Source     []string    `json:"source"`
+	Timestamp  []time.Time `json:"timestamp"`
+	TrackName  []string    `json:"track_name"`
+	ArtistName []string    `json:"artist_name"`
+	AlbumName  []string    `json:"album_name"`
+	Isrc       []string    `json:"isrc"`
+	DurationMs []int32     `json:"duration_ms"`
+}
+
+func (q *Queries) HistoryImportReviewInsertBulk(ctx context.Context, arg HistoryImportReviewInsertBulkParams) error {
+	_, err := q.db.Exec(ctx, historyImportReviewInsertBulk,
+		arg.UserIds,
+		arg.Source,
+		arg.Timestamp,
+		arg.TrackName,
+		arg.ArtistName,
+		arg.AlbumName,
+		arg.Isrc,
+		arg.DurationMs,
+	)
+	return err
+}
+
 const historyInsertBulk = `-- name: HistoryInsertBulk :exec
 INSERT INTO SPOTIFY_HISTORY(
     user_id,
@@ -1561,7 +2243,8 @@ INSERT INTO SPOTIFY_HISTORY(
     offline,
     incognito_mode,
     from_history,
-    isrc)
+    isrc,
+    source)
 VALUES (
     unnest(
         $1::uuid[]),
@@ -1604,7 +2287,9 @@ VALUES (
     unnest(
         $20::boolean[]),
     unnest(
-        $21::text[]))
+        $21::text[]),
+    unnest(
+        $22::text[]))
 ON CONFLICT
     DO NOTHING
 `
@@ -1631,6 +2316,7 @@ type HistoryInsertBulkParams struct {
 	IncognitoMode    []bool      `json:"incognito_mode"`
 	FromHistory      []bool      `json:"from_history"`
 	Isrc             []string    `json:"isrc"`
+	Source           []string    `json:"source"`
 }
 
 func (q *Queries) HistoryInsertBulk(ctx context.Context, arg HistoryInsertBulkParams) error {
@@ -1656,6 +2342,7 @@ func (q *Queries) HistoryInsertBulk(ctx context.Context, arg HistoryInsertBulkPa
 		arg.IncognitoMode,
 		arg.FromHistory,
 		arg.Isrc,
+		arg.Source,
 	)
 	return err
 }
@@ -1683,7 +2370,8 @@ INSERT INTO SPOTIFY_HISTORY(
     offline_timestamp,
     incognito_mode,
     from_history,
-    isrc)
+    isrc,
+    source)
 VALUES (
     $1,
     $2,
@@ -1706,7 +2394,8 @@ VALUES (
     $19,
     $20,
     $21,
-    $22)
+    $22,
+    $23)
 `
 
 type HistoryInsertOneParams struct {
@@ -1732,6 +2421,7 @@ type HistoryInsertOneParams struct {
 	IncognitoMode    bool       `json:"incognito_mode"`
 	FromHistory      bool       `json:"from_history"`
 	Isrc             *string    `json:"isrc"`
+	Source           string     `json:"source"`
 }
 
 func (q *Queries) HistoryInsertOne(ctx context.Context, arg HistoryInsertOneParams) error {
@@ -1758,71 +2448,48 @@ func (q *Queries) HistoryInsertOne(ctx context.Context, arg HistoryInsertOnePara
 		arg.IncognitoMode,
 		arg.FromHistory,
 		arg.Isrc,
+		arg.Source,
 	)
 	return err
 }
 
-const historySetURIsForTrack = `-- name: HistorySetURIsForTrack :exec
-UPDATE
-    spotify_history
-SET
-    spotify_artist_uri = $1,
-    spotify_album_uri = $2
-WHERE
-    spotify_track_uri = $3
-`
-
-type HistorySetURIsForTrackParams struct {
-	SpotifyArtistUri *string `json:"spotify_artist_uri"`
-	SpotifyAlbumUri  *string `json:"spotify_album_uri"`
-	SpotifyTrackUri  string  `json:"spotify_track_uri"`
-}
-
-func (q *Queries) HistorySetURIsForTrack(ctx context.Context, arg HistorySetURIsForTrackParams) error {
-	_, err := q.db.Exec(ctx, historySetURIsForTrack, arg.SpotifyArtistUri, arg.SpotifyAlbumUri, arg.SpotifyTrackUri)
-	return err
-}
-
-const missingArtistURIs = `-- name: MissingArtistURIs :many
+const historyMergeGetAll = `-- name: HistoryMergeGetAll :many
 SELECT
-  SPOTIFY_TRACK_URI,
-  COUNT(SPOTIFY_TRACK_URI),
-(array_agg(track_name))[1] AS track_name,
-(array_agg(artist_name))[1] AS artist_name,
-(array_agg(DISTINCT user_id))::text[] AS user_ids
+    id, user_id, upload_job_id, spotify_track_uri, kept_timestamp, kept_ms_played, removed_timestamp, removed_ms_played, created
 FROM
-  spotify_history
+    history_merges
 WHERE
-  spotify_artist_uri IS NULL
-GROUP BY
-  SPOTIFY_TRACK_URI
+    user_id = $1
 ORDER BY
-  COUNT DESC
+    created DESC,
+    removed_timestamp DESC
+LIMIT $2
 `
 
-type MissingArtistURIsRow struct {
-	SpotifyTrackUri string      `json:"spotify_track_uri"`
-	Count           int64       `json:"count"`
-	TrackName       interface{} `json:"track_name"`
-	ArtistName      interface{} `json:"artist_name"`
-	UserIds         []string    `json:"user_ids"`
+type HistoryMergeGetAllParams struct {
+	UserID uuid.UUID `json:"user_id"`
+	Max    int32     `json:"max"`
 }
 
-func (q *Queries) MissingArtistURIs(ctx context.Context) ([]*MissingArtistURIsRow, error) {
-	rows, err := q.db.Query(ctx, missingArtistURIs)
+func (q *Queries) HistoryMergeGetAll(ctx context.Context, arg HistoryMergeGetAllParams) ([]*HistoryMerge, error) {
+	rows, err := q.db.Query(ctx, historyMergeGetAll, arg.UserID, arg.Max)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []*MissingArtistURIsRow
+	var items []*HistoryMerge
 	for rows.Next() {
-		var i MissingArtistURIsRow
+		var i HistoryMerge
 		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.UploadJobID,
 			&i.SpotifyTrackUri,
-			&i.Count,
-			&i.TrackName,
-			&i.ArtistName,
-			&i.UserIds,
+			&i.KeptTimestamp,
+			&i.KeptMsPlayed,
+			&i.RemovedTimestamp,
+			&i.RemovedMsPlayed,
+			&i.Created,
 		); err != nil {
 			return nil, err
 		}
@@ -1834,78 +2501,1369 @@ func (q *Queries) MissingArtistURIs(ctx context.Context) ([]*MissingArtistURIsRo
 	return items, nil
 }
 
-const missingISRCNumbers = `-- name: MissingISRCNumbers :many
+const historyMergeInsertBulk = `-- name: HistoryMergeInsertBulk :many
+INSERT INTO history_merges(
+    user_id,
+    upload_job_id,
+    spotify_track_uri,
+    kept_timestamp,
+    kept_ms_played,
+    removed_timestamp,
+    removed_ms_played)
 SELECT
-  h.user_id, h.timestamp, h.platform, h.ms_played, h.conn_country, h.ip_addr, h.user_agent, h.track_name, h.artist_name, h.album_name, h.spotify_track_uri, h.reason_start, h.reason_end, h.shuffle, h.skipped, h.offline, h.offline_timestamp, h.incognito_mode, h.spotify_artist_uri, h.spotify_album_uri, h.from_history, h.isrc,
-  tc.isrc
-FROM
-  SPOTIFY_HISTORY h
-  JOIN spotify_track_cache tc ON h.ISRC IS NULL
-    AND tc.isrc IS NOT NULL
-    AND tc.uri = h.spotify_track_uri
-  ORDER BY
-    TIMESTAMP DESC
+    $1::uuid,
+    $2::uuid,
+    unnest($3::text[]),
+    unnest($4::timestamp[]),
+    unnest($5::integer[]),
+    unnest($6::timestamp[]),
+    unnest($7::integer[])
+RETURNING
+    id, user_id, upload_job_id, spotify_track_uri, kept_timestamp, kept_ms_played, removed_timestamp, removed_ms_played, created
 `
 
-type MissingISRCNumbersRow struct {
-	UserID           uuid.UUID  `json:"user_id"`
-	Timestamp        time.Time  `json:"timestamp"`
-	Platform         string     `json:"platform"`
-	MsPlayed         int32      `json:"ms_played"`
-	ConnCountry      string     `json:"conn_country"`
-	IpAddr           *string    `json:"ip_addr"`
-	UserAgent        *string    `json:"user_agent"`
-	TrackName        string     `json:"track_name"`
-	ArtistName       string     `json:"artist_name"`
-	AlbumName        string     `json:"album_name"`
-	SpotifyTrackUri  string     `json:"spotify_track_uri"`
-	ReasonStart      *string    `json:"reason_start"`
-	ReasonEnd        *string    `json:"reason_end"`
-	Shuffle          bool       `json:"shuffle"`
-	Skipped          *bool      `json:"skipped"`
-	Offline          bool       `json:"offline"`
-	OfflineTimestamp *time.Time `json:"offline_timestamp"`
-	IncognitoMode    bool       `json:"incognito_mode"`
-	SpotifyArtistUri *string    `json:"spotify_artist_uri"`
-	SpotifyAlbumUri  *string    `json:"spotify_album_uri"`
-	FromHistory      bool       `json:"from_history"`
-	Isrc             *string    `json:"isrc"`
-	Isrc_2           *string    `json:"isrc_2"`
+type HistoryMergeInsertBulkParams struct {
+	UserID           uuid.UUID   `json:"user_id"`
+	UploadJobID      *uuid.UUID  `json:"upload_job_id"`
+	SpotifyTrackUri  []string    `json:"spotify_track_uri"`
+	KeptTimestamp    []time.Time `json:"kept_timestamp"`
+	KeptMsPlayed     []int32     `json:"kept_ms_played"`
+	RemovedTimestamp []time.Time `json:"removed_timestamp"`
+	RemovedMsPlayed  []int32     `json:"removed_ms_played"`
 }
 
-func (q *Queries) MissingISRCNumbers(ctx context.Context) ([]*MissingISRCNumbersRow, error) {
-	rows, err := q.db.Query(ctx, missingISRCNumbers)
+func (q *Queries) HistoryMergeInsertBulk(ctx context.Context, arg HistoryMergeInsertBulkParams) ([]*HistoryMerge, error) {
+	rows, err := q.db.Query(ctx, historyMergeInsertBulk,
+		arg.UserID,
+		arg.UploadJobID,
+		arg.SpotifyTrackUri,
+		arg.KeptTimestamp,
+		arg.KeptMsPlayed,
+		arg.RemovedTimestamp,
+		arg.RemovedMsPlayed,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []*MissingISRCNumbersRow
+	var items []*HistoryMerge
 	for rows.Next() {
-		var i MissingISRCNumbersRow
+		var i HistoryMerge
 		if err := rows.Scan(
+			&i.ID,
 			&i.UserID,
+			&i.UploadJobID,
+			&i.SpotifyTrackUri,
+			&i.KeptTimestamp,
+			&i.KeptMsPlayed,
+			&i.RemovedTimestamp,
+			&i.RemovedMsPlayed,
+			&i.Created,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const historyNearDuplicates = `-- name: HistoryNearDuplicates :many
+SELECT
+    h.timestamp,
+    h.spotify_track_uri,
+    h.ms_played,
+    kept.timestamp AS kept_timestamp,
+    kept.ms_played AS kept_ms_played
+FROM
+    spotify_history h
+    JOIN LATERAL (
+        SELECT
+            k.timestamp,
+            k.ms_played
+        FROM
+            spotify_history k
+        WHERE
+            k.user_id = h.user_id
+            AND k.from_history
+            AND k.spotify_track_uri = h.spotify_track_uri
+            AND k.timestamp BETWEEN h.timestamp - make_interval(secs => $1::integer)
+            AND h.timestamp + make_interval(secs => $1::integer)
+        ORDER BY
+            abs(extract(epoch FROM k.timestamp - h.timestamp))
+        LIMIT 1) kept ON TRUE
+WHERE
+    h.user_id = $2
+    AND NOT h.from_history
+    AND h.source = 'spotify'
+ORDER BY
+    h.timestamp
+`
+
+type HistoryNearDuplicatesParams struct {
+	ToleranceSeconds int32     `json:"tolerance_seconds"`
+	UserID           uuid.UUID `json:"user_id"`
+}
+
+type HistoryNearDuplicatesRow struct {
+	Timestamp       time.Time `json:"timestamp"`
+	SpotifyTrackUri string    `json:"spotify_track_uri"`
+	MsPlayed        int32     `json:"ms_played"`
+	KeptTimestamp   time.Time `json:"kept_timestamp"`
+	KeptMsPlayed    int32     `json:"kept_ms_played"`
+}
+
+func (q *Queries) HistoryNearDuplicates(ctx context.Context, arg HistoryNearDuplicatesParams) ([]*HistoryNearDuplicatesRow, error) {
+	rows, err := q.db.Query(ctx, historyNearDuplicates, arg.ToleranceSeconds, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*HistoryNearDuplicatesRow
+	for rows.Next() {
+		var i HistoryNearDuplicatesRow
+		if err := rows.Scan(
 			&i.Timestamp,
-			&i.Platform,
+			&i.SpotifyTrackUri,
 			&i.MsPlayed,
-			&i.ConnCountry,
-			&i.IpAddr,
-			&i.UserAgent,
+			&i.KeptTimestamp,
+			&i.KeptMsPlayed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const historySetURIsForTrack = `-- name: HistorySetURIsForTrack :exec
+UPDATE
+    spotify_history
+SET
+    spotify_artist_uri = $1,
+    spotify_album_uri = $2
+WHERE
+    spotify_track_uri = $3
+`
+
+type HistorySetURIsForTrackParams struct {
+	SpotifyArtistUri *string `json:"spotify_artist_uri"`
+	SpotifyAlbumUri  *string `json:"spotify_album_uri"`
+	SpotifyTrackUri  string  `json:"spotify_track_uri"`
+}
+
+func (q *Queries) HistorySetURIsForTrack(ctx context.Context, arg HistorySetURIsForTrackParams) error {
+	_, err := q.db.Exec(ctx, historySetURIsForTrack, arg.SpotifyArtistUri, arg.SpotifyAlbumUri, arg.SpotifyTrackUri)
+	return err
+}
+
+const historyUploadJobFileDone = `-- name: HistoryUploadJobFileDone :exec
+WITH done AS (
+    INSERT INTO history_upload_job_files(
+        job_id,
+        name,
+        entries)
+    VALUES (
+        $1,
+        $2,
+        $3)
+RETURNING
+    job_id,
+    entries)
+UPDATE
+    history_upload_jobs
+SET
+    files_done = files_done + 1,
+    entries_done = entries_done + done.entries,
+    updated = NOW()
+FROM
+    done
+WHERE
+    id = done.job_id
+`
+
+type HistoryUploadJobFileDoneParams struct {
+	JobID   uuid.UUID `json:"job_id"`
+	Name    string    `json:"name"`
+	Entries int32     `json:"entries"`
+}
+
+func (q *Queries) HistoryUploadJobFileDone(ctx context.Context, arg HistoryUploadJobFileDoneParams) error {
+	_, err := q.db.Exec(ctx, historyUploadJobFileDone, arg.JobID, arg.Name, arg.Entries)
+	return err
+}
+
+const historyUploadJobGet = `-- name: HistoryUploadJobGet :one
+SELECT
+    id, user_id, status, archive_path, files_total, files_done, entries_done, error, created, updated, source
+FROM
+    history_upload_jobs
+WHERE
+    id = $1
+    AND user_id = $2
+`
+
+type HistoryUploadJobGetParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) HistoryUploadJobGet(ctx context.Context, arg HistoryUploadJobGetParams) (*HistoryUploadJob, error) {
+	row := q.db.QueryRow(ctx, historyUploadJobGet, arg.ID, arg.UserID)
+	var i HistoryUploadJob
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Status,
+		&i.ArchivePath,
+		&i.FilesTotal,
+		&i.FilesDone,
+		&i.EntriesDone,
+		&i.Error,
+		&i.Created,
+		&i.Updated,
+		&i.Source,
+	)
+	return &i, err
+}
+
+const historyUploadJobGetDoneFiles = `-- name: HistoryUploadJobGetDoneFiles :many
+SELECT
+    name
+FROM
+    history_upload_job_files
+WHERE
+    job_id = $1
+`
+
+func (q *Queries) HistoryUploadJobGetDoneFiles(ctx context.Context, jobID uuid.UUID) ([]string, error) {
+	rows, err := q.db.Query(ctx, historyUploadJobGetDoneFiles, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const historyUploadJobInsert = `-- name: HistoryUploadJobInsert :one
+INSERT INTO history_upload_jobs(
+    user_id,
+    source,
+    archive_path,
+    files_total)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4)
+RETURNING
+    id, user_id, status, archive_path, files_total, files_done, entries_done, error, created, updated, source
+`
+
+type HistoryUploadJobInsertParams struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Source      string    `json:"source"`
+	ArchivePath string    `json:"archive_path"`
+	FilesTotal  int32     `json:"files_total"`
+}
+
+func (q *Queries) HistoryUploadJobInsert(ctx context.Context, arg HistoryUploadJobInsertParams) (*HistoryUploadJob, error) {
+	row := q.db.QueryRow(ctx, historyUploadJobInsert,
+		arg.UserID,
+		arg.Source,
+		arg.ArchivePath,
+		arg.FilesTotal,
+	)
+	var i HistoryUploadJob
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Status,
+		&i.ArchivePath,
+		&i.FilesTotal,
+		&i.FilesDone,
+		&i.EntriesDone,
+		&i.Error,
+		&i.Created,
+		&i.Updated,
+		&i.Source,
+	)
+	return &i, err
+}
+
+const historyUploadJobResume = `-- name: HistoryUploadJobResume :one
+UPDATE
+    history_upload_jobs
+SET
+    status = 'running',
+    error = NULL,
+    updated = NOW()
+WHERE
+    id = $1
+    AND user_id = $2
+    AND (status = 'failed'
+        OR (status = 'running'
+            AND updated < $3))
+RETURNING
+    id, user_id, status, archive_path, files_total, files_done, entries_done, error, created, updated, source
+`
+
+type HistoryUploadJobResumeParams struct {
+	ID          uuid.UUID `json:"id"`
+	UserID      uuid.UUID `json:"user_id"`
+	StaleBefore time.Time `json:"stale_before"`
+}
+
+func (q *Queries) HistoryUploadJobResume(ctx context.Context, arg HistoryUploadJobResumeParams) (*HistoryUploadJob, error) {
+	row := q.db.QueryRow(ctx, historyUploadJobResume, arg.ID, arg.UserID, arg.StaleBefore)
+	var i HistoryUploadJob
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Status,
+		&i.ArchivePath,
+		&i.FilesTotal,
+		&i.FilesDone,
+		&i.EntriesDone,
+		&i.Error,
+		&i.Created,
+		&i.Updated,
+		&i.Source,
+	)
+	return &i, err
+}
+
+const historyUploadJobSetStatus = `-- name: HistoryUploadJobSetStatus :exec
+UPDATE
+    history_upload_jobs
+SET
+    status = $1,
+    error = $2,
+    updated = NOW()
+WHERE
+    id = $3
+`
+
+type HistoryUploadJobSetStatusParams struct {
+	Status string    `json:"status"`
+	Error  *string   `json:"error"`
+	ID     uuid.UUID `json:"id"`
+}
+
+func (q *Queries) HistoryUploadJobSetStatus(ctx context.Context, arg HistoryUploadJobSetStatusParams) error {
+	_, err := q.db.Exec(ctx, historyUploadJobSetStatus, arg.Status, arg.Error, arg.ID)
+	return err
+}
+
+const missingArtistURIs = `-- name: MissingArtistURIs :many
+SELECT
+  SPOTIFY_TRACK_URI,
+  COUNT(SPOTIFY_TRACK_URI),
+(array_agg(track_name))[1] AS track_name,
+(array_agg(artist_name))[1] AS artist_name,
+(array_agg(DISTINCT user_id))::text[] AS user_ids
+FROM
+  spotify_history
+WHERE
+  spotify_artist_uri IS NULL
+GROUP BY
+  SPOTIFY_TRACK_URI
+ORDER BY
+  COUNT DESC
+`
+
+type MissingArtistURIsRow struct {
+	SpotifyTrackUri string      `json:"spotify_track_uri"`
+	Count           int64       `json:"count"`
+	TrackName       interface{} `json:"track_name"`
+	ArtistName      interface{} `json:"artist_name"`
+	UserIds         []string    `json:"user_ids"`
+}
+
+func (q *Queries) MissingArtistURIs(ctx context.Context) ([]*MissingArtistURIsRow, error) {
+	rows, err := q.db.Query(ctx, missingArtistURIs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*MissingArtistURIsRow
+	for rows.Next() {
+		var i MissingArtistURIsRow
+		if err := rows.Scan(
+			&i.SpotifyTrackUri,
+			&i.Count,
 			&i.TrackName,
 			&i.ArtistName,
-			&i.AlbumName,
-			&i.SpotifyTrackUri,
-			&i.ReasonStart,
-			&i.ReasonEnd,
-			&i.Shuffle,
-			&i.Skipped,
-			&i.Offline,
-			&i.OfflineTimestamp,
-			&i.IncognitoMode,
-			&i.SpotifyArtistUri,
-			&i.SpotifyAlbumUri,
-			&i.FromHistory,
-			&i.Isrc,
-			&i.Isrc_2,
+			&i.UserIds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const missingISRCNumbers = `-- name: MissingISRCNumbers :many
+SELECT
+  h.user_id, h.timestamp, h.platform, h.ms_played, h.conn_country, h.ip_addr, h.user_agent, h.track_name, h.artist_name, h.album_name, h.spotify_track_uri, h.reason_start, h.reason_end, h.shuffle, h.skipped, h.offline, h.offline_timestamp, h.incognito_mode, h.spotify_artist_uri, h.spotify_album_uri, h.from_history, h.isrc, h.source,
+  tc.isrc
+FROM
+  SPOTIFY_HISTORY h
+  JOIN spotify_track_cache tc ON h.ISRC IS NULL
+    AND tc.isrc IS NOT NULL
+    AND tc.uri = h.spotify_track_uri
+  ORDER BY
+    TIMESTAMP DESC
+`
+
+type MissingISRCNumbersRow struct {
+	UserID           uuid.UUID  `json:"user_id"`
+	Timestamp        time.Time  `json:"timestamp"`
+	Platform         string     `json:"platform"`
+	MsPlayed         int32      `json:"ms_played"`
+	ConnCountry      string     `json:"conn_country"`
+	IpAddr           *string    `json:"ip_addr"`
+	UserAgent        *string    `json:"user_agent"`
+	TrackName        string     `json:"track_name"`
+	ArtistName       string     `json:"artist_name"`
+	AlbumName        string     `json:"album_name"`
+	SpotifyTrackUri  string     `json:"spotify_track_uri"`
+	ReasonStart      *string    `json:"reason_start"`
+	ReasonEnd        *string    `json:"reason_end"`
+	Shuffle          bool       `json:"shuffle"`
+	Skipped          *bool      `json:"skipped"`
+	Offline          bool       `json:"offline"`
+	OfflineTimestamp *time.Time `json:"offline_timestamp"`
+	IncognitoMode    bool       `json:"incognito_mode"`
+	SpotifyArtistUri *string    `json:"spotify_artist_uri"`
+	SpotifyAlbumUri  *string    `json:"spotify_album_uri"`
+	FromHistory      bool       `json:"from_history"`
+	Isrc             *string    `json:"isrc"`
+	Source           string     `json:"source"`
+	Isrc_2           *string    `json:"isrc_2"`
+}
+
+func (q *Queries) MissingISRCNumbers(ctx context.Context) ([]*MissingISRCNumbersRow, error) {
+	rows, err := q.db.Query(ctx, missingISRCNumbers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*MissingISRCNumbersRow
+	for rows.Next() {
+		var i MissingISRCNumbersRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Timestamp,
+			&i.Platform,
+			&i.MsPlayed,
+			&i.ConnCountry,
+			&i.IpAddr,
+			&i.UserAgent,
+			&i.TrackName,
+			&i.ArtistName,
+			&i.AlbumName,
+			&i.SpotifyTrackUri,
+			&i.ReasonStart,
+			&i.ReasonEnd,
+			&i.Shuffle,
+			&i.Skipped,
+			&i.Offline,
+			&i.OfflineTimestamp,
+			&i.IncognitoMode,
+			&i.SpotifyArtistUri,
+			&i.SpotifyAlbumUri,
+			&i.FromHistory,
+			&i.Isrc,
+			&i.Source,
+			&i.Isrc_2,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const roomAddMember = `-- name: RoomAddMember :exec
+INSERT INTO room_members(
+    user_id,
+    room_id)
+VALUES (
+    $1,
+    $2)
+`
+
+type RoomAddMemberParams struct {
+	UserID uuid.UUID `json:"user_id"`
+	RoomID uuid.UUID `json:"room_id"`
+}
+
+func (q *Queries) RoomAddMember(ctx context.Context, arg RoomAddMemberParams) error {
+	_, err := q.db.Exec(ctx, roomAddMember, arg.UserID, arg.RoomID)
+	return err
+}
+
+const roomAddMemberByUsername = `-- name: RoomAddMemberByUsername :one
+INSERT INTO room_members(
+    user_id,
+    room_id,
+    is_moderator)
+SELECT
+    u.id,
+    $1,
+    $3
+FROM
+    users u
+WHERE
+    u.username = $2
+RETURNING
+    id
+`
+
+type RoomAddMemberByUsernameParams struct {
+	RoomID      uuid.UUID `json:"room_id"`
+	Username    string    `json:"username"`
+	IsModerator bool      `json:"is_moderator"`
+}
+
+func (q *Queries) RoomAddMemberByUsername(ctx context.Context, arg RoomAddMemberByUsernameParams) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, roomAddMemberByUsername, arg.RoomID, arg.Username, arg.IsModerator)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const roomAddMemberWithRole = `-- name: RoomAddMemberWithRole :exec
+INSERT INTO room_members(
+    user_id,
+    room_id,
+    is_moderator)
+VALUES (
+    $1,
+    $2,
+    $3)
+ON CONFLICT (user_id,
+    room_id)
+    DO UPDATE SET
+        is_moderator = room_members.is_moderator
+        OR EXCLUDED.is_moderator
+`
+
+type RoomAddMemberWithRoleParams struct {
+	UserID      uuid.UUID `json:"user_id"`
+	RoomID      uuid.UUID `json:"room_id"`
+	IsModerator bool      `json:"is_moderator"`
+}
+
+func (q *Queries) RoomAddMemberWithRole(ctx context.Context, arg RoomAddMemberWithRoleParams) error {
+	_, err := q.db.Exec(ctx, roomAddMemberWithRole, arg.UserID, arg.RoomID, arg.IsModerator)
+	return err
+}
+
+const roomBlocklistDelete = `-- name: RoomBlocklistDelete :execrows
+DELETE FROM room_blocklist
+WHERE id = $1
+    AND room_id = $2
+`
+
+type RoomBlocklistDeleteParams struct {
+	ID     uuid.UUID `json:"id"`
+	RoomID uuid.UUID `json:"room_id"`
+}
+
+func (q *Queries) RoomBlocklistDelete(ctx context.Context, arg RoomBlocklistDeleteParams) (int64, error) {
+	result, err := q.db.Exec(ctx, roomBlocklistDelete, arg.ID, arg.RoomID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const roomBlocklistGetAll = `-- name: RoomBlocklistGetAll :many
+SELECT
+    id, room_id, kind, value, added_by, created
+FROM
+    room_blocklist
+WHERE
+    room_id = $1
+ORDER BY
+    created DESC
+`
+
+func (q *Queries) RoomBlocklistGetAll(ctx context.Context, roomID uuid.UUID) ([]*RoomBlocklist, error) {
+	rows, err := q.db.Query(ctx, roomBlocklistGetAll, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*RoomBlocklist
+	for rows.Next() {
+		var i RoomBlocklist
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Kind,
+			&i.Value,
+			&i.AddedBy,
+			&i.Created,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const roomBlocklistInsert = `-- name: RoomBlocklistInsert :one
+INSERT INTO room_blocklist(
+    room_id,
+    kind,
+    value,
+    added_by)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4)
+RETURNING
+    id, room_id, kind, value, added_by, created
+`
+
+type RoomBlocklistInsertParams struct {
+	RoomID  uuid.UUID  `json:"room_id"`
+	Kind    string     `json:"kind"`
+	Value   string     `json:"value"`
+	AddedBy *uuid.UUID `json:"added_by"`
+}
+
+func (q *Queries) RoomBlocklistInsert(ctx context.Context, arg RoomBlocklistInsertParams) (*RoomBlocklist, error) {
+	row := q.db.QueryRow(ctx, roomBlocklistInsert,
+		arg.RoomID,
+		arg.Kind,
+		arg.Value,
+		arg.AddedBy,
+	)
+	var i RoomBlocklist
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Kind,
+		&i.Value,
+		&i.AddedBy,
+		&i.Created,
+	)
+	return &i, err
+}
+
+const roomCountActiveParticipants = `-- name: RoomCountActiveParticipants :one
+SELECT
+    COUNT(DISTINCT participant_id)::integer AS active_count
+FROM (
+    SELECT
+        COALESCE(user_id, guest_id) AS participant_id
+    FROM
+        room_queue_tracks
+    WHERE
+        room_id = $1
+        AND timestamp >= $2::timestamptz
+    UNION
+    SELECT
+        v.voter_id
+    FROM
+        room_queue_votes v
+        JOIN room_queue_tracks t ON t.id = v.queue_track_id
+    WHERE
+        t.room_id = $1
+        AND v.timestamp >= $2::timestamptz
+    UNION
+    SELECT
+        voter_id
+    FROM
+        room_skip_votes
+    WHERE
+        room_id = $1
+        AND timestamp >= $2::timestamptz
+    UNION
+    SELECT
+        host_id
+    FROM
+        rooms
+    WHERE
+        id = $1) participants
+WHERE
+    participant_id IS NOT NULL
+`
+
+type RoomCountActiveParticipantsParams struct {
+	RoomID uuid.UUID `json:"room_id"`
+	Since  time.Time `json:"since"`
+}
+
+func (q *Queries) RoomCountActiveParticipants(ctx context.Context, arg RoomCountActiveParticipantsParams) (int32, error) {
+	row := q.db.QueryRow(ctx, roomCountActiveParticipants, arg.RoomID, arg.Since)
+	var activeCount int32
+	err := row.Scan(&activeCount)
+	return activeCount, err
+}
+
+const roomDeleteByID = `-- name: RoomDeleteByID :exec
+DELETE FROM rooms r
+WHERE r.code = $1
+`
+
+func (q *Queries) RoomDeleteByID(ctx context.Context, code string) error {
+	_, err := q.db.Exec(ctx, roomDeleteByID, code)
+	return err
+}
+
+const roomDeleteSchedule = `-- name: RoomDeleteSchedule :exec
+DELETE FROM room_schedules
+WHERE room_id = $1
+`
+
+func (q *Queries) RoomDeleteSchedule(ctx context.Context, roomID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, roomDeleteSchedule, roomID)
+	return err
+}
+
+const roomGetAllGuests = `-- name: RoomGetAllGuests :many
+SELECT
+    rg.name,
+    rg.id,
+    CASE WHEN counts.queued_tracks IS NOT NULL THEN
+        counts.queued_tracks
+    ELSE
+        0
+    END AS queued_tracks
+FROM
+    room_guests AS rg
+    LEFT JOIN (
+        SELECT
+            guest_id,
+            COUNT(*) AS queued_tracks
+        FROM
+            room_queue_tracks
+        GROUP BY
+            guest_id) counts ON rg.id = counts.guest_id
+WHERE
+    rg.room_id = $1
+`
+
+type RoomGetAllGuestsRow struct {
+	Name         string    `json:"name"`
+	ID           uuid.UUID `json:"id"`
+	QueuedTracks int32     `json:"queued_tracks"`
+}
+
+func (q *Queries) RoomGetAllGuests(ctx context.Context, roomID uuid.UUID) ([]*RoomGetAllGuestsRow, error) {
+	rows, err := q.db.Query(ctx, roomGetAllGuests, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*RoomGetAllGuestsRow
+	for rows.Next() {
+		var i RoomGetAllGuestsRow
+		if err := rows.Scan(&i.Name, &i.ID, &i.QueuedTracks); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const roomGetAllMembers = `-- name: RoomGetAllMembers :many
+SELECT
+    u.id AS user_id,
+    u.username,
+    u.display_name,
+    u.spotify_name,
+    u.spotify_image_url,
+    m.is_moderator,
+    CASE WHEN counts.queued_tracks IS NOT NULL THEN
+        counts.queued_tracks
+    ELSE
+        0
+    END AS queued_tracks
+FROM
+    room_members AS m
+    JOIN users u ON m.user_id = u.id
+        AND m.room_id = $1
+    LEFT JOIN (
+        SELECT
+            user_id,
+            COUNT(*) AS queued_tracks
+        FROM
+            room_queue_tracks
+        GROUP BY
+            user_id) counts ON u.id = counts.user_id
+`
+
+type RoomGetAllMembersRow struct {
+	UserID          uuid.UUID `json:"user_id"`
+	Username        string    `json:"username"`
+	DisplayName     string    `json:"display_name"`
+	SpotifyName     *string   `json:"spotify_name"`
+	SpotifyImageUrl *string   `json:"spotify_image_url"`
+	IsModerator     bool      `json:"is_moderator"`
+	QueuedTracks    int32     `json:"queued_tracks"`
+}
+
+func (q *Queries) RoomGetAllMembers(ctx context.Context, roomID uuid.UUID) ([]*RoomGetAllMembersRow, error) {
+	rows, err := q.db.Query(ctx, roomGetAllMembers, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*RoomGetAllMembersRow
+	for rows.Next() {
+		var i RoomGetAllMembersRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Username,
+			&i.DisplayName,
+			&i.SpotifyName,
+			&i.SpotifyImageUrl,
+			&i.IsModerator,
+			&i.QueuedTracks,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const roomGetByCode = `-- name: RoomGetByCode :one
+SELECT
+    r.id,
+    r.name,
+    r.host_id,
+    u.username AS host_username,
+    u.display_name AS host_display,
+    u.spotify_name AS host_spotify_name,
+    u.spotify_image_url AS host_image,
+    r.code,
+    r.created
+FROM
+    rooms AS r
+    JOIN users AS u ON r.code = $1
+        AND u.id = r.host_id
+`
+
+type RoomGetByCodeRow struct {
+	ID              uuid.UUID `json:"id"`
+	Name            string    `json:"name"`
+	HostID          uuid.UUID `json:"host_id"`
+	HostUsername    string    `json:"host_username"`
+	HostDisplay     string    `json:"host_display"`
+	HostSpotifyName *string   `json:"host_spotify_name"`
+	HostImage       *string   `json:"host_image"`
+	Code            string    `json:"code"`
+	Created         time.Time `json:"created"`
+}
+
+func (q *Queries) RoomGetByCode(ctx context.Context, code string) (*RoomGetByCodeRow, error) {
+	row := q.db.QueryRow(ctx, roomGetByCode, code)
+	var i RoomGetByCodeRow
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.HostID,
+		&i.HostUsername,
+		&i.HostDisplay,
+		&i.HostSpotifyName,
+		&i.HostImage,
+		&i.Code,
+		&i.Created,
+	)
+	return &i, err
+}
+
+const roomGetHostID = `-- name: RoomGetHostID :one
+SELECT
+    r.host_id
+FROM
+    rooms r
+WHERE
+    r.code = $1
+`
+
+func (q *Queries) RoomGetHostID(ctx context.Context, code string) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, roomGetHostID, code)
+	var host_id uuid.UUID
+	err := row.Scan(&host_id)
+	return host_id, err
+}
+
+const roomGetIDByCode = `-- name: RoomGetIDByCode :one
+SELECT
+    id
+FROM
+    rooms
+WHERE (code = $1)
+`
+
+func (q *Queries) RoomGetIDByCode(ctx context.Context, code string) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, roomGetIDByCode, code)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const roomGetPasswordHash = `-- name: RoomGetPasswordHash :one
+SELECT
+    rp.room_id,
+    rp.encrypted_password
+FROM
+    room_passwords AS rp
+    JOIN rooms r ON r.id = rp.room_id
+        AND r.code = $1
+`
+
+type RoomGetPasswordHashRow struct {
+	RoomID            uuid.UUID `json:"room_id"`
+	EncryptedPassword *string   `json:"encrypted_password"`
+}
+
+func (q *Queries) RoomGetPasswordHash(ctx context.Context, code string) (*RoomGetPasswordHashRow, error) {
+	row := q.db.QueryRow(ctx, roomGetPasswordHash, code)
+	var i RoomGetPasswordHashRow
+	err := row.Scan(&i.RoomID, &i.EncryptedPassword)
+	return &i, err
+}
+
+const roomGetQueuePolicy = `-- name: RoomGetQueuePolicy :one
+SELECT
+    room_id, max_pending_per_participant, cooldown_seconds, replay_block_minutes, block_explicit, max_duration_ms, updated, skip_vote_count, skip_vote_percent
+FROM
+    room_queue_policies
+WHERE
+    room_id = $1
+`
+
+func (q *Queries) RoomGetQueuePolicy(ctx context.Context, roomID uuid.UUID) (*RoomQueuePolicy, error) {
+	row := q.db.QueryRow(ctx, roomGetQueuePolicy, roomID)
+	var i RoomQueuePolicy
+	err := row.Scan(
+		&i.RoomID,
+		&i.MaxPendingPerParticipant,
+		&i.CooldownSeconds,
+		&i.ReplayBlockMinutes,
+		&i.BlockExplicit,
+		&i.MaxDurationMs,
+		&i.Updated,
+		&i.SkipVoteCount,
+		&i.SkipVotePercent,
+	)
+	return &i, err
+}
+
+const roomGetQueueTracks = `-- name: RoomGetQueueTracks :many
+SELECT
+    t.id,
+    track_id,
+    t.guest_id,
+    t.user_id,
+    g.name AS guest_name,
+    u.display_name AS member_name,
+    t.pushed_at::timestamptz AS timestamp,
+    played,
+    COALESCE((
+        SELECT
+            SUM(v.vote)
+        FROM room_queue_votes v
+        WHERE
+            v.queue_track_id = t.id), 0)::integer AS score
+FROM
+    room_queue_tracks t
+    LEFT JOIN room_guests g ON g.id = t.guest_id
+    LEFT JOIN users u ON u.id = t.user_id
+WHERE
+    t.room_id = $1
+    AND t.pushed_at IS NOT NULL
+ORDER BY
+    t.pushed_at DESC
+`
+
+type RoomGetQueueTracksRow struct {
+	ID         uuid.UUID  `json:"id"`
+	TrackID    string     `json:"track_id"`
+	GuestID    *uuid.UUID `json:"guest_id"`
+	UserID     *uuid.UUID `json:"user_id"`
+	GuestName  *string    `json:"guest_name"`
+	MemberName *string    `json:"member_name"`
+	Timestamp  time.Time  `json:"timestamp"`
+	Played     bool       `json:"played"`
+	Score      int32      `json:"score"`
+}
+
+func (q *Queries) RoomGetQueueTracks(ctx context.Context, roomID uuid.UUID) ([]*RoomGetQueueTracksRow, error) {
+	rows, err := q.db.Query(ctx, roomGetQueueTracks, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*RoomGetQueueTracksRow
+	for rows.Next() {
+		var i RoomGetQueueTracksRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TrackID,
+			&i.GuestID,
+			&i.UserID,
+			&i.GuestName,
+			&i.MemberName,
+			&i.Timestamp,
+			&i.Played,
+			&i.Score,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const roomGetRecentlyActive = `-- name: RoomGetRecentlyActive :many
+SELECT
+    r.id,
+    r.code
+FROM
+    rooms r
+WHERE
+    r.is_open
+    AND EXISTS (
+        SELECT
+            1
+        FROM
+            room_queue_tracks t
+        WHERE
+            t.room_id = r.id
+            AND (NOT t.played
+                OR t.timestamp > $1
+                OR t.pushed_at > $1))
+`
+
+type RoomGetRecentlyActiveRow struct {
+	ID   uuid.UUID `json:"id"`
+	Code string    `json:"code"`
+}
+
+func (q *Queries) RoomGetRecentlyActive(ctx context.Context, since time.Time) ([]*RoomGetRecentlyActiveRow, error) {
+	rows, err := q.db.Query(ctx, roomGetRecentlyActive, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*RoomGetRecentlyActiveRow
+	for rows.Next() {
+		var i RoomGetRecentlyActiveRow
+		if err := rows.Scan(&i.ID, &i.Code); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const roomGetSchedule = `-- name: RoomGetSchedule :one
+SELECT
+    room_id, starts_at, ends_at, idle_timeout_minutes, recurrence, last_opened, updated
+FROM
+    room_schedules
+WHERE
+    room_id = $1
+`
+
+func (q *Queries) RoomGetSchedule(ctx context.Context, roomID uuid.UUID) (*RoomSchedule, error) {
+	row := q.db.QueryRow(ctx, roomGetSchedule, roomID)
+	var i RoomSchedule
+	err := row.Scan(
+		&i.RoomID,
+		&i.StartsAt,
+		&i.EndsAt,
+		&i.IdleTimeoutMinutes,
+		&i.Recurrence,
+		&i.LastOpened,
+		&i.Updated,
+	)
+	return &i, err
+}
+
+const roomGuestGetName = `-- name: RoomGuestGetName :one
+SELECT
+    name
+FROM
+    room_guests
+WHERE
+    room_id = $1
+    AND id = $2::uuid
+`
+
+type RoomGuestGetNameParams struct {
+	RoomID  uuid.UUID `json:"room_id"`
+	GuestID uuid.UUID `json:"guest_id"`
+}
+
+func (q *Queries) RoomGuestGetName(ctx context.Context, arg RoomGuestGetNameParams) (string, error) {
+	row := q.db.QueryRow(ctx, roomGuestGetName, arg.RoomID, arg.GuestID)
+	var name string
+	err := row.Scan(&name)
+	return name, err
+}
+
+const roomGuestHasInvite = `-- name: RoomGuestHasInvite :one
+SELECT
+    EXISTS (
+        SELECT
+            1
+        FROM
+            room_guests g
+            JOIN room_invites i ON i.id = g.invite_id
+        WHERE
+            g.room_id = $1
+            AND g.id = $2::uuid
+            AND i.id = $3::uuid
+            AND i.revoked_at IS NULL
+            AND (i.expires_at IS NULL
+                OR i.expires_at > now()))
+`
+
+type RoomGuestHasInviteParams struct {
+	RoomID   uuid.UUID `json:"room_id"`
+	GuestID  uuid.UUID `json:"guest_id"`
+	InviteID uuid.UUID `json:"invite_id"`
+}
+
+func (q *Queries) RoomGuestHasInvite(ctx context.Context, arg RoomGuestHasInviteParams) (bool, error) {
+	row := q.db.QueryRow(ctx, roomGuestHasInvite, arg.RoomID, arg.GuestID, arg.InviteID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const roomGuestInsert = `-- name: RoomGuestInsert :one
+INSERT INTO room_guests(
+    room_id,
+    name)
+SELECT
+    r.id,
+    $1
+FROM
+    rooms AS r
+WHERE
+    r.code = $2::text
+RETURNING
+    id,
+    name
+`
+
+type RoomGuestInsertParams struct {
+	Name     string `json:"name"`
+	RoomCode string `json:"room_code"`
+}
+
+type RoomGuestInsertRow struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+func (q *Queries) RoomGuestInsert(ctx context.Context, arg RoomGuestInsertParams) (*RoomGuestInsertRow, error) {
+	row := q.db.QueryRow(ctx, roomGuestInsert, arg.Name, arg.RoomCode)
+	var i RoomGuestInsertRow
+	err := row.Scan(&i.ID, &i.Name)
+	return &i, err
+}
+
+const roomGuestInsertWithID = `-- name: RoomGuestInsertWithID :one
+INSERT INTO room_guests(
+    id,
+    room_id,
+    name)
+SELECT
+    $2::uuid,
+    r.id,
+    $1
+FROM
+    rooms AS r
+WHERE
+    r.code = $3::text
+RETURNING
+    id,
+    name
+`
+
+type RoomGuestInsertWithIDParams struct {
+	Name     string    `json:"name"`
+	GuestID  uuid.UUID `json:"guest_id"`
+	RoomCode string    `json:"room_code"`
+}
+
+type RoomGuestInsertWithIDRow struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+func (q *Queries) RoomGuestInsertWithID(ctx context.Context, arg RoomGuestInsertWithIDParams) (*RoomGuestInsertWithIDRow, error) {
+	row := q.db.QueryRow(ctx, roomGuestInsertWithID, arg.Name, arg.GuestID, arg.RoomCode)
+	var i RoomGuestInsertWithIDRow
+	err := row.Scan(&i.ID, &i.Name)
+	return &i, err
+}
+
+const roomGuestSetInvite = `-- name: RoomGuestSetInvite :exec
+UPDATE
+    room_guests
+SET
+    invite_id = $3
+WHERE
+    room_id = $1
+    AND id = $2
+`
+
+type RoomGuestSetInviteParams struct {
+	RoomID   uuid.UUID  `json:"room_id"`
+	ID       uuid.UUID  `json:"id"`
+	InviteID *uuid.UUID `json:"invite_id"`
+}
+
+func (q *Queries) RoomGuestSetInvite(ctx context.Context, arg RoomGuestSetInviteParams) error {
+	_, err := q.db.Exec(ctx, roomGuestSetInvite, arg.RoomID, arg.ID, arg.InviteID)
+	return err
+}
+
+const roomInsertWithPassword = `-- name: RoomInsertWithPassword :one
+WITH new_room AS (
+    INSERT INTO rooms(
+        name,
+        host_id)
+    VALUES (
+        $1,
+        $2)
+RETURNING
+    id,
+    name,
+    host_id,
+    code,
+    created
+),
+new_pass AS (
+    INSERT INTO room_passwords(
+        room_id,
+        encrypted_password)
+    SELECT
+        id,
+        $3::text
+    FROM
+        new_room
+)
+SELECT
+    id, name, host_id, code, created
+FROM
+    new_room
+`
+
+type RoomInsertWithPasswordParams struct {
+	Name         string    `json:"name"`
+	HostID       uuid.UUID `json:"host_id"`
+	PasswordHash string    `json:"password_hash"`
+}
+
+type RoomInsertWithPasswordRow struct {
+	ID      uuid.UUID `json:"id"`
+	Name    string    `json:"name"`
+	HostID  uuid.UUID `json:"host_id"`
+	Code    string    `json:"code"`
+	Created time.Time `json:"created"`
+}
+
+func (q *Queries) RoomInsertWithPassword(ctx context.Context, arg RoomInsertWithPasswordParams) (*RoomInsertWithPasswordRow, error) {
+	row := q.db.QueryRow(ctx, roomInsertWithPassword, arg.Name, arg.HostID, arg.PasswordHash)
+	var i RoomInsertWithPasswordRow
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.HostID,
+		&i.Code,
+		&i.Created,
+	)
+	return &i, err
+}
+
+const roomInviteGetActive = `-- name: RoomInviteGetActive :many
+SELECT
+    id, room_id, role, created_by, created, expires_at, max_uses, uses, revoked_at
+FROM
+    room_invites
+WHERE
+    room_id = $1
+    AND revoked_at IS NULL
+    AND (expires_at IS NULL
+        OR expires_at > now())
+    AND (max_uses IS NULL
+        OR uses < max_uses)
+ORDER BY
+    created DESC
+`
+
+func (q *Queries) RoomInviteGetActive(ctx context.Context, roomID uuid.UUID) ([]*RoomInvite, error) {
+	rows, err := q.db.Query(ctx, roomInviteGetActive, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*RoomInvite
+	for rows.Next() {
+		var i RoomInvite
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Role,
+			&i.CreatedBy,
+			&i.Created,
+			&i.ExpiresAt,
+			&i.MaxUses,
+			&i.Uses,
+			&i.RevokedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -1917,104 +3875,271 @@ func (q *Queries) MissingISRCNumbers(ctx context.Context) ([]*MissingISRCNumbers
 	return items, nil
 }
 
-const roomAddMember = `-- name: RoomAddMember :exec
-INSERT INTO room_members(
-    user_id,
-    room_id)
+const roomInviteInsert = `-- name: RoomInviteInsert :one
+INSERT INTO room_invites(
+    room_id,
+    role,
+    created_by,
+    expires_at,
+    max_uses)
 VALUES (
     $1,
-    $2)
+    $2,
+    $3,
+    $4,
+    $5)
+RETURNING
+    id, room_id, role, created_by, created, expires_at, max_uses, uses, revoked_at
 `
 
-type RoomAddMemberParams struct {
-	UserID uuid.UUID `json:"user_id"`
+type RoomInviteInsertParams struct {
+	RoomID    uuid.UUID  `json:"room_id"`
+	Role      string     `json:"role"`
+	CreatedBy uuid.UUID  `json:"created_by"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxUses   *int32     `json:"max_uses"`
+}
+
+func (q *Queries) RoomInviteInsert(ctx context.Context, arg RoomInviteInsertParams) (*RoomInvite, error) {
+	row := q.db.QueryRow(ctx, roomInviteInsert,
+		arg.RoomID,
+		arg.Role,
+		arg.CreatedBy,
+		arg.ExpiresAt,
+		arg.MaxUses,
+	)
+	var i RoomInvite
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Role,
+		&i.CreatedBy,
+		&i.Created,
+		&i.ExpiresAt,
+		&i.MaxUses,
+		&i.Uses,
+		&i.RevokedAt,
+	)
+	return &i, err
+}
+
+const roomInviteRevoke = `-- name: RoomInviteRevoke :execrows
+UPDATE
+    room_invites
+SET
+    revoked_at = now()
+WHERE
+    id = $1
+    AND room_id = $2
+    AND revoked_at IS NULL
+`
+
+type RoomInviteRevokeParams struct {
+	ID     uuid.UUID `json:"id"`
 	RoomID uuid.UUID `json:"room_id"`
 }
 
-func (q *Queries) RoomAddMember(ctx context.Context, arg RoomAddMemberParams) error {
-	_, err := q.db.Exec(ctx, roomAddMember, arg.UserID, arg.RoomID)
-	return err
+func (q *Queries) RoomInviteRevoke(ctx context.Context, arg RoomInviteRevokeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, roomInviteRevoke, arg.ID, arg.RoomID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-const roomAddMemberByUsername = `-- name: RoomAddMemberByUsername :one
-INSERT INTO room_members(
-    user_id,
-    room_id,
-    is_moderator)
+const roomInviteUse = `-- name: RoomInviteUse :execrows
+UPDATE
+    room_invites
+SET
+    uses = uses + 1
+WHERE
+    id = $1
+    AND room_id = $2
+    AND revoked_at IS NULL
+    AND (expires_at IS NULL
+        OR expires_at > now())
+    AND (max_uses IS NULL
+        OR uses < max_uses)
+`
+
+type RoomInviteUseParams struct {
+	ID     uuid.UUID `json:"id"`
+	RoomID uuid.UUID `json:"room_id"`
+}
+
+func (q *Queries) RoomInviteUse(ctx context.Context, arg RoomInviteUseParams) (int64, error) {
+	result, err := q.db.Exec(ctx, roomInviteUse, arg.ID, arg.RoomID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const roomLockForUpdate = `-- name: RoomLockForUpdate :exec
 SELECT
-    u.id,
-    $1,
-    $3
+    id
 FROM
-    users u
+    rooms
 WHERE
-    u.username = $2
-RETURNING
-    id
+    id = $1
+FOR UPDATE
 `
 
-type RoomAddMemberByUsernameParams struct {
-	RoomID      uuid.UUID `json:"room_id"`
-	Username    string    `json:"username"`
-	IsModerator bool      `json:"is_moderator"`
+func (q *Queries) RoomLockForUpdate(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, roomLockForUpdate, id)
+	return err
 }
 
-func (q *Queries) RoomAddMemberByUsername(ctx context.Context, arg RoomAddMemberByUsernameParams) (uuid.UUID, error) {
-	row := q.db.QueryRow(ctx, roomAddMemberByUsername, arg.RoomID, arg.Username, arg.IsModerator)
-	var id uuid.UUID
-	err := row.Scan(&id)
-	return id, err
+const roomMarkTracksAsPlayed = `-- name: RoomMarkTracksAsPlayed :exec
+UPDATE
+    room_queue_tracks
+SET
+    played = TRUE
+WHERE
+    room_id = $1
+    AND pushed_at <= $2::timestamptz
+`
+
+type RoomMarkTracksAsPlayedParams struct {
+	RoomID uuid.UUID `json:"room_id"`
+	Since  time.Time `json:"since"`
 }
 
-const roomDeleteByID = `-- name: RoomDeleteByID :exec
-DELETE FROM rooms r
-WHERE r.code = $1
+func (q *Queries) RoomMarkTracksAsPlayed(ctx context.Context, arg RoomMarkTracksAsPlayedParams) error {
+	_, err := q.db.Exec(ctx, roomMarkTracksAsPlayed, arg.RoomID, arg.Since)
+	return err
+}
+
+const roomPlayLogCount = `-- name: RoomPlayLogCount :one
+SELECT
+    COUNT(*)
+FROM
+    room_play_log
+WHERE
+    room_id = $1
+    AND ($2::uuid IS NULL
+        OR guest_id = $2::uuid
+        OR user_id = $2::uuid)
 `
 
-func (q *Queries) RoomDeleteByID(ctx context.Context, code string) error {
-	_, err := q.db.Exec(ctx, roomDeleteByID, code)
-	return err
+type RoomPlayLogCountParams struct {
+	RoomID        uuid.UUID  `json:"room_id"`
+	ParticipantID *uuid.UUID `json:"participant_id"`
 }
 
-const roomGetAllGuests = `-- name: RoomGetAllGuests :many
+func (q *Queries) RoomPlayLogCount(ctx context.Context, arg RoomPlayLogCountParams) (int64, error) {
+	row := q.db.QueryRow(ctx, roomPlayLogCount, arg.RoomID, arg.ParticipantID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const roomPlayLogGetLatest = `-- name: RoomPlayLogGetLatest :one
 SELECT
-    rg.name,
-    rg.id,
-    CASE WHEN counts.queued_tracks IS NOT NULL THEN
-        counts.queued_tracks
-    ELSE
-        0
-    END AS queued_tracks
+    id, room_id, track_id, started_at, duration_ms, queue_track_id, guest_id, user_id, score, skipped, skip_votes
 FROM
-    room_guests AS rg
-    LEFT JOIN (
-        SELECT
-            guest_id,
-            COUNT(*) AS queued_tracks
-        FROM
-            room_queue_tracks
-        GROUP BY
-            guest_id) counts ON rg.id = counts.guest_id
+    room_play_log
 WHERE
-    rg.room_id = $1
+    room_id = $1
+ORDER BY
+    started_at DESC
+LIMIT 1
 `
 
-type RoomGetAllGuestsRow struct {
-	Name         string    `json:"name"`
-	ID           uuid.UUID `json:"id"`
-	QueuedTracks int32     `json:"queued_tracks"`
+func (q *Queries) RoomPlayLogGetLatest(ctx context.Context, roomID uuid.UUID) (*RoomPlayLog, error) {
+	row := q.db.QueryRow(ctx, roomPlayLogGetLatest, roomID)
+	var i RoomPlayLog
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.TrackID,
+		&i.StartedAt,
+		&i.DurationMs,
+		&i.QueueTrackID,
+		&i.GuestID,
+		&i.UserID,
+		&i.Score,
+		&i.Skipped,
+		&i.SkipVotes,
+	)
+	return &i, err
 }
 
-func (q *Queries) RoomGetAllGuests(ctx context.Context, roomID uuid.UUID) ([]*RoomGetAllGuestsRow, error) {
-	rows, err := q.db.Query(ctx, roomGetAllGuests, roomID)
+const roomPlayLogGetPage = `-- name: RoomPlayLogGetPage :many
+SELECT
+    l.id,
+    l.track_id,
+    l.started_at,
+    l.duration_ms,
+    l.guest_id,
+    l.user_id,
+    g.name AS guest_name,
+    u.display_name AS member_name,
+    l.score,
+    l.skipped,
+    l.skip_votes
+FROM
+    room_play_log l
+    LEFT JOIN room_guests g ON g.id = l.guest_id
+    LEFT JOIN users u ON u.id = l.user_id
+WHERE
+    l.room_id = $1
+    AND ($2::uuid IS NULL
+        OR l.guest_id = $2::uuid
+        OR l.user_id = $2::uuid)
+ORDER BY
+    l.started_at DESC
+LIMIT $3 OFFSET $4
+`
+
+type RoomPlayLogGetPageParams struct {
+	RoomID        uuid.UUID  `json:"room_id"`
+	ParticipantID *uuid.UUID `json:"participant_id"`
+	MaxCount      int32      `json:"max_count"`
+	PageOffset    int32      `json:"page_offset"`
+}
+
+type RoomPlayLogGetPageRow struct {
+	ID         uuid.UUID  `json:"id"`
+	TrackID    string     `json:"track_id"`
+	StartedAt  time.Time  `json:"started_at"`
+	DurationMs int32      `json:"duration_ms"`
+	GuestID    *uuid.UUID `json:"guest_id"`
+	UserID     *uuid.UUID `json:"user_id"`
+	GuestName  *string    `json:"guest_name"`
+	MemberName *string    `json:"member_name"`
+	Score      int32      `json:"score"`
+	Skipped    bool       `json:"skipped"`
+	SkipVotes  *int32     `json:"skip_votes"`
+}
+
+func (q *Queries) RoomPlayLogGetPage(ctx context.Context, arg RoomPlayLogGetPageParams) ([]*RoomPlayLogGetPageRow, error) {
+	rows, err := q.db.Query(ctx, roomPlayLogGetPage,
+		arg.RoomID,
+		arg.ParticipantID,
+		arg.MaxCount,
+		arg.PageOffset,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []*RoomGetAllGuestsRow
+	var items []*RoomPlayLogGetPageRow
 	for rows.Next() {
-		var i RoomGetAllGuestsRow
-		if err := rows.Scan(&i.Name, &i.ID, &i.QueuedTracks); err != nil {
+		var i RoomPlayLogGetPageRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TrackID,
+			&i.StartedAt,
+			&i.DurationMs,
+			&i.GuestID,
+			&i.UserID,
+			&i.GuestName,
+			&i.MemberName,
+			&i.Score,
+			&i.Skipped,
+			&i.SkipVotes,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, &i)
@@ -2025,60 +4150,59 @@ func (q *Queries) RoomGetAllGuests(ctx context.Context, roomID uuid.UUID) ([]*Ro
 	return items, nil
 }
 
-const roomGetAllMembers = `-- name: RoomGetAllMembers :many
+const roomPlayLogGetTracks = `-- name: RoomPlayLogGetTracks :many
 SELECT
-    u.id AS user_id,
-    u.username,
-    u.display_name,
-    u.spotify_name,
-    u.spotify_image_url,
-    m.is_moderator,
-    CASE WHEN counts.queued_tracks IS NOT NULL THEN
-        counts.queued_tracks
-    ELSE
-        0
-    END AS queued_tracks
+    track_id,
+    started_at,
+    score,
+    skipped
 FROM
-    room_members AS m
-    JOIN users u ON m.user_id = u.id
-        AND m.room_id = $1
-    LEFT JOIN (
-        SELECT
-            user_id,
-            COUNT(*) AS queued_tracks
-        FROM
-            room_queue_tracks
-        GROUP BY
-            user_id) counts ON u.id = counts.user_id
+    room_play_log
+WHERE
+    room_id = $1
+    AND ($2::timestamptz IS NULL
+        OR started_at >= $2::timestamptz)
+    AND ($3::timestamptz IS NULL
+        OR started_at < $3::timestamptz)
+    AND NOT ($4::boolean
+        AND skipped)
+ORDER BY
+    started_at ASC
 `
 
-type RoomGetAllMembersRow struct {
-	UserID          uuid.UUID `json:"user_id"`
-	Username        string    `json:"username"`
-	DisplayName     string    `json:"display_name"`
-	SpotifyName     *string   `json:"spotify_name"`
-	SpotifyImageUrl *string   `json:"spotify_image_url"`
-	IsModerator     bool      `json:"is_moderator"`
-	QueuedTracks    int32     `json:"queued_tracks"`
+type RoomPlayLogGetTracksParams struct {
+	RoomID         uuid.UUID  `json:"room_id"`
+	StartTime      *time.Time `json:"start_time"`
+	EndTime        *time.Time `json:"end_time"`
+	ExcludeSkipped bool       `json:"exclude_skipped"`
 }
 
-func (q *Queries) RoomGetAllMembers(ctx context.Context, roomID uuid.UUID) ([]*RoomGetAllMembersRow, error) {
-	rows, err := q.db.Query(ctx, roomGetAllMembers, roomID)
+type RoomPlayLogGetTracksRow struct {
+	TrackID   string    `json:"track_id"`
+	StartedAt time.Time `json:"started_at"`
+	Score     int32     `json:"score"`
+	Skipped   bool      `json:"skipped"`
+}
+
+func (q *Queries) RoomPlayLogGetTracks(ctx context.Context, arg RoomPlayLogGetTracksParams) ([]*RoomPlayLogGetTracksRow, error) {
+	rows, err := q.db.Query(ctx, roomPlayLogGetTracks,
+		arg.RoomID,
+		arg.StartTime,
+		arg.EndTime,
+		arg.ExcludeSkipped,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []*RoomGetAllMembersRow
+	var items []*RoomPlayLogGetTracksRow
 	for rows.Next() {
-		var i RoomGetAllMembersRow
+		var i RoomPlayLogGetTracksRow
 		if err := rows.Scan(
-			&i.UserID,
-			&i.Username,
-			&i.DisplayName,
-			&i.SpotifyName,
-			&i.SpotifyImageUrl,
-			&i.IsModerator,
-			&i.QueuedTracks,
+			&i.TrackID,
+			&i.StartedAt,
+			&i.Score,
+			&i.Skipped,
 		); err != nil {
 			return nil, err
 		}
@@ -2087,126 +4211,203 @@ func (q *Queries) RoomGetAllMembers(ctx context.Context, roomID uuid.UUID) ([]*R
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-	return items, nil
+	return items, nil
+}
+
+const roomPlayLogInsert = `-- name: RoomPlayLogInsert :exec
+INSERT INTO room_play_log(
+    room_id,
+    track_id,
+    started_at,
+    duration_ms,
+    queue_track_id,
+    guest_id,
+    user_id,
+    score)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8)
+`
+
+type RoomPlayLogInsertParams struct {
+	RoomID       uuid.UUID  `json:"room_id"`
+	TrackID      string     `json:"track_id"`
+	StartedAt    time.Time  `json:"started_at"`
+	DurationMs   int32      `json:"duration_ms"`
+	QueueTrackID *uuid.UUID `json:"queue_track_id"`
+	GuestID      *uuid.UUID `json:"guest_id"`
+	UserID       *uuid.UUID `json:"user_id"`
+	Score        int32      `json:"score"`
+}
+
+func (q *Queries) RoomPlayLogInsert(ctx context.Context, arg RoomPlayLogInsertParams) error {
+	_, err := q.db.Exec(ctx, roomPlayLogInsert,
+		arg.RoomID,
+		arg.TrackID,
+		arg.StartedAt,
+		arg.DurationMs,
+		arg.QueueTrackID,
+		arg.GuestID,
+		arg.UserID,
+		arg.Score,
+	)
+	return err
+}
+
+const roomPlayLogMarkSkipped = `-- name: RoomPlayLogMarkSkipped :exec
+UPDATE
+    room_play_log
+SET
+    skipped = TRUE,
+    skip_votes = $2
+WHERE
+    id = $1
+`
+
+type RoomPlayLogMarkSkippedParams struct {
+	ID        uuid.UUID `json:"id"`
+	SkipVotes *int32    `json:"skip_votes"`
+}
+
+func (q *Queries) RoomPlayLogMarkSkipped(ctx context.Context, arg RoomPlayLogMarkSkippedParams) error {
+	_, err := q.db.Exec(ctx, roomPlayLogMarkSkipped, arg.ID, arg.SkipVotes)
+	return err
+}
+
+const roomQueueDeleteTrack = `-- name: RoomQueueDeleteTrack :execrows
+DELETE FROM room_queue_tracks t
+WHERE t.id = $1
+    AND t.room_id = $2
+    AND t.pushed_at IS NULL
+`
+
+type RoomQueueDeleteTrackParams struct {
+	ID     uuid.UUID `json:"id"`
+	RoomID uuid.UUID `json:"room_id"`
+}
+
+func (q *Queries) RoomQueueDeleteTrack(ctx context.Context, arg RoomQueueDeleteTrackParams) (int64, error) {
+	result, err := q.db.Exec(ctx, roomQueueDeleteTrack, arg.ID, arg.RoomID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-const roomGetByCode = `-- name: RoomGetByCode :one
-SELECT
-    r.id,
-    r.name,
-    r.host_id,
-    u.username AS host_username,
-    u.display_name AS host_display,
-    u.spotify_name AS host_spotify_name,
-    u.spotify_image_url AS host_image,
-    r.code,
-    r.created
-FROM
-    rooms AS r
-    JOIN users AS u ON r.code = $1
-        AND u.id = r.host_id
+const roomQueueDeleteVote = `-- name: RoomQueueDeleteVote :exec
+DELETE FROM room_queue_votes v
+WHERE v.queue_track_id = $1
+    AND v.voter_id = $2
 `
 
-type RoomGetByCodeRow struct {
-	ID              uuid.UUID `json:"id"`
-	Name            string    `json:"name"`
-	HostID          uuid.UUID `json:"host_id"`
-	HostUsername    string    `json:"host_username"`
-	HostDisplay     string    `json:"host_display"`
-	HostSpotifyName *string   `json:"host_spotify_name"`
-	HostImage       *string   `json:"host_image"`
-	Code            string    `json:"code"`
-	Created         time.Time `json:"created"`
+type RoomQueueDeleteVoteParams struct {
+	QueueTrackID uuid.UUID `json:"queue_track_id"`
+	VoterID      uuid.UUID `json:"voter_id"`
 }
 
-func (q *Queries) RoomGetByCode(ctx context.Context, code string) (*RoomGetByCodeRow, error) {
-	row := q.db.QueryRow(ctx, roomGetByCode, code)
-	var i RoomGetByCodeRow
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.HostID,
-		&i.HostUsername,
-		&i.HostDisplay,
-		&i.HostSpotifyName,
-		&i.HostImage,
-		&i.Code,
-		&i.Created,
-	)
-	return &i, err
+func (q *Queries) RoomQueueDeleteVote(ctx context.Context, arg RoomQueueDeleteVoteParams) error {
+	_, err := q.db.Exec(ctx, roomQueueDeleteVote, arg.QueueTrackID, arg.VoterID)
+	return err
 }
 
-const roomGetHostID = `-- name: RoomGetHostID :one
+const roomQueueGetParticipantStats = `-- name: RoomQueueGetParticipantStats :one
 SELECT
-    r.host_id
+    COUNT(*) FILTER (WHERE NOT played)::integer AS pending_count,
+    COALESCE(MAX(timestamp), 'epoch'::timestamptz)::timestamptz AS last_added
 FROM
-    rooms r
+    room_queue_tracks
 WHERE
-    r.code = $1
+    room_id = $1
+    AND (user_id = $2::uuid
+        OR guest_id = $2::uuid)
 `
 
-func (q *Queries) RoomGetHostID(ctx context.Context, code string) (uuid.UUID, error) {
-	row := q.db.QueryRow(ctx, roomGetHostID, code)
-	var host_id uuid.UUID
-	err := row.Scan(&host_id)
-	return host_id, err
+type RoomQueueGetParticipantStatsParams struct {
+	RoomID        uuid.UUID `json:"room_id"`
+	ParticipantID uuid.UUID `json:"participant_id"`
 }
 
-const roomGetIDByCode = `-- name: RoomGetIDByCode :one
-SELECT
-    id
-FROM
-    rooms
-WHERE (code = $1)
-`
+type RoomQueueGetParticipantStatsRow struct {
+	PendingCount int32     `json:"pending_count"`
+	LastAdded    time.Time `json:"last_added"`
+}
 
-func (q *Queries) RoomGetIDByCode(ctx context.Context, code string) (uuid.UUID, error) {
-	row := q.db.QueryRow(ctx, roomGetIDByCode, code)
-	var id uuid.UUID
-	err := row.Scan(&id)
-	return id, err
+func (q *Queries) RoomQueueGetParticipantStats(ctx context.Context, arg RoomQueueGetParticipantStatsParams) (*RoomQueueGetParticipantStatsRow, error) {
+	row := q.db.QueryRow(ctx, roomQueueGetParticipantStats, arg.RoomID, arg.ParticipantID)
+	var i RoomQueueGetParticipantStatsRow
+	err := row.Scan(&i.PendingCount, &i.LastAdded)
+	return &i, err
 }
 
-const roomGetQueueTracks = `-- name: RoomGetQueueTracks :many
+const roomQueueGetPending = `-- name: RoomQueueGetPending :many
 SELECT
-    track_id,
+    t.id,
+    t.track_id,
     g.name AS guest_name,
     u.display_name AS member_name,
-    timestamp,
-    played
+    t.timestamp,
+    t.priority,
+    COALESCE(SUM(v.vote), 0)::integer AS score,
+    COALESCE(SUM(v.vote) FILTER (WHERE v.voter_id = $2::uuid), 0)::integer AS voter_vote
 FROM
     room_queue_tracks t
     LEFT JOIN room_guests g ON g.id = t.guest_id
     LEFT JOIN users u ON u.id = t.user_id
+    LEFT JOIN room_queue_votes v ON v.queue_track_id = t.id
 WHERE
     t.room_id = $1
+    AND t.pushed_at IS NULL
+    AND NOT t.played
+GROUP BY
+    t.id,
+    g.name,
+    u.display_name
 ORDER BY
-    timestamp DESC
+    t.priority DESC,
+    score DESC,
+    t.timestamp ASC
 `
 
-type RoomGetQueueTracksRow struct {
+type RoomQueueGetPendingParams struct {
+	RoomID  uuid.UUID `json:"room_id"`
+	VoterID uuid.UUID `json:"voter_id"`
+}
+
+type RoomQueueGetPendingRow struct {
+	ID         uuid.UUID `json:"id"`
 	TrackID    string    `json:"track_id"`
 	GuestName  *string   `json:"guest_name"`
 	MemberName *string   `json:"member_name"`
 	Timestamp  time.Time `json:"timestamp"`
-	Played     bool      `json:"played"`
+	Priority   int32     `json:"priority"`
+	Score      int32     `json:"score"`
+	VoterVote  int32     `json:"voter_vote"`
 }
 
-func (q *Queries) RoomGetQueueTracks(ctx context.Context, roomID uuid.UUID) ([]*RoomGetQueueTracksRow, error) {
-	rows, err := q.db.Query(ctx, roomGetQueueTracks, roomID)
+func (q *Queries) RoomQueueGetPending(ctx context.Context, arg RoomQueueGetPendingParams) ([]*RoomQueueGetPendingRow, error) {
+	rows, err := q.db.Query(ctx, roomQueueGetPending, arg.RoomID, arg.VoterID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []*RoomGetQueueTracksRow
+	var items []*RoomQueueGetPendingRow
 	for rows.Next() {
-		var i RoomGetQueueTracksRow
+		var i RoomQueueGetPendingRow
 		if err := rows.Scan(
+			&i.ID,
 			&i.TrackID,
 			&i.GuestName,
 			&i.MemberName,
 			&i.Timestamp,
-			&i.Played,
+			&i.Priority,
+			&i.Score,
+			&i.VoterVote,
 		); err != nil {
 			return nil, err
 		}
@@ -2218,173 +4419,204 @@ func (q *Queries) RoomGetQueueTracks(ctx context.Context, roomID uuid.UUID) ([]*
 	return items, nil
 }
 
-const roomGuestGetName = `-- name: RoomGuestGetName :one
+const roomQueueGetPushedTrackIDs = `-- name: RoomQueueGetPushedTrackIDs :many
 SELECT
-    name
+    track_id
 FROM
-    room_guests
+    room_queue_tracks
 WHERE
     room_id = $1
-    AND id = $2::uuid
+    AND pushed_at IS NOT NULL
+    AND NOT played
 `
 
-type RoomGuestGetNameParams struct {
-	RoomID  uuid.UUID `json:"room_id"`
-	GuestID uuid.UUID `json:"guest_id"`
-}
-
-func (q *Queries) RoomGuestGetName(ctx context.Context, arg RoomGuestGetNameParams) (string, error) {
-	row := q.db.QueryRow(ctx, roomGuestGetName, arg.RoomID, arg.GuestID)
-	var name string
-	err := row.Scan(&name)
-	return name, err
+func (q *Queries) RoomQueueGetPushedTrackIDs(ctx context.Context, roomID uuid.UUID) ([]string, error) {
+	rows, err := q.db.Query(ctx, roomQueueGetPushedTrackIDs, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var track_id string
+		if err := rows.Scan(&track_id); err != nil {
+			return nil, err
+		}
+		items = append(items, track_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const roomGuestInsert = `-- name: RoomGuestInsert :one
-INSERT INTO room_guests(
-    room_id,
-    name)
-SELECT
+const roomQueueGetRoomsWithPending = `-- name: RoomQueueGetRoomsWithPending :many
+SELECT DISTINCT
     r.id,
-    $1
+    r.code
 FROM
-    rooms AS r
+    rooms r
+    JOIN room_queue_tracks t ON t.room_id = r.id
+        AND t.pushed_at IS NULL
+        AND NOT t.played
 WHERE
-    r.code = $2::text
-RETURNING
-    id,
-    name
+    r.is_open
 `
 
-type RoomGuestInsertParams struct {
-	Name     string `json:"name"`
-	RoomCode string `json:"room_code"`
-}
-
-type RoomGuestInsertRow struct {
+type RoomQueueGetRoomsWithPendingRow struct {
 	ID   uuid.UUID `json:"id"`
-	Name string    `json:"name"`
+	Code string    `json:"code"`
 }
 
-func (q *Queries) RoomGuestInsert(ctx context.Context, arg RoomGuestInsertParams) (*RoomGuestInsertRow, error) {
-	row := q.db.QueryRow(ctx, roomGuestInsert, arg.Name, arg.RoomCode)
-	var i RoomGuestInsertRow
-	err := row.Scan(&i.ID, &i.Name)
-	return &i, err
+func (q *Queries) RoomQueueGetRoomsWithPending(ctx context.Context) ([]*RoomQueueGetRoomsWithPendingRow, error) {
+	rows, err := q.db.Query(ctx, roomQueueGetRoomsWithPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*RoomQueueGetRoomsWithPendingRow
+	for rows.Next() {
+		var i RoomQueueGetRoomsWithPendingRow
+		if err := rows.Scan(&i.ID, &i.Code); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const roomGuestInsertWithID = `-- name: RoomGuestInsertWithID :one
-INSERT INTO room_guests(
-    id,
-    room_id,
-    name)
+const roomQueueLockFill = `-- name: RoomQueueLockFill :exec
 SELECT
-    $2::uuid,
-    r.id,
-    $1
-FROM
-    rooms AS r
-WHERE
-    r.code = $3::text
-RETURNING
-    id,
-    name
+    pg_advisory_xact_lock(hashtext('room_queue_fill'), hashtext($1::text))
 `
 
-type RoomGuestInsertWithIDParams struct {
-	Name     string    `json:"name"`
-	GuestID  uuid.UUID `json:"guest_id"`
-	RoomCode string    `json:"room_code"`
+func (q *Queries) RoomQueueLockFill(ctx context.Context, roomID string) error {
+	_, err := q.db.Exec(ctx, roomQueueLockFill, roomID)
+	return err
 }
 
-type RoomGuestInsertWithIDRow struct {
-	ID   uuid.UUID `json:"id"`
-	Name string    `json:"name"`
-}
+const roomQueueResetPriorities = `-- name: RoomQueueResetPriorities :exec
+UPDATE
+    room_queue_tracks
+SET
+    priority = 0
+WHERE
+    room_id = $1
+    AND pushed_at IS NULL
+`
 
-func (q *Queries) RoomGuestInsertWithID(ctx context.Context, arg RoomGuestInsertWithIDParams) (*RoomGuestInsertWithIDRow, error) {
-	row := q.db.QueryRow(ctx, roomGuestInsertWithID, arg.Name, arg.GuestID, arg.RoomCode)
-	var i RoomGuestInsertWithIDRow
-	err := row.Scan(&i.ID, &i.Name)
-	return &i, err
+func (q *Queries) RoomQueueResetPriorities(ctx context.Context, roomID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, roomQueueResetPriorities, roomID)
+	return err
 }
 
-const roomInsertWithPassword = `-- name: RoomInsertWithPassword :one
-WITH new_room AS (
-    INSERT INTO rooms(
-        name,
-        host_id)
-    VALUES (
-        $1,
-        $2)
-RETURNING
-    id,
-    name,
-    host_id,
-    code,
-    created
-),
-new_pass AS (
-    INSERT INTO room_passwords(
-        room_id,
-        encrypted_password)
-    SELECT
-        id,
-        crypt($3, gen_salt('bf'))
-    FROM
-        new_room
-)
-SELECT
-    id, name, host_id, code, created
+const roomQueueSetPriorities = `-- name: RoomQueueSetPriorities :exec
+UPDATE
+    room_queue_tracks t
+SET
+    priority = cardinality($1::uuid[]) - o.idx + 1
 FROM
-    new_room
+    unnest($1::uuid[])
+    WITH ORDINALITY AS o(id, idx)
+WHERE
+    t.id = o.id
+    AND t.room_id = $2
+    AND t.pushed_at IS NULL
 `
-
-type RoomInsertWithPasswordParams struct {
-	Name     string    `json:"name"`
-	HostID   uuid.UUID `json:"host_id"`
-	RoomPass string    `json:"room_pass"`
-}
-
-type RoomInsertWithPasswordRow struct {
-	ID      uuid.UUID `json:"id"`
-	Name    string    `json:"name"`
-	HostID  uuid.UUID `json:"host_id"`
-	Code    string    `json:"code"`
-	Created time.Time `json:"created"`
-}
-
-func (q *Queries) RoomInsertWithPassword(ctx context.Context, arg RoomInsertWithPasswordParams) (*RoomInsertWithPasswordRow, error) {
-	row := q.db.QueryRow(ctx, roomInsertWithPassword, arg.Name, arg.HostID, arg.RoomPass)
-	var i RoomInsertWithPasswordRow
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.HostID,
-		&i.Code,
-		&i.Created,
-	)
-	return &i, err
+
+type RoomQueueSetPrioritiesParams struct {
+	QueueTrackIds []uuid.UUID `json:"queue_track_ids"`
+	RoomID        uuid.UUID   `json:"room_id"`
 }
 
-const roomMarkTracksAsPlayed = `-- name: RoomMarkTracksAsPlayed :exec
+func (q *Queries) RoomQueueSetPriorities(ctx context.Context, arg RoomQueueSetPrioritiesParams) error {
+	_, err := q.db.Exec(ctx, roomQueueSetPriorities, arg.QueueTrackIds, arg.RoomID)
+	return err
+}
+
+const roomQueueSetPushed = `-- name: RoomQueueSetPushed :exec
 UPDATE
     room_queue_tracks
 SET
-    played = TRUE
+    pushed_at = now()
 WHERE
-    room_id = $1
-    AND timestamp <= $2
+    id = $1
 `
 
-type RoomMarkTracksAsPlayedParams struct {
-	RoomID    uuid.UUID `json:"room_id"`
-	Timestamp time.Time `json:"timestamp"`
+func (q *Queries) RoomQueueSetPushed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, roomQueueSetPushed, id)
+	return err
 }
 
-func (q *Queries) RoomMarkTracksAsPlayed(ctx context.Context, arg RoomMarkTracksAsPlayedParams) error {
-	_, err := q.db.Exec(ctx, roomMarkTracksAsPlayed, arg.RoomID, arg.Timestamp)
-	return err
+const roomQueueTrackPlayedSince = `-- name: RoomQueueTrackPlayedSince :one
+SELECT
+    EXISTS (
+        SELECT
+            1
+        FROM
+            room_queue_tracks
+        WHERE
+            room_id = $1
+            AND track_id = $2
+            AND pushed_at >= $3::timestamptz)
+`
+
+type RoomQueueTrackPlayedSinceParams struct {
+	RoomID  uuid.UUID `json:"room_id"`
+	TrackID string    `json:"track_id"`
+	Since   time.Time `json:"since"`
+}
+
+func (q *Queries) RoomQueueTrackPlayedSince(ctx context.Context, arg RoomQueueTrackPlayedSinceParams) (bool, error) {
+	row := q.db.QueryRow(ctx, roomQueueTrackPlayedSince, arg.RoomID, arg.TrackID, arg.Since)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const roomQueueVote = `-- name: RoomQueueVote :execrows
+INSERT INTO room_queue_votes(
+    queue_track_id,
+    voter_id,
+    vote)
+SELECT
+    t.id,
+    $1::uuid,
+    $2::integer
+FROM
+    room_queue_tracks t
+WHERE
+    t.id = $3::uuid
+    AND t.room_id = $4::uuid
+    AND t.pushed_at IS NULL
+ON CONFLICT (queue_track_id,
+    voter_id)
+    DO UPDATE SET
+        vote = EXCLUDED.vote,
+        timestamp = now()
+`
+
+type RoomQueueVoteParams struct {
+	VoterID      uuid.UUID `json:"voter_id"`
+	Vote         int32     `json:"vote"`
+	QueueTrackID uuid.UUID `json:"queue_track_id"`
+	RoomID       uuid.UUID `json:"room_id"`
+}
+
+func (q *Queries) RoomQueueVote(ctx context.Context, arg RoomQueueVoteParams) (int64, error) {
+	result, err := q.db.Exec(ctx, roomQueueVote,
+		arg.VoterID,
+		arg.Vote,
+		arg.QueueTrackID,
+		arg.RoomID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const roomRemoveMember = `-- name: RoomRemoveMember :exec
@@ -2403,6 +4635,97 @@ func (q *Queries) RoomRemoveMember(ctx context.Context, arg RoomRemoveMemberPara
 	return err
 }
 
+const roomScheduleGetAll = `-- name: RoomScheduleGetAll :many
+SELECT
+    r.id,
+    r.code,
+    r.is_open,
+    s.starts_at,
+    s.ends_at,
+    s.idle_timeout_minutes,
+    s.recurrence,
+    s.last_opened,
+    COALESCE((
+        SELECT
+            MAX(t.timestamp)
+        FROM room_queue_tracks t
+        WHERE
+            t.room_id = r.id), r.created)::timestamptz AS last_activity
+FROM
+    room_schedules s
+    JOIN rooms r ON r.id = s.room_id
+`
+
+type RoomScheduleGetAllRow struct {
+	ID                 uuid.UUID  `json:"id"`
+	Code               string     `json:"code"`
+	IsOpen             bool       `json:"is_open"`
+	StartsAt           *time.Time `json:"starts_at"`
+	EndsAt             *time.Time `json:"ends_at"`
+	IdleTimeoutMinutes *int32     `json:"idle_timeout_minutes"`
+	Recurrence         *string    `json:"recurrence"`
+	LastOpened         *time.Time `json:"last_opened"`
+	LastActivity       time.Time  `json:"last_activity"`
+}
+
+func (q *Queries) RoomScheduleGetAll(ctx context.Context) ([]*RoomScheduleGetAllRow, error) {
+	rows, err := q.db.Query(ctx, roomScheduleGetAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*RoomScheduleGetAllRow
+	for rows.Next() {
+		var i RoomScheduleGetAllRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Code,
+			&i.IsOpen,
+			&i.StartsAt,
+			&i.EndsAt,
+			&i.IdleTimeoutMinutes,
+			&i.Recurrence,
+			&i.LastOpened,
+			&i.LastActivity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const roomScheduleUpdateState = `-- name: RoomScheduleUpdateState :exec
+UPDATE
+    room_schedules
+SET
+    starts_at = $2,
+    ends_at = $3,
+    last_opened = $4
+WHERE
+    room_id = $1
+`
+
+type RoomScheduleUpdateStateParams struct {
+	RoomID     uuid.UUID  `json:"room_id"`
+	StartsAt   *time.Time `json:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at"`
+	LastOpened *time.Time `json:"last_opened"`
+}
+
+func (q *Queries) RoomScheduleUpdateState(ctx context.Context, arg RoomScheduleUpdateStateParams) error {
+	_, err := q.db.Exec(ctx, roomScheduleUpdateState,
+		arg.RoomID,
+		arg.StartsAt,
+		arg.EndsAt,
+		arg.LastOpened,
+	)
+	return err
+}
+
 const roomSetGuestQueueTrack = `-- name: RoomSetGuestQueueTrack :exec
 INSERT INTO room_queue_tracks(
     track_id,
@@ -2484,14 +4807,373 @@ WHERE
     AND user_id = $2
 `
 
-type RoomSetModeratorParams struct {
-	RoomID      uuid.UUID `json:"room_id"`
-	UserID      uuid.UUID `json:"user_id"`
-	IsModerator bool      `json:"is_moderator"`
+type RoomSetModeratorParams struct {
+	RoomID      uuid.UUID `json:"room_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	IsModerator bool      `json:"is_moderator"`
+}
+
+func (q *Queries) RoomSetModerator(ctx context.Context, arg RoomSetModeratorParams) error {
+	_, err := q.db.Exec(ctx, roomSetModerator, arg.RoomID, arg.UserID, arg.IsModerator)
+	return err
+}
+
+const roomSetQueuePolicy = `-- name: RoomSetQueuePolicy :exec
+INSERT INTO room_queue_policies(
+    room_id,
+    max_pending_per_participant,
+    cooldown_seconds,
+    replay_block_minutes,
+    block_explicit,
+    max_duration_ms,
+    skip_vote_count,
+    skip_vote_percent)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8)
+ON CONFLICT (room_id)
+    DO UPDATE SET
+        max_pending_per_participant = EXCLUDED.max_pending_per_participant,
+        cooldown_seconds = EXCLUDED.cooldown_seconds,
+        replay_block_minutes = EXCLUDED.replay_block_minutes,
+        block_explicit = EXCLUDED.block_explicit,
+        max_duration_ms = EXCLUDED.max_duration_ms,
+        skip_vote_count = EXCLUDED.skip_vote_count,
+        skip_vote_percent = EXCLUDED.skip_vote_percent,
+        updated = now()
+`
+
+type RoomSetQueuePolicyParams struct {
+	RoomID                   uuid.UUID `json:"room_id"`
+	MaxPendingPerParticipant *int32    `json:"max_pending_per_participant"`
+	CooldownSeconds          *int32    `json:"cooldown_seconds"`
+	ReplayBlockMinutes       *int32    `json:"replay_block_minutes"`
+	BlockExplicit            bool      `json:"block_explicit"`
+	MaxDurationMs            *int32    `json:"max_duration_ms"`
+	SkipVoteCount            *int32    `json:"skip_vote_count"`
+	SkipVotePercent          *int32    `json:"skip_vote_percent"`
+}
+
+func (q *Queries) RoomSetQueuePolicy(ctx context.Context, arg RoomSetQueuePolicyParams) error {
+	_, err := q.db.Exec(ctx, roomSetQueuePolicy,
+		arg.RoomID,
+		arg.MaxPendingPerParticipant,
+		arg.CooldownSeconds,
+		arg.ReplayBlockMinutes,
+		arg.BlockExplicit,
+		arg.MaxDurationMs,
+		arg.SkipVoteCount,
+		arg.SkipVotePercent,
+	)
+	return err
+}
+
+const roomSetSchedule = `-- name: RoomSetSchedule :exec
+INSERT INTO room_schedules(
+    room_id,
+    starts_at,
+    ends_at,
+    idle_timeout_minutes,
+    recurrence,
+    last_opened)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6)
+ON CONFLICT (room_id)
+    DO UPDATE SET
+        starts_at = EXCLUDED.starts_at,
+        ends_at = EXCLUDED.ends_at,
+        idle_timeout_minutes = EXCLUDED.idle_timeout_minutes,
+        recurrence = EXCLUDED.recurrence,
+        last_opened = EXCLUDED.last_opened,
+        updated = now()
+`
+
+type RoomSetScheduleParams struct {
+	RoomID             uuid.UUID  `json:"room_id"`
+	StartsAt           *time.Time `json:"starts_at"`
+	EndsAt             *time.Time `json:"ends_at"`
+	IdleTimeoutMinutes *int32     `json:"idle_timeout_minutes"`
+	Recurrence         *string    `json:"recurrence"`
+	LastOpened         *time.Time `json:"last_opened"`
+}
+
+func (q *Queries) RoomSetSchedule(ctx context.Context, arg RoomSetScheduleParams) error {
+	_, err := q.db.Exec(ctx, roomSetSchedule,
+		arg.RoomID,
+		arg.StartsAt,
+		arg.EndsAt,
+		arg.IdleTimeoutMinutes,
+		arg.Recurrence,
+		arg.LastOpened,
+	)
+	return err
+}
+
+const roomSkipGetAll = `-- name: RoomSkipGetAll :many
+SELECT
+    id, room_id, track_id, vote_count, required_votes, timestamp, played_at
+FROM
+    room_skips
+WHERE
+    room_id = $1
+ORDER BY
+    timestamp DESC
+`
+
+func (q *Queries) RoomSkipGetAll(ctx context.Context, roomID uuid.UUID) ([]*RoomSkip, error) {
+	rows, err := q.db.Query(ctx, roomSkipGetAll, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*RoomSkip
+	for rows.Next() {
+		var i RoomSkip
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.TrackID,
+			&i.VoteCount,
+			&i.RequiredVotes,
+			&i.Timestamp,
+			&i.PlayedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const roomSkipGetVoters = `-- name: RoomSkipGetVoters :many
+SELECT
+    sv.skip_id,
+    sv.voter_id,
+    g.name AS guest_name,
+    u.display_name AS member_name
+FROM
+    room_skip_voters sv
+    JOIN room_skips s ON s.id = sv.skip_id
+    LEFT JOIN room_guests g ON g.id = sv.voter_id
+    LEFT JOIN users u ON u.id = sv.voter_id
+WHERE
+    s.room_id = $1
+`
+
+type RoomSkipGetVotersRow struct {
+	SkipID     uuid.UUID `json:"skip_id"`
+	VoterID    uuid.UUID `json:"voter_id"`
+	GuestName  *string   `json:"guest_name"`
+	MemberName *string   `json:"member_name"`
+}
+
+func (q *Queries) RoomSkipGetVoters(ctx context.Context, roomID uuid.UUID) ([]*RoomSkipGetVotersRow, error) {
+	rows, err := q.db.Query(ctx, roomSkipGetVoters, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*RoomSkipGetVotersRow
+	for rows.Next() {
+		var i RoomSkipGetVotersRow
+		if err := rows.Scan(
+			&i.SkipID,
+			&i.VoterID,
+			&i.GuestName,
+			&i.MemberName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const roomSkipInsert = `-- name: RoomSkipInsert :one
+INSERT INTO room_skips(
+    room_id,
+    track_id,
+    vote_count,
+    required_votes,
+    played_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5)
+ON CONFLICT (room_id,
+    track_id,
+    played_at)
+    DO NOTHING
+RETURNING
+    id
+`
+
+type RoomSkipInsertParams struct {
+	RoomID        uuid.UUID `json:"room_id"`
+	TrackID       string    `json:"track_id"`
+	VoteCount     int32     `json:"vote_count"`
+	RequiredVotes int32     `json:"required_votes"`
+	PlayedAt      time.Time `json:"played_at"`
+}
+
+func (q *Queries) RoomSkipInsert(ctx context.Context, arg RoomSkipInsertParams) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, roomSkipInsert,
+		arg.RoomID,
+		arg.TrackID,
+		arg.VoteCount,
+		arg.RequiredVotes,
+		arg.PlayedAt,
+	)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const roomSkipInsertVoters = `-- name: RoomSkipInsertVoters :exec
+INSERT INTO room_skip_voters(
+    skip_id,
+    voter_id)
+SELECT
+    $1,
+    voter_id
+FROM
+    room_skip_votes
+WHERE
+    room_id = $2
+    AND track_id = $3
+`
+
+type RoomSkipInsertVotersParams struct {
+	SkipID  uuid.UUID `json:"skip_id"`
+	RoomID  uuid.UUID `json:"room_id"`
+	TrackID string    `json:"track_id"`
+}
+
+func (q *Queries) RoomSkipInsertVoters(ctx context.Context, arg RoomSkipInsertVotersParams) error {
+	_, err := q.db.Exec(ctx, roomSkipInsertVoters, arg.SkipID, arg.RoomID, arg.TrackID)
+	return err
+}
+
+const roomSkipVoteClear = `-- name: RoomSkipVoteClear :exec
+DELETE FROM room_skip_votes
+WHERE room_id = $1
+`
+
+func (q *Queries) RoomSkipVoteClear(ctx context.Context, roomID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, roomSkipVoteClear, roomID)
+	return err
+}
+
+const roomSkipVoteDelete = `-- name: RoomSkipVoteDelete :exec
+DELETE FROM room_skip_votes
+WHERE room_id = $1
+    AND voter_id = $2
+`
+
+type RoomSkipVoteDeleteParams struct {
+	RoomID  uuid.UUID `json:"room_id"`
+	VoterID uuid.UUID `json:"voter_id"`
+}
+
+func (q *Queries) RoomSkipVoteDelete(ctx context.Context, arg RoomSkipVoteDeleteParams) error {
+	_, err := q.db.Exec(ctx, roomSkipVoteDelete, arg.RoomID, arg.VoterID)
+	return err
+}
+
+const roomSkipVoteGetVoters = `-- name: RoomSkipVoteGetVoters :many
+SELECT
+    voter_id
+FROM
+    room_skip_votes
+WHERE
+    room_id = $1
+    AND track_id = $2
+`
+
+type RoomSkipVoteGetVotersParams struct {
+	RoomID  uuid.UUID `json:"room_id"`
+	TrackID string    `json:"track_id"`
+}
+
+func (q *Queries) RoomSkipVoteGetVoters(ctx context.Context, arg RoomSkipVoteGetVotersParams) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, roomSkipVoteGetVoters, arg.RoomID, arg.TrackID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var voterID uuid.UUID
+		if err := rows.Scan(&voterID); err != nil {
+			return nil, err
+		}
+		items = append(items, voterID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const roomSkipVoteInsert = `-- name: RoomSkipVoteInsert :exec
+INSERT INTO room_skip_votes(
+    room_id,
+    voter_id,
+    track_id)
+VALUES (
+    $1,
+    $2,
+    $3)
+ON CONFLICT (room_id,
+    voter_id)
+    DO UPDATE SET
+        track_id = EXCLUDED.track_id,
+        timestamp = now()
+`
+
+type RoomSkipVoteInsertParams struct {
+	RoomID  uuid.UUID `json:"room_id"`
+	VoterID uuid.UUID `json:"voter_id"`
+	TrackID string    `json:"track_id"`
+}
+
+func (q *Queries) RoomSkipVoteInsert(ctx context.Context, arg RoomSkipVoteInsertParams) error {
+	_, err := q.db.Exec(ctx, roomSkipVoteInsert, arg.RoomID, arg.VoterID, arg.TrackID)
+	return err
+}
+
+const roomSkipVoteResetStale = `-- name: RoomSkipVoteResetStale :exec
+DELETE FROM room_skip_votes
+WHERE room_id = $1
+    AND track_id <> $2
+`
+
+type RoomSkipVoteResetStaleParams struct {
+	RoomID  uuid.UUID `json:"room_id"`
+	TrackID string    `json:"track_id"`
 }
 
-func (q *Queries) RoomSetModerator(ctx context.Context, arg RoomSetModeratorParams) error {
-	_, err := q.db.Exec(ctx, roomSetModerator, arg.RoomID, arg.UserID, arg.IsModerator)
+func (q *Queries) RoomSkipVoteResetStale(ctx context.Context, arg RoomSkipVoteResetStaleParams) error {
+	_, err := q.db.Exec(ctx, roomSkipVoteResetStale, arg.RoomID, arg.TrackID)
 	return err
 }
 
@@ -2499,18 +5181,18 @@ const roomUpdatePassword = `-- name: RoomUpdatePassword :exec
 UPDATE
     room_passwords
 SET
-    encrypted_password = crypt($2, gen_salt('bf'))
+    encrypted_password = $2::text
 WHERE
     room_id = $1
 `
 
 type RoomUpdatePasswordParams struct {
-	RoomID   uuid.UUID `json:"room_id"`
-	RoomPass string    `json:"room_pass"`
+	RoomID       uuid.UUID `json:"room_id"`
+	PasswordHash string    `json:"password_hash"`
 }
 
 func (q *Queries) RoomUpdatePassword(ctx context.Context, arg RoomUpdatePasswordParams) error {
-	_, err := q.db.Exec(ctx, roomUpdatePassword, arg.RoomID, arg.RoomPass)
+	_, err := q.db.Exec(ctx, roomUpdatePassword, arg.RoomID, arg.PasswordHash)
 	return err
 }
 
@@ -2567,25 +5249,74 @@ func (q *Queries) RoomUserIsMember(ctx context.Context, arg RoomUserIsMemberPara
 	return is_moderator, err
 }
 
-const roomValidatePassword = `-- name: RoomValidatePassword :one
-SELECT
-    (encrypted_password = crypt($2::text, encrypted_password::text))
-FROM
-    room_passwords AS rp
-    JOIN rooms r ON r.id = rp.room_id
-        AND r.code = $1
+const spotifyLoginStateConsume = `-- name: SpotifyLoginStateConsume :one
+DELETE FROM spotify_login_states
+WHERE state = $1
+  AND expires_at > NOW()
+RETURNING
+  user_id,
+  redirect_uri,
+  code_verifier
+`
+
+type SpotifyLoginStateConsumeRow struct {
+	UserID       uuid.UUID `json:"user_id"`
+	RedirectUri  string    `json:"redirect_uri"`
+	CodeVerifier string    `json:"code_verifier"`
+}
+
+func (q *Queries) SpotifyLoginStateConsume(ctx context.Context, state string) (*SpotifyLoginStateConsumeRow, error) {
+	row := q.db.QueryRow(ctx, spotifyLoginStateConsume, state)
+	var i SpotifyLoginStateConsumeRow
+	err := row.Scan(&i.UserID, &i.RedirectUri, &i.CodeVerifier)
+	return &i, err
+}
+
+const spotifyLoginStateDeleteExpired = `-- name: SpotifyLoginStateDeleteExpired :execrows
+DELETE FROM spotify_login_states
+WHERE expires_at <= NOW()
+`
+
+func (q *Queries) SpotifyLoginStateDeleteExpired(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, spotifyLoginStateDeleteExpired)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const spotifyLoginStateInsert = `-- name: SpotifyLoginStateInsert :exec
+INSERT INTO spotify_login_states(
+  state,
+  user_id,
+  redirect_uri,
+  code_verifier,
+  expires_at)
+VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5)
 `
 
-type RoomValidatePasswordParams struct {
-	Code     string `json:"code"`
-	RoomPass string `json:"room_pass"`
+type SpotifyLoginStateInsertParams struct {
+	State        string    `json:"state"`
+	UserID       uuid.UUID `json:"user_id"`
+	RedirectUri  string    `json:"redirect_uri"`
+	CodeVerifier string    `json:"code_verifier"`
+	ExpiresAt    time.Time `json:"expires_at"`
 }
 
-func (q *Queries) RoomValidatePassword(ctx context.Context, arg RoomValidatePasswordParams) (bool, error) {
-	row := q.db.QueryRow(ctx, roomValidatePassword, arg.Code, arg.RoomPass)
-	var column_1 bool
-	err := row.Scan(&column_1)
-	return column_1, err
+func (q *Queries) SpotifyLoginStateInsert(ctx context.Context, arg SpotifyLoginStateInsertParams) error {
+	_, err := q.db.Exec(ctx, spotifyLoginStateInsert,
+		arg.State,
+		arg.UserID,
+		arg.RedirectUri,
+		arg.CodeVerifier,
+		arg.ExpiresAt,
+	)
+	return err
 }
 
 const tableSizesAndRows = `-- name: TableSizesAndRows :many
@@ -2642,6 +5373,56 @@ func (q *Queries) TableSizesAndRows(ctx context.Context) ([]*TableSizesAndRowsRo
 	return items, nil
 }
 
+const trackCacheGetByArtistNames = `-- name: TrackCacheGetByArtistNames :many
+SELECT
+    id, uri, name, album_id, album_uri, album_name, artist_id, artist_uri, artist_name, image_url, other_artists, duration_ms, popularity, explicit, preview_url, disc_number, track_number, type, external_ids, isrc
+FROM
+    SPOTIFY_TRACK_CACHE
+WHERE
+    lower(artist_name) = ANY ($1::text[])
+`
+
+func (q *Queries) TrackCacheGetByArtistNames(ctx context.Context, artistNames []string) ([]*TrackData, error) {
+	rows, err := q.db.Query(ctx, trackCacheGetByArtistNames, artistNames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*TrackData
+	for rows.Next() {
+		var i TrackData
+		if err := rows.Scan(
+			&i.ID,
+			&i.URI,
+			&i.Name,
+			&i.AlbumID,
+			&i.AlbumURI,
+			&i.AlbumName,
+			&i.ArtistID,
+			&i.ArtistURI,
+			&i.ArtistName,
+			&i.ImageUrl,
+			&i.OtherArtists,
+			&i.DurationMs,
+			&i.Popularity,
+			&i.Explicit,
+			&i.PreviewUrl,
+			&i.DiscNumber,
+			&i.TrackNumber,
+			&i.Type,
+			&i.ExternalIds,
+			&i.Isrc,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const trackCacheGetByID = `-- name: TrackCacheGetByID :many
 SELECT
     id, uri, name, album_id, album_uri, album_name, artist_id, artist_uri, artist_name, image_url, other_artists, duration_ms, popularity, explicit, preview_url, disc_number, track_number, type, external_ids, isrc
@@ -2692,6 +5473,56 @@ func (q *Queries) TrackCacheGetByID(ctx context.Context, trackIds []string) ([]*
 	return items, nil
 }
 
+const trackCacheGetByISRC = `-- name: TrackCacheGetByISRC :many
+SELECT
+    id, uri, name, album_id, album_uri, album_name, artist_id, artist_uri, artist_name, image_url, other_artists, duration_ms, popularity, explicit, preview_url, disc_number, track_number, type, external_ids, isrc
+FROM
+    SPOTIFY_TRACK_CACHE
+WHERE
+    isrc = ANY ($1::text[])
+`
+
+func (q *Queries) TrackCacheGetByISRC(ctx context.Context, isrcs []string) ([]*TrackData, error) {
+	rows, err := q.db.Query(ctx, trackCacheGetByISRC, isrcs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*TrackData
+	for rows.Next() {
+		var i TrackData
+		if err := rows.Scan(
+			&i.ID,
+			&i.URI,
+			&i.Name,
+			&i.AlbumID,
+			&i.AlbumURI,
+			&i.AlbumName,
+			&i.ArtistID,
+			&i.ArtistURI,
+			&i.ArtistName,
+			&i.ImageUrl,
+			&i.OtherArtists,
+			&i.DurationMs,
+			&i.Popularity,
+			&i.Explicit,
+			&i.PreviewUrl,
+			&i.DiscNumber,
+			&i.TrackNumber,
+			&i.Type,
+			&i.ExternalIds,
+			&i.Isrc,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const trackCacheInsertBulk = `-- name: TrackCacheInsertBulk :exec
 INSERT INTO SPOTIFY_TRACK_CACHE(
     id,
@@ -2924,6 +5755,48 @@ func (q *Queries) UncachedTracks(ctx context.Context) ([]*UncachedTracksRow, err
 	return items, nil
 }
 
+const userDataExportGetTime = `-- name: UserDataExportGetTime :one
+SELECT
+  exported_at
+FROM
+  user_data_exports
+WHERE
+  user_id = $1
+`
+
+func (q *Queries) UserDataExportGetTime(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	row := q.db.QueryRow(ctx, userDataExportGetTime, userID)
+	var exportedAt time.Time
+	err := row.Scan(&exportedAt)
+	return exportedAt, err
+}
+
+const userDataExportSet = `-- name: UserDataExportSet :exec
+INSERT INTO user_data_exports(user_id)
+  VALUES ($1)
+ON CONFLICT (user_id)
+  DO UPDATE SET
+    exported_at = now()
+`
+
+func (q *Queries) UserDataExportSet(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, userDataExportSet, userID)
+	return err
+}
+
+const userDelete = `-- name: UserDelete :execrows
+DELETE FROM users
+WHERE id = $1
+`
+
+func (q *Queries) UserDelete(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, userDelete, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const userDeleteFriendRequest = `-- name: UserDeleteFriendRequest :exec
 DELETE FROM user_friend_requests
 WHERE user_id = $1
@@ -2940,6 +5813,19 @@ func (q *Queries) UserDeleteFriendRequest(ctx context.Context, arg UserDeleteFri
 	return err
 }
 
+const userDeleteHistory = `-- name: UserDeleteHistory :execrows
+DELETE FROM spotify_history
+WHERE user_id = $1
+`
+
+func (q *Queries) UserDeleteHistory(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, userDeleteHistory, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const userDeleteSpotifyInfo = `-- name: UserDeleteSpotifyInfo :exec
 UPDATE
   users
@@ -2961,14 +5847,183 @@ DELETE FROM spotify_tokens
 WHERE user_id = $1
 `
 
-func (q *Queries) UserDeleteSpotifyToken(ctx context.Context, userID uuid.UUID) error {
-	_, err := q.db.Exec(ctx, userDeleteSpotifyToken, userID)
-	return err
+func (q *Queries) UserDeleteSpotifyToken(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, userDeleteSpotifyToken, userID)
+	return err
+}
+
+const userExportEpisodeHistory = `-- name: UserExportEpisodeHistory :many
+SELECT
+  user_id, timestamp, kind, platform, ms_played, conn_country, show_name, show_uri, episode_name, episode_uri, video, reason_start, reason_end, shuffle, skipped, offline, incognito_mode
+FROM
+  spotify_episode_history
+WHERE
+  user_id = $1
+  AND timestamp > $2
+ORDER BY
+  timestamp
+LIMIT $3
+`
+
+type UserExportEpisodeHistoryParams struct {
+	UserID   uuid.UUID `json:"user_id"`
+	After    time.Time `json:"after"`
+	PageSize int32     `json:"page_size"`
+}
+
+func (q *Queries) UserExportEpisodeHistory(ctx context.Context, arg UserExportEpisodeHistoryParams) ([]*SpotifyEpisodeHistory, error) {
+	rows, err := q.db.Query(ctx, userExportEpisodeHistory, arg.UserID, arg.After, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*SpotifyEpisodeHistory
+	for rows.Next() {
+		var i SpotifyEpisodeHistory
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Timestamp,
+			&i.Kind,
+			&i.Platform,
+			&i.MsPlayed,
+			&i.ConnCountry,
+			&i.ShowName,
+			&i.ShowUri,
+			&i.EpisodeName,
+			&i.EpisodeUri,
+			&i.Video,
+			&i.ReasonStart,
+			&i.ReasonEnd,
+			&i.Shuffle,
+			&i.Skipped,
+			&i.Offline,
+			&i.IncognitoMode,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const userExportHistory = `-- name: UserExportHistory :many
+SELECT
+  user_id, timestamp, platform, ms_played, conn_country, ip_addr, user_agent, track_name, artist_name, album_name, spotify_track_uri, reason_start, reason_end, shuffle, skipped, offline, offline_timestamp, incognito_mode, spotify_artist_uri, spotify_album_uri, from_history, isrc, source
+FROM
+  spotify_history
+WHERE
+  user_id = $1
+  AND timestamp > $2
+ORDER BY
+  timestamp
+LIMIT $3
+`
+
+type UserExportHistoryParams struct {
+	UserID   uuid.UUID `json:"user_id"`
+	After    time.Time `json:"after"`
+	PageSize int32     `json:"page_size"`
+}
+
+func (q *Queries) UserExportHistory(ctx context.Context, arg UserExportHistoryParams) ([]*SpotifyHistory, error) {
+	rows, err := q.db.Query(ctx, userExportHistory, arg.UserID, arg.After, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*SpotifyHistory
+	for rows.Next() {
+		var i SpotifyHistory
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Timestamp,
+			&i.Platform,
+			&i.MsPlayed,
+			&i.ConnCountry,
+			&i.IpAddr,
+			&i.UserAgent,
+			&i.TrackName,
+			&i.ArtistName,
+			&i.AlbumName,
+			&i.SpotifyTrackUri,
+			&i.ReasonStart,
+			&i.ReasonEnd,
+			&i.Shuffle,
+			&i.Skipped,
+			&i.Offline,
+			&i.OfflineTimestamp,
+			&i.IncognitoMode,
+			&i.SpotifyArtistUri,
+			&i.SpotifyAlbumUri,
+			&i.FromHistory,
+			&i.Isrc,
+			&i.Source,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const userExportHostedRooms = `-- name: UserExportHostedRooms :many
+SELECT
+  id,
+  code,
+  name,
+  is_open,
+  created
+FROM
+  rooms
+WHERE
+  host_id = $1
+ORDER BY
+  created
+`
+
+type UserExportHostedRoomsRow struct {
+	ID      uuid.UUID `json:"id"`
+	Code    string    `json:"code"`
+	Name    string    `json:"name"`
+	IsOpen  bool      `json:"is_open"`
+	Created time.Time `json:"created"`
+}
+
+func (q *Queries) UserExportHostedRooms(ctx context.Context, hostID uuid.UUID) ([]*UserExportHostedRoomsRow, error) {
+	rows, err := q.db.Query(ctx, userExportHostedRooms, hostID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*UserExportHostedRoomsRow
+	for rows.Next() {
+		var i UserExportHostedRoomsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Code,
+			&i.Name,
+			&i.IsOpen,
+			&i.Created,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const userGetAllWithSpotify = `-- name: UserGetAllWithSpotify :many
 SELECT
-  id, username, display_name, spotify_account, spotify_name, spotify_image_url, created
+  id, username, display_name, spotify_account, spotify_name, spotify_image_url, created, role
 FROM
   users
 WHERE
@@ -2992,6 +6047,7 @@ func (q *Queries) UserGetAllWithSpotify(ctx context.Context) ([]*User, error) {
 			&i.SpotifyName,
 			&i.SpotifyImageUrl,
 			&i.Created,
+			&i.Role,
 		); err != nil {
 			return nil, err
 		}
@@ -3010,7 +6066,8 @@ SELECT
   display_name,
   spotify_account,
   spotify_name,
-  spotify_image_url
+  spotify_image_url,
+  role
 FROM
   users u
 WHERE
@@ -3024,6 +6081,7 @@ type UserGetByIDRow struct {
 	SpotifyAccount  *string   `json:"spotify_account"`
 	SpotifyName     *string   `json:"spotify_name"`
 	SpotifyImageUrl *string   `json:"spotify_image_url"`
+	Role            string    `json:"role"`
 }
 
 func (q *Queries) UserGetByID(ctx context.Context, id uuid.UUID) (*UserGetByIDRow, error) {
@@ -3036,6 +6094,7 @@ func (q *Queries) UserGetByID(ctx context.Context, id uuid.UUID) (*UserGetByIDRo
 		&i.SpotifyAccount,
 		&i.SpotifyName,
 		&i.SpotifyImageUrl,
+		&i.Role,
 	)
 	return &i, err
 }
@@ -3047,7 +6106,8 @@ SELECT
   display_name,
   spotify_account,
   spotify_name,
-  spotify_image_url
+  spotify_image_url,
+  role
 FROM
   users u
 WHERE
@@ -3061,6 +6121,7 @@ type UserGetByUsernameRow struct {
 	SpotifyAccount  *string   `json:"spotify_account"`
 	SpotifyName     *string   `json:"spotify_name"`
 	SpotifyImageUrl *string   `json:"spotify_image_url"`
+	Role            string    `json:"role"`
 }
 
 func (q *Queries) UserGetByUsername(ctx context.Context, username string) (*UserGetByUsernameRow, error) {
@@ -3073,6 +6134,7 @@ func (q *Queries) UserGetByUsername(ctx context.Context, username string) (*User
 		&i.SpotifyAccount,
 		&i.SpotifyName,
 		&i.SpotifyImageUrl,
+		&i.Role,
 	)
 	return &i, err
 }
@@ -3171,7 +6233,7 @@ func (q *Queries) UserGetFriendSuggestions(ctx context.Context, userID uuid.UUID
 
 const userGetFriends = `-- name: UserGetFriends :many
 SELECT
-  u.id, u.username, u.display_name, u.spotify_account, u.spotify_name, u.spotify_image_url, u.created
+  u.id, u.username, u.display_name, u.spotify_account, u.spotify_name, u.spotify_image_url, u.created, u.role
 FROM
   user_friends f
   JOIN users u ON u.id = f.friend_id
@@ -3195,6 +6257,7 @@ func (q *Queries) UserGetFriends(ctx context.Context, userID uuid.UUID) ([]*User
 			&i.SpotifyName,
 			&i.SpotifyImageUrl,
 			&i.Created,
+			&i.Role,
 		); err != nil {
 			return nil, err
 		}
@@ -3215,12 +6278,24 @@ SELECT
   u.id AS host_id,
   u.username AS host_username,
   u.display_name AS host_display_name,
-  u.spotify_image_url AS host_spotify_image_url
+  u.spotify_image_url AS host_spotify_image_url,
+  s.starts_at,
+  s.ends_at,
+  s.idle_timeout_minutes,
+  s.recurrence,
+  s.last_opened,
+  COALESCE((
+    SELECT
+      MAX(t.timestamp)
+    FROM room_queue_tracks t
+    WHERE
+      t.room_id = r.id), r.created)::timestamptz AS last_activity
 FROM
   rooms r
   JOIN users u ON r.host_id = u.id
     AND u.id = $1
     AND r.is_open = $2
+  LEFT JOIN room_schedules s ON s.room_id = r.id
 `
 
 type UserGetHostedRoomsParams struct {
@@ -3229,14 +6304,20 @@ type UserGetHostedRoomsParams struct {
 }
 
 type UserGetHostedRoomsRow struct {
-	ID                  uuid.UUID `json:"id"`
-	Name                string    `json:"name"`
-	Code                string    `json:"code"`
-	Created             time.Time `json:"created"`
-	HostID              uuid.UUID `json:"host_id"`
-	HostUsername        string    `json:"host_username"`
-	HostDisplayName     string    `json:"host_display_name"`
-	HostSpotifyImageUrl *string   `json:"host_spotify_image_url"`
+	ID                  uuid.UUID  `json:"id"`
+	Name                string     `json:"name"`
+	Code                string     `json:"code"`
+	Created             time.Time  `json:"created"`
+	HostID              uuid.UUID  `json:"host_id"`
+	HostUsername        string     `json:"host_username"`
+	HostDisplayName     string     `json:"host_display_name"`
+	HostSpotifyImageUrl *string    `json:"host_spotify_image_url"`
+	StartsAt            *time.Time `json:"starts_at"`
+	EndsAt              *time.Time `json:"ends_at"`
+	IdleTimeoutMinutes  *int32     `json:"idle_timeout_minutes"`
+	Recurrence          *string    `json:"recurrence"`
+	LastOpened          *time.Time `json:"last_opened"`
+	LastActivity        time.Time  `json:"last_activity"`
 }
 
 func (q *Queries) UserGetHostedRooms(ctx context.Context, arg UserGetHostedRoomsParams) ([]*UserGetHostedRoomsRow, error) {
@@ -3257,6 +6338,12 @@ func (q *Queries) UserGetHostedRooms(ctx context.Context, arg UserGetHostedRooms
 			&i.HostUsername,
 			&i.HostDisplayName,
 			&i.HostSpotifyImageUrl,
+			&i.StartsAt,
+			&i.EndsAt,
+			&i.IdleTimeoutMinutes,
+			&i.Recurrence,
+			&i.LastOpened,
+			&i.LastActivity,
 		); err != nil {
 			return nil, err
 		}
@@ -3277,13 +6364,25 @@ SELECT
   u.id AS host_id,
   u.username AS host_username,
   u.display_name AS host_display_name,
-  u.spotify_image_url AS host_spotify_image_url
+  u.spotify_image_url AS host_spotify_image_url,
+  s.starts_at,
+  s.ends_at,
+  s.idle_timeout_minutes,
+  s.recurrence,
+  s.last_opened,
+  COALESCE((
+    SELECT
+      MAX(t.timestamp)
+    FROM room_queue_tracks t
+    WHERE
+      t.room_id = r.id), r.created)::timestamptz AS last_activity
 FROM
   rooms r
   JOIN room_members rm ON rm.user_id = $1
     AND r.id = rm.room_id
     AND r.is_open = $2
   JOIN users u ON r.host_id = u.id
+  LEFT JOIN room_schedules s ON s.room_id = r.id
 `
 
 type UserGetJoinedRoomsParams struct {
@@ -3292,14 +6391,20 @@ type UserGetJoinedRoomsParams struct {
 }
 
 type UserGetJoinedRoomsRow struct {
-	ID                  uuid.UUID `json:"id"`
-	Name                string    `json:"name"`
-	Code                string    `json:"code"`
-	Created             time.Time `json:"created"`
-	HostID              uuid.UUID `json:"host_id"`
-	HostUsername        string    `json:"host_username"`
-	HostDisplayName     string    `json:"host_display_name"`
-	HostSpotifyImageUrl *string   `json:"host_spotify_image_url"`
+	ID                  uuid.UUID  `json:"id"`
+	Name                string     `json:"name"`
+	Code                string     `json:"code"`
+	Created             time.Time  `json:"created"`
+	HostID              uuid.UUID  `json:"host_id"`
+	HostUsername        string     `json:"host_username"`
+	HostDisplayName     string     `json:"host_display_name"`
+	HostSpotifyImageUrl *string    `json:"host_spotify_image_url"`
+	StartsAt            *time.Time `json:"starts_at"`
+	EndsAt              *time.Time `json:"ends_at"`
+	IdleTimeoutMinutes  *int32     `json:"idle_timeout_minutes"`
+	Recurrence          *string    `json:"recurrence"`
+	LastOpened          *time.Time `json:"last_opened"`
+	LastActivity        time.Time  `json:"last_activity"`
 }
 
 func (q *Queries) UserGetJoinedRooms(ctx context.Context, arg UserGetJoinedRoomsParams) ([]*UserGetJoinedRoomsRow, error) {
@@ -3320,6 +6425,12 @@ func (q *Queries) UserGetJoinedRooms(ctx context.Context, arg UserGetJoinedRooms
 			&i.HostUsername,
 			&i.HostDisplayName,
 			&i.HostSpotifyImageUrl,
+			&i.StartsAt,
+			&i.EndsAt,
+			&i.IdleTimeoutMinutes,
+			&i.Recurrence,
+			&i.LastOpened,
+			&i.LastActivity,
 		); err != nil {
 			return nil, err
 		}
@@ -3331,6 +6442,28 @@ func (q *Queries) UserGetJoinedRooms(ctx context.Context, arg UserGetJoinedRooms
 	return items, nil
 }
 
+const userGetPasswordHash = `-- name: UserGetPasswordHash :one
+SELECT
+  up.user_id,
+  up.encrypted_password
+FROM
+  user_passwords AS up
+  JOIN users u ON u.id = up.user_id
+    AND UPPER(u.username) = UPPER($1::text)
+`
+
+type UserGetPasswordHashRow struct {
+	UserID            uuid.UUID `json:"user_id"`
+	EncryptedPassword string    `json:"encrypted_password"`
+}
+
+func (q *Queries) UserGetPasswordHash(ctx context.Context, username string) (*UserGetPasswordHashRow, error) {
+	row := q.db.QueryRow(ctx, userGetPasswordHash, username)
+	var i UserGetPasswordHashRow
+	err := row.Scan(&i.UserID, &i.EncryptedPassword)
+	return &i, err
+}
+
 const userGetReceivedFriendRequests = `-- name: UserGetReceivedFriendRequests :many
 SELECT
   id,
@@ -3425,6 +6558,22 @@ func (q *Queries) UserGetSentFriendRequests(ctx context.Context, userID uuid.UUI
 	return items, nil
 }
 
+const userGetSpotifyPermissionsVersion = `-- name: UserGetSpotifyPermissionsVersion :one
+SELECT
+  permissions_version
+FROM
+  spotify_tokens
+WHERE
+  user_id = $1
+`
+
+func (q *Queries) UserGetSpotifyPermissionsVersion(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, userGetSpotifyPermissionsVersion, userID)
+	var permissionsVersion int64
+	err := row.Scan(&permissionsVersion)
+	return permissionsVersion, err
+}
+
 const userGetSpotifyTokens = `-- name: UserGetSpotifyTokens :one
 SELECT
   st.encrypted_access_token,
@@ -3449,11 +6598,58 @@ func (q *Queries) UserGetSpotifyTokens(ctx context.Context, userID uuid.UUID) (*
 	return &i, err
 }
 
+const userGetSpotifyTokensToReencrypt = `-- name: UserGetSpotifyTokensToReencrypt :many
+SELECT
+  id,
+  encrypted_access_token,
+  encrypted_refresh_token
+FROM
+  spotify_tokens
+WHERE (substring(encrypted_access_token FROM 1 FOR length($1::bytea)) <> $1::bytea
+  OR substring(encrypted_refresh_token FROM 1 FOR length($1::bytea)) <> $1::bytea)
+  AND id > $2::uuid
+ORDER BY
+  id
+LIMIT $3::int
+`
+
+type UserGetSpotifyTokensToReencryptParams struct {
+	KeyPrefix []byte    `json:"key_prefix"`
+	After     uuid.UUID `json:"after"`
+	BatchSize int32     `json:"batch_size"`
+}
+
+type UserGetSpotifyTokensToReencryptRow struct {
+	ID                    uuid.UUID `json:"id"`
+	EncryptedAccessToken  []byte    `json:"encrypted_access_token"`
+	EncryptedRefreshToken []byte    `json:"encrypted_refresh_token"`
+}
+
+func (q *Queries) UserGetSpotifyTokensToReencrypt(ctx context.Context, arg UserGetSpotifyTokensToReencryptParams) ([]*UserGetSpotifyTokensToReencryptRow, error) {
+	rows, err := q.db.Query(ctx, userGetSpotifyTokensToReencrypt, arg.KeyPrefix, arg.After, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*UserGetSpotifyTokensToReencryptRow
+	for rows.Next() {
+		var i UserGetSpotifyTokensToReencryptRow
+		if err := rows.Scan(&i.ID, &i.EncryptedAccessToken, &i.EncryptedRefreshToken); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const userHasSpotifyHistory = `-- name: UserHasSpotifyHistory :one
 SELECT
   EXISTS (
     SELECT
-      user_id, timestamp, platform, ms_played, conn_country, ip_addr, user_agent, track_name, artist_name, album_name, spotify_track_uri, reason_start, reason_end, shuffle, skipped, offline, offline_timestamp, incognito_mode, spotify_artist_uri, spotify_album_uri, from_history, isrc
+      user_id, timestamp, platform, ms_played, conn_country, ip_addr, user_agent, track_name, artist_name, album_name, spotify_track_uri, reason_start, reason_end, shuffle, skipped, offline, offline_timestamp, incognito_mode, spotify_artist_uri, spotify_album_uri, from_history, isrc, source
     FROM
       SPOTIFY_HISTORY
     WHERE
@@ -3505,7 +6701,7 @@ INSERT INTO user_passwords(
   encrypted_password)
 SELECT
   id,
-  crypt($3, gen_salt('bf'))
+  $3::text
 FROM
   new_user
 RETURNING (
@@ -3516,13 +6712,13 @@ RETURNING (
 `
 
 type UserInsertWithPasswordParams struct {
-	Username    string `json:"username"`
-	DisplayName string `json:"display_name"`
-	UserPass    string `json:"user_pass"`
+	Username     string `json:"username"`
+	DisplayName  string `json:"display_name"`
+	PasswordHash string `json:"password_hash"`
 }
 
 func (q *Queries) UserInsertWithPassword(ctx context.Context, arg UserInsertWithPasswordParams) (uuid.UUID, error) {
-	row := q.db.QueryRow(ctx, userInsertWithPassword, arg.Username, arg.DisplayName, arg.UserPass)
+	row := q.db.QueryRow(ctx, userInsertWithPassword, arg.Username, arg.DisplayName, arg.PasswordHash)
 	var id uuid.UUID
 	err := row.Scan(&id)
 	return id, err
@@ -3552,6 +6748,40 @@ func (q *Queries) UserIsFriends(ctx context.Context, arg UserIsFriendsParams) (b
 	return exists, err
 }
 
+const userReencryptSpotifyTokens = `-- name: UserReencryptSpotifyTokens :execrows
+UPDATE
+  spotify_tokens
+SET
+  encrypted_access_token = $1,
+  encrypted_refresh_token = $2
+WHERE
+  id = $3
+  AND encrypted_access_token = $4
+  AND encrypted_refresh_token = $5
+`
+
+type UserReencryptSpotifyTokensParams struct {
+	EncryptedAccessToken  []byte    `json:"encrypted_access_token"`
+	EncryptedRefreshToken []byte    `json:"encrypted_refresh_token"`
+	ID                    uuid.UUID `json:"id"`
+	PreviousAccessToken   []byte    `json:"previous_access_token"`
+	PreviousRefreshToken  []byte    `json:"previous_refresh_token"`
+}
+
+func (q *Queries) UserReencryptSpotifyTokens(ctx context.Context, arg UserReencryptSpotifyTokensParams) (int64, error) {
+	result, err := q.db.Exec(ctx, userReencryptSpotifyTokens,
+		arg.EncryptedAccessToken,
+		arg.EncryptedRefreshToken,
+		arg.ID,
+		arg.PreviousAccessToken,
+		arg.PreviousRefreshToken,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const userSendFriendRequest = `-- name: UserSendFriendRequest :exec
 INSERT INTO user_friend_requests(
   user_id,
@@ -3571,22 +6801,320 @@ func (q *Queries) UserSendFriendRequest(ctx context.Context, arg UserSendFriendR
 	return err
 }
 
-const userUpdatePassword = `-- name: UserUpdatePassword :exec
+const userSessionGetActive = `-- name: UserSessionGetActive :many
+SELECT
+  id,
+  user_agent,
+  ip_address,
+  created,
+  last_used,
+  expires_at
+FROM
+  user_sessions
+WHERE
+  user_id = $1
+  AND revoked_at IS NULL
+  AND expires_at > NOW()
+ORDER BY
+  last_used DESC
+`
+
+type UserSessionGetActiveRow struct {
+	ID        uuid.UUID `json:"id"`
+	UserAgent *string   `json:"user_agent"`
+	IpAddress *string   `json:"ip_address"`
+	Created   time.Time `json:"created"`
+	LastUsed  time.Time `json:"last_used"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) UserSessionGetActive(ctx context.Context, userID uuid.UUID) ([]*UserSessionGetActiveRow, error) {
+	rows, err := q.db.Query(ctx, userSessionGetActive, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*UserSessionGetActiveRow
+	for rows.Next() {
+		var i UserSessionGetActiveRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.Created,
+			&i.LastUsed,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const userSessionInsert = `-- name: UserSessionInsert :one
+INSERT INTO user_sessions(
+  user_id,
+  refresh_token_hash,
+  user_agent,
+  ip_address,
+  expires_at)
+VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5)
+RETURNING
+  id
+`
+
+type UserSessionInsertParams struct {
+	UserID           uuid.UUID `json:"user_id"`
+	RefreshTokenHash []byte    `json:"refresh_token_hash"`
+	UserAgent        *string   `json:"user_agent"`
+	IpAddress        *string   `json:"ip_address"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+func (q *Queries) UserSessionInsert(ctx context.Context, arg UserSessionInsertParams) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, userSessionInsert,
+		arg.UserID,
+		arg.RefreshTokenHash,
+		arg.UserAgent,
+		arg.IpAddress,
+		arg.ExpiresAt,
+	)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const userSessionIsActive = `-- name: UserSessionIsActive :one
+SELECT
+  EXISTS (
+    SELECT
+      id, user_id, refresh_token_hash, previous_refresh_token_hash, user_agent, ip_address, created, last_used, expires_at, revoked_at
+    FROM
+      user_sessions
+    WHERE
+      id = $1
+      AND user_id = $2
+      AND revoked_at IS NULL
+      AND expires_at > NOW())
+`
+
+type UserSessionIsActiveParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) UserSessionIsActive(ctx context.Context, arg UserSessionIsActiveParams) (bool, error) {
+	row := q.db.QueryRow(ctx, userSessionIsActive, arg.ID, arg.UserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const userSessionRevoke = `-- name: UserSessionRevoke :execrows
+UPDATE
+  user_sessions
+SET
+  revoked_at = NOW()
+WHERE
+  id = $1
+  AND user_id = $2
+  AND revoked_at IS NULL
+`
+
+type UserSessionRevokeParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) UserSessionRevoke(ctx context.Context, arg UserSessionRevokeParams) (int64, error) {
+	result, err := q.db.Exec(ctx, userSessionRevoke, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const userSessionRevokeOthers = `-- name: UserSessionRevokeOthers :execrows
+UPDATE
+  user_sessions
+SET
+  revoked_at = NOW()
+WHERE
+  user_id = $1
+  AND id != $2
+  AND revoked_at IS NULL
+`
+
+type UserSessionRevokeOthersParams struct {
+	UserID           uuid.UUID `json:"user_id"`
+	CurrentSessionID uuid.UUID `json:"current_session_id"`
+}
+
+func (q *Queries) UserSessionRevokeOthers(ctx context.Context, arg UserSessionRevokeOthersParams) (int64, error) {
+	result, err := q.db.Exec(ctx, userSessionRevokeOthers, arg.UserID, arg.CurrentSessionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const userSessionRevokeReused = `-- name: UserSessionRevokeReused :execrows
+UPDATE
+  user_sessions s
+SET
+  revoked_at = NOW()
+FROM
+  user_session_used_tokens t
+WHERE
+  t.refresh_token_hash = $1
+  AND s.id = t.session_id
+  AND s.revoked_at IS NULL
+`
+
+func (q *Queries) UserSessionRevokeReused(ctx context.Context, refreshTokenHash []byte) (int64, error) {
+	result, err := q.db.Exec(ctx, userSessionRevokeReused, refreshTokenHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const userSessionRotate = `-- name: UserSessionRotate :one
+WITH rotated AS (
+  UPDATE
+    user_sessions
+  SET
+    refresh_token_hash = $1,
+    last_used = NOW(),
+    expires_at = $2
+  WHERE
+    refresh_token_hash = $3
+    AND revoked_at IS NULL
+    AND expires_at > NOW()
+  RETURNING
+    id,
+    user_id
+),
+used AS (
+  INSERT INTO user_session_used_tokens(
+    refresh_token_hash,
+    session_id)
+  SELECT
+    $3,
+    id
+  FROM
+    rotated)
+SELECT
+  id,
+  user_id
+FROM
+  rotated
+`
+
+type UserSessionRotateParams struct {
+	NewRefreshTokenHash []byte    `json:"new_refresh_token_hash"`
+	ExpiresAt           time.Time `json:"expires_at"`
+	RefreshTokenHash    []byte    `json:"refresh_token_hash"`
+}
+
+type UserSessionRotateRow struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) UserSessionRotate(ctx context.Context, arg UserSessionRotateParams) (*UserSessionRotateRow, error) {
+	row := q.db.QueryRow(ctx, userSessionRotate, arg.NewRefreshTokenHash, arg.ExpiresAt, arg.RefreshTokenHash)
+	var i UserSessionRotateRow
+	err := row.Scan(&i.ID, &i.UserID)
+	return &i, err
+}
+
+const userSetLatestSpotifyPermissions = `-- name: UserSetLatestSpotifyPermissions :exec
+UPDATE
+  spotify_tokens
+SET
+  permissions_version = (
+    SELECT
+      MAX(id)
+    FROM
+      spotify_permissions_versions)
+WHERE
+  user_id = $1
+`
+
+func (q *Queries) UserSetLatestSpotifyPermissions(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, userSetLatestSpotifyPermissions, userID)
+	return err
+}
+
+const userSetRole = `-- name: UserSetRole :execrows
+UPDATE
+  users
+SET
+  role = $1
+WHERE
+  UPPER(username) = UPPER($2::text)
+`
+
+type UserSetRoleParams struct {
+	Role     string `json:"role"`
+	Username string `json:"username"`
+}
+
+func (q *Queries) UserSetRole(ctx context.Context, arg UserSetRoleParams) (int64, error) {
+	result, err := q.db.Exec(ctx, userSetRole, arg.Role, arg.Username)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const userUpdateNames = `-- name: UserUpdateNames :exec
 UPDATE
   users
 SET
-  encrypted_password = crypt($1, gen_salt('bf'))
+  username = $1,
+  display_name = $2
+WHERE
+  id = $3
+`
+
+type UserUpdateNamesParams struct {
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	ID          uuid.UUID `json:"id"`
+}
+
+func (q *Queries) UserUpdateNames(ctx context.Context, arg UserUpdateNamesParams) error {
+	_, err := q.db.Exec(ctx, userUpdateNames, arg.Username, arg.DisplayName, arg.ID)
+	return err
+}
+
+const userUpdatePassword = `-- name: UserUpdatePassword :exec
+UPDATE
+  user_passwords
+SET
+  encrypted_password = $1::text
 WHERE
-  id = $2
+  user_id = $2
 `
 
 type UserUpdatePasswordParams struct {
-	UserPass string    `json:"user_pass"`
-	UserID   uuid.UUID `json:"user_id"`
+	PasswordHash string    `json:"password_hash"`
+	UserID       uuid.UUID `json:"user_id"`
 }
 
 func (q *Queries) UserUpdatePassword(ctx context.Context, arg UserUpdatePasswordParams) error {
-	_, err := q.db.Exec(ctx, userUpdatePassword, arg.UserPass, arg.UserID)
+	_, err := q.db.Exec(ctx, userUpdatePassword, arg.PasswordHash, arg.UserID)
 	return err
 }
 
@@ -3667,30 +7195,9 @@ func (q *Queries) UserUpdateSpotifyTokens(ctx context.Context, arg UserUpdateSpo
 	return err
 }
 
-const userValidatePassword = `-- name: UserValidatePassword :one
-SELECT
-  (encrypted_password = crypt($1, encrypted_password))
-FROM
-  user_passwords AS up
-  JOIN users u ON u.id = up.user_id
-    AND UPPER(u.username) = UPPER($2::text)
-`
-
-type UserValidatePasswordParams struct {
-	UserPass string `json:"user_pass"`
-	Username string `json:"username"`
-}
-
-func (q *Queries) UserValidatePassword(ctx context.Context, arg UserValidatePasswordParams) (bool, error) {
-	row := q.db.QueryRow(ctx, userValidatePassword, arg.UserPass, arg.Username)
-	var column_1 bool
-	err := row.Scan(&column_1)
-	return column_1, err
-}
-
 const usersToFetchHistory = `-- name: UsersToFetchHistory :many
 SELECT
-  u.id, u.username, u.display_name, u.spotify_account, u.spotify_name, u.spotify_image_url, u.created
+  u.id, u.username, u.display_name, u.spotify_account, u.spotify_name, u.spotify_image_url, u.created, u.role
 FROM
   users u
   JOIN spotify_tokens st ON u.id = st.user_id
@@ -3714,6 +7221,7 @@ func (q *Queries) UsersToFetchHistory(ctx context.Context) ([]*User, error) {
 			&i.SpotifyName,
 			&i.SpotifyImageUrl,
 			&i.Created,
+			&i.Role,
 		); err != nil {
 			return nil, err
 		}
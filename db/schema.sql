@@ -75,6 +75,116 @@ SET default_tablespace = '';
 
 SET default_table_access_method = heap;
 
+--
+-- Name: admin_audit_log; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.admin_audit_log (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    user_id uuid,
+    action text NOT NULL,
+    status_code integer,
+    ip_address text,
+    created timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.admin_audit_log OWNER TO queue_share;
+
+--
+-- Name: history_import_review; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.history_import_review (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    user_id uuid NOT NULL,
+    source text NOT NULL,
+    "timestamp" timestamp without time zone NOT NULL,
+    track_name text NOT NULL,
+    artist_name text NOT NULL,
+    album_name text NOT NULL,
+    isrc text,
+    duration_ms integer,
+    created timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT history_import_review_source_check CHECK ((source = ANY (ARRAY['lastfm'::text, 'listenbrainz'::text])))
+);
+
+
+ALTER TABLE public.history_import_review OWNER TO queue_share;
+
+--
+-- Name: history_merges; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.history_merges (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    user_id uuid NOT NULL,
+    upload_job_id uuid,
+    spotify_track_uri text NOT NULL,
+    kept_timestamp timestamp without time zone NOT NULL,
+    kept_ms_played integer NOT NULL,
+    removed_timestamp timestamp without time zone NOT NULL,
+    removed_ms_played integer NOT NULL,
+    created timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.history_merges OWNER TO queue_share;
+
+--
+-- Name: history_upload_job_files; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.history_upload_job_files (
+    job_id uuid NOT NULL,
+    name text NOT NULL,
+    entries integer NOT NULL,
+    created timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.history_upload_job_files OWNER TO queue_share;
+
+--
+-- Name: history_upload_jobs; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.history_upload_jobs (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    user_id uuid NOT NULL,
+    status text DEFAULT 'running'::text NOT NULL,
+    archive_path text NOT NULL,
+    files_total integer NOT NULL,
+    files_done integer DEFAULT 0 NOT NULL,
+    entries_done integer DEFAULT 0 NOT NULL,
+    error text,
+    created timestamp with time zone DEFAULT now() NOT NULL,
+    updated timestamp with time zone DEFAULT now() NOT NULL,
+    source text DEFAULT 'spotify'::text NOT NULL,
+    CONSTRAINT history_upload_jobs_source_check CHECK ((source = ANY (ARRAY['spotify'::text, 'lastfm'::text, 'listenbrainz'::text]))),
+    CONSTRAINT history_upload_jobs_status_check CHECK ((status = ANY (ARRAY['running'::text, 'failed'::text, 'complete'::text])))
+);
+
+
+ALTER TABLE public.history_upload_jobs OWNER TO queue_share;
+
+--
+-- Name: room_blocklist; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.room_blocklist (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    room_id uuid NOT NULL,
+    kind text NOT NULL,
+    value text NOT NULL,
+    added_by uuid,
+    created timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT room_blocklist_kind_check CHECK ((kind = ANY (ARRAY['track'::text, 'artist'::text, 'isrc'::text])))
+);
+
+
+ALTER TABLE public.room_blocklist OWNER TO queue_share;
+
 --
 -- Name: room_guests; Type: TABLE; Schema: public; Owner: postgres
 --
@@ -82,12 +192,34 @@ SET default_table_access_method = heap;
 CREATE TABLE public.room_guests (
     id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
     room_id uuid NOT NULL,
-    name text NOT NULL
+    name text NOT NULL,
+    invite_id uuid
 );
 
 
 ALTER TABLE public.room_guests OWNER TO postgres;
 
+--
+-- Name: room_invites; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.room_invites (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    room_id uuid NOT NULL,
+    role text NOT NULL,
+    created_by uuid NOT NULL,
+    created timestamp with time zone DEFAULT now() NOT NULL,
+    expires_at timestamp with time zone,
+    max_uses integer,
+    uses integer DEFAULT 0 NOT NULL,
+    revoked_at timestamp with time zone,
+    CONSTRAINT room_invites_max_uses_check CHECK ((max_uses > 0)),
+    CONSTRAINT room_invites_role_check CHECK ((role = ANY (ARRAY['guest'::text, 'member'::text, 'moderator'::text])))
+);
+
+
+ALTER TABLE public.room_invites OWNER TO queue_share;
+
 --
 -- Name: room_members; Type: TABLE; Schema: public; Owner: queue_share
 --
@@ -115,6 +247,47 @@ CREATE TABLE public.room_passwords (
 
 ALTER TABLE public.room_passwords OWNER TO postgres;
 
+--
+-- Name: room_play_log; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.room_play_log (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    room_id uuid NOT NULL,
+    track_id text NOT NULL,
+    started_at timestamp with time zone NOT NULL,
+    duration_ms integer NOT NULL,
+    queue_track_id uuid,
+    guest_id uuid,
+    user_id uuid,
+    score integer DEFAULT 0 NOT NULL,
+    skipped boolean DEFAULT false NOT NULL,
+    skip_votes integer
+);
+
+
+ALTER TABLE public.room_play_log OWNER TO queue_share;
+
+--
+-- Name: room_queue_policies; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.room_queue_policies (
+    room_id uuid NOT NULL,
+    max_pending_per_participant integer,
+    cooldown_seconds integer,
+    replay_block_minutes integer,
+    block_explicit boolean DEFAULT false NOT NULL,
+    max_duration_ms integer,
+    updated timestamp with time zone DEFAULT now() NOT NULL,
+    skip_vote_count integer,
+    skip_vote_percent integer,
+    CONSTRAINT room_queue_policies_skip_vote_percent_check CHECK (((skip_vote_percent >= 1) AND (skip_vote_percent <= 100)))
+);
+
+
+ALTER TABLE public.room_queue_policies OWNER TO queue_share;
+
 --
 -- Name: room_queue_tracks; Type: TABLE; Schema: public; Owner: postgres
 --
@@ -126,12 +299,93 @@ CREATE TABLE public.room_queue_tracks (
     room_id uuid NOT NULL,
     "timestamp" timestamp with time zone DEFAULT now() NOT NULL,
     user_id uuid,
-    played boolean DEFAULT false NOT NULL
+    played boolean DEFAULT false NOT NULL,
+    priority integer DEFAULT 0 NOT NULL,
+    pushed_at timestamp with time zone
 );
 
 
 ALTER TABLE public.room_queue_tracks OWNER TO postgres;
 
+--
+-- Name: room_queue_votes; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.room_queue_votes (
+    queue_track_id uuid NOT NULL,
+    voter_id uuid NOT NULL,
+    vote integer NOT NULL,
+    "timestamp" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT room_queue_votes_vote_check CHECK ((vote = ANY (ARRAY['-1'::integer, 1])))
+);
+
+
+ALTER TABLE public.room_queue_votes OWNER TO queue_share;
+
+--
+-- Name: room_schedules; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.room_schedules (
+    room_id uuid NOT NULL,
+    starts_at timestamp with time zone,
+    ends_at timestamp with time zone,
+    idle_timeout_minutes integer,
+    recurrence text,
+    last_opened timestamp with time zone,
+    updated timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT room_schedules_check CHECK (((starts_at IS NULL) OR (ends_at IS NULL) OR (ends_at > starts_at))),
+    CONSTRAINT room_schedules_check1 CHECK (((recurrence IS NULL) OR ((starts_at IS NOT NULL) AND (ends_at IS NOT NULL)))),
+    CONSTRAINT room_schedules_idle_timeout_minutes_check CHECK ((idle_timeout_minutes > 0)),
+    CONSTRAINT room_schedules_recurrence_check CHECK ((recurrence = ANY (ARRAY['daily'::text, 'weekly'::text])))
+);
+
+
+ALTER TABLE public.room_schedules OWNER TO queue_share;
+
+--
+-- Name: room_skip_voters; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.room_skip_voters (
+    skip_id uuid NOT NULL,
+    voter_id uuid NOT NULL
+);
+
+
+ALTER TABLE public.room_skip_voters OWNER TO queue_share;
+
+--
+-- Name: room_skip_votes; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.room_skip_votes (
+    room_id uuid NOT NULL,
+    voter_id uuid NOT NULL,
+    track_id text NOT NULL,
+    "timestamp" timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.room_skip_votes OWNER TO queue_share;
+
+--
+-- Name: room_skips; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.room_skips (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    room_id uuid NOT NULL,
+    track_id text NOT NULL,
+    vote_count integer NOT NULL,
+    required_votes integer NOT NULL,
+    "timestamp" timestamp with time zone DEFAULT now() NOT NULL,
+    played_at timestamp with time zone NOT NULL
+);
+
+
+ALTER TABLE public.room_skips OWNER TO queue_share;
+
 --
 -- Name: rooms; Type: TABLE; Schema: public; Owner: postgres
 --
@@ -205,6 +459,34 @@ CREATE TABLE public.spotify_artist_cache (
 
 ALTER TABLE public.spotify_artist_cache OWNER TO queue_share;
 
+--
+-- Name: spotify_episode_history; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.spotify_episode_history (
+    user_id uuid NOT NULL,
+    "timestamp" timestamp without time zone NOT NULL,
+    kind text NOT NULL,
+    platform text NOT NULL,
+    ms_played integer NOT NULL,
+    conn_country text NOT NULL,
+    show_name text NOT NULL,
+    show_uri text,
+    episode_name text NOT NULL,
+    episode_uri text NOT NULL,
+    video boolean DEFAULT false NOT NULL,
+    reason_start text,
+    reason_end text,
+    shuffle boolean NOT NULL,
+    skipped boolean,
+    offline boolean NOT NULL,
+    incognito_mode boolean NOT NULL,
+    CONSTRAINT spotify_episode_history_kind_check CHECK ((kind = ANY (ARRAY['podcast'::text, 'audiobook'::text])))
+);
+
+
+ALTER TABLE public.spotify_episode_history OWNER TO queue_share;
+
 --
 -- Name: spotify_history; Type: TABLE; Schema: public; Owner: queue_share
 --
@@ -231,12 +513,30 @@ CREATE TABLE public.spotify_history (
     spotify_artist_uri text,
     spotify_album_uri text,
     from_history boolean DEFAULT false NOT NULL,
-    isrc text
+    isrc text,
+    source text DEFAULT 'spotify'::text NOT NULL,
+    CONSTRAINT spotify_history_source_check CHECK ((source = ANY (ARRAY['spotify'::text, 'lastfm'::text, 'listenbrainz'::text])))
 );
 
 
 ALTER TABLE public.spotify_history OWNER TO queue_share;
 
+--
+-- Name: spotify_login_states; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.spotify_login_states (
+    state text NOT NULL,
+    user_id uuid NOT NULL,
+    redirect_uri text NOT NULL,
+    code_verifier text NOT NULL,
+    created timestamp with time zone DEFAULT now() NOT NULL,
+    expires_at timestamp with time zone NOT NULL
+);
+
+
+ALTER TABLE public.spotify_login_states OWNER TO queue_share;
+
 --
 -- Name: spotify_permissions_versions; Type: TABLE; Schema: public; Owner: queue_share
 --
@@ -316,6 +616,18 @@ CREATE TABLE public.spotify_track_cache (
 
 ALTER TABLE public.spotify_track_cache OWNER TO queue_share;
 
+--
+-- Name: user_data_exports; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.user_data_exports (
+    user_id uuid NOT NULL,
+    exported_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.user_data_exports OWNER TO queue_share;
+
 --
 -- Name: user_friend_requests; Type: TABLE; Schema: public; Owner: queue_share
 --
@@ -355,6 +667,38 @@ CREATE TABLE public.user_passwords (
 
 ALTER TABLE public.user_passwords OWNER TO postgres;
 
+--
+-- Name: user_session_used_tokens; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.user_session_used_tokens (
+    refresh_token_hash bytea NOT NULL,
+    session_id uuid NOT NULL,
+    used_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+ALTER TABLE public.user_session_used_tokens OWNER TO queue_share;
+
+--
+-- Name: user_sessions; Type: TABLE; Schema: public; Owner: queue_share
+--
+
+CREATE TABLE public.user_sessions (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    user_id uuid NOT NULL,
+    refresh_token_hash bytea NOT NULL,
+    user_agent text,
+    ip_address text,
+    created timestamp with time zone DEFAULT now() NOT NULL,
+    last_used timestamp with time zone DEFAULT now() NOT NULL,
+    expires_at timestamp with time zone NOT NULL,
+    revoked_at timestamp with time zone
+);
+
+
+ALTER TABLE public.user_sessions OWNER TO queue_share;
+
 --
 -- Name: users; Type: TABLE; Schema: public; Owner: postgres
 --
@@ -366,7 +710,9 @@ CREATE TABLE public.users (
     spotify_account text,
     spotify_name text,
     spotify_image_url text,
-    created timestamp with time zone DEFAULT now() NOT NULL
+    created timestamp with time zone DEFAULT now() NOT NULL,
+    role text DEFAULT 'user'::text NOT NULL,
+    CONSTRAINT users_role_check CHECK ((role = ANY (ARRAY['user'::text, 'admin'::text])))
 );
 
 
@@ -379,6 +725,54 @@ ALTER TABLE public.users OWNER TO postgres;
 ALTER TABLE ONLY public.spotify_permissions_versions ALTER COLUMN id SET DEFAULT nextval('public.spotify_permissions_versions_id_seq'::regclass);
 
 
+--
+-- Name: admin_audit_log admin_audit_log_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.admin_audit_log
+    ADD CONSTRAINT admin_audit_log_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: history_import_review history_import_review_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.history_import_review
+    ADD CONSTRAINT history_import_review_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: history_import_review history_import_review_user_id_source_timestamp_key; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.history_import_review
+    ADD CONSTRAINT history_import_review_user_id_source_timestamp_key UNIQUE (user_id, source, "timestamp");
+
+
+--
+-- Name: history_merges history_merges_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.history_merges
+    ADD CONSTRAINT history_merges_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: history_upload_job_files history_upload_job_files_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.history_upload_job_files
+    ADD CONSTRAINT history_upload_job_files_pkey PRIMARY KEY (job_id, name);
+
+
+--
+-- Name: history_upload_jobs history_upload_jobs_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.history_upload_jobs
+    ADD CONSTRAINT history_upload_jobs_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: room_members no_duplicate_room_members; Type: CONSTRAINT; Schema: public; Owner: queue_share
 --
@@ -387,6 +781,22 @@ ALTER TABLE ONLY public.room_members
     ADD CONSTRAINT no_duplicate_room_members UNIQUE (user_id, room_id);
 
 
+--
+-- Name: room_blocklist no_duplicate_room_blocklist; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_blocklist
+    ADD CONSTRAINT no_duplicate_room_blocklist UNIQUE (room_id, kind, value);
+
+
+--
+-- Name: room_blocklist room_blocklist_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_blocklist
+    ADD CONSTRAINT room_blocklist_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: room_guests room_guests_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
 --
@@ -395,6 +805,14 @@ ALTER TABLE ONLY public.room_guests
     ADD CONSTRAINT room_guests_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: room_invites room_invites_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_invites
+    ADD CONSTRAINT room_invites_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: room_members room_members_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
 --
@@ -411,6 +829,22 @@ ALTER TABLE ONLY public.room_passwords
     ADD CONSTRAINT room_passwords_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: room_play_log room_play_log_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_play_log
+    ADD CONSTRAINT room_play_log_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: room_queue_policies room_queue_policies_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_queue_policies
+    ADD CONSTRAINT room_queue_policies_pkey PRIMARY KEY (room_id);
+
+
 --
 -- Name: room_queue_tracks room_queue_tracks_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
 --
@@ -419,6 +853,54 @@ ALTER TABLE ONLY public.room_queue_tracks
     ADD CONSTRAINT room_queue_tracks_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: room_queue_votes room_queue_votes_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_queue_votes
+    ADD CONSTRAINT room_queue_votes_pkey PRIMARY KEY (queue_track_id, voter_id);
+
+
+--
+-- Name: room_schedules room_schedules_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_schedules
+    ADD CONSTRAINT room_schedules_pkey PRIMARY KEY (room_id);
+
+
+--
+-- Name: room_skip_voters room_skip_voters_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_skip_voters
+    ADD CONSTRAINT room_skip_voters_pkey PRIMARY KEY (skip_id, voter_id);
+
+
+--
+-- Name: room_skip_votes room_skip_votes_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_skip_votes
+    ADD CONSTRAINT room_skip_votes_pkey PRIMARY KEY (room_id, voter_id);
+
+
+--
+-- Name: room_skips room_skips_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_skips
+    ADD CONSTRAINT room_skips_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: room_skips room_skips_room_id_track_id_played_at_key; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_skips
+    ADD CONSTRAINT room_skips_room_id_track_id_played_at_key UNIQUE (room_id, track_id, played_at);
+
+
 --
 -- Name: rooms rooms_code_key; Type: CONSTRAINT; Schema: public; Owner: postgres
 --
@@ -459,6 +941,14 @@ ALTER TABLE ONLY public.spotify_artist_cache
     ADD CONSTRAINT spotify_artist_cache_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: spotify_episode_history spotify_episode_history_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.spotify_episode_history
+    ADD CONSTRAINT spotify_episode_history_pkey PRIMARY KEY (user_id, "timestamp");
+
+
 --
 -- Name: spotify_history spotify_history_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
 --
@@ -467,6 +957,14 @@ ALTER TABLE ONLY public.spotify_history
     ADD CONSTRAINT spotify_history_pkey PRIMARY KEY (user_id, "timestamp");
 
 
+--
+-- Name: spotify_login_states spotify_login_states_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.spotify_login_states
+    ADD CONSTRAINT spotify_login_states_pkey PRIMARY KEY (state);
+
+
 --
 -- Name: spotify_permissions_versions spotify_permissions_versions_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
 --
@@ -499,6 +997,14 @@ ALTER TABLE ONLY public.spotify_track_cache
     ADD CONSTRAINT spotify_track_cache_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: user_data_exports user_data_exports_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.user_data_exports
+    ADD CONSTRAINT user_data_exports_pkey PRIMARY KEY (user_id);
+
+
 --
 -- Name: user_friend_requests user_friend_requests_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
 --
@@ -523,6 +1029,30 @@ ALTER TABLE ONLY public.user_passwords
     ADD CONSTRAINT user_passwords_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: user_session_used_tokens user_session_used_tokens_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.user_session_used_tokens
+    ADD CONSTRAINT user_session_used_tokens_pkey PRIMARY KEY (refresh_token_hash);
+
+
+--
+-- Name: user_sessions user_sessions_pkey; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.user_sessions
+    ADD CONSTRAINT user_sessions_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: user_sessions user_sessions_refresh_token_hash_key; Type: CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.user_sessions
+    ADD CONSTRAINT user_sessions_refresh_token_hash_key UNIQUE (refresh_token_hash);
+
+
 --
 -- Name: users users_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
 --
@@ -531,6 +1061,48 @@ ALTER TABLE ONLY public.users
     ADD CONSTRAINT users_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: admin_audit_log_created_idx; Type: INDEX; Schema: public; Owner: queue_share
+--
+
+CREATE INDEX admin_audit_log_created_idx ON public.admin_audit_log USING btree (created);
+
+
+--
+-- Name: history_merges_user_id_idx; Type: INDEX; Schema: public; Owner: queue_share
+--
+
+CREATE INDEX history_merges_user_id_idx ON public.history_merges USING btree (user_id);
+
+
+--
+-- Name: history_upload_jobs_user_id_idx; Type: INDEX; Schema: public; Owner: queue_share
+--
+
+CREATE INDEX history_upload_jobs_user_id_idx ON public.history_upload_jobs USING btree (user_id);
+
+
+--
+-- Name: room_play_log_room_started_idx; Type: INDEX; Schema: public; Owner: queue_share
+--
+
+CREATE INDEX room_play_log_room_started_idx ON public.room_play_log USING btree (room_id, started_at);
+
+
+--
+-- Name: spotify_episode_history_show_idx; Type: INDEX; Schema: public; Owner: queue_share
+--
+
+CREATE INDEX spotify_episode_history_show_idx ON public.spotify_episode_history USING btree (user_id, show_name);
+
+
+--
+-- Name: spotify_login_states_expires_at_idx; Type: INDEX; Schema: public; Owner: queue_share
+--
+
+CREATE INDEX spotify_login_states_expires_at_idx ON public.spotify_login_states USING btree (expires_at);
+
+
 --
 -- Name: track_cache_isrc_idx; Type: INDEX; Schema: public; Owner: queue_share
 --
@@ -545,6 +1117,20 @@ CREATE INDEX track_cache_isrc_idx ON public.spotify_track_cache USING btree (isr
 CREATE INDEX track_cache_uri_idx ON public.spotify_track_cache USING btree (uri);
 
 
+--
+-- Name: user_session_used_tokens_session_id_idx; Type: INDEX; Schema: public; Owner: queue_share
+--
+
+CREATE INDEX user_session_used_tokens_session_id_idx ON public.user_session_used_tokens USING btree (session_id);
+
+
+--
+-- Name: user_sessions_user_id_idx; Type: INDEX; Schema: public; Owner: queue_share
+--
+
+CREATE INDEX user_sessions_user_id_idx ON public.user_sessions USING btree (user_id);
+
+
 --
 -- Name: username_case_insensitive; Type: INDEX; Schema: public; Owner: postgres
 --
@@ -552,6 +1138,78 @@ CREATE INDEX track_cache_uri_idx ON public.spotify_track_cache USING btree (uri)
 CREATE UNIQUE INDEX username_case_insensitive ON public.users USING btree (upper(username));
 
 
+--
+-- Name: admin_audit_log admin_audit_log_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.admin_audit_log
+    ADD CONSTRAINT admin_audit_log_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
+--
+-- Name: history_import_review history_import_review_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.history_import_review
+    ADD CONSTRAINT history_import_review_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: history_merges history_merges_upload_job_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.history_merges
+    ADD CONSTRAINT history_merges_upload_job_id_fkey FOREIGN KEY (upload_job_id) REFERENCES public.history_upload_jobs(id) ON DELETE SET NULL;
+
+
+--
+-- Name: history_merges history_merges_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.history_merges
+    ADD CONSTRAINT history_merges_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: history_upload_job_files history_upload_job_files_job_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.history_upload_job_files
+    ADD CONSTRAINT history_upload_job_files_job_id_fkey FOREIGN KEY (job_id) REFERENCES public.history_upload_jobs(id) ON DELETE CASCADE;
+
+
+--
+-- Name: history_upload_jobs history_upload_jobs_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.history_upload_jobs
+    ADD CONSTRAINT history_upload_jobs_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: room_blocklist room_blocklist_added_by_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_blocklist
+    ADD CONSTRAINT room_blocklist_added_by_fkey FOREIGN KEY (added_by) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
+--
+-- Name: room_blocklist room_blocklist_room_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_blocklist
+    ADD CONSTRAINT room_blocklist_room_id_fkey FOREIGN KEY (room_id) REFERENCES public.rooms(id) ON DELETE CASCADE;
+
+
+--
+-- Name: room_guests room_guests_invite_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_guests
+    ADD CONSTRAINT room_guests_invite_id_fkey FOREIGN KEY (invite_id) REFERENCES public.room_invites(id) ON DELETE SET NULL;
+
+
 --
 -- Name: room_guests room_guests_room_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: postgres
 --
@@ -560,6 +1218,22 @@ ALTER TABLE ONLY public.room_guests
     ADD CONSTRAINT room_guests_room_id_fkey FOREIGN KEY (room_id) REFERENCES public.rooms(id) ON DELETE CASCADE;
 
 
+--
+-- Name: room_invites room_invites_created_by_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_invites
+    ADD CONSTRAINT room_invites_created_by_fkey FOREIGN KEY (created_by) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: room_invites room_invites_room_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_invites
+    ADD CONSTRAINT room_invites_room_id_fkey FOREIGN KEY (room_id) REFERENCES public.rooms(id) ON DELETE CASCADE;
+
+
 --
 -- Name: room_members room_members_room_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
 --
@@ -584,6 +1258,30 @@ ALTER TABLE ONLY public.room_passwords
     ADD CONSTRAINT room_passwords_room_id_fkey FOREIGN KEY (room_id) REFERENCES public.rooms(id) ON DELETE CASCADE;
 
 
+--
+-- Name: room_play_log room_play_log_queue_track_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_play_log
+    ADD CONSTRAINT room_play_log_queue_track_id_fkey FOREIGN KEY (queue_track_id) REFERENCES public.room_queue_tracks(id) ON DELETE SET NULL;
+
+
+--
+-- Name: room_play_log room_play_log_room_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_play_log
+    ADD CONSTRAINT room_play_log_room_id_fkey FOREIGN KEY (room_id) REFERENCES public.rooms(id) ON DELETE CASCADE;
+
+
+--
+-- Name: room_queue_policies room_queue_policies_room_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_queue_policies
+    ADD CONSTRAINT room_queue_policies_room_id_fkey FOREIGN KEY (room_id) REFERENCES public.rooms(id) ON DELETE CASCADE;
+
+
 --
 -- Name: room_queue_tracks room_queue_tracks_guest_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: postgres
 --
@@ -608,6 +1306,46 @@ ALTER TABLE ONLY public.room_queue_tracks
     ADD CONSTRAINT room_queue_tracks_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
 
 
+--
+-- Name: room_queue_votes room_queue_votes_queue_track_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_queue_votes
+    ADD CONSTRAINT room_queue_votes_queue_track_id_fkey FOREIGN KEY (queue_track_id) REFERENCES public.room_queue_tracks(id) ON DELETE CASCADE;
+
+
+--
+-- Name: room_schedules room_schedules_room_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_schedules
+    ADD CONSTRAINT room_schedules_room_id_fkey FOREIGN KEY (room_id) REFERENCES public.rooms(id) ON DELETE CASCADE;
+
+
+--
+-- Name: room_skip_voters room_skip_voters_skip_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_skip_voters
+    ADD CONSTRAINT room_skip_voters_skip_id_fkey FOREIGN KEY (skip_id) REFERENCES public.room_skips(id) ON DELETE CASCADE;
+
+
+--
+-- Name: room_skip_votes room_skip_votes_room_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_skip_votes
+    ADD CONSTRAINT room_skip_votes_room_id_fkey FOREIGN KEY (room_id) REFERENCES public.rooms(id) ON DELETE CASCADE;
+
+
+--
+-- Name: room_skips room_skips_room_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.room_skips
+    ADD CONSTRAINT room_skips_room_id_fkey FOREIGN KEY (room_id) REFERENCES public.rooms(id) ON DELETE CASCADE;
+
+
 --
 -- Name: rooms rooms_host_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: postgres
 --
@@ -616,6 +1354,14 @@ ALTER TABLE ONLY public.rooms
     ADD CONSTRAINT rooms_host_id_fkey FOREIGN KEY (host_id) REFERENCES public.users(id) ON DELETE CASCADE;
 
 
+--
+-- Name: spotify_episode_history spotify_episode_history_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.spotify_episode_history
+    ADD CONSTRAINT spotify_episode_history_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
 --
 -- Name: spotify_history spotify_history_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
 --
@@ -624,6 +1370,14 @@ ALTER TABLE ONLY public.spotify_history
     ADD CONSTRAINT spotify_history_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
 
 
+--
+-- Name: spotify_login_states spotify_login_states_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.spotify_login_states
+    ADD CONSTRAINT spotify_login_states_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
 --
 -- Name: spotify_tokens spotify_tokens_permissions_version_fkey; Type: FK CONSTRAINT; Schema: public; Owner: postgres
 --
@@ -640,6 +1394,14 @@ ALTER TABLE ONLY public.spotify_tokens
     ADD CONSTRAINT spotify_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
 
 
+--
+-- Name: user_data_exports user_data_exports_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.user_data_exports
+    ADD CONSTRAINT user_data_exports_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
 --
 -- Name: user_friend_requests user_friend_requests_friend_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
 --
@@ -680,6 +1442,22 @@ ALTER TABLE ONLY public.user_passwords
     ADD CONSTRAINT user_passwords_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
 
 
+--
+-- Name: user_session_used_tokens user_session_used_tokens_session_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.user_session_used_tokens
+    ADD CONSTRAINT user_session_used_tokens_session_id_fkey FOREIGN KEY (session_id) REFERENCES public.user_sessions(id) ON DELETE CASCADE;
+
+
+--
+-- Name: user_sessions user_sessions_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: queue_share
+--
+
+ALTER TABLE ONLY public.user_sessions
+    ADD CONSTRAINT user_sessions_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
 --
 -- PostgreSQL database dump complete
 --
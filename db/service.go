@@ -3,7 +3,6 @@ package db
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/andrewbenington/queue-share-api/config"
 
@@ -28,24 +27,26 @@ func Service() *DBService {
 }
 
 func (d *DBService) Initialize() error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	maxConns, maxConnIdleTime, connectTimeout := config.GetDBPool()
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
 	defer cancel()
 
 	cfg, err := pgxpool.ParseConfig(config.GetDBString())
 	if err != nil {
 		return fmt.Errorf("get db pool config: %w", err)
 	}
-	cfg.MaxConns = 30
-	cfg.MaxConnIdleTime = time.Minute * 5
+	cfg.MaxConns = maxConns
+	cfg.MaxConnIdleTime = maxConnIdleTime
 	cfg.BeforeAcquire = func(ctx context.Context, c *pgx.Conn) bool {
 		d.usedConnCount++
-		// log.Printf("Pool usage: %d/30", d.usedConnCount)
+		// log.Printf("Pool usage: %d/%d", d.usedConnCount, maxConns)
 		return true
 	}
 
 	cfg.AfterRelease = func(c *pgx.Conn) bool {
 		d.usedConnCount--
-		// log.Printf("Pool usage: %d/30", d.usedConnCount)
+		// log.Printf("Pool usage: %d/%d", d.usedConnCount, maxConns)
 		return true
 	}
 
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"context"
+	"log"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/user"
+	"github.com/google/uuid"
+)
+
+const (
+	reencrypt_batch_size = 100
+)
+
+// doReencryptCycle moves Spotify tokens encrypted with older keys onto the
+// active encryption key, one batch per transaction, so that old keys can be
+// removed once no tokens use them
+func doReencryptCycle(ctx context.Context) {
+	total := 0
+	// tokens that can't be moved are skipped over rather than fetched again
+	after := uuid.Nil
+	for {
+		tx, err := db.Service().BeginTx(ctx)
+		if err != nil {
+			log.Printf("Could not connect to database to re-encrypt tokens: %s", err)
+			return
+		}
+
+		last, found, moved, err := user.ReencryptSpotifyTokens(ctx, tx, after, reencrypt_batch_size)
+		if err != nil {
+			tx.Rollback(ctx)
+			log.Printf("Error re-encrypting Spotify tokens: %s", err)
+			return
+		}
+
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Printf("Error committing re-encrypted Spotify tokens: %s", err)
+			return
+		}
+
+		total += moved
+		if found < reencrypt_batch_size {
+			break
+		}
+		after = last
+	}
+
+	if total > 0 {
+		log.Printf("Re-encrypted %d Spotify tokens with the active encryption key", total)
+	}
+}
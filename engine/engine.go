@@ -9,27 +9,25 @@ import (
 	"github.com/andrewbenington/queue-share-api/util"
 )
 
-const (
-	cycle_period_history         = time.Minute * 30
-	cycle_period_spotify_profile = time.Hour * 24
-	cycle_period_save_logs       = time.Second * 10
-)
-
 var (
-	cycle_period               = time.Second * 10
-	cycle_period_load_uris     = time.Minute * 30
+	// whether the last URI cycle found URIs to load
+	load_uris_busy             = false
 	last_cycle_history         *time.Time
 	last_cycle_load_uris       *time.Time
 	last_cycle_spotify_profile *time.Time
 	last_cycle_save_logs       *time.Time
+	last_cycle_room_queue      *time.Time
+	last_cycle_room_schedule   *time.Time
+	last_cycle_login_states    *time.Time
+	last_cycle_reencrypt       *time.Time
 )
 
 func Run() {
 	for {
 		cycle()
-		// log.Printf("engine sleeping for %s", cycle_period.String())
+		// log.Printf("engine sleeping for %s", config.GetEngine().CyclePeriod.String())
 
-		time.Sleep(cycle_period)
+		time.Sleep(config.GetEngine().CyclePeriod)
 
 	}
 }
@@ -42,6 +40,7 @@ func cycle() {
 	fmt.Println("good morning: starting engine cycle")
 	ctx := context.Background()
 	now := time.Now()
+	periods := config.GetEngine()
 
 	// if last_cycle_load_uris == nil {
 	// 	last_cycle_load_uris = &now
@@ -52,25 +51,25 @@ func cycle() {
 	// }
 
 	if config.GetIsProd() {
-		if shouldDoCycle(last_cycle_load_uris, cycle_period_load_uris) {
+		loadURIsPeriod := periods.LoadURIsPeriod
+		if load_uris_busy {
+			loadURIsPeriod = periods.LoadURIsBusyPeriod
+		}
+		if shouldDoCycle(last_cycle_load_uris, loadURIsPeriod) {
 			fmt.Println("doing uri cycle")
 			total := loadURIsByPopularity(ctx)
 			fmt.Println("Total:", total)
-			if total == 0 {
-				cycle_period_load_uris = time.Minute * 30
-			} else {
-				cycle_period_load_uris = time.Second * 10
-			}
+			load_uris_busy = total > 0
 			last_cycle_load_uris = &now
 		}
 
-		if shouldDoCycle(last_cycle_history, cycle_period_history) {
+		if shouldDoCycle(last_cycle_history, periods.HistoryPeriod) {
 			fmt.Println("doing history cycle")
 			doHistoryCycle(ctx)
 			last_cycle_history = &now
 		}
 
-		if shouldDoCycle(last_cycle_spotify_profile, cycle_period_spotify_profile) {
+		if shouldDoCycle(last_cycle_spotify_profile, periods.SpotifyProfilePeriod) {
 			fmt.Println("doing profile cycle")
 			doSpotifyProfileCycle(ctx)
 			last_cycle_spotify_profile = &now
@@ -78,7 +77,28 @@ func cycle() {
 		}
 	}
 
-	if shouldDoCycle(last_cycle_save_logs, cycle_period_save_logs) {
+	if shouldDoCycle(last_cycle_room_queue, periods.RoomQueuePeriod) {
+		doRoomNowPlayingCycle(ctx)
+		doRoomQueueCycle(ctx)
+		last_cycle_room_queue = &now
+	}
+
+	if shouldDoCycle(last_cycle_room_schedule, periods.RoomSchedulePeriod) {
+		doRoomScheduleCycle(ctx)
+		last_cycle_room_schedule = &now
+	}
+
+	if shouldDoCycle(last_cycle_login_states, periods.LoginStatesPeriod) {
+		doSpotifyLoginStateCycle(ctx)
+		last_cycle_login_states = &now
+	}
+
+	if shouldDoCycle(last_cycle_reencrypt, periods.ReencryptPeriod) {
+		doReencryptCycle(ctx)
+		last_cycle_reencrypt = &now
+	}
+
+	if shouldDoCycle(last_cycle_save_logs, periods.SaveLogsPeriod) {
 		fmt.Println("doing log cycle")
 		util.WriteChannelLogsToFile()
 		last_cycle_save_logs = &now
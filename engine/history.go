@@ -112,7 +112,7 @@ func getHistoryForUser(ctx context.Context, user *db.User) {
 	}
 }
 
-func processHistory(ctx context.Context, spClient *spotify.Client, items []spotify.RecentlyPlayedItem, userID uuid.UUID) ([]db.HistoryInsertOneParams, error) {
+func processHistory(ctx context.Context, spClient service.MusicProvider, items []spotify.RecentlyPlayedItem, userID uuid.UUID) ([]db.HistoryInsertOneParams, error) {
 	allRows := []db.HistoryInsertOneParams{}
 
 	for i := range items {
@@ -150,6 +150,7 @@ func processHistory(ctx context.Context, spClient *spotify.Client, items []spoti
 			SpotifyArtistUri: &trackData.ArtistURI,
 			SpotifyAlbumUri:  &trackData.AlbumURI,
 			Isrc:             trackData.Isrc,
+			Source:           history.SourceSpotify,
 		}
 
 		allRows = append(allRows, row)
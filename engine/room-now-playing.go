@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/broadcast"
+	"github.com/andrewbenington/queue-share-api/client"
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/room"
+	"github.com/andrewbenington/queue-share-api/service"
+)
+
+const (
+	// rooms whose queue has been used this recently have their now playing
+	// logged by the engine
+	room_now_playing_active_window = time.Hour
+)
+
+// doRoomNowPlayingCycle logs the track playing in each room with recent queue
+// activity, so that the play log is kept whether or not anyone has the room
+// open. Rooms with subscribers on this server are skipped, because their event
+// poller already logs what is playing.
+func doRoomNowPlayingCycle(ctx context.Context) {
+	rooms, err := db.New(db.Service().Pool).RoomGetRecentlyActive(ctx, time.Now().Add(-room_now_playing_active_window))
+	if err != nil {
+		fmt.Printf("Could not get active rooms: %s\n", err)
+		return
+	}
+
+	for _, rm := range rooms {
+		if broadcast.HasSubscribers(rm.ID.String()) {
+			continue
+		}
+
+		cancelCtx, cancel := context.WithTimeout(ctx, time.Second*10)
+		err := recordRoomNowPlaying(cancelCtx, rm.ID.String(), rm.Code)
+		cancel()
+		if err != nil {
+			log.Printf("Error logging now playing for room %s: %s", rm.Code, err)
+		}
+	}
+}
+
+func recordRoomNowPlaying(ctx context.Context, roomID string, roomCode string) error {
+	_, spClient, err := client.ForRoom(ctx, roomCode)
+	if err != nil {
+		return err
+	}
+
+	currentQueue, err := service.GetUserQueue(ctx, spClient)
+	if err != nil {
+		return err
+	}
+	if currentQueue.CurrentlyPlaying.ID == "" {
+		return nil
+	}
+
+	err = service.UpdateUserPlayback(ctx, spClient, currentQueue)
+	if err != nil {
+		return err
+	}
+
+	return RecordNowPlaying(ctx, roomID, currentQueue)
+}
+
+// RecordNowPlaying logs the track playing in the room's Spotify queue. The
+// queue's start time should already be set by service.UpdateUserPlayback.
+func RecordNowPlaying(ctx context.Context, roomID string, currentQueue *service.CurrentQueue) error {
+	nowPlaying := currentQueue.CurrentlyPlaying
+	if nowPlaying.ID == "" {
+		return nil
+	}
+	startedAt := time.Now()
+	if nowPlaying.StartedPlayingEpochMilis != nil {
+		startedAt = time.UnixMilli(*nowPlaying.StartedPlayingEpochMilis)
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// the room's event pollers, queue fills and skips can all log the same play
+	err = room.LockRoom(ctx, tx, roomID)
+	if err != nil {
+		return err
+	}
+
+	err = room.RecordNowPlaying(ctx, tx, roomID, room.NowPlaying{
+		TrackID:    nowPlaying.ID,
+		StartedAt:  startedAt,
+		DurationMS: nowPlaying.DurationMS,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
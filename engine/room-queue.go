@@ -0,0 +1,211 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/broadcast"
+	"github.com/andrewbenington/queue-share-api/client"
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/room"
+	"github.com/andrewbenington/queue-share-api/service"
+	"github.com/samber/lo"
+)
+
+const (
+	// number of room tracks to keep in the host's Spotify queue ahead of playback
+	room_queue_lookahead = 2
+)
+
+var (
+	// rooms whose queue should be filled before the next room queue cycle
+	roomQueueFills     = make(chan roomQueueFill, 100)
+	roomQueueFillsOnce sync.Once
+)
+
+type roomQueueFill struct {
+	roomID   string
+	roomCode string
+}
+
+// RequestRoomQueueFill asks for the room's queue to be filled soon, without
+// waiting for Spotify. Fills run in the background whether or not the engine is
+// running. If too many fills are already waiting, the room's queue is filled
+// before returning instead.
+func RequestRoomQueueFill(ctx context.Context, roomID string, roomCode string) {
+	roomQueueFillsOnce.Do(func() {
+		go runRoomQueueFills(context.Background())
+	})
+
+	select {
+	case roomQueueFills <- roomQueueFill{roomID: roomID, roomCode: roomCode}:
+		return
+	default:
+	}
+
+	log.Printf("Room queue fills are backed up; filling queue for room %s now", roomCode)
+	_, err := FillRoomQueue(ctx, roomID, roomCode)
+	if err != nil {
+		log.Printf("Error filling queue for room %s: %s", roomCode, err)
+	}
+}
+
+func runRoomQueueFills(ctx context.Context) {
+	for fill := range roomQueueFills {
+		cancelCtx, cancel := context.WithTimeout(ctx, time.Second*10)
+		_, err := FillRoomQueue(cancelCtx, fill.roomID, fill.roomCode)
+		cancel()
+		if err != nil {
+			log.Printf("Error filling queue for room %s: %s", fill.roomCode, err)
+		}
+	}
+}
+
+func doRoomQueueCycle(ctx context.Context) {
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		fmt.Printf("Could not connect to database to get room queues: %s\n", err)
+		return
+	}
+	defer tx.Commit(ctx)
+
+	rooms, err := db.New(tx).RoomQueueGetRoomsWithPending(ctx)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	for _, rm := range rooms {
+		cancelCtx, cancel := context.WithTimeout(ctx, time.Second*10)
+		_, err := FillRoomQueue(cancelCtx, rm.ID.String(), rm.Code)
+		cancel()
+		if err != nil {
+			log.Printf("Error filling queue for room %s: %s", rm.Code, err)
+		}
+	}
+}
+
+// FillRoomQueue pushes the room's highest ranked pending tracks to the host's
+// Spotify queue until room_queue_lookahead of them are waiting to be played.
+// It returns the number of tracks pushed.
+func FillRoomQueue(ctx context.Context, roomID string, roomCode string) (int, error) {
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// fills of the same room are run one at a time across servers, so a pending
+	// track isn't pushed twice
+	err = room.LockQueueFill(ctx, tx, roomID)
+	if err != nil {
+		return 0, fmt.Errorf("lock room queue: %w", err)
+	}
+
+	_, spClient, err := client.ForRoom(ctx, roomCode)
+	if err != nil {
+		return 0, err
+	}
+
+	currentQueue, err := service.GetUserQueue(ctx, spClient)
+	if err != nil {
+		return 0, err
+	}
+
+	// the play log is kept up to date with every fill, so that pushed tracks
+	// which have played are marked before counting the ones still waiting
+	err = service.UpdateUserPlayback(ctx, spClient, currentQueue)
+	if err != nil {
+		return 0, err
+	}
+	err = RecordNowPlaying(ctx, roomID, currentQueue)
+	if err != nil {
+		return 0, fmt.Errorf("record now playing: %w", err)
+	}
+
+	pushedIDs, err := room.GetPushedTrackIDs(ctx, tx, roomID)
+	if err != nil {
+		return 0, fmt.Errorf("get pushed tracks: %w", err)
+	}
+	pushed := lo.SliceToMap(pushedIDs, func(id string) (string, bool) {
+		return id, true
+	})
+
+	waiting := lo.CountBy(currentQueue.Queue, func(track service.TrackInfo) bool {
+		return pushed[track.ID]
+	})
+	if waiting >= room_queue_lookahead {
+		return 0, nil
+	}
+
+	pending, err := room.GetPendingQueue(ctx, tx, roomID, "")
+	if err != nil {
+		return 0, fmt.Errorf("get pending tracks: %w", err)
+	}
+
+	// tracks may have been blocked since they were added, and ones missing from
+	// the cache are fetched so they can be checked
+	blocklist, err := room.GetBlocklist(ctx, tx, roomID)
+	if err != nil {
+		return 0, fmt.Errorf("get blocklist: %w", err)
+	}
+	trackIDs := lo.Uniq(lo.Map(pending, func(track room.PendingTrack, _ int) string {
+		return track.TrackID
+	}))
+	tracks, err := service.GetTracks(ctx, spClient, trackIDs)
+	if err != nil {
+		return 0, fmt.Errorf("get pending track data: %w", err)
+	}
+
+	count := 0
+	removed := 0
+	for _, track := range pending {
+		if waiting+count >= room_queue_lookahead {
+			break
+		}
+
+		// a track Spotify didn't return can't be checked, so it waits for a
+		// later fill
+		trackData, ok := tracks[track.TrackID]
+		if !ok {
+			continue
+		}
+		if blocklist.Blocks(trackData) != nil {
+			err = room.RemovePendingTrack(ctx, tx, roomID, track.ID)
+			if err != nil {
+				break
+			}
+			removed++
+			continue
+		}
+
+		err = service.PushToUserQueue(ctx, spClient, track.TrackID)
+		if err != nil {
+			break
+		}
+
+		err = room.SetQueueTrackPushed(ctx, tx, track.ID)
+		if err != nil {
+			break
+		}
+		count++
+	}
+
+	// tracks already pushed to Spotify need to be recorded even if a later push failed
+	commitErr := tx.Commit(ctx)
+	if err != nil {
+		return count, err
+	}
+	if commitErr != nil {
+		return count, fmt.Errorf("commit transaction: %w", commitErr)
+	}
+
+	if count > 0 || removed > 0 {
+		broadcast.Refresh(roomID)
+	}
+
+	return count, nil
+}
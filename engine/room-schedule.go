@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/broadcast"
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/room"
+)
+
+// doRoomScheduleCycle opens and closes scheduled rooms whose windows have started
+// or ended, and closes rooms that have been idle longer than their timeout
+func doRoomScheduleCycle(ctx context.Context) {
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		fmt.Printf("Could not connect to database to get room schedules: %s\n", err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	rooms, err := room.GetScheduledRooms(ctx, tx)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	now := time.Now()
+	changed := []string{}
+	for _, rm := range rooms {
+		next, ok := rm.State.Next(now)
+		if !ok {
+			continue
+		}
+
+		err = room.UpdateScheduleState(ctx, tx, rm.ID, next)
+		if err != nil {
+			log.Printf("Error updating schedule for room %s: %s", rm.Code, err)
+			return
+		}
+		if next.IsOpen != rm.State.IsOpen {
+			log.Printf("Room %s is now open: %t", rm.Code, next.IsOpen)
+			changed = append(changed, rm.ID)
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Printf("Error committing room schedules: %s", err)
+		return
+	}
+
+	for _, roomID := range changed {
+		broadcast.Refresh(roomID)
+	}
+}
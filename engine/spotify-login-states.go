@@ -0,0 +1,22 @@
+package engine
+
+import (
+	"context"
+	"log"
+
+	"github.com/andrewbenington/queue-share-api/auth"
+	"github.com/andrewbenington/queue-share-api/db"
+)
+
+// doSpotifyLoginStateCycle deletes Spotify logins that were abandoned before
+// Spotify redirected back
+func doSpotifyLoginStateCycle(ctx context.Context) {
+	count, err := auth.DeleteExpiredSpotifyLoginStates(ctx, db.Service().Pool)
+	if err != nil {
+		log.Printf("Error deleting expired Spotify login states: %s", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("Deleted %d expired Spotify login states", count)
+	}
+}
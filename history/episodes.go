@@ -0,0 +1,269 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/google/uuid"
+)
+
+const (
+	EpisodeKindPodcast   = "podcast"
+	EpisodeKindAudiobook = "audiobook"
+)
+
+// episodeStream is a stream of a podcast episode or audiobook chapter
+type episodeStream struct {
+	Kind        string
+	ShowName    string
+	ShowURI     *string
+	EpisodeName string
+	EpisodeURI  string
+}
+
+// episode returns what a streaming history entry is a stream of if it isn't
+// music
+func (e StreamingEntry) episode() (*episodeStream, bool) {
+	if e.SpotifyEpisodeUri != nil && *e.SpotifyEpisodeUri != "" {
+		return &episodeStream{
+			Kind:        EpisodeKindPodcast,
+			ShowName:    stringOrEmpty(e.EpisodeShowName),
+			EpisodeName: stringOrEmpty(e.EpisodeName),
+			EpisodeURI:  *e.SpotifyEpisodeUri,
+		}, true
+	}
+	if e.AudiobookChapterUri != nil && *e.AudiobookChapterUri != "" {
+		return &episodeStream{
+			Kind:        EpisodeKindAudiobook,
+			ShowName:    stringOrEmpty(e.AudiobookTitle),
+			ShowURI:     e.AudiobookUri,
+			EpisodeName: stringOrEmpty(e.AudiobookChapterTitle),
+			EpisodeURI:  *e.AudiobookChapterUri,
+		}, true
+	}
+	return nil, false
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// InsertEpisodesFromHistory inserts the podcast episodes and audiobook chapters
+// in streaming history entries, skipping the music. video is whether they come
+// from a Streaming_History_Video file.
+func InsertEpisodesFromHistory(ctx context.Context, transaction db.DBTX, userID uuid.UUID, entries []StreamingEntry, video bool) error {
+	params := db.HistoryEpisodeInsertBulkNullableParams{
+		UserIds:       []uuid.UUID{},
+		Timestamp:     []time.Time{},
+		Kind:          []string{},
+		Platform:      []string{},
+		MsPlayed:      []int32{},
+		ConnCountry:   []string{},
+		ShowName:      []string{},
+		ShowUri:       []*string{},
+		EpisodeName:   []string{},
+		EpisodeUri:    []string{},
+		Video:         []bool{},
+		ReasonStart:   []*string{},
+		ReasonEnd:     []*string{},
+		Shuffle:       []bool{},
+		Skipped:       []*bool{},
+		Offline:       []bool{},
+		IncognitoMode: []bool{},
+	}
+
+	for _, entry := range entries {
+		episode, ok := entry.episode()
+		if !ok {
+			continue
+		}
+
+		parsedTime, err := time.Parse("2006-01-02T15:04:05Z", entry.Timestamp)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		params.UserIds = append(params.UserIds, userID)
+		params.Timestamp = append(params.Timestamp, parsedTime)
+		params.Kind = append(params.Kind, episode.Kind)
+		params.Platform = append(params.Platform, entry.Platform)
+		params.MsPlayed = append(params.MsPlayed, entry.MsPlayed)
+		params.ConnCountry = append(params.ConnCountry, entry.ConnCountry)
+		params.ShowName = append(params.ShowName, episode.ShowName)
+		params.ShowUri = append(params.ShowUri, episode.ShowURI)
+		params.EpisodeName = append(params.EpisodeName, episode.EpisodeName)
+		params.EpisodeUri = append(params.EpisodeUri, episode.EpisodeURI)
+		params.Video = append(params.Video, video)
+		params.ReasonStart = append(params.ReasonStart, entry.ReasonStart)
+		params.ReasonEnd = append(params.ReasonEnd, entry.ReasonEnd)
+		params.Shuffle = append(params.Shuffle, entry.Shuffle)
+		params.Skipped = append(params.Skipped, entry.Skipped)
+		params.Offline = append(params.Offline, entry.Offline)
+		params.IncognitoMode = append(params.IncognitoMode, entry.IncognitoMode)
+	}
+	if len(params.UserIds) == 0 {
+		return nil
+	}
+	return db.New(transaction).HistoryEpisodeInsertBulkNullable(ctx, params)
+}
+
+type ShowRankings struct {
+	Shows                []*ShowStreams `json:"shows"`
+	StartDateUnixSeconds int64          `json:"start_date_unix_seconds"`
+	Timeframe            Timeframe      `json:"timeframe"`
+}
+
+// ShowStreams are the streams of a podcast or audiobook, ranked by listening
+// time
+type ShowStreams struct {
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+	Streams    int64  `json:"stream_count"`
+	ListenedMs int64  `json:"listened_ms"`
+	Episodes   int64  `json:"episode_count"`
+	// CompletionRate is the fraction of streams that played to the end
+	CompletionRate float64 `json:"completion_rate"`
+	Rank           int64   `json:"rank"`
+	RankChange     *int64  `json:"rank_change,omitempty"`
+}
+
+// ShowRankingsByTimeframe ranks the podcasts and audiobooks streamed in each
+// timeframe. kind limits them to podcasts or audiobooks if it is set.
+func ShowRankingsByTimeframe(ctx context.Context, transaction db.DBTX, userUUID uuid.UUID, filter FilterParams, kind *string) ([]*ShowRankings, int, error) {
+	filter.ensureMinimum()
+
+	var firstStart time.Time
+	if defaultFirstStart := filter.Timeframe.DefaultFirstStartTime(); defaultFirstStart != nil {
+		firstStart = *defaultFirstStart
+	} else {
+		timestampRange, err := db.New(transaction).HistoryEpisodeGetTimestampRange(ctx, userUUID)
+		if err != nil {
+			return nil, http.StatusNotFound, err
+		}
+		firstStart = time.Date(timestampRange.First.Year(), 1, 1, 0, 0, 0, 0, time.Local)
+	}
+
+	results := []*ShowRankings{}
+	lastRanks := map[string]int64{}
+
+	current := firstStart
+	for current.Before(time.Now()) {
+		nextStart := filter.Timeframe.GetNextStartTime(current)
+
+		rows, err := db.New(transaction).HistoryGetTopShowsInTimeframe(ctx, db.HistoryGetTopShowsInTimeframeParams{
+			UserID:      userUUID,
+			MinMsPlayed: filter.MinMSPlayed,
+			StartDate:   current.UTC(),
+			EndDate:     nextStart.UTC(),
+			Kind:        kind,
+			Max:         filter.Max,
+		})
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+
+		shows, ranks := rankShows(rows, lastRanks)
+		if len(shows) > 0 {
+			results = append(results, &ShowRankings{
+				Shows:                shows,
+				StartDateUnixSeconds: current.Unix(),
+				Timeframe:            filter.Timeframe,
+			})
+		}
+
+		lastRanks = ranks
+		current = nextStart
+	}
+
+	return results, 0, nil
+}
+
+// rankShows ranks rows ordered by listening time, giving ties the same rank,
+// and compares the ranks to the previous timeframe's. Completion counts the
+// streams Spotify ended with "trackdone".
+func rankShows(rows []*db.HistoryGetTopShowsInTimeframeRow, lastRanks map[string]int64) ([]*ShowStreams, map[string]int64) {
+	shows := []*ShowStreams{}
+	ranks := map[string]int64{}
+
+	var prevListenedMs int64 = 0
+	var currentRank int64 = 0
+	for _, row := range rows {
+		if row.ListenedMs != prevListenedMs {
+			currentRank++
+			prevListenedMs = row.ListenedMs
+		}
+
+		show := &ShowStreams{
+			Name:           row.ShowName,
+			Kind:           row.Kind,
+			Streams:        row.Occurrences,
+			ListenedMs:     row.ListenedMs,
+			Episodes:       row.EpisodeCount,
+			CompletionRate: completionRate(row.Completed, row.Occurrences),
+			Rank:           currentRank,
+		}
+
+		key := row.Kind + ":" + row.ShowName
+		if lastRank, ok := lastRanks[key]; ok {
+			change := lastRank - currentRank
+			show.RankChange = &change
+		}
+		ranks[key] = currentRank
+
+		shows = append(shows, show)
+	}
+
+	return shows, ranks
+}
+
+func completionRate(completed int64, streams int64) float64 {
+	if streams == 0 {
+		return 0
+	}
+	return float64(completed) / float64(streams)
+}
+
+// EpisodeStreams are the streams of one podcast episode or audiobook chapter
+type EpisodeStreams struct {
+	Name           string    `json:"name"`
+	URI            string    `json:"uri"`
+	Streams        int64     `json:"stream_count"`
+	ListenedMs     int64     `json:"listened_ms"`
+	CompletionRate float64   `json:"completion_rate"`
+	LastPlayed     time.Time `json:"last_played"`
+}
+
+// ShowEpisodeStreams returns the streams of each episode of a podcast or chapter
+// of an audiobook, most recently played first
+func ShowEpisodeStreams(ctx context.Context, transaction db.DBTX, userUUID uuid.UUID, showName string, filter FilterParams) ([]*EpisodeStreams, error) {
+	filter.ensureMinimum()
+
+	rows, err := db.New(transaction).HistoryGetShowEpisodes(ctx, db.HistoryGetShowEpisodesParams{
+		UserID:      userUUID,
+		ShowName:    showName,
+		MinMsPlayed: filter.MinMSPlayed,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	episodes := []*EpisodeStreams{}
+	for _, row := range rows {
+		episodes = append(episodes, &EpisodeStreams{
+			Name:           row.EpisodeName,
+			URI:            row.EpisodeUri,
+			Streams:        row.Occurrences,
+			ListenedMs:     row.ListenedMs,
+			CompletionRate: completionRate(row.Completed, row.Occurrences),
+			LastPlayed:     row.LastPlayed,
+		})
+	}
+
+	return episodes, nil
+}
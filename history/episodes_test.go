@@ -0,0 +1,148 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/db/dbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	episodeAudio = `[
+		{"ts": "2023-06-01T08:00:00Z", "ms_played": 200000, "master_metadata_track_name": "One", "master_metadata_album_artist_name": "Artist", "master_metadata_album_album_name": "Album", "spotify_track_uri": "spotify:track:upload0"},
+		{"ts": "2023-06-01T09:00:00Z", "ms_played": 3000000, "reason_end": "trackdone", "episode_name": "Pilot", "episode_show_name": "The Show", "spotify_episode_uri": "spotify:episode:ep1"},
+		{"ts": "2023-06-02T09:00:00Z", "ms_played": 600000, "reason_end": "endplay", "episode_name": "Second", "episode_show_name": "The Show", "spotify_episode_uri": "spotify:episode:ep2"},
+		{"ts": "2023-06-03T09:00:00Z", "ms_played": 900000, "reason_end": "trackdone", "audiobook_title": "The Book", "audiobook_uri": "spotify:audiobook:book", "audiobook_chapter_title": "Chapter 1", "audiobook_chapter_uri": "spotify:chapter:ch1"}
+	]`
+	episodeVideo = `[
+		{"ts": "2023-06-04T09:00:00Z", "ms_played": 1200000, "reason_end": "trackdone", "episode_name": "Third", "episode_show_name": "The Show", "spotify_episode_uri": "spotify:episode:ep3"}
+	]`
+)
+
+func TestStreamingEntryEpisode(t *testing.T) {
+	entries := []StreamingEntry{}
+	assert.NoError(t, json.Unmarshal([]byte(episodeAudio), &entries))
+
+	t.Run("music", func(t *testing.T) {
+		_, ok := entries[0].episode()
+		assert.False(t, ok)
+	})
+
+	t.Run("podcast", func(t *testing.T) {
+		episode, ok := entries[1].episode()
+		if assert.True(t, ok) {
+			assert.Equal(t, EpisodeKindPodcast, episode.Kind)
+			assert.Equal(t, "The Show", episode.ShowName)
+			assert.Nil(t, episode.ShowURI)
+			assert.Equal(t, "Pilot", episode.EpisodeName)
+			assert.Equal(t, "spotify:episode:ep1", episode.EpisodeURI)
+		}
+	})
+
+	t.Run("audiobook", func(t *testing.T) {
+		episode, ok := entries[3].episode()
+		if assert.True(t, ok) {
+			assert.Equal(t, EpisodeKindAudiobook, episode.Kind)
+			assert.Equal(t, "The Book", episode.ShowName)
+			assert.Equal(t, "spotify:audiobook:book", *episode.ShowURI)
+			assert.Equal(t, "Chapter 1", episode.EpisodeName)
+			assert.Equal(t, "spotify:chapter:ch1", episode.EpisodeURI)
+		}
+	})
+}
+
+func TestRankShows(t *testing.T) {
+	rows := []*db.HistoryGetTopShowsInTimeframeRow{
+		{ShowName: "A", Kind: EpisodeKindPodcast, Occurrences: 4, ListenedMs: 5000, Completed: 1},
+		{ShowName: "B", Kind: EpisodeKindPodcast, Occurrences: 2, ListenedMs: 5000, Completed: 2},
+		{ShowName: "C", Kind: EpisodeKindAudiobook, Occurrences: 1, ListenedMs: 1000},
+	}
+
+	shows, ranks := rankShows(rows, map[string]int64{"podcast:B": 1, "audiobook:C": 1})
+	if assert.Len(t, shows, 3) {
+		assert.Equal(t, int64(1), shows[0].Rank)
+		assert.Equal(t, int64(1), shows[1].Rank)
+		assert.Equal(t, int64(2), shows[2].Rank)
+
+		assert.Equal(t, 0.25, shows[0].CompletionRate)
+		assert.Equal(t, 1.0, shows[1].CompletionRate)
+		assert.Equal(t, 0.0, shows[2].CompletionRate)
+
+		assert.Nil(t, shows[0].RankChange)
+		assert.Equal(t, int64(0), *shows[1].RankChange)
+		assert.Equal(t, int64(-1), *shows[2].RankChange)
+	}
+	assert.Equal(t, map[string]int64{"podcast:A": 1, "podcast:B": 1, "audiobook:C": 2}, ranks)
+}
+
+func TestUploadEpisodes(t *testing.T) {
+	pool := dbtest.New(t)
+	dbtest.Seed(t, pool)
+	ctx := context.Background()
+
+	archivePath := filepath.Join(t.TempDir(), "history.zip")
+	data := writeZip(t, map[string]string{
+		"Spotify Extended Streaming History/Streaming_History_Audio_2023_0.json": episodeAudio,
+		"Spotify Extended Streaming History/Streaming_History_Video_2023.json":   episodeVideo,
+	})
+	assert.NoError(t, os.WriteFile(archivePath, data, 0644))
+
+	job, err := CreateUploadJob(ctx, pool, dbtest.AliceID, archivePath)
+	assert.NoError(t, err)
+	assert.NoError(t, RunUploadJob(ctx, job))
+
+	t.Run("music excludes episodes", func(t *testing.T) {
+		var count int
+		err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM spotify_history WHERE user_id = $1 AND timestamp >= '2023-06-01' AND timestamp < '2023-07-01'", dbtest.AliceID).Scan(&count)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("video", func(t *testing.T) {
+		var video bool
+		err := pool.QueryRow(ctx, "SELECT video FROM spotify_episode_history WHERE user_id = $1 AND episode_uri = 'spotify:episode:ep3'", dbtest.AliceID).Scan(&video)
+		assert.NoError(t, err)
+		assert.True(t, video)
+	})
+
+	t.Run("rankings", func(t *testing.T) {
+		filter := FilterParams{Max: 10, Timeframe: TimeframeYear}
+		rankings, _, err := ShowRankingsByTimeframe(ctx, pool, dbtest.AliceID, filter, nil)
+		assert.NoError(t, err)
+		if assert.Len(t, rankings, 1) && assert.Len(t, rankings[0].Shows, 2) {
+			show := rankings[0].Shows[0]
+			assert.Equal(t, "The Show", show.Name)
+			assert.Equal(t, EpisodeKindPodcast, show.Kind)
+			assert.Equal(t, int64(3), show.Streams)
+			assert.Equal(t, int64(4800000), show.ListenedMs)
+			assert.Equal(t, int64(3), show.Episodes)
+			assert.InDelta(t, 2.0/3.0, show.CompletionRate, 0.0001)
+
+			assert.Equal(t, "The Book", rankings[0].Shows[1].Name)
+			assert.Equal(t, int64(2), rankings[0].Shows[1].Rank)
+		}
+
+		audiobook := EpisodeKindAudiobook
+		rankings, _, err = ShowRankingsByTimeframe(ctx, pool, dbtest.AliceID, filter, &audiobook)
+		assert.NoError(t, err)
+		if assert.Len(t, rankings, 1) && assert.Len(t, rankings[0].Shows, 1) {
+			assert.Equal(t, "The Book", rankings[0].Shows[0].Name)
+		}
+	})
+
+	t.Run("episodes", func(t *testing.T) {
+		episodes, err := ShowEpisodeStreams(ctx, pool, dbtest.AliceID, "The Show", FilterParams{})
+		assert.NoError(t, err)
+		if assert.Len(t, episodes, 3) {
+			assert.Equal(t, "Third", episodes[0].Name)
+			assert.Equal(t, "Pilot", episodes[2].Name)
+			assert.Equal(t, 1.0, episodes[2].CompletionRate)
+			assert.Equal(t, 0.0, episodes[1].CompletionRate)
+		}
+	})
+}
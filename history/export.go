@@ -0,0 +1,316 @@
+package history
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/google/uuid"
+	"github.com/parquet-go/parquet-go"
+)
+
+const (
+	ExportFormatCSV     = "csv"
+	ExportFormatJSONL   = "jsonl"
+	ExportFormatParquet = "parquet"
+)
+
+// exportPageSize is how many rows are read at a time
+var exportPageSize int32 = 5000
+
+// parquetRowGroupSize is how many rows a Parquet export buffers in memory before
+// writing them out as a row group
+const parquetRowGroupSize = 10000
+
+// ExportContentType is the Content-Type of each export format
+var ExportContentType = map[string]string{
+	ExportFormatCSV:     "text/csv",
+	ExportFormatJSONL:   "application/jsonl",
+	ExportFormatParquet: "application/vnd.apache.parquet",
+}
+
+// exportColumns are the columns of CSV exports, in the order of exportValues and
+// the fields of parquetExportRow
+var exportColumns = []string{
+	"timestamp",
+	"track_name",
+	"artist_name",
+	"album_name",
+	"ms_played",
+	"platform",
+	"conn_country",
+	"spotify_track_uri",
+	"spotify_artist_uri",
+	"spotify_album_uri",
+	"isrc",
+	"reason_start",
+	"reason_end",
+	"shuffle",
+	"skipped",
+	"offline",
+	"incognito_mode",
+	"from_history",
+	"source",
+	"track_duration_ms",
+	"track_popularity",
+	"explicit",
+	"album_type",
+	"album_release_date",
+	// separated by semicolons, since a flat file has no lists
+	"artist_genres",
+	"artist_popularity",
+}
+
+// parquetExportRow is a row of a Parquet export. Nil pointers are null values,
+// and times are nanosecond timestamps.
+type parquetExportRow struct {
+	Timestamp        time.Time  `parquet:"timestamp"`
+	TrackName        string     `parquet:"track_name"`
+	ArtistName       string     `parquet:"artist_name"`
+	AlbumName        string     `parquet:"album_name"`
+	MsPlayed         int32      `parquet:"ms_played"`
+	Platform         string     `parquet:"platform"`
+	ConnCountry      string     `parquet:"conn_country"`
+	SpotifyTrackUri  string     `parquet:"spotify_track_uri"`
+	SpotifyArtistUri *string    `parquet:"spotify_artist_uri"`
+	SpotifyAlbumUri  *string    `parquet:"spotify_album_uri"`
+	Isrc             *string    `parquet:"isrc"`
+	ReasonStart      *string    `parquet:"reason_start"`
+	ReasonEnd        *string    `parquet:"reason_end"`
+	Shuffle          bool       `parquet:"shuffle"`
+	Skipped          *bool      `parquet:"skipped"`
+	Offline          bool       `parquet:"offline"`
+	IncognitoMode    bool       `parquet:"incognito_mode"`
+	FromHistory      bool       `parquet:"from_history"`
+	Source           string     `parquet:"source"`
+	TrackDurationMs  *int32     `parquet:"track_duration_ms"`
+	TrackPopularity  *int32     `parquet:"track_popularity"`
+	Explicit         *bool      `parquet:"explicit"`
+	AlbumType        *string    `parquet:"album_type"`
+	AlbumReleaseDate *time.Time `parquet:"album_release_date"`
+	ArtistGenres     *string    `parquet:"artist_genres"`
+	ArtistPopularity *int32     `parquet:"artist_popularity"`
+}
+
+func exportValues(row *db.HistoryExportPageRow) []any {
+	genres := joinGenres(row.ArtistGenres)
+
+	return []any{
+		row.Timestamp,
+		row.TrackName,
+		row.ArtistName,
+		row.AlbumName,
+		row.MsPlayed,
+		row.Platform,
+		row.ConnCountry,
+		row.SpotifyTrackUri,
+		nilOrValue(row.SpotifyArtistUri),
+		nilOrValue(row.SpotifyAlbumUri),
+		nilOrValue(row.Isrc),
+		nilOrValue(row.ReasonStart),
+		nilOrValue(row.ReasonEnd),
+		row.Shuffle,
+		nilOrValue(row.Skipped),
+		row.Offline,
+		row.IncognitoMode,
+		row.FromHistory,
+		row.Source,
+		nilOrValue(row.TrackDurationMs),
+		nilOrValue(row.TrackPopularity),
+		nilOrValue(row.Explicit),
+		nilOrValue(row.AlbumType),
+		nilOrValue(row.AlbumReleaseDate),
+		nilOrValue(genres),
+		nilOrValue(row.ArtistPopularity),
+	}
+}
+
+func joinGenres(genres []string) *string {
+	if genres == nil {
+		return nil
+	}
+	joined := strings.Join(genres, ";")
+	return &joined
+}
+
+// nilOrValue dereferences ptr, so that a missing value is an untyped nil
+func nilOrValue[T any](ptr *T) any {
+	if ptr == nil {
+		return nil
+	}
+	return *ptr
+}
+
+type exportWriter interface {
+	Write(row *db.HistoryExportPageRow) error
+	Close() error
+}
+
+// ExportHistory writes every stream in the user's history that matches filter
+// to w, oldest first, with metadata from the track, album and artist caches.
+// Rows are read a page at a time so the history is never all in memory.
+func ExportHistory(ctx context.Context, dbtx db.DBTX, userUUID uuid.UUID, filter FilterParams, format string, w io.Writer) error {
+	var writer exportWriter
+	switch format {
+	case ExportFormatCSV:
+		writer = newCSVExportWriter(w)
+	case ExportFormatJSONL:
+		writer = &jsonlExportWriter{encoder: json.NewEncoder(w)}
+	case ExportFormatParquet:
+		writer = &parquetExportWriter{writer: parquet.NewGenericWriter[parquetExportRow](w,
+			parquet.MaxRowsPerRowGroup(int64(parquetRowGroupSize)),
+			parquet.Compression(&parquet.Snappy),
+		)}
+	default:
+		return fmt.Errorf("unsupported export format %s", format)
+	}
+
+	filter.ensureStartAndEnd()
+	after := time.Time{}
+	for {
+		rows, err := db.New(dbtx).HistoryExportPage(ctx, db.HistoryExportPageParams{
+			UserID:      userUUID,
+			After:       after,
+			StartDate:   filter.Start.UTC(),
+			EndDate:     filter.End.UTC(),
+			MinMsPlayed: filter.MinMSPlayed,
+			ArtistUris:  filter.ArtistURIs,
+			AlbumURI:    filter.AlbumURI,
+			Sources:     filter.Sources,
+			PageSize:    exportPageSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			err = writer.Write(row)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(rows) < int(exportPageSize) {
+			break
+		}
+		// streams are unique by user and timestamp
+		after = rows[len(rows)-1].Timestamp
+	}
+
+	return writer.Close()
+}
+
+type csvExportWriter struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func newCSVExportWriter(w io.Writer) *csvExportWriter {
+	return &csvExportWriter{writer: csv.NewWriter(w)}
+}
+
+func (c *csvExportWriter) writeHeader() error {
+	c.headerWritten = true
+	return c.writer.Write(exportColumns)
+}
+
+func (c *csvExportWriter) Write(row *db.HistoryExportPageRow) error {
+	if !c.headerWritten {
+		err := c.writeHeader()
+		if err != nil {
+			return err
+		}
+	}
+
+	record := []string{}
+	for _, value := range exportValues(row) {
+		record = append(record, csvValue(value))
+	}
+	return c.writer.Write(record)
+}
+
+func (c *csvExportWriter) Close() error {
+	// an empty export still has a header
+	if !c.headerWritten {
+		err := c.writeHeader()
+		if err != nil {
+			return err
+		}
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func csvValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int32:
+		return strconv.Itoa(int(v))
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	}
+	return fmt.Sprint(value)
+}
+
+type jsonlExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (j *jsonlExportWriter) Write(row *db.HistoryExportPageRow) error {
+	return j.encoder.Encode(row)
+}
+
+func (j *jsonlExportWriter) Close() error {
+	return nil
+}
+
+type parquetExportWriter struct {
+	writer *parquet.GenericWriter[parquetExportRow]
+}
+
+func (p *parquetExportWriter) Write(row *db.HistoryExportPageRow) error {
+	_, err := p.writer.Write([]parquetExportRow{{
+		Timestamp:        row.Timestamp,
+		TrackName:        row.TrackName,
+		ArtistName:       row.ArtistName,
+		AlbumName:        row.AlbumName,
+		MsPlayed:         row.MsPlayed,
+		Platform:         row.Platform,
+		ConnCountry:      row.ConnCountry,
+		SpotifyTrackUri:  row.SpotifyTrackUri,
+		SpotifyArtistUri: row.SpotifyArtistUri,
+		SpotifyAlbumUri:  row.SpotifyAlbumUri,
+		Isrc:             row.Isrc,
+		ReasonStart:      row.ReasonStart,
+		ReasonEnd:        row.ReasonEnd,
+		Shuffle:          row.Shuffle,
+		Skipped:          row.Skipped,
+		Offline:          row.Offline,
+		IncognitoMode:    row.IncognitoMode,
+		FromHistory:      row.FromHistory,
+		Source:           row.Source,
+		TrackDurationMs:  row.TrackDurationMs,
+		TrackPopularity:  row.TrackPopularity,
+		Explicit:         row.Explicit,
+		AlbumType:        row.AlbumType,
+		AlbumReleaseDate: row.AlbumReleaseDate,
+		ArtistGenres:     joinGenres(row.ArtistGenres),
+		ArtistPopularity: row.ArtistPopularity,
+	}})
+	return err
+}
+
+func (p *parquetExportWriter) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,163 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/db/dbtest"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVExportWriter(t *testing.T) {
+	artistURI := "spotify:artist:qsartist0000000000000"
+	popularity := int32(42)
+	row := &db.HistoryExportPageRow{
+		Timestamp:        time.Date(2024, 1, 2, 8, 0, 0, 0, time.UTC),
+		TrackName:        "Porch Light, Again",
+		ArtistName:       "The Paper Lanterns",
+		AlbumName:        "Lanterns",
+		MsPlayed:         180000,
+		Platform:         "ios",
+		ConnCountry:      "US",
+		SpotifyTrackUri:  "spotify:track:qstrack000000000000000",
+		SpotifyArtistUri: &artistURI,
+		Shuffle:          true,
+		Source:           SourceSpotify,
+		ArtistGenres:     []string{"indie folk", "chamber pop"},
+		ArtistPopularity: &popularity,
+	}
+
+	buf := bytes.Buffer{}
+	writer := newCSVExportWriter(&buf)
+	assert.NoError(t, writer.Write(row))
+	assert.NoError(t, writer.Close())
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	assert.NoError(t, err)
+	if !assert.Len(t, records, 2) {
+		return
+	}
+	assert.Len(t, records[0], len(exportColumns))
+
+	values := map[string]string{}
+	for i, column := range records[0] {
+		values[column] = records[1][i]
+	}
+	assert.Equal(t, "2024-01-02T08:00:00Z", values["timestamp"])
+	assert.Equal(t, "Porch Light, Again", values["track_name"])
+	assert.Equal(t, "180000", values["ms_played"])
+	assert.Equal(t, artistURI, values["spotify_artist_uri"])
+	assert.Equal(t, "", values["spotify_album_uri"])
+	assert.Equal(t, "true", values["shuffle"])
+	assert.Equal(t, "", values["skipped"])
+	assert.Equal(t, "indie folk;chamber pop", values["artist_genres"])
+	assert.Equal(t, "42", values["artist_popularity"])
+
+	t.Run("parquet", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		writer := &parquetExportWriter{writer: parquet.NewGenericWriter[parquetExportRow](&buf)}
+		assert.NoError(t, writer.Write(row))
+		assert.NoError(t, writer.Close())
+
+		rows, err := parquet.Read[parquetExportRow](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		assert.NoError(t, err)
+		if !assert.Len(t, rows, 1) {
+			return
+		}
+		assert.True(t, row.Timestamp.Equal(rows[0].Timestamp))
+		assert.Equal(t, "Porch Light, Again", rows[0].TrackName)
+		assert.Equal(t, &artistURI, rows[0].SpotifyArtistUri)
+		assert.Nil(t, rows[0].SpotifyAlbumUri)
+		assert.Nil(t, rows[0].Skipped)
+		assert.Nil(t, rows[0].AlbumReleaseDate)
+		assert.Equal(t, "indie folk;chamber pop", *rows[0].ArtistGenres)
+
+		// the columns are in the same order as a CSV export's
+		columns := []string{}
+		for _, field := range parquet.SchemaOf(parquetExportRow{}).Fields() {
+			columns = append(columns, field.Name())
+		}
+		assert.Equal(t, exportColumns, columns)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		writer := newCSVExportWriter(&buf)
+		assert.NoError(t, writer.Close())
+		assert.True(t, strings.HasPrefix(buf.String(), "timestamp,track_name,"))
+	})
+}
+
+func TestExportHistory(t *testing.T) {
+	pool := dbtest.New(t)
+	dbtest.Seed(t, pool)
+	ctx := context.Background()
+
+	var expected int
+	err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM spotify_history WHERE user_id = $1", dbtest.AliceID).Scan(&expected)
+	assert.NoError(t, err)
+
+	// read several pages
+	defaultPageSize := exportPageSize
+	exportPageSize = 7
+	defer func() { exportPageSize = defaultPageSize }()
+
+	t.Run("jsonl", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		assert.NoError(t, ExportHistory(ctx, pool, dbtest.AliceID, FilterParams{}, ExportFormatJSONL, &buf))
+
+		decoder := json.NewDecoder(&buf)
+		rows := []db.HistoryExportPageRow{}
+		for decoder.More() {
+			row := db.HistoryExportPageRow{}
+			assert.NoError(t, decoder.Decode(&row))
+			rows = append(rows, row)
+		}
+		assert.Len(t, rows, expected)
+		for i := 1; i < len(rows); i++ {
+			assert.True(t, rows[i].Timestamp.After(rows[i-1].Timestamp))
+		}
+		if assert.NotEmpty(t, rows) {
+			assert.NotNil(t, rows[0].TrackDurationMs)
+		}
+	})
+
+	t.Run("parquet", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		assert.NoError(t, ExportHistory(ctx, pool, dbtest.AliceID, FilterParams{}, ExportFormatParquet, &buf))
+
+		rows, err := parquet.Read[parquetExportRow](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		assert.NoError(t, err)
+		assert.Len(t, rows, expected)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		assert.NoError(t, ExportHistory(ctx, pool, dbtest.AliceID, FilterParams{}, ExportFormatCSV, &buf))
+
+		records, err := csv.NewReader(&buf).ReadAll()
+		assert.NoError(t, err)
+		assert.Len(t, records, expected+1)
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		filter := FilterParams{Sources: []string{SourceLastFM}}
+		assert.NoError(t, ExportHistory(ctx, pool, dbtest.AliceID, filter, ExportFormatCSV, &buf))
+
+		records, err := csv.NewReader(&buf).ReadAll()
+		assert.NoError(t, err)
+		assert.Len(t, records, 1)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		assert.Error(t, ExportHistory(ctx, pool, dbtest.AliceID, FilterParams{}, "xml", &bytes.Buffer{}))
+	})
+}
@@ -77,9 +77,6 @@ func GetSpotifyHistoryForUser(ctx context.Context, user *db.UserGetByIDRow) ([]s
 		return nil, err
 	}
 
-	token, _ := spClient.Token()
-	log.Printf("%+v", token)
-
 	var rows []spotify.RecentlyPlayedItem
 	var before *time.Time
 
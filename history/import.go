@@ -0,0 +1,391 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/service"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/samber/lo"
+)
+
+const importBatchSize = 1000
+
+var ErrReviewNotFound = errors.New("review entry not found")
+
+// ImportResult counts what happened to the scrobbles of an import
+type ImportResult struct {
+	Matched int `json:"matched"`
+	// Review is how many couldn't be matched to a Spotify track and were
+	// queued for the user to review
+	Review  int `json:"review"`
+	Skipped int `json:"skipped"`
+}
+
+// ImportScrobbles matches scrobbles from source to cached Spotify tracks and
+// inserts them into the user's history. Scrobbles that can't be matched are
+// queued for review. Each batch is committed separately, and importing the
+// same export again doesn't duplicate anything.
+func ImportScrobbles(ctx context.Context, userID uuid.UUID, source string, scrobbles []Scrobble) (*ImportResult, error) {
+	importer := newScrobbleImporter(ctx, userID, source)
+	for _, scrobble := range scrobbles {
+		err := importer.Add(scrobble)
+		if err != nil {
+			return &importer.result, err
+		}
+	}
+	err := importer.Flush()
+	return &importer.result, err
+}
+
+// scrobbleImporter imports scrobbles a batch at a time as they are added, so
+// an export doesn't have to be in memory all at once
+type scrobbleImporter struct {
+	ctx    context.Context
+	userID uuid.UUID
+	source string
+	batch  []Scrobble
+	result ImportResult
+}
+
+func newScrobbleImporter(ctx context.Context, userID uuid.UUID, source string) *scrobbleImporter {
+	return &scrobbleImporter{ctx: ctx, userID: userID, source: source}
+}
+
+// Add imports the scrobble with the next batch, importing the batch if it's
+// full. Scrobbles missing a track or artist name are skipped.
+func (i *scrobbleImporter) Add(scrobble Scrobble) error {
+	if scrobble.TrackName == "" || scrobble.ArtistName == "" {
+		i.result.Skipped++
+		return nil
+	}
+
+	i.batch = append(i.batch, scrobble)
+	if len(i.batch) < importBatchSize {
+		return nil
+	}
+	return i.Flush()
+}
+
+// Flush imports the scrobbles added since the last batch
+func (i *scrobbleImporter) Flush() error {
+	if len(i.batch) == 0 {
+		return nil
+	}
+
+	matched, review, err := importScrobbleBatch(i.ctx, i.userID, i.source, i.batch)
+	if err != nil {
+		return err
+	}
+	i.result.Matched += matched
+	i.result.Review += review
+	i.batch = nil
+	return nil
+}
+
+// runImportJob imports the Last.fm or ListenBrainz export of an upload job.
+// Importing the same scrobbles again doesn't duplicate them, so a resumed job
+// reads the export from the start.
+func runImportJob(ctx context.Context, job *db.HistoryUploadJob) error {
+	done, err := db.New(db.Service().Pool).HistoryUploadJobGetDoneFiles(ctx, job.ID)
+	if err != nil {
+		return fmt.Errorf("get imported files: %w", err)
+	}
+	if lo.Contains(done, path.Base(job.ArchivePath)) {
+		return nil
+	}
+
+	file, err := os.Open(job.ArchivePath)
+	if err != nil {
+		return fmt.Errorf("open export: %w", err)
+	}
+	defer file.Close()
+
+	importer := newScrobbleImporter(ctx, job.UserID, job.Source)
+	switch job.Source {
+	case SourceLastFM:
+		err = ReadLastFMExport(file, importer.Add)
+	case SourceListenBrainz:
+		info, statErr := file.Stat()
+		if statErr != nil {
+			return fmt.Errorf("stat export: %w", statErr)
+		}
+		err = ReadListenBrainzExport(file, info.Size(), importer.Add)
+	default:
+		err = fmt.Errorf("unsupported import source %s", job.Source)
+	}
+	if err == nil {
+		err = importer.Flush()
+	}
+	if err != nil {
+		return err
+	}
+
+	result := importer.result
+	log.Printf("history upload %s: matched %d scrobbles, %d for review, %d skipped", job.ID, result.Matched, result.Review, result.Skipped)
+
+	err = db.New(db.Service().Pool).HistoryUploadJobFileDone(ctx, db.HistoryUploadJobFileDoneParams{
+		JobID:   job.ID,
+		Name:    path.Base(job.ArchivePath),
+		Entries: int32(result.Matched + result.Review),
+	})
+	if err != nil {
+		return fmt.Errorf("mark imported: %w", err)
+	}
+	return nil
+}
+
+func importScrobbleBatch(ctx context.Context, userID uuid.UUID, source string, scrobbles []Scrobble) (int, int, error) {
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	tracks, err := matchScrobbles(ctx, tx, scrobbles)
+	if err != nil {
+		return 0, 0, fmt.Errorf("match scrobbles: %w", err)
+	}
+
+	entries := []db.HistoryInsertOneParams{}
+	review := db.HistoryImportReviewInsertBulkNullableParams{
+		UserIds:    []uuid.UUID{},
+		Source:     []string{},
+		Timestamp:  []time.Time{},
+		TrackName:  []string{},
+		ArtistName: []string{},
+		AlbumName:  []string{},
+		Isrc:       []*string{},
+		DurationMs: []*int32{},
+	}
+
+	for i, scrobble := range scrobbles {
+		if track, ok := tracks[i]; ok {
+			entries = append(entries, scrobbleEntry(userID, source, scrobble.Timestamp, track))
+			continue
+		}
+		review.UserIds = append(review.UserIds, userID)
+		review.Source = append(review.Source, source)
+		review.Timestamp = append(review.Timestamp, scrobble.Timestamp.UTC())
+		review.TrackName = append(review.TrackName, scrobble.TrackName)
+		review.ArtistName = append(review.ArtistName, scrobble.ArtistName)
+		review.AlbumName = append(review.AlbumName, scrobble.AlbumName)
+		review.Isrc = append(review.Isrc, scrobble.ISRC)
+		review.DurationMs = append(review.DurationMs, scrobble.DurationMs)
+	}
+
+	if len(entries) > 0 {
+		err = InsertEntries(ctx, tx, entries)
+		if err != nil {
+			return 0, 0, fmt.Errorf("insert entries: %w", err)
+		}
+	}
+	if len(review.UserIds) > 0 {
+		err = db.New(tx).HistoryImportReviewInsertBulkNullable(ctx, review)
+		if err != nil {
+			return 0, 0, fmt.Errorf("queue for review: %w", err)
+		}
+	}
+
+	return len(entries), len(review.UserIds), tx.Commit(ctx)
+}
+
+// scrobbleEntry is the history entry for a scrobble of track. Scrobbles don't
+// say how long the track played, so it counts as played to the end.
+func scrobbleEntry(userID uuid.UUID, source string, timestamp time.Time, track db.TrackData) db.HistoryInsertOneParams {
+	return db.HistoryInsertOneParams{
+		UserID:           userID,
+		Timestamp:        timestamp.UTC(),
+		MsPlayed:         track.DurationMs,
+		TrackName:        track.Name,
+		ArtistName:       track.ArtistName,
+		AlbumName:        track.AlbumName,
+		SpotifyTrackUri:  track.URI,
+		SpotifyArtistUri: &track.ArtistURI,
+		SpotifyAlbumUri:  &track.AlbumURI,
+		Isrc:             track.Isrc,
+		Source:           source,
+	}
+}
+
+// matchScrobbles finds the cached Spotify track of each scrobble it can, by
+// Spotify ID, then ISRC, then artist and normalized track name. The result is
+// keyed by the scrobble's index.
+func matchScrobbles(ctx context.Context, tx db.DBTX, scrobbles []Scrobble) (map[int]db.TrackData, error) {
+	matches := map[int]db.TrackData{}
+
+	ids := lo.Uniq(lo.FilterMap(scrobbles, func(scrobble Scrobble, _ int) (string, bool) {
+		return lo.FromPtr(scrobble.SpotifyID), scrobble.SpotifyID != nil
+	}))
+	byID := map[string]db.TrackData{}
+	if len(ids) > 0 {
+		var err error
+		byID, err = service.GetTracksFromCache(ctx, tx, ids)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	isrcs := lo.Uniq(lo.FilterMap(scrobbles, func(scrobble Scrobble, _ int) (string, bool) {
+		return strings.ToUpper(lo.FromPtr(scrobble.ISRC)), scrobble.ISRC != nil
+	}))
+	byISRC := map[string]db.TrackData{}
+	if len(isrcs) > 0 {
+		rows, err := db.New(tx).TrackCacheGetByISRC(ctx, isrcs)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			addMostPopular(byISRC, strings.ToUpper(*row.Isrc), row)
+		}
+	}
+
+	artists := lo.Uniq(lo.Map(scrobbles, func(scrobble Scrobble, _ int) string {
+		return strings.ToLower(strings.TrimSpace(scrobble.ArtistName))
+	}))
+	byName := map[string]db.TrackData{}
+	rows, err := db.New(tx).TrackCacheGetByArtistNames(ctx, artists)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		addMostPopular(byName, trackMatchKey(row.ArtistName, row.Name), row)
+	}
+
+	for i, scrobble := range scrobbles {
+		if scrobble.SpotifyID != nil {
+			if track, ok := byID[*scrobble.SpotifyID]; ok {
+				matches[i] = track
+				continue
+			}
+		}
+		if scrobble.ISRC != nil {
+			if track, ok := byISRC[strings.ToUpper(*scrobble.ISRC)]; ok {
+				matches[i] = track
+				continue
+			}
+		}
+		if track, ok := byName[trackMatchKey(scrobble.ArtistName, scrobble.TrackName)]; ok {
+			matches[i] = track
+		}
+	}
+
+	return matches, nil
+}
+
+// addMostPopular keeps the most popular track for each key, since the same
+// recording is often on a single, an album and compilations
+func addMostPopular(tracks map[string]db.TrackData, key string, track *db.TrackData) {
+	if existing, ok := tracks[key]; ok && existing.Popularity >= track.Popularity {
+		return
+	}
+	tracks[key] = *track
+}
+
+func trackMatchKey(artistName string, trackName string) string {
+	return strings.ToLower(strings.TrimSpace(artistName)) + "\x00" + normalizeTrackName(trackName)
+}
+
+// normalizeTrackName strips what differs between how services name the same
+// track, like "Song (feat. Someone) - 2011 Remaster" and "Song"
+func normalizeTrackName(name string) string {
+	normalized := strings.ToLower(name)
+	normalized = stripBracketed(normalized)
+	for _, separator := range []string{" - ", " feat. ", " ft. ", " featuring "} {
+		if before, _, found := strings.Cut(normalized, separator); found {
+			normalized = before
+		}
+	}
+
+	normalized = alphanumeric(normalized)
+	if normalized == "" {
+		// names that are only brackets or punctuation
+		return alphanumeric(strings.ToLower(name))
+	}
+	return normalized
+}
+
+func stripBracketed(s string) string {
+	builder := strings.Builder{}
+	depth := 0
+	for _, r := range s {
+		switch r {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			if depth > 0 {
+				depth--
+			}
+		default:
+			if depth == 0 {
+				builder.WriteRune(r)
+			}
+		}
+	}
+	return builder.String()
+}
+
+func alphanumeric(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// GetImportReview returns the scrobbles waiting for the user to pick their
+// Spotify track, oldest first
+func GetImportReview(ctx context.Context, dbtx db.DBTX, userID uuid.UUID, max int32) ([]*db.HistoryImportReview, error) {
+	return db.New(dbtx).HistoryImportReviewGetAll(ctx, db.HistoryImportReviewGetAllParams{
+		UserID: userID,
+		Max:    max,
+	})
+}
+
+// ResolveImportReview inserts a scrobble waiting for review as a stream of
+// track and removes it from the review queue
+func ResolveImportReview(ctx context.Context, tx db.DBTX, userID uuid.UUID, reviewID uuid.UUID, track db.TrackData) error {
+	row, err := db.New(tx).HistoryImportReviewGet(ctx, db.HistoryImportReviewGetParams{
+		ID:     reviewID,
+		UserID: userID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrReviewNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	err = InsertEntries(ctx, tx, []db.HistoryInsertOneParams{scrobbleEntry(userID, row.Source, row.Timestamp, track)})
+	if err != nil {
+		return err
+	}
+
+	return DismissImportReview(ctx, tx, userID, reviewID)
+}
+
+// DismissImportReview removes a scrobble from the review queue without
+// importing it
+func DismissImportReview(ctx context.Context, dbtx db.DBTX, userID uuid.UUID, reviewID uuid.UUID) error {
+	deleted, err := db.New(dbtx).HistoryImportReviewDelete(ctx, db.HistoryImportReviewDeleteParams{
+		ID:     reviewID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrReviewNotFound
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package history
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/db/dbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTrackName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"Porch Light":                         "porchlight",
+		"Porch Light - 2011 Remaster":         "porchlight",
+		"Porch Light (feat. Marisol Vega)":    "porchlight",
+		"Porch Light feat. Marisol Vega":      "porchlight",
+		"Porch Light [Live at the Ferry]":     "porchlight",
+		"Don't Stop":                          "dontstop",
+		"Ça Plane Pour Moi":                   "çaplanepourmoi",
+		"(Untitled)":                          "untitled",
+		"Kite Season (Acoustic) - Radio Edit": "kiteseason",
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, expected, normalizeTrackName(name))
+		})
+	}
+}
+
+func TestImportScrobbles(t *testing.T) {
+	pool := dbtest.New(t)
+	dbtest.Seed(t, pool)
+	ctx := context.Background()
+
+	isrc := "QSTEST000001"
+	unknownISRC := "QSTEST999999"
+	scrobbles := []Scrobble{
+		// matched by name
+		{Timestamp: time.Date(2015, 3, 1, 18, 30, 0, 0, time.UTC), TrackName: "Porch Light - Remastered", ArtistName: "the paper lanterns"},
+		// matched by ISRC even though the name differs
+		{Timestamp: time.Date(2015, 3, 1, 18, 34, 0, 0, time.UTC), TrackName: "Kite Season (Demo)", ArtistName: "Paper Lanterns", ISRC: &isrc},
+		// no match
+		{Timestamp: time.Date(2015, 3, 1, 18, 38, 0, 0, time.UTC), TrackName: "Unreleased", ArtistName: "Marisol Vega", ISRC: &unknownISRC},
+		// missing the artist
+		{Timestamp: time.Date(2015, 3, 1, 18, 42, 0, 0, time.UTC), TrackName: "Calor"},
+	}
+
+	result, err := ImportScrobbles(ctx, dbtest.AliceID, SourceLastFM, scrobbles)
+	assert.NoError(t, err)
+	assert.Equal(t, &ImportResult{Matched: 2, Review: 1, Skipped: 1}, result)
+
+	t.Run("history", func(t *testing.T) {
+		rows, err := pool.Query(ctx, "SELECT spotify_track_uri, ms_played, from_history FROM spotify_history WHERE user_id = $1 AND source = 'lastfm' ORDER BY timestamp", dbtest.AliceID)
+		assert.NoError(t, err)
+		defer rows.Close()
+
+		uris := []string{}
+		for rows.Next() {
+			var uri string
+			var msPlayed int32
+			var fromHistory bool
+			assert.NoError(t, rows.Scan(&uri, &msPlayed, &fromHistory))
+			assert.False(t, fromHistory)
+			assert.Greater(t, msPlayed, int32(0))
+			uris = append(uris, uri)
+		}
+		assert.Equal(t, []string{"spotify:track:qstrack000000000000000", "spotify:track:qstrack000000000000001"}, uris)
+	})
+
+	t.Run("reimport", func(t *testing.T) {
+		result, err := ImportScrobbles(ctx, dbtest.AliceID, SourceLastFM, scrobbles)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, result.Matched)
+
+		var count int
+		err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM spotify_history WHERE user_id = $1 AND source = 'lastfm'", dbtest.AliceID).Scan(&count)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("rankings by source", func(t *testing.T) {
+		start := time.Date(2015, 3, 1, 0, 0, 0, 0, time.UTC)
+		end := time.Date(2015, 4, 1, 0, 0, 0, 0, time.UTC)
+		filter := FilterParams{Max: 10, Start: &start, End: &end}
+
+		_, _, tracks, err := CalcTrackStreamsAndRanks(ctx, dbtest.AliceID, filter, pool, nil, nil)
+		assert.NoError(t, err)
+		assert.Len(t, tracks, 2)
+
+		filter.Sources = []string{SourceSpotify}
+		_, _, tracks, err = CalcTrackStreamsAndRanks(ctx, dbtest.AliceID, filter, pool, nil, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, tracks)
+	})
+
+	t.Run("review", func(t *testing.T) {
+		review, err := GetImportReview(ctx, pool, dbtest.AliceID, 10)
+		assert.NoError(t, err)
+		if !assert.Len(t, review, 1) {
+			return
+		}
+		assert.Equal(t, "Unreleased", review[0].TrackName)
+		assert.Equal(t, SourceLastFM, review[0].Source)
+
+		bobReview, err := GetImportReview(ctx, pool, dbtest.BobID, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, bobReview)
+		assert.ErrorIs(t, DismissImportReview(ctx, pool, dbtest.BobID, review[0].ID), ErrReviewNotFound)
+
+		tracks, err := db.New(pool).TrackCacheGetByID(ctx, []string{"qstrack000000000000007"})
+		assert.NoError(t, err)
+		assert.NoError(t, ResolveImportReview(ctx, pool, dbtest.AliceID, review[0].ID, *tracks[0]))
+
+		review, err = GetImportReview(ctx, pool, dbtest.AliceID, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, review)
+
+		var uri string
+		err = pool.QueryRow(ctx, "SELECT spotify_track_uri FROM spotify_history WHERE user_id = $1 AND timestamp = '2015-03-01 18:38:00'", dbtest.AliceID).Scan(&uri)
+		assert.NoError(t, err)
+		assert.Equal(t, "spotify:track:qstrack000000000000007", uri)
+	})
+}
+
+func TestRunImportJob(t *testing.T) {
+	pool := dbtest.New(t)
+	dbtest.Seed(t, pool)
+	ctx := context.Background()
+
+	exportPath := filepath.Join(t.TempDir(), "scrobbles.csv")
+	export := "The Paper Lanterns,Lights Out Early,Porch Light,01 Mar 2015 18:30\n" +
+		"Marisol Vega,,Unreleased,01 Mar 2015 18:34\n" +
+		",,Calor,01 Mar 2015 18:38\n"
+	assert.NoError(t, os.WriteFile(exportPath, []byte(export), 0644))
+
+	job, err := CreateImportJob(ctx, pool, dbtest.AliceID, SourceLastFM, exportPath)
+	assert.NoError(t, err)
+	assert.Equal(t, SourceLastFM, job.Source)
+	assert.Equal(t, int32(1), job.FilesTotal)
+
+	err = RunUploadJob(ctx, job)
+	assert.NoError(t, err)
+
+	job, err = GetUploadJob(ctx, pool, dbtest.AliceID, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, UploadStatusComplete, job.Status)
+	assert.Equal(t, int32(1), job.FilesDone)
+	assert.Equal(t, int32(2), job.EntriesDone)
+	assert.NoFileExists(t, exportPath)
+
+	review, err := GetImportReview(ctx, pool, dbtest.AliceID, 10)
+	assert.NoError(t, err)
+	assert.Len(t, review, 1)
+}
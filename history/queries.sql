@@ -21,7 +21,8 @@ INSERT INTO SPOTIFY_HISTORY(
     offline_timestamp,
     incognito_mode,
     from_history,
-    isrc)
+    isrc,
+    source)
 VALUES (
     @user_id,
     @timestamp,
@@ -44,7 +45,8 @@ VALUES (
     @offline_timestamp,
     @incognito_mode,
     @from_history,
-    @isrc);
+    @isrc,
+    @source);
 
 -- name: HistoryInsertBulk :exec
 INSERT INTO SPOTIFY_HISTORY(
@@ -68,7 +70,8 @@ INSERT INTO SPOTIFY_HISTORY(
     offline,
     incognito_mode,
     from_history,
-    isrc)
+    isrc,
+    source)
 VALUES (
     unnest(
         @user_ids::uuid[]),
@@ -111,7 +114,9 @@ VALUES (
     unnest(
         @from_history::boolean[]),
     unnest(
-        @isrc::text[]))
+        @isrc::text[]),
+    unnest(
+        @source::text[]))
 ON CONFLICT
     DO NOTHING;
 
@@ -296,6 +301,8 @@ WITH top_isrcs AS (
             OR spotify_artist_uri = ANY (sqlc.narg(artist_uris)::text[]))
         AND (sqlc.narg(album_uri)::text IS NULL
             OR h.spotify_album_uri = sqlc.narg(album_uri)::text)
+        AND (sqlc.narg(sources)::text[] IS NULL
+            OR h.source = ANY (sqlc.narg(sources)::text[]))
     GROUP BY
         tc.isrc
     ORDER BY
@@ -341,6 +348,8 @@ WHERE
     user_id = @user_id
     AND ms_played >= @min_ms_played
     AND timestamp BETWEEN @start_date::timestamp AND @end_date::timestamp
+    AND (sqlc.narg(sources)::text[] IS NULL
+        OR source = ANY (sqlc.narg(sources)::text[]))
 GROUP BY
     spotify_artist_uri
 ORDER BY
@@ -360,6 +369,8 @@ WHERE
     AND timestamp BETWEEN @start_date::timestamp AND @end_date::timestamp
     AND (sqlc.narg(artist_uri)::text IS NULL
         OR spotify_artist_uri = sqlc.narg(artist_uri)::text)
+    AND (sqlc.narg(sources)::text[] IS NULL
+        OR source = ANY (sqlc.narg(sources)::text[]))
 GROUP BY
     spotify_album_uri
 ORDER BY
@@ -545,3 +556,365 @@ ORDER BY
     count DESC
 LIMIT 1000;
 
+
+-- name: HistoryUploadJobInsert :one
+INSERT INTO history_upload_jobs(
+    user_id,
+    source,
+    archive_path,
+    files_total)
+VALUES (
+    @user_id,
+    @source,
+    @archive_path,
+    @files_total)
+RETURNING
+    *;
+
+-- name: HistoryUploadJobGet :one
+SELECT
+    *
+FROM
+    history_upload_jobs
+WHERE
+    id = @id
+    AND user_id = @user_id;
+
+-- name: HistoryUploadJobGetDoneFiles :many
+SELECT
+    name
+FROM
+    history_upload_job_files
+WHERE
+    job_id = @job_id;
+
+-- name: HistoryUploadJobFileDone :exec
+WITH done AS (
+    INSERT INTO history_upload_job_files(
+        job_id,
+        name,
+        entries)
+    VALUES (
+        @job_id,
+        @name,
+        @entries)
+RETURNING
+    job_id,
+    entries)
+UPDATE
+    history_upload_jobs
+SET
+    files_done = files_done + 1,
+    entries_done = entries_done + done.entries,
+    updated = NOW()
+FROM
+    done
+WHERE
+    id = done.job_id;
+
+-- name: HistoryUploadJobSetStatus :exec
+UPDATE
+    history_upload_jobs
+SET
+    status = @status,
+    error = @error,
+    updated = NOW()
+WHERE
+    id = @id;
+
+-- name: HistoryUploadJobResume :one
+UPDATE
+    history_upload_jobs
+SET
+    status = 'running',
+    error = NULL,
+    updated = NOW()
+WHERE
+    id = @id
+    AND user_id = @user_id
+    AND (status = 'failed'
+        OR (status = 'running'
+            AND updated < @stale_before))
+RETURNING
+    *;
+
+-- name: HistoryEpisodeInsertBulk :exec
+INSERT INTO spotify_episode_history(
+    user_id,
+    timestamp,
+    kind,
+    platform,
+    ms_played,
+    conn_country,
+    show_name,
+    show_uri,
+    episode_name,
+    episode_uri,
+    video,
+    reason_start,
+    reason_end,
+    shuffle,
+    skipped,
+    offline,
+    incognito_mode)
+VALUES (
+    unnest(
+        @user_ids::uuid[]),
+    unnest(
+        @timestamp::timestamp[]),
+    unnest(
+        @kind::text[]),
+    unnest(
+        @platform::text[]),
+    unnest(
+        @ms_played::integer[]),
+    unnest(
+        @conn_country::text[]),
+    unnest(
+        @show_name::text[]),
+    unnest(
+        @show_uri::text[]),
+    unnest(
+        @episode_name::text[]),
+    unnest(
+        @episode_uri::text[]),
+    unnest(
+        @video::boolean[]),
+    unnest(
+        @reason_start::text[]),
+    unnest(
+        @reason_end::text[]),
+    unnest(
+        @shuffle::boolean[]),
+    unnest(
+        @skipped::boolean[]),
+    unnest(
+        @offline::boolean[]),
+    unnest(
+        @incognito_mode::boolean[]))
+ON CONFLICT
+    DO NOTHING;
+
+-- name: HistoryEpisodeGetTimestampRange :one
+SELECT
+    MIN(timestamp)::timestamp AS first,
+    MAX(timestamp)::timestamp AS last
+FROM
+    spotify_episode_history
+WHERE
+    user_id = @user_id;
+
+-- name: HistoryGetTopShowsInTimeframe :many
+SELECT
+    show_name,
+    kind,
+    COUNT(*) AS occurrences,
+    SUM(ms_played)::bigint AS listened_ms,
+    COUNT(DISTINCT episode_uri) AS episode_count,
+    COUNT(*) FILTER (WHERE reason_end = 'trackdone') AS completed
+FROM
+    spotify_episode_history
+WHERE
+    user_id = @user_id
+    AND ms_played >= @min_ms_played
+    AND timestamp BETWEEN @start_date::timestamp AND @end_date::timestamp
+    AND (sqlc.narg(kind)::text IS NULL
+        OR kind = sqlc.narg(kind)::text)
+GROUP BY
+    show_name,
+    kind
+ORDER BY
+    SUM(ms_played) DESC
+LIMIT @max;
+
+-- name: HistoryGetShowEpisodes :many
+SELECT
+    episode_name,
+    episode_uri,
+    COUNT(*) AS occurrences,
+    SUM(ms_played)::bigint AS listened_ms,
+    COUNT(*) FILTER (WHERE reason_end = 'trackdone') AS completed,
+    MAX(timestamp)::timestamp AS last_played
+FROM
+    spotify_episode_history
+WHERE
+    user_id = @user_id
+    AND show_name = @show_name
+    AND ms_played >= @min_ms_played
+GROUP BY
+    episode_name,
+    episode_uri
+ORDER BY
+    MAX(timestamp) DESC;
+
+-- name: HistoryImportReviewInsertBulk :exec
+INSERT INTO history_import_review(
+    user_id,
+    source,
+    timestamp,
+    track_name,
+    artist_name,
+    album_name,
+    isrc,
+    duration_ms)
+VALUES (
+    unnest(
+        @user_ids::uuid[]),
+    unnest(
+        @source::text[]),
+    unnest(
+        @timestamp::timestamp[]),
+    unnest(
+        @track_name::text[]),
+    unnest(
+        @artist_name::text[]),
+    unnest(
+        @album_name::text[]),
+    unnest(
+        @isrc::text[]),
+    unnest(
+        @duration_ms::integer[]))
+ON CONFLICT
+    DO NOTHING;
+
+-- name: HistoryImportReviewGetAll :many
+SELECT
+    *
+FROM
+    history_import_review
+WHERE
+    user_id = @user_id
+ORDER BY
+    timestamp
+LIMIT @max;
+
+-- name: HistoryImportReviewGet :one
+SELECT
+    *
+FROM
+    history_import_review
+WHERE
+    id = @id
+    AND user_id = @user_id;
+
+-- name: HistoryImportReviewDelete :execrows
+DELETE FROM history_import_review
+WHERE id = @id
+    AND user_id = @user_id;
+
+-- name: HistoryExportPage :many
+SELECT
+    h.timestamp,
+    h.track_name,
+    h.artist_name,
+    h.album_name,
+    h.ms_played,
+    h.platform,
+    h.conn_country,
+    h.spotify_track_uri,
+    h.spotify_artist_uri,
+    h.spotify_album_uri,
+    h.isrc,
+    h.reason_start,
+    h.reason_end,
+    h.shuffle,
+    h.skipped,
+    h.offline,
+    h.incognito_mode,
+    h.from_history,
+    h.source,
+    tc.duration_ms AS track_duration_ms,
+    tc.popularity AS track_popularity,
+    tc.explicit,
+    al.album_type,
+    al.release_date AS album_release_date,
+    ar.genres AS artist_genres,
+    ar.popularity AS artist_popularity
+FROM
+    spotify_history h
+    LEFT JOIN spotify_track_cache tc ON tc.uri = h.spotify_track_uri
+    LEFT JOIN spotify_album_cache al ON al.uri = h.spotify_album_uri
+    LEFT JOIN spotify_artist_cache ar ON ar.uri = h.spotify_artist_uri
+WHERE
+    h.user_id = @user_id
+    AND h.timestamp > @after::timestamp
+    AND h.timestamp BETWEEN @start_date::timestamp AND @end_date::timestamp
+    AND h.ms_played >= @min_ms_played
+    AND (sqlc.narg(artist_uris)::text[] IS NULL
+        OR h.spotify_artist_uri = ANY (sqlc.narg(artist_uris)::text[]))
+    AND (sqlc.narg(album_uri)::text IS NULL
+        OR h.spotify_album_uri = sqlc.narg(album_uri)::text)
+    AND (sqlc.narg(sources)::text[] IS NULL
+        OR h.source = ANY (sqlc.narg(sources)::text[]))
+ORDER BY
+    h.timestamp
+LIMIT @page_size;
+
+-- name: HistoryNearDuplicates :many
+SELECT
+    h.timestamp,
+    h.spotify_track_uri,
+    h.ms_played,
+    kept.timestamp AS kept_timestamp,
+    kept.ms_played AS kept_ms_played
+FROM
+    spotify_history h
+    JOIN LATERAL (
+        SELECT
+            k.timestamp,
+            k.ms_played
+        FROM
+            spotify_history k
+        WHERE
+            k.user_id = h.user_id
+            AND k.from_history
+            AND k.spotify_track_uri = h.spotify_track_uri
+            AND k.timestamp BETWEEN h.timestamp - make_interval(secs => @tolerance_seconds::integer)
+            AND h.timestamp + make_interval(secs => @tolerance_seconds::integer)
+        ORDER BY
+            abs(extract(epoch FROM k.timestamp - h.timestamp))
+        LIMIT 1) kept ON TRUE
+WHERE
+    h.user_id = @user_id
+    AND NOT h.from_history
+    AND h.source = 'spotify'
+ORDER BY
+    h.timestamp;
+
+-- name: HistoryDeleteStreams :execrows
+DELETE FROM spotify_history
+WHERE user_id = @user_id
+    AND NOT from_history
+    AND timestamp = ANY (@timestamps::timestamp[]);
+
+-- name: HistoryMergeInsertBulk :many
+INSERT INTO history_merges(
+    user_id,
+    upload_job_id,
+    spotify_track_uri,
+    kept_timestamp,
+    kept_ms_played,
+    removed_timestamp,
+    removed_ms_played)
+SELECT
+    @user_id::uuid,
+    sqlc.narg(upload_job_id)::uuid,
+    unnest(@spotify_track_uri::text[]),
+    unnest(@kept_timestamp::timestamp[]),
+    unnest(@kept_ms_played::integer[]),
+    unnest(@removed_timestamp::timestamp[]),
+    unnest(@removed_ms_played::integer[])
+RETURNING
+    *;
+
+-- name: HistoryMergeGetAll :many
+SELECT
+    *
+FROM
+    history_merges
+WHERE
+    user_id = @user_id
+ORDER BY
+    created DESC,
+    removed_timestamp DESC
+LIMIT @max;
@@ -0,0 +1,83 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/google/uuid"
+)
+
+// duplicateTolerance is how far apart the recently played poller and an
+// uploaded history can record the end of the same play
+const duplicateTolerance = time.Minute
+
+// ReconcileHistory removes streams recorded by the recently played poller that
+// an uploaded history also has, since the poller's timestamps are slightly off
+// and its ms_played is estimated. The uploaded streams are kept, and each
+// merge is recorded so it can be reviewed. uploadJobID is the upload that
+// triggered it, if any.
+func ReconcileHistory(ctx context.Context, dbtx db.DBTX, userID uuid.UUID, uploadJobID *uuid.UUID) ([]*db.HistoryMerge, error) {
+	duplicates, err := db.New(dbtx).HistoryNearDuplicates(ctx, db.HistoryNearDuplicatesParams{
+		ToleranceSeconds: int32(duplicateTolerance.Seconds()),
+		UserID:           userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates = pairNearDuplicates(duplicates)
+	if len(duplicates) == 0 {
+		return []*db.HistoryMerge{}, nil
+	}
+
+	params := db.HistoryMergeInsertBulkParams{
+		UserID:           userID,
+		UploadJobID:      uploadJobID,
+		SpotifyTrackUri:  []string{},
+		KeptTimestamp:    []time.Time{},
+		KeptMsPlayed:     []int32{},
+		RemovedTimestamp: []time.Time{},
+		RemovedMsPlayed:  []int32{},
+	}
+	for _, duplicate := range duplicates {
+		params.SpotifyTrackUri = append(params.SpotifyTrackUri, duplicate.SpotifyTrackUri)
+		params.KeptTimestamp = append(params.KeptTimestamp, duplicate.KeptTimestamp)
+		params.KeptMsPlayed = append(params.KeptMsPlayed, duplicate.KeptMsPlayed)
+		params.RemovedTimestamp = append(params.RemovedTimestamp, duplicate.Timestamp)
+		params.RemovedMsPlayed = append(params.RemovedMsPlayed, duplicate.MsPlayed)
+	}
+
+	_, err = db.New(dbtx).HistoryDeleteStreams(ctx, db.HistoryDeleteStreamsParams{
+		UserID:     userID,
+		Timestamps: params.RemovedTimestamp,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return db.New(dbtx).HistoryMergeInsertBulk(ctx, params)
+}
+
+// pairNearDuplicates keeps one duplicate for each uploaded stream, so a track
+// played twice in a row isn't merged into a single play. Duplicates are in
+// timestamp order.
+func pairNearDuplicates(duplicates []*db.HistoryNearDuplicatesRow) []*db.HistoryNearDuplicatesRow {
+	paired := []*db.HistoryNearDuplicatesRow{}
+	kept := map[time.Time]bool{}
+	for _, duplicate := range duplicates {
+		if kept[duplicate.KeptTimestamp] {
+			continue
+		}
+		kept[duplicate.KeptTimestamp] = true
+		paired = append(paired, duplicate)
+	}
+	return paired
+}
+
+func GetHistoryMerges(ctx context.Context, dbtx db.DBTX, userID uuid.UUID, max int32) ([]*db.HistoryMerge, error) {
+	return db.New(dbtx).HistoryMergeGetAll(ctx, db.HistoryMergeGetAllParams{
+		UserID: userID,
+		Max:    max,
+	})
+}
@@ -0,0 +1,112 @@
+package history
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/db/dbtest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPairNearDuplicates(t *testing.T) {
+	kept := time.Date(2024, 1, 3, 8, 0, 0, 0, time.UTC)
+	otherKept := time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC)
+	duplicates := []*db.HistoryNearDuplicatesRow{
+		{Timestamp: kept.Add(-10 * time.Second), KeptTimestamp: kept},
+		// a repeat of the same track, which the upload only has once
+		{Timestamp: kept.Add(20 * time.Second), KeptTimestamp: kept},
+		{Timestamp: otherKept.Add(5 * time.Second), KeptTimestamp: otherKept},
+	}
+
+	paired := pairNearDuplicates(duplicates)
+	assert.Equal(t, []*db.HistoryNearDuplicatesRow{duplicates[0], duplicates[2]}, paired)
+	assert.Empty(t, pairNearDuplicates(nil))
+}
+
+func TestReconcileHistory(t *testing.T) {
+	pool := dbtest.New(t)
+	dbtest.Seed(t, pool)
+	ctx := context.Background()
+
+	tracks := []*db.TrackData{}
+	for _, id := range []string{"qstrack000000000000000", "qstrack000000000000003"} {
+		track, err := db.New(pool).TrackCacheGetByID(ctx, []string{id})
+		assert.NoError(t, err)
+		if !assert.Len(t, track, 1) {
+			return
+		}
+		tracks = append(tracks, track[0])
+	}
+
+	polled := func(track *db.TrackData, timestamp time.Time, source string) db.HistoryInsertOneParams {
+		return db.HistoryInsertOneParams{
+			UserID:           dbtest.AliceID,
+			Timestamp:        timestamp,
+			Platform:         "ios",
+			MsPlayed:         60000,
+			ConnCountry:      "US",
+			TrackName:        track.Name,
+			ArtistName:       track.ArtistName,
+			AlbumName:        track.AlbumName,
+			SpotifyTrackUri:  track.URI,
+			SpotifyArtistUri: &track.ArtistURI,
+			SpotifyAlbumUri:  &track.AlbumURI,
+			Source:           source,
+		}
+	}
+	// alice's uploaded history has track 0 at 08:00 on January 3rd and 4th
+	err := InsertEntries(ctx, pool, []db.HistoryInsertOneParams{
+		polled(tracks[0], time.Date(2024, 1, 3, 8, 0, 20, 0, time.UTC), SourceSpotify),
+		// a different track at about the same time
+		polled(tracks[1], time.Date(2024, 1, 3, 8, 0, 40, 0, time.UTC), SourceSpotify),
+		// too far from the uploaded stream
+		polled(tracks[0], time.Date(2024, 1, 3, 8, 5, 0, 0, time.UTC), SourceSpotify),
+		// scrobbles are matched separately
+		polled(tracks[0], time.Date(2024, 1, 4, 8, 0, 10, 0, time.UTC), SourceLastFM),
+	})
+	assert.NoError(t, err)
+
+	countStreams := func(t *testing.T) int {
+		var count int
+		err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM spotify_history WHERE user_id = $1 AND NOT from_history", dbtest.AliceID).Scan(&count)
+		assert.NoError(t, err)
+		return count
+	}
+
+	merges, err := ReconcileHistory(ctx, pool, dbtest.AliceID, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, merges, 1) {
+		assert.Equal(t, tracks[0].URI, merges[0].SpotifyTrackUri)
+		assert.Equal(t, time.Date(2024, 1, 3, 8, 0, 0, 0, time.UTC), merges[0].KeptTimestamp.UTC())
+		assert.Equal(t, time.Date(2024, 1, 3, 8, 0, 20, 0, time.UTC), merges[0].RemovedTimestamp.UTC())
+		assert.Equal(t, int32(60000), merges[0].RemovedMsPlayed)
+		assert.Nil(t, merges[0].UploadJobID)
+	}
+	assert.Equal(t, 3, countStreams(t))
+
+	t.Run("reconciled again", func(t *testing.T) {
+		merges, err := ReconcileHistory(ctx, pool, dbtest.AliceID, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, merges)
+		assert.Equal(t, 3, countStreams(t))
+	})
+
+	t.Run("merges", func(t *testing.T) {
+		merges, err := GetHistoryMerges(ctx, pool, dbtest.AliceID, 10)
+		assert.NoError(t, err)
+		assert.Len(t, merges, 1)
+
+		bobMerges, err := GetHistoryMerges(ctx, pool, dbtest.BobID, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, bobMerges)
+	})
+
+	t.Run("other users", func(t *testing.T) {
+		merges, err := ReconcileHistory(ctx, pool, uuid.New(), nil)
+		assert.NoError(t, err)
+		assert.Empty(t, merges)
+	})
+}
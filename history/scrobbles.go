@@ -0,0 +1,367 @@
+package history
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	SourceSpotify      = "spotify"
+	SourceLastFM       = "lastfm"
+	SourceListenBrainz = "listenbrainz"
+)
+
+var ErrScrobbleExportFormat = errors.New("unrecognized export format")
+
+// Scrobble is a listen from another service's export, before it is matched to
+// a Spotify track
+type Scrobble struct {
+	Timestamp  time.Time
+	TrackName  string
+	ArtistName string
+	AlbumName  string
+	ISRC       *string
+	DurationMs *int32
+	// SpotifyID is set when the service already knows the Spotify track
+	SpotifyID *string
+}
+
+// ReadLastFMExport parses a Last.fm scrobble export, either as CSV or as JSON
+// pages from the user.getRecentTracks API, and calls each with every scrobble.
+// CSV is read a row at a time. JSON has to be read in full, since the pages can
+// be nested in several ways.
+func ReadLastFMExport(r io.Reader, each func(Scrobble) error) error {
+	reader := bufio.NewReader(r)
+	first, err := peekContent(reader)
+	if err == io.EOF {
+		return ErrScrobbleExportFormat
+	}
+	if err != nil {
+		return err
+	}
+
+	if first == '[' || first == '{' {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		scrobbles, err := parseLastFMJSON(bytes.TrimSpace(data))
+		if err != nil {
+			return err
+		}
+		for _, scrobble := range scrobbles {
+			err = each(scrobble)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return readLastFMCSV(reader, each)
+}
+
+// peekContent skips a byte order mark and any whitespace, and returns the first
+// byte after them without reading it
+func peekContent(reader *bufio.Reader) (byte, error) {
+	bom, _ := reader.Peek(3)
+	if bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		reader.Discard(3)
+	}
+	for {
+		next, err := reader.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !unicode.IsSpace(rune(next[0])) {
+			return next[0], nil
+		}
+		reader.Discard(1)
+	}
+}
+
+type lastFMText struct {
+	Text string `json:"#text"`
+	Name string `json:"name"`
+}
+
+func (t lastFMText) String() string {
+	if t.Text != "" {
+		return t.Text
+	}
+	return t.Name
+}
+
+type lastFMTrack struct {
+	Name   string     `json:"name"`
+	Artist lastFMText `json:"artist"`
+	Album  lastFMText `json:"album"`
+	Date   *struct {
+		UTS string `json:"uts"`
+	} `json:"date"`
+}
+
+type lastFMPage struct {
+	Track       []lastFMTrack `json:"track"`
+	RecentTrack *lastFMPage   `json:"recenttracks"`
+}
+
+func parseLastFMJSON(data []byte) ([]Scrobble, error) {
+	tracks := []lastFMTrack{}
+
+	if data[0] == '{' {
+		page := lastFMPage{}
+		err := json.Unmarshal(data, &page)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrScrobbleExportFormat, err)
+		}
+		if page.RecentTrack != nil {
+			page = *page.RecentTrack
+		}
+		tracks = page.Track
+	} else {
+		// exports are either a list of API pages or a list of tracks
+		pages := []lastFMPage{}
+		err := json.Unmarshal(data, &pages)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrScrobbleExportFormat, err)
+		}
+		for _, page := range pages {
+			if page.RecentTrack != nil {
+				page = *page.RecentTrack
+			}
+			tracks = append(tracks, page.Track...)
+		}
+		if len(tracks) == 0 {
+			err = json.Unmarshal(data, &tracks)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrScrobbleExportFormat, err)
+			}
+		}
+	}
+
+	scrobbles := []Scrobble{}
+	for _, track := range tracks {
+		// the track that is playing now has no date
+		if track.Date == nil {
+			continue
+		}
+		uts, err := strconv.ParseInt(track.Date.UTS, 10, 64)
+		if err != nil {
+			continue
+		}
+		scrobbles = append(scrobbles, Scrobble{
+			Timestamp:  time.Unix(uts, 0).UTC(),
+			TrackName:  track.Name,
+			ArtistName: track.Artist.String(),
+			AlbumName:  track.Album.String(),
+		})
+	}
+	return scrobbles, nil
+}
+
+// readLastFMCSV parses either a CSV with a header naming the columns, or one
+// without a header with artist, album, track and date columns
+func readLastFMCSV(r io.Reader, each func(Scrobble) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	first, err := reader.Read()
+	if err == io.EOF {
+		return ErrScrobbleExportFormat
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrScrobbleExportFormat, err)
+	}
+
+	columns := map[string]int{"artist": 0, "album": 1, "track": 2, "date": 3}
+	header := map[string]int{}
+	for i, name := range first {
+		header[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	_, hasArtist := header["artist"]
+	_, hasTrack := header["track"]
+	row := first
+	if hasArtist && hasTrack {
+		columns = header
+		row = nil
+	}
+
+	field := func(row []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	for {
+		if row != nil {
+			timestamp, ok := lastFMCSVTime(field(row, "uts"), field(row, "utc_time"), field(row, "date"))
+			if ok {
+				err = each(Scrobble{
+					Timestamp:  timestamp,
+					TrackName:  field(row, "track"),
+					ArtistName: field(row, "artist"),
+					AlbumName:  field(row, "album"),
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		row, err = reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrScrobbleExportFormat, err)
+		}
+	}
+}
+
+func lastFMCSVTime(uts string, utcTime string, date string) (time.Time, bool) {
+	if seconds, err := strconv.ParseInt(uts, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), true
+	}
+	for _, value := range []string{utcTime, date} {
+		for _, layout := range []string{"02 Jan 2006, 15:04", "02 Jan 2006 15:04"} {
+			if parsed, err := time.Parse(layout, value); err == nil {
+				return parsed, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+type listenBrainzListen struct {
+	ListenedAt    int64 `json:"listened_at"`
+	TrackMetadata struct {
+		ArtistName     string `json:"artist_name"`
+		TrackName      string `json:"track_name"`
+		ReleaseName    string `json:"release_name"`
+		AdditionalInfo struct {
+			ISRC       *string  `json:"isrc"`
+			DurationMs *float64 `json:"duration_ms"`
+			SpotifyID  *string  `json:"spotify_id"`
+		} `json:"additional_info"`
+	} `json:"track_metadata"`
+}
+
+// ReadListenBrainzExport parses a ListenBrainz listen export, either the zip
+// of listens/*.jsonl files or a single JSON or JSON Lines file, and calls each
+// with every scrobble. Listens are decoded one at a time.
+func ReadListenBrainzExport(r io.ReaderAt, size int64, each func(Scrobble) error) error {
+	magic := make([]byte, 2)
+	_, err := r.ReadAt(magic, 0)
+	if err != nil || !bytes.Equal(magic, []byte("PK")) {
+		return readListenBrainzJSON(io.NewSectionReader(r, 0, size), each)
+	}
+
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrScrobbleExportFormat, err)
+	}
+
+	for _, file := range archive.File {
+		ext := path.Ext(file.Name)
+		if ext != ".jsonl" && ext != ".json" {
+			continue
+		}
+		zippedFile, err := file.Open()
+		if err != nil {
+			return err
+		}
+		err = readListenBrainzJSON(zippedFile, each)
+		zippedFile.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.Name, err)
+		}
+	}
+	return nil
+}
+
+// readListenBrainzJSON decodes either a JSON array of listens or JSON Lines with
+// one listen per line
+func readListenBrainzJSON(r io.Reader, each func(Scrobble) error) error {
+	reader := bufio.NewReader(r)
+	first, err := peekContent(reader)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(reader)
+	if first == '[' {
+		_, err = decoder.Token()
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrScrobbleExportFormat, err)
+		}
+	}
+
+	for decoder.More() {
+		listen := listenBrainzListen{}
+		err = decoder.Decode(&listen)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrScrobbleExportFormat, err)
+		}
+		if listen.ListenedAt == 0 {
+			continue
+		}
+		err = each(scrobbleFromListen(listen))
+		if err != nil {
+			return err
+		}
+	}
+
+	if first == '[' {
+		_, err = decoder.Token()
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrScrobbleExportFormat, err)
+		}
+	}
+	return nil
+}
+
+func scrobbleFromListen(listen listenBrainzListen) Scrobble {
+	metadata := listen.TrackMetadata
+	var durationMs *int32
+	if metadata.AdditionalInfo.DurationMs != nil {
+		ms := int32(*metadata.AdditionalInfo.DurationMs)
+		durationMs = &ms
+	}
+	return Scrobble{
+		Timestamp:  time.Unix(listen.ListenedAt, 0).UTC(),
+		TrackName:  metadata.TrackName,
+		ArtistName: metadata.ArtistName,
+		AlbumName:  metadata.ReleaseName,
+		ISRC:       metadata.AdditionalInfo.ISRC,
+		DurationMs: durationMs,
+		SpotifyID:  spotifyIDFromLink(metadata.AdditionalInfo.SpotifyID),
+	}
+}
+
+// spotifyIDFromLink returns the track ID from an open.spotify.com link, which
+// is how ListenBrainz records tracks played with Spotify
+func spotifyIDFromLink(link *string) *string {
+	if link == nil {
+		return nil
+	}
+	id := path.Base(strings.SplitN(*link, "?", 2)[0])
+	if id == "" || id == "." || id == "/" {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,135 @@
+package history
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readLastFM collects the scrobbles of a Last.fm export
+func readLastFM(data []byte) ([]Scrobble, error) {
+	scrobbles := []Scrobble{}
+	err := ReadLastFMExport(bytes.NewReader(data), func(scrobble Scrobble) error {
+		scrobbles = append(scrobbles, scrobble)
+		return nil
+	})
+	return scrobbles, err
+}
+
+// readListenBrainz collects the scrobbles of a ListenBrainz export
+func readListenBrainz(data []byte) ([]Scrobble, error) {
+	scrobbles := []Scrobble{}
+	err := ReadListenBrainzExport(bytes.NewReader(data), int64(len(data)), func(scrobble Scrobble) error {
+		scrobbles = append(scrobbles, scrobble)
+		return nil
+	})
+	return scrobbles, err
+}
+
+func TestReadLastFMExport(t *testing.T) {
+	expected := []Scrobble{
+		{Timestamp: time.Date(2015, 3, 1, 18, 30, 0, 0, time.UTC), TrackName: "Porch Light", ArtistName: "The Paper Lanterns", AlbumName: "Lights Out Early"},
+		{Timestamp: time.Date(2015, 3, 1, 18, 34, 0, 0, time.UTC), TrackName: "Calor", ArtistName: "Marisol Vega", AlbumName: ""},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		scrobbles, err := readLastFM([]byte("The Paper Lanterns,Lights Out Early,Porch Light,01 Mar 2015 18:30\n" +
+			"Marisol Vega,,Calor,01 Mar 2015 18:34\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, scrobbles)
+	})
+
+	t.Run("csv with header", func(t *testing.T) {
+		scrobbles, err := readLastFM([]byte("\xef\xbb\xbfuts,utc_time,artist,artist_mbid,album,album_mbid,track,track_mbid\n" +
+			"1425234600,\"01 Mar 2015, 18:30\",The Paper Lanterns,,Lights Out Early,,Porch Light,\n" +
+			"1425234840,\"01 Mar 2015, 18:34\",Marisol Vega,,,,Calor,\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, scrobbles)
+	})
+
+	t.Run("json pages", func(t *testing.T) {
+		scrobbles, err := readLastFM([]byte(`[{"track": [
+			{"name": "Now Playing", "artist": {"#text": "Someone"}, "album": {"#text": ""}, "@attr": {"nowplaying": "true"}},
+			{"name": "Porch Light", "artist": {"#text": "The Paper Lanterns"}, "album": {"#text": "Lights Out Early"}, "date": {"uts": "1425234600"}}
+		]}, {"track": [
+			{"name": "Calor", "artist": {"name": "Marisol Vega"}, "album": {"#text": ""}, "date": {"uts": "1425234840"}}
+		]}]`))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, scrobbles)
+	})
+
+	t.Run("json recent tracks", func(t *testing.T) {
+		scrobbles, err := readLastFM([]byte(`{"recenttracks": {"track": [
+			{"name": "Porch Light", "artist": {"#text": "The Paper Lanterns"}, "album": {"#text": "Lights Out Early"}, "date": {"uts": "1425234600"}},
+			{"name": "Calor", "artist": {"#text": "Marisol Vega"}, "album": {"#text": ""}, "date": {"uts": "1425234840"}}
+		]}}`))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, scrobbles)
+	})
+
+	t.Run("json tracks", func(t *testing.T) {
+		scrobbles, err := readLastFM([]byte(`[
+			{"name": "Porch Light", "artist": {"#text": "The Paper Lanterns"}, "album": {"#text": "Lights Out Early"}, "date": {"uts": "1425234600"}},
+			{"name": "Calor", "artist": {"#text": "Marisol Vega"}, "album": {"#text": ""}, "date": {"uts": "1425234840"}}
+		]`))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, scrobbles)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := readLastFM([]byte(" \n"))
+		assert.ErrorIs(t, err, ErrScrobbleExportFormat)
+	})
+}
+
+func TestReadListenBrainzExport(t *testing.T) {
+	listen0 := `{"listened_at": 1425234600, "track_metadata": {"artist_name": "The Paper Lanterns", "track_name": "Porch Light", "release_name": "Lights Out Early", "additional_info": {"isrc": "QSTEST000000", "duration_ms": 180000, "spotify_id": "https://open.spotify.com/track/qstrack000000000000000"}}}`
+	listen1 := `{"listened_at": 1425234840, "track_metadata": {"artist_name": "Marisol Vega", "track_name": "Calor", "additional_info": {}}}`
+
+	isrc := "QSTEST000000"
+	var duration int32 = 180000
+	id := "qstrack000000000000000"
+	expected := []Scrobble{
+		{Timestamp: time.Date(2015, 3, 1, 18, 30, 0, 0, time.UTC), TrackName: "Porch Light", ArtistName: "The Paper Lanterns", AlbumName: "Lights Out Early", ISRC: &isrc, DurationMs: &duration, SpotifyID: &id},
+		{Timestamp: time.Date(2015, 3, 1, 18, 34, 0, 0, time.UTC), TrackName: "Calor", ArtistName: "Marisol Vega"},
+	}
+
+	t.Run("jsonl", func(t *testing.T) {
+		scrobbles, err := readListenBrainz([]byte(listen0 + "\n" + listen1 + "\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, scrobbles)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		scrobbles, err := readListenBrainz([]byte("[" + listen0 + ",\n" + listen1 + "]"))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, scrobbles)
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		writer := zip.NewWriter(&buf)
+		for _, file := range []struct{ name, contents string }{
+			{"listens/2015/3.jsonl", listen0 + "\n" + listen1 + "\n"},
+			{"listens/notes.txt", "not listens"},
+		} {
+			zipped, err := writer.Create(file.name)
+			assert.NoError(t, err)
+			_, err = zipped.Write([]byte(file.contents))
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, writer.Close())
+
+		scrobbles, err := readListenBrainz(buf.Bytes())
+		assert.NoError(t, err)
+		assert.Equal(t, expected, scrobbles)
+	})
+
+	t.Run("not json", func(t *testing.T) {
+		_, err := readListenBrainz([]byte("not json"))
+		assert.ErrorIs(t, err, ErrScrobbleExportFormat)
+	})
+}
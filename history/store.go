@@ -107,6 +107,14 @@ type StreamingEntry struct {
 	Skipped         *bool   `json:"skipped"`
 	Offline         bool    `json:"offline"`
 	IncognitoMode   bool    `json:"incognito_mode"`
+
+	EpisodeName           *string `json:"episode_name"`
+	EpisodeShowName       *string `json:"episode_show_name"`
+	SpotifyEpisodeUri     *string `json:"spotify_episode_uri"`
+	AudiobookTitle        *string `json:"audiobook_title"`
+	AudiobookUri          *string `json:"audiobook_uri"`
+	AudiobookChapterUri   *string `json:"audiobook_chapter_uri"`
+	AudiobookChapterTitle *string `json:"audiobook_chapter_title"`
 }
 
 func InsertEntry(ctx context.Context, transaction db.DBTX, entry db.HistoryInsertOneParams) error {
@@ -134,6 +142,7 @@ func InsertEntries(ctx context.Context, transaction db.DBTX, entries []db.Histor
 		IncognitoMode:   []bool{},
 		FromHistory:     []bool{},
 		ISRC:            []*string{},
+		Source:          []string{},
 	}
 
 	for _, entry := range entries {
@@ -164,6 +173,7 @@ func InsertEntries(ctx context.Context, transaction db.DBTX, entries []db.Histor
 		params.IncognitoMode = append(params.IncognitoMode, entry.IncognitoMode)
 		params.FromHistory = append(params.FromHistory, entry.FromHistory)
 		params.ISRC = append(params.ISRC, entry.Isrc)
+		params.Source = append(params.Source, entry.Source)
 	}
 	return db.New(transaction).HistoryInsertBulkNullable(ctx, params)
 }
@@ -197,6 +207,7 @@ func InsertEntriesFromHistory(ctx context.Context, transaction db.DBTX, userID u
 		IncognitoMode:   []bool{},
 		FromHistory:     []bool{},
 		ISRC:            []*string{},
+		Source:          []string{},
 	}
 
 	for _, entry := range entries {
@@ -204,13 +215,12 @@ func InsertEntriesFromHistory(ctx context.Context, transaction db.DBTX, userID u
 			continue
 		}
 
-		params.UserIds = append(params.UserIds, userID)
-
 		parsedTime, err := time.Parse("2006-01-02T15:04:05Z", entry.Timestamp)
 		if err != nil {
 			fmt.Println(err)
 			continue
 		}
+		params.UserIds = append(params.UserIds, userID)
 		params.Timestamp = append(params.Timestamp, parsedTime)
 		params.Platform = append(params.Platform, entry.Platform)
 		params.MsPlayed = append(params.MsPlayed, entry.MsPlayed)
@@ -237,6 +247,7 @@ func InsertEntriesFromHistory(ctx context.Context, transaction db.DBTX, userID u
 		params.Offline = append(params.Offline, entry.Offline)
 		params.IncognitoMode = append(params.IncognitoMode, entry.IncognitoMode)
 		params.FromHistory = append(params.FromHistory, true)
+		params.Source = append(params.Source, SourceSpotify)
 	}
 	return db.New(transaction).HistoryInsertBulkNullable(ctx, params)
 }
@@ -246,9 +257,12 @@ type FilterParams struct {
 	Max         int32
 	ArtistURIs  []string
 	AlbumURI    *string
-	Timeframe   Timeframe
-	Start       *time.Time
-	End         *time.Time
+	// Sources limits the streams to where they were imported from, or
+	// includes every source if it is empty
+	Sources   []string
+	Timeframe Timeframe
+	Start     *time.Time
+	End       *time.Time
 }
 
 func (f *FilterParams) ensureMinimum() {
@@ -343,6 +357,9 @@ func CalcTrackStreamsAndRanks(ctx context.Context, userUUID uuid.UUID, filter Fi
 	if filter.ArtistURIs != nil {
 		cacheIdentifier += "-" + strings.Join(filter.ArtistURIs, ",")
 	}
+	if filter.Sources != nil {
+		cacheIdentifier += "-" + strings.Join(filter.Sources, ",")
+	}
 
 	// trackCacheLock.Lock()
 	// cachedRows, ok := trackRankingsCache[cacheIdentifier]
@@ -359,6 +376,7 @@ func CalcTrackStreamsAndRanks(ctx context.Context, userUUID uuid.UUID, filter Fi
 		MaxTracks:   filter.Max + 20,
 		ArtistUris:  filter.ArtistURIs,
 		AlbumURI:    filter.AlbumURI,
+		Sources:     filter.Sources,
 	})
 	if err != nil {
 		return nil, nil, nil, err
@@ -435,6 +453,7 @@ func CalcAlbumStreamsAndRanks(ctx context.Context, userUUID uuid.UUID, filter Fi
 		MinMsPlayed: filter.MinMSPlayed,
 		StartDate:   start.UTC(),
 		EndDate:     end.UTC(),
+		Sources:     filter.Sources,
 		Max:         filter.Max + 20,
 	})
 	if err != nil {
@@ -514,6 +533,7 @@ func CalcArtistStreamsAndRanks(ctx context.Context, userUUID uuid.UUID, filter F
 		MinMsPlayed: filter.MinMSPlayed,
 		StartDate:   start.UTC(),
 		EndDate:     end.UTC(),
+		Sources:     filter.Sources,
 		Max:         filter.Max + 20,
 	})
 	if err != nil {
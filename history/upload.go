@@ -0,0 +1,275 @@
+package history
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/samber/lo"
+)
+
+const (
+	UploadStatusRunning  = "running"
+	UploadStatusFailed   = "failed"
+	UploadStatusComplete = "complete"
+
+	// a running upload that hasn't finished a file in this long was
+	// interrupted, like by a restart, and can be resumed
+	uploadStaleAfter = 10 * time.Minute
+)
+
+var (
+	ErrAccountDataArchive  = errors.New("this is the wrong file. Please upload your \"Extended Streaming History\", NOT your \"Account Data\"")
+	ErrNoStreamingHistory  = errors.New("no Streaming_History_Audio or Streaming_History_Video files in the archive")
+	ErrUploadNotResumable  = errors.New("upload is not resumable")
+	ErrUploadNotFound      = errors.New("upload not found")
+	errUploadArchiveFormat = errors.New("not a zip archive")
+)
+
+// streamingHistoryFiles returns the audio and video streaming history files in
+// a Spotify Extended Streaming History export, in the order they are imported
+func streamingHistoryFiles(archive *zip.Reader) ([]*zip.File, error) {
+	files := []*zip.File{}
+	for _, file := range archive.File {
+		name := path.Base(file.Name)
+		if strings.EqualFold(name, "Userdata.json") {
+			return nil, ErrAccountDataArchive
+		}
+		if strings.HasPrefix(name, "Streaming_History_Audio") || isVideoHistoryFile(file) {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return nil, ErrNoStreamingHistory
+	}
+	return files, nil
+}
+
+func isVideoHistoryFile(file *zip.File) bool {
+	return strings.HasPrefix(path.Base(file.Name), "Streaming_History_Video")
+}
+
+// CreateUploadJob checks the streaming history archive at archivePath and
+// records a job to import it. The job is run with RunUploadJob.
+func CreateUploadJob(ctx context.Context, dbtx db.DBTX, userID uuid.UUID, archivePath string) (*db.HistoryUploadJob, error) {
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errUploadArchiveFormat, err)
+	}
+	defer archive.Close()
+
+	files, err := streamingHistoryFiles(&archive.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.New(dbtx).HistoryUploadJobInsert(ctx, db.HistoryUploadJobInsertParams{
+		UserID:      userID,
+		Source:      SourceSpotify,
+		ArchivePath: archivePath,
+		FilesTotal:  int32(len(files)),
+	})
+}
+
+// CreateImportJob records a job to import the Last.fm or ListenBrainz export at
+// exportPath. The job is run with RunUploadJob, and the export is only parsed
+// then, so a malformed export fails the job.
+func CreateImportJob(ctx context.Context, dbtx db.DBTX, userID uuid.UUID, source string, exportPath string) (*db.HistoryUploadJob, error) {
+	return db.New(dbtx).HistoryUploadJobInsert(ctx, db.HistoryUploadJobInsertParams{
+		UserID:      userID,
+		Source:      source,
+		ArchivePath: exportPath,
+		FilesTotal:  1,
+	})
+}
+
+// IsUploadRejected returns whether err is the uploaded archive's fault rather
+// than the server's
+func IsUploadRejected(err error) bool {
+	return errors.Is(err, ErrAccountDataArchive) ||
+		errors.Is(err, ErrNoStreamingHistory) ||
+		errors.Is(err, errUploadArchiveFormat)
+}
+
+func GetUploadJob(ctx context.Context, dbtx db.DBTX, userID uuid.UUID, jobID uuid.UUID) (*db.HistoryUploadJob, error) {
+	job, err := db.New(dbtx).HistoryUploadJobGet(ctx, db.HistoryUploadJobGetParams{
+		ID:     jobID,
+		UserID: userID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUploadNotFound
+	}
+	return job, err
+}
+
+// ResumeUploadJob marks a failed or interrupted job as running again. The
+// files that were already imported are skipped when it is run.
+func ResumeUploadJob(ctx context.Context, dbtx db.DBTX, userID uuid.UUID, jobID uuid.UUID) (*db.HistoryUploadJob, error) {
+	_, err := GetUploadJob(ctx, dbtx, userID, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := db.New(dbtx).HistoryUploadJobResume(ctx, db.HistoryUploadJobResumeParams{
+		ID:          jobID,
+		UserID:      userID,
+		StaleBefore: time.Now().Add(-uploadStaleAfter),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUploadNotResumable
+	}
+	return job, err
+}
+
+// RunUploadJob imports every streaming history file of the job that hasn't
+// been imported yet, committing each file separately, and records whether the
+// job completed. Jobs importing a Last.fm or ListenBrainz export import its
+// scrobbles instead. The archive is deleted once everything is imported.
+func RunUploadJob(ctx context.Context, job *db.HistoryUploadJob) error {
+	var err error
+	if job.Source == SourceSpotify {
+		err = runUploadJob(ctx, job)
+	} else {
+		err = runImportJob(ctx, job)
+	}
+	if err == nil {
+		// the upload is imported either way, so this doesn't fail the job
+		reconcileErr := reconcileUpload(ctx, job)
+		if reconcileErr != nil {
+			log.Printf("history upload %s: reconcile: %s", job.ID, reconcileErr)
+		}
+	}
+
+	status := UploadStatusComplete
+	var message *string
+	if err != nil {
+		log.Printf("history upload %s: %s", job.ID, err)
+		status = UploadStatusFailed
+		message = lo.ToPtr(err.Error())
+	}
+
+	// the job's context may be what failed, so record the result regardless
+	statusErr := db.New(db.Service().Pool).HistoryUploadJobSetStatus(context.Background(), db.HistoryUploadJobSetStatusParams{
+		ID:     job.ID,
+		Status: status,
+		Error:  message,
+	})
+	if statusErr != nil {
+		log.Printf("history upload %s: set status %s: %s", job.ID, status, statusErr)
+	}
+
+	if err == nil {
+		removeErr := os.Remove(job.ArchivePath)
+		if removeErr != nil {
+			log.Printf("history upload %s: remove archive: %s", job.ID, removeErr)
+		}
+	}
+
+	return errors.Join(err, statusErr)
+}
+
+func runUploadJob(ctx context.Context, job *db.HistoryUploadJob) error {
+	done, err := db.New(db.Service().Pool).HistoryUploadJobGetDoneFiles(ctx, job.ID)
+	if err != nil {
+		return fmt.Errorf("get imported files: %w", err)
+	}
+
+	archive, err := zip.OpenReader(job.ArchivePath)
+	if err != nil {
+		return fmt.Errorf("open archive: %w", err)
+	}
+	defer archive.Close()
+
+	files, err := streamingHistoryFiles(&archive.Reader)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if lo.Contains(done, file.Name) {
+			continue
+		}
+		err = importUploadFile(ctx, job, file)
+		if err != nil {
+			return fmt.Errorf("import %s: %w", path.Base(file.Name), err)
+		}
+		log.Printf("history upload %s: imported %s", job.ID, file.Name)
+	}
+
+	return nil
+}
+
+// reconcileUpload merges the streams the recently played poller recorded that
+// the upload also has
+func reconcileUpload(ctx context.Context, job *db.HistoryUploadJob) error {
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	merges, err := ReconcileHistory(ctx, tx, job.UserID, &job.ID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("history upload %s: merged %d duplicate streams", job.ID, len(merges))
+	return nil
+}
+
+// importUploadFile inserts the entries of one file and marks it imported in the
+// same transaction, so a resumed job neither skips nor repeats part of a file
+func importUploadFile(ctx context.Context, job *db.HistoryUploadJob, file *zip.File) error {
+	zippedFile, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer zippedFile.Close()
+
+	entries := []StreamingEntry{}
+	err = json.NewDecoder(zippedFile).Decode(&entries)
+	if err != nil {
+		return fmt.Errorf("decode JSON: %w", err)
+	}
+
+	tx, err := db.Service().BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = InsertEntriesFromHistory(ctx, tx, job.UserID, entries)
+	if err != nil {
+		return err
+	}
+
+	err = InsertEpisodesFromHistory(ctx, tx, job.UserID, entries, isVideoHistoryFile(file))
+	if err != nil {
+		return fmt.Errorf("insert episodes: %w", err)
+	}
+
+	err = db.New(tx).HistoryUploadJobFileDone(ctx, db.HistoryUploadJobFileDoneParams{
+		JobID:   job.ID,
+		Name:    file.Name,
+		Entries: int32(len(entries)),
+	})
+	if err != nil {
+		return fmt.Errorf("mark imported: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
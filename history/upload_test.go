@@ -0,0 +1,181 @@
+package history
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrewbenington/queue-share-api/db/dbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	uploadAudio0 = `[
+		{"ts": "2023-05-01T10:00:00Z", "ms_played": 200000, "master_metadata_track_name": "One", "master_metadata_album_artist_name": "Artist", "master_metadata_album_album_name": "Album", "spotify_track_uri": "spotify:track:upload0"},
+		{"ts": "2023-05-01T10:04:00Z", "ms_played": 200000, "master_metadata_track_name": "Two", "master_metadata_album_artist_name": "Artist", "master_metadata_album_album_name": "Album", "spotify_track_uri": "spotify:track:upload1"}
+	]`
+	uploadAudio1 = `[
+		{"ts": "2023-05-02T10:00:00Z", "ms_played": 200000, "master_metadata_track_name": "One", "master_metadata_album_artist_name": "Artist", "master_metadata_album_album_name": "Album", "spotify_track_uri": "spotify:track:upload0"}
+	]`
+)
+
+func writeZip(t *testing.T, files map[string]string) []byte {
+	buf := bytes.Buffer{}
+	writer := zip.NewWriter(&buf)
+	// write the files in a fixed order, like Spotify's exports
+	for _, name := range []string{
+		"Spotify Extended Streaming History/ReadMeFirst.pdf",
+		"Spotify Extended Streaming History/Streaming_History_Audio_2023_0.json",
+		"Spotify Extended Streaming History/Streaming_History_Audio_2023_1.json",
+		"Spotify Extended Streaming History/Streaming_History_Video_2023.json",
+		"Spotify Account Data/Userdata.json",
+	} {
+		contents, ok := files[name]
+		if !ok {
+			continue
+		}
+		file, err := writer.Create(name)
+		assert.NoError(t, err)
+		_, err = file.Write([]byte(contents))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestStreamingHistoryFiles(t *testing.T) {
+	open := func(t *testing.T, files map[string]string) *zip.Reader {
+		data := writeZip(t, files)
+		reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		assert.NoError(t, err)
+		return reader
+	}
+
+	t.Run("history files only", func(t *testing.T) {
+		files, err := streamingHistoryFiles(open(t, map[string]string{
+			"Spotify Extended Streaming History/ReadMeFirst.pdf":                     "",
+			"Spotify Extended Streaming History/Streaming_History_Audio_2023_0.json": "[]",
+			"Spotify Extended Streaming History/Streaming_History_Audio_2023_1.json": "[]",
+			"Spotify Extended Streaming History/Streaming_History_Video_2023.json":   "[]",
+		}))
+		assert.NoError(t, err)
+		if assert.Len(t, files, 3) {
+			assert.Equal(t, "Spotify Extended Streaming History/Streaming_History_Audio_2023_0.json", files[0].Name)
+			assert.Equal(t, "Spotify Extended Streaming History/Streaming_History_Audio_2023_1.json", files[1].Name)
+			assert.Equal(t, "Spotify Extended Streaming History/Streaming_History_Video_2023.json", files[2].Name)
+			assert.False(t, isVideoHistoryFile(files[0]))
+			assert.True(t, isVideoHistoryFile(files[2]))
+		}
+	})
+
+	t.Run("account data", func(t *testing.T) {
+		_, err := streamingHistoryFiles(open(t, map[string]string{
+			"Spotify Account Data/Userdata.json": "{}",
+		}))
+		assert.ErrorIs(t, err, ErrAccountDataArchive)
+		assert.True(t, IsUploadRejected(err))
+	})
+
+	t.Run("no history files", func(t *testing.T) {
+		_, err := streamingHistoryFiles(open(t, map[string]string{
+			"Spotify Extended Streaming History/ReadMeFirst.pdf": "",
+		}))
+		assert.ErrorIs(t, err, ErrNoStreamingHistory)
+		assert.True(t, IsUploadRejected(err))
+	})
+}
+
+func TestCreateUploadJobNotZip(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "history.zip")
+	assert.NoError(t, os.WriteFile(archivePath, []byte("not a zip"), 0644))
+
+	_, err := CreateUploadJob(context.Background(), nil, dbtest.AliceID, archivePath)
+	assert.True(t, IsUploadRejected(err))
+}
+
+func TestRunUploadJob(t *testing.T) {
+	pool := dbtest.New(t)
+	dbtest.Seed(t, pool)
+	ctx := context.Background()
+
+	archivePath := filepath.Join(t.TempDir(), "history.zip")
+	writeArchive := func(audio1 string) {
+		data := writeZip(t, map[string]string{
+			"Spotify Extended Streaming History/Streaming_History_Audio_2023_0.json": uploadAudio0,
+			"Spotify Extended Streaming History/Streaming_History_Audio_2023_1.json": audio1,
+		})
+		assert.NoError(t, os.WriteFile(archivePath, data, 0644))
+	}
+	countStreams := func() int {
+		var count int
+		err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM spotify_history WHERE user_id = $1 AND spotify_track_uri LIKE 'spotify:track:upload%'", dbtest.AliceID).Scan(&count)
+		assert.NoError(t, err)
+		return count
+	}
+
+	// the second file is cut off, so only the first is imported
+	writeArchive(uploadAudio1[:20])
+	job, err := CreateUploadJob(ctx, pool, dbtest.AliceID, archivePath)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), job.FilesTotal)
+	assert.Equal(t, UploadStatusRunning, job.Status)
+
+	err = RunUploadJob(ctx, job)
+	assert.ErrorContains(t, err, "Streaming_History_Audio_2023_1.json")
+
+	t.Run("failed", func(t *testing.T) {
+		job, err := GetUploadJob(ctx, pool, dbtest.AliceID, job.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, UploadStatusFailed, job.Status)
+		assert.Equal(t, int32(1), job.FilesDone)
+		assert.Equal(t, int32(2), job.EntriesDone)
+		assert.NotNil(t, job.Error)
+		assert.Equal(t, 2, countStreams())
+		assert.FileExists(t, archivePath)
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		_, err := GetUploadJob(ctx, pool, dbtest.BobID, job.ID)
+		assert.ErrorIs(t, err, ErrUploadNotFound)
+		_, err = ResumeUploadJob(ctx, pool, dbtest.BobID, job.ID)
+		assert.ErrorIs(t, err, ErrUploadNotFound)
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		writeArchive(uploadAudio1)
+		resumed, err := ResumeUploadJob(ctx, pool, dbtest.AliceID, job.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, UploadStatusRunning, resumed.Status)
+		assert.Nil(t, resumed.Error)
+
+		// a job that is running can't be resumed twice
+		_, err = ResumeUploadJob(ctx, pool, dbtest.AliceID, job.ID)
+		assert.ErrorIs(t, err, ErrUploadNotResumable)
+
+		err = RunUploadJob(ctx, resumed)
+		assert.NoError(t, err)
+
+		job, err := GetUploadJob(ctx, pool, dbtest.AliceID, job.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, UploadStatusComplete, job.Status)
+		assert.Equal(t, int32(2), job.FilesDone)
+		assert.Equal(t, int32(3), job.EntriesDone)
+		assert.Equal(t, 3, countStreams())
+		assert.NoFileExists(t, archivePath)
+	})
+
+	t.Run("reupload", func(t *testing.T) {
+		// uploading the same export again doesn't duplicate streams
+		writeArchive(uploadAudio1)
+		job, err := CreateUploadJob(ctx, pool, dbtest.AliceID, archivePath)
+		assert.NoError(t, err)
+		assert.NoError(t, RunUploadJob(ctx, job))
+		assert.Equal(t, 3, countStreams())
+
+		_, err = ResumeUploadJob(ctx, pool, dbtest.AliceID, job.ID)
+		assert.ErrorIs(t, err, ErrUploadNotResumable)
+	})
+}
@@ -0,0 +1,15 @@
+package requests
+
+import (
+	"net"
+	"net/http"
+)
+
+// ClientIP returns the IP address the request was made from, without the port
+func ClientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
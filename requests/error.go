@@ -23,6 +23,17 @@ func RespondWithDBError(w http.ResponseWriter, err error) {
 	RespondInternalError(w)
 }
 
+// RespondWithRuleError responds with the room rule that prevented the request
+func RespondWithRuleError(w http.ResponseWriter, status int, rule string, message string) {
+	body, err := json.MarshalIndent(RuleErrorResponse{Error: message, Rule: rule}, "", " ")
+	if err != nil {
+		RespondInternalError(w)
+		return
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
 func RespondWithRoomAuthError(w http.ResponseWriter, permissionLevel int) {
 	if permissionLevel == 0 {
 		RespondWithError(w, http.StatusUnauthorized, constants.ErrorPassword)
@@ -62,3 +73,8 @@ func marshalErrorBody(e string) []byte {
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
+
+type RuleErrorResponse struct {
+	Error string `json:"error"`
+	Rule  string `json:"rule"`
+}
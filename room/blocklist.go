@@ -0,0 +1,206 @@
+package room
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+const (
+	BlockTrack  = "track"
+	BlockArtist = "artist"
+	BlockISRC   = "isrc"
+
+	RuleBlocked = "blocked"
+)
+
+type BlockedItem struct {
+	ID      string    `json:"id"`
+	Kind    string    `json:"kind"`
+	Value   string    `json:"value"`
+	Created time.Time `json:"created"`
+}
+
+type Blocklist []BlockedItem
+
+// NormalizeBlockedValue checks that the value can be blocked as the given kind and
+// returns it in the form it is stored in
+func NormalizeBlockedValue(kind string, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch kind {
+	case BlockTrack, BlockArtist:
+		prefix := fmt.Sprintf("spotify:%s:", kind)
+		if !strings.HasPrefix(value, prefix) || len(value) == len(prefix) {
+			return "", fmt.Errorf("%s must be a URI starting with %s", kind, prefix)
+		}
+		return value, nil
+	case BlockISRC:
+		if value == "" {
+			return "", fmt.Errorf("isrc cannot be empty")
+		}
+		return strings.ToUpper(value), nil
+	default:
+		return "", fmt.Errorf("unknown blocklist kind %q", kind)
+	}
+}
+
+func GetBlocklist(ctx context.Context, dbtx db.DBTX, roomID string) (Blocklist, error) {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("parse room UUID: %w", err)
+	}
+
+	rows, err := db.New(dbtx).RoomBlocklistGetAll(ctx, roomUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocklist := make(Blocklist, 0, len(rows))
+	for _, row := range rows {
+		blocklist = append(blocklist, blockedItemFromRow(row))
+	}
+
+	return blocklist, nil
+}
+
+func AddToBlocklist(ctx context.Context, dbtx db.DBTX, roomID string, kind string, value string, userID string) (*BlockedItem, error) {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("parse room UUID: %w", err)
+	}
+	var addedBy *uuid.UUID
+	if userID != "" {
+		userUUID, err := uuid.Parse(userID)
+		if err != nil {
+			return nil, fmt.Errorf("parse user UUID: %w", err)
+		}
+		addedBy = &userUUID
+	}
+
+	row, err := db.New(dbtx).RoomBlocklistInsert(ctx, db.RoomBlocklistInsertParams{
+		RoomID:  roomUUID,
+		Kind:    kind,
+		Value:   value,
+		AddedBy: addedBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	item := blockedItemFromRow(row)
+	return &item, nil
+}
+
+// RemoveFromBlocklist returns sql.ErrNoRows if the item is not in the room's blocklist
+func RemoveFromBlocklist(ctx context.Context, dbtx db.DBTX, roomID string, itemID string) error {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return fmt.Errorf("parse room UUID: %w", err)
+	}
+	itemUUID, err := uuid.Parse(itemID)
+	if err != nil {
+		return fmt.Errorf("parse blocklist item UUID: %w", err)
+	}
+
+	count, err := db.New(dbtx).RoomBlocklistDelete(ctx, db.RoomBlocklistDeleteParams{
+		ID:     itemUUID,
+		RoomID: roomUUID,
+	})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Blocks returns the blocklist entry that matches the track, checking the track's
+// URI, ISRC, primary artist and any other artists credited on it
+func (b Blocklist) Blocks(track db.TrackData) *BlockedItem {
+	artistURIs := []string{track.ArtistURI}
+	for _, artist := range track.OtherArtists {
+		if artist.URI != nil {
+			artistURIs = append(artistURIs, *artist.URI)
+		}
+	}
+
+	for i, item := range b {
+		switch item.Kind {
+		case BlockTrack:
+			if item.Value == track.URI {
+				return &b[i]
+			}
+		case BlockArtist:
+			if lo.Contains(artistURIs, item.Value) {
+				return &b[i]
+			}
+		case BlockISRC:
+			if track.Isrc != nil && strings.EqualFold(item.Value, *track.Isrc) {
+				return &b[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+// RemoveBlockedPendingTracks removes the room's pending tracks that the
+// blocklist blocks, so tracks added before they were blocked aren't pushed to
+// Spotify. It returns the number of tracks removed. Tracks missing from the
+// cache are left pending; the queue fill fetches and checks every track before
+// pushing it.
+func RemoveBlockedPendingTracks(ctx context.Context, dbtx db.DBTX, roomID string, blocklist Blocklist) (int, error) {
+	if len(blocklist) == 0 {
+		return 0, nil
+	}
+
+	pending, err := GetPendingQueue(ctx, dbtx, roomID, "")
+	if err != nil {
+		return 0, err
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	trackIDs := lo.Uniq(lo.Map(pending, func(track PendingTrack, _ int) string {
+		return track.TrackID
+	}))
+	tracks, err := db.New(dbtx).TrackCacheGetByID(ctx, trackIDs)
+	if err != nil {
+		return 0, err
+	}
+	tracksByID := lo.KeyBy(tracks, func(track *db.TrackData) string {
+		return track.ID
+	})
+
+	removed := 0
+	for _, pendingTrack := range pending {
+		track, ok := tracksByID[pendingTrack.TrackID]
+		if !ok || blocklist.Blocks(*track) == nil {
+			continue
+		}
+		err = RemovePendingTrack(ctx, dbtx, roomID, pendingTrack.ID)
+		if err != nil {
+			return removed, err
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+func blockedItemFromRow(row *db.RoomBlocklist) BlockedItem {
+	return BlockedItem{
+		ID:      row.ID.String(),
+		Kind:    row.Kind,
+		Value:   row.Value,
+		Created: row.Created,
+	}
+}
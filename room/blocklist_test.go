@@ -0,0 +1,96 @@
+package room
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/db/dbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlocklistBlocks(t *testing.T) {
+	featuredURI := "spotify:artist:featured"
+	isrc := "USABC1234567"
+	track := db.TrackData{
+		URI:          "spotify:track:track",
+		ArtistURI:    "spotify:artist:main",
+		OtherArtists: db.TrackArtists{{URI: &featuredURI, Name: "Featured"}},
+		Isrc:         &isrc,
+	}
+
+	tests := []struct {
+		name    string
+		item    BlockedItem
+		blocked bool
+	}{
+		{"track", BlockedItem{Kind: BlockTrack, Value: "spotify:track:track"}, true},
+		{"other track", BlockedItem{Kind: BlockTrack, Value: "spotify:track:other"}, false},
+		{"main artist", BlockedItem{Kind: BlockArtist, Value: "spotify:artist:main"}, true},
+		{"featured artist", BlockedItem{Kind: BlockArtist, Value: featuredURI}, true},
+		{"other artist", BlockedItem{Kind: BlockArtist, Value: "spotify:artist:other"}, false},
+		{"isrc", BlockedItem{Kind: BlockISRC, Value: "usabc1234567"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocked := Blocklist{tt.item}.Blocks(track)
+			if tt.blocked {
+				assert.Equal(t, &tt.item, blocked)
+			} else {
+				assert.Nil(t, blocked)
+			}
+		})
+	}
+}
+
+func TestNormalizeBlockedValue(t *testing.T) {
+	value, err := NormalizeBlockedValue(BlockTrack, " spotify:track:abc ")
+	assert.NoError(t, err)
+	assert.Equal(t, "spotify:track:abc", value)
+
+	_, err = NormalizeBlockedValue(BlockArtist, "spotify:track:abc")
+	assert.Error(t, err)
+
+	value, err = NormalizeBlockedValue(BlockISRC, "usabc1234567")
+	assert.NoError(t, err)
+	assert.Equal(t, "USABC1234567", value)
+
+	_, err = NormalizeBlockedValue("album", "spotify:album:abc")
+	assert.Error(t, err)
+}
+
+func TestRemoveBlockedPendingTracks(t *testing.T) {
+	pool := dbtest.New(t)
+	dbtest.Seed(t, pool)
+	ctx := context.Background()
+	roomID := dbtest.RoomID.String()
+	aliceID := dbtest.AliceID.String()
+
+	// tracks 0 and 1 are by The Paper Lanterns, track 6 by Marisol Vega
+	for _, trackID := range []string{"qstrack000000000000000", "qstrack000000000000001", "qstrack000000000000006"} {
+		assert.NoError(t, SetQueueTrackUser(ctx, pool, dbtest.RoomCode, trackID, aliceID))
+	}
+
+	removed, err := RemoveBlockedPendingTracks(ctx, pool, roomID, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, removed)
+
+	item, err := AddToBlocklist(ctx, pool, roomID, BlockArtist, "spotify:artist:qsartist00000000000000", aliceID)
+	assert.NoError(t, err)
+	removed, err = RemoveBlockedPendingTracks(ctx, pool, roomID, Blocklist{*item})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	pending, err := GetPendingQueue(ctx, pool, roomID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"qstrack000000000000006"}, pendingTrackIDs(pending))
+
+	t.Run("isrc", func(t *testing.T) {
+		item, err := AddToBlocklist(ctx, pool, roomID, BlockISRC, "QSTEST000006", aliceID)
+		assert.NoError(t, err)
+		removed, err := RemoveBlockedPendingTracks(ctx, pool, roomID, Blocklist{*item})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, removed)
+	})
+}
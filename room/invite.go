@@ -0,0 +1,267 @@
+package room
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/auth"
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	InviteRoleGuest     = "guest"
+	InviteRoleMember    = "member"
+	InviteRoleModerator = "moderator"
+)
+
+type Invite struct {
+	ID        string     `json:"id"`
+	Role      string     `json:"role"`
+	Token     string     `json:"token"`
+	Created   time.Time  `json:"created"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxUses   *int       `json:"max_uses"`
+	Uses      int        `json:"uses"`
+}
+
+// InviteClaims are the contents of a signed invite token
+type InviteClaims struct {
+	InviteID string
+	RoomID   string
+	Role     string
+}
+
+func IsInviteRole(role string) bool {
+	return role == InviteRoleGuest || role == InviteRoleMember || role == InviteRoleModerator
+}
+
+// signInviteToken signs a token for the invite. The claims only depend on the
+// invite, so the same token is returned until the active signing key changes.
+func signInviteToken(invite *db.RoomInvite) (string, error) {
+	claims := jwt.MapClaims{
+		"inv":  invite.ID.String(),
+		"room": invite.RoomID.String(),
+		"role": invite.Role,
+		"iat":  invite.Created.Unix(),
+	}
+	if invite.ExpiresAt != nil {
+		claims["exp"] = invite.ExpiresAt.Unix()
+	}
+	return auth.SigningKeys().Sign(claims)
+}
+
+// ParseInviteToken checks the token's signature and expiry and returns its claims.
+// Whether the invite has been revoked or used up is checked against the database.
+func ParseInviteToken(tokenStr string) (*InviteClaims, error) {
+	token, err := jwt.Parse(tokenStr, auth.SigningKeys().Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid invite token")
+	}
+	inviteID, _ := claims["inv"].(string)
+	roomID, _ := claims["room"].(string)
+	role, _ := claims["role"].(string)
+	if inviteID == "" || roomID == "" || !IsInviteRole(role) {
+		return nil, fmt.Errorf("invalid invite token")
+	}
+
+	return &InviteClaims{
+		InviteID: inviteID,
+		RoomID:   roomID,
+		Role:     role,
+	}, nil
+}
+
+func inviteFromRow(row *db.RoomInvite) (Invite, error) {
+	token, err := signInviteToken(row)
+	if err != nil {
+		return Invite{}, fmt.Errorf("sign invite token: %w", err)
+	}
+	return Invite{
+		ID:        row.ID.String(),
+		Role:      row.Role,
+		Token:     token,
+		Created:   row.Created,
+		ExpiresAt: row.ExpiresAt,
+		MaxUses:   intPtrFromInt32(row.MaxUses),
+		Uses:      int(row.Uses),
+	}, nil
+}
+
+func CreateInvite(ctx context.Context, dbtx db.DBTX, roomID string, createdBy string, role string, expiresAt *time.Time, maxUses *int) (*Invite, error) {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("parse room UUID: %w", err)
+	}
+	userUUID, err := uuid.Parse(createdBy)
+	if err != nil {
+		return nil, fmt.Errorf("parse user UUID: %w", err)
+	}
+
+	row, err := db.New(dbtx).RoomInviteInsert(ctx, db.RoomInviteInsertParams{
+		RoomID:    roomUUID,
+		Role:      role,
+		CreatedBy: userUUID,
+		ExpiresAt: expiresAt,
+		MaxUses:   int32PtrFromInt(maxUses),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	invite, err := inviteFromRow(row)
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// GetActiveInvites returns the room's invites that have not been revoked, expired
+// or used up, newest first
+func GetActiveInvites(ctx context.Context, dbtx db.DBTX, roomID string) ([]Invite, error) {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("parse room UUID: %w", err)
+	}
+
+	rows, err := db.New(dbtx).RoomInviteGetActive(ctx, roomUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	invites := make([]Invite, 0, len(rows))
+	for _, row := range rows {
+		invite, err := inviteFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+	return invites, nil
+}
+
+// RevokeInvite stops the invite from being redeemed and removes access from guests
+// who joined with it
+func RevokeInvite(ctx context.Context, dbtx db.DBTX, roomID string, inviteID string) error {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return fmt.Errorf("parse room UUID: %w", err)
+	}
+	inviteUUID, err := uuid.Parse(inviteID)
+	if err != nil {
+		return fmt.Errorf("parse invite UUID: %w", err)
+	}
+
+	count, err := db.New(dbtx).RoomInviteRevoke(ctx, db.RoomInviteRevokeParams{
+		ID:     inviteUUID,
+		RoomID: roomUUID,
+	})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UseInvite counts a redemption of the invite, returning sql.ErrNoRows if it has
+// been revoked, has expired or has no uses left
+func UseInvite(ctx context.Context, dbtx db.DBTX, roomID string, inviteID string) error {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return fmt.Errorf("parse room UUID: %w", err)
+	}
+	inviteUUID, err := uuid.Parse(inviteID)
+	if err != nil {
+		return fmt.Errorf("parse invite UUID: %w", err)
+	}
+
+	count, err := db.New(dbtx).RoomInviteUse(ctx, db.RoomInviteUseParams{
+		ID:     inviteUUID,
+		RoomID: roomUUID,
+	})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func SetGuestInvite(ctx context.Context, dbtx db.DBTX, roomID string, guestID string, inviteID string) error {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return fmt.Errorf("parse room UUID: %w", err)
+	}
+	guestUUID, err := uuid.Parse(guestID)
+	if err != nil {
+		return fmt.Errorf("parse guest UUID: %w", err)
+	}
+	inviteUUID, err := uuid.Parse(inviteID)
+	if err != nil {
+		return fmt.Errorf("parse invite UUID: %w", err)
+	}
+
+	return db.New(dbtx).RoomGuestSetInvite(ctx, db.RoomGuestSetInviteParams{
+		RoomID:   roomUUID,
+		ID:       guestUUID,
+		InviteID: &inviteUUID,
+	})
+}
+
+// GuestHasValidInvite returns whether the guest joined the room with the invite in
+// the token and the invite has not since been revoked or expired
+func GuestHasValidInvite(ctx context.Context, dbtx db.DBTX, roomID string, guestID string, token string) (bool, error) {
+	claims, err := ParseInviteToken(token)
+	if err != nil || claims.RoomID != roomID || claims.Role != InviteRoleGuest {
+		return false, nil
+	}
+
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return false, fmt.Errorf("parse room UUID: %w", err)
+	}
+	guestUUID, err := uuid.Parse(guestID)
+	if err != nil {
+		return false, nil
+	}
+	inviteUUID, err := uuid.Parse(claims.InviteID)
+	if err != nil {
+		return false, nil
+	}
+
+	return db.New(dbtx).RoomGuestHasInvite(ctx, db.RoomGuestHasInviteParams{
+		RoomID:   roomUUID,
+		GuestID:  guestUUID,
+		InviteID: inviteUUID,
+	})
+}
+
+// AddInvitedMember adds the user to the room as a member, or as a moderator if
+// isModerator is true. Existing moderators are never demoted.
+func AddInvitedMember(ctx context.Context, dbtx db.DBTX, roomID string, userID string, isModerator bool) error {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return fmt.Errorf("parse room UUID: %w", err)
+	}
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("parse user UUID: %w", err)
+	}
+
+	return db.New(dbtx).RoomAddMemberWithRole(ctx, db.RoomAddMemberWithRoleParams{
+		UserID:      userUUID,
+		RoomID:      roomUUID,
+		IsModerator: isModerator,
+	})
+}
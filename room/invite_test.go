@@ -0,0 +1,69 @@
+package room
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/auth/authtest"
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInviteToken(t *testing.T) {
+	authtest.UseKeys(t)
+	invite := &db.RoomInvite{
+		ID:      uuid.New(),
+		RoomID:  uuid.New(),
+		Role:    InviteRoleMember,
+		Created: time.Now(),
+	}
+
+	t.Run("sign -> parse", func(t *testing.T) {
+		token, err := signInviteToken(invite)
+		assert.NoError(t, err)
+
+		claims, err := ParseInviteToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, invite.ID.String(), claims.InviteID)
+		assert.Equal(t, invite.RoomID.String(), claims.RoomID)
+		assert.Equal(t, InviteRoleMember, claims.Role)
+	})
+
+	t.Run("same token each time", func(t *testing.T) {
+		first, err := signInviteToken(invite)
+		assert.NoError(t, err)
+		second, err := signInviteToken(invite)
+		assert.NoError(t, err)
+		assert.Equal(t, first, second)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		expired := *invite
+		expiresAt := time.Now().Add(-time.Minute)
+		expired.ExpiresAt = &expiresAt
+
+		token, err := signInviteToken(&expired)
+		assert.NoError(t, err)
+
+		_, err = ParseInviteToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		token, err := signInviteToken(invite)
+		assert.NoError(t, err)
+
+		_, err = ParseInviteToken(token + "x")
+		assert.Error(t, err)
+	})
+
+	t.Run("from request", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/room/TEST?invite=query", nil)
+		assert.Equal(t, "", InviteTokenFromRequest(r))
+
+		r.Header.Set("X-Room-Invite", "header")
+		assert.Equal(t, "header", InviteTokenFromRequest(r))
+	})
+}
@@ -0,0 +1,282 @@
+package room
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+const (
+	// a track replaced this long before it would have finished counts as skipped
+	play_log_skip_grace = time.Second * 5
+)
+
+const (
+	OrderByPlayed = "played"
+	OrderByVotes  = "votes"
+)
+
+type NowPlaying struct {
+	TrackID    string
+	StartedAt  time.Time
+	DurationMS int
+}
+
+type PlayedTrack struct {
+	TrackID   string
+	StartedAt time.Time
+	Score     int
+}
+
+type PlayLogEntry struct {
+	ID         string    `json:"id"`
+	TrackID    string    `json:"track_id"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int       `json:"duration_ms"`
+	AddedBy    string    `json:"added_by,omitempty"`
+	AddedByID  string    `json:"added_by_id,omitempty"`
+	Score      int       `json:"score"`
+	Skipped    bool      `json:"skipped"`
+	SkipVotes  *int      `json:"skip_votes"`
+}
+
+// LogNowPlaying adds the track playing in the room to the room's play log unless
+// it has already been logged. queuedFrom is the room queue entry the track was
+// played from, if there is one. If the previous track was replaced before it
+// finished, it is marked as skipped.
+func LogNowPlaying(ctx context.Context, dbtx db.DBTX, roomID string, playing NowPlaying, queuedFrom *QueuedTrack) error {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return fmt.Errorf("parse room UUID: %w", err)
+	}
+
+	latest, err := db.New(dbtx).RoomPlayLogGetLatest(ctx, roomUUID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("get latest play: %w", err)
+	}
+	if err == nil {
+		if !isNewPlay(latest, playing) {
+			return nil
+		}
+		if !latest.Skipped && endedEarly(latest, playing.StartedAt) {
+			err = db.New(dbtx).RoomPlayLogMarkSkipped(ctx, db.RoomPlayLogMarkSkippedParams{ID: latest.ID})
+			if err != nil {
+				return fmt.Errorf("mark previous play skipped: %w", err)
+			}
+		}
+	}
+
+	params := db.RoomPlayLogInsertParams{
+		RoomID:     roomUUID,
+		TrackID:    playing.TrackID,
+		StartedAt:  playing.StartedAt,
+		DurationMs: int32(playing.DurationMS),
+	}
+	if queuedFrom != nil {
+		params.QueueTrackID, err = optionalUUID(queuedFrom.ID)
+		if err != nil {
+			return fmt.Errorf("parse queue track UUID: %w", err)
+		}
+		params.GuestID, err = optionalUUID(queuedFrom.GuestID)
+		if err != nil {
+			return fmt.Errorf("parse guest UUID: %w", err)
+		}
+		params.UserID, err = optionalUUID(queuedFrom.UserID)
+		if err != nil {
+			return fmt.Errorf("parse user UUID: %w", err)
+		}
+		params.Score = int32(queuedFrom.Score)
+	}
+
+	return db.New(dbtx).RoomPlayLogInsert(ctx, params)
+}
+
+// RecordNowPlaying logs the track playing in the room, crediting the most
+// recently pushed room queue entry for the track if it hasn't been played yet.
+// That entry and every entry pushed before it are marked as played.
+func RecordNowPlaying(ctx context.Context, dbtx db.DBTX, roomID string, playing NowPlaying) error {
+	queued, err := GetQueueTrackAddedBy(ctx, dbtx, roomID)
+	if err != nil {
+		return fmt.Errorf("get queued tracks: %w", err)
+	}
+
+	// queued tracks are ordered most recently pushed first
+	queuedFrom, ok := lo.Find(queued, func(track QueuedTrack) bool {
+		return track.TrackID == playing.TrackID && !track.Played
+	})
+	if !ok {
+		return LogNowPlaying(ctx, dbtx, roomID, playing, nil)
+	}
+
+	err = MarkTracksAsPlayedSince(ctx, dbtx, roomID, queuedFrom.Timestamp)
+	if err != nil {
+		return fmt.Errorf("mark tracks played: %w", err)
+	}
+
+	return LogNowPlaying(ctx, dbtx, roomID, playing, &queuedFrom)
+}
+
+// markSkippedByVote marks the room's latest play as skipped if it is the track the
+// room voted to skip
+func markSkippedByVote(ctx context.Context, dbtx db.DBTX, roomUUID uuid.UUID, trackID string, votes int) error {
+	latest, err := db.New(dbtx).RoomPlayLogGetLatest(ctx, roomUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if latest.TrackID != trackID {
+		return nil
+	}
+
+	skipVotes := int32(votes)
+	return db.New(dbtx).RoomPlayLogMarkSkipped(ctx, db.RoomPlayLogMarkSkippedParams{
+		ID:        latest.ID,
+		SkipVotes: &skipVotes,
+	})
+}
+
+// GetSessionHistory returns a page of the tracks played in the room, most recent
+// first, along with the total number of matching plays. If participantID is not
+// empty, only tracks queued by that guest or member are included.
+func GetSessionHistory(ctx context.Context, dbtx db.DBTX, roomID string, participantID string, limit int, offset int) ([]PlayLogEntry, int, error) {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("parse room UUID: %w", err)
+	}
+	participantUUID, err := optionalUUID(participantID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("parse participant UUID: %w", err)
+	}
+
+	rows, err := db.New(dbtx).RoomPlayLogGetPage(ctx, db.RoomPlayLogGetPageParams{
+		RoomID:        roomUUID,
+		ParticipantID: participantUUID,
+		MaxCount:      int32(limit),
+		PageOffset:    int32(offset),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := db.New(dbtx).RoomPlayLogCount(ctx, db.RoomPlayLogCountParams{
+		RoomID:        roomUUID,
+		ParticipantID: participantUUID,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("count plays: %w", err)
+	}
+
+	entries := make([]PlayLogEntry, 0, len(rows))
+	for _, row := range rows {
+		entry := PlayLogEntry{
+			ID:         row.ID.String(),
+			TrackID:    row.TrackID,
+			StartedAt:  row.StartedAt,
+			DurationMS: int(row.DurationMs),
+			Score:      int(row.Score),
+			Skipped:    row.Skipped,
+			SkipVotes:  intPtrFromInt32(row.SkipVotes),
+		}
+		if row.GuestID != nil {
+			entry.AddedByID = row.GuestID.String()
+			if row.GuestName != nil {
+				entry.AddedBy = *row.GuestName
+			}
+		} else if row.UserID != nil {
+			entry.AddedByID = row.UserID.String()
+			if row.MemberName != nil {
+				entry.AddedBy = *row.MemberName
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, int(total), nil
+}
+
+// GetPlayedTracks returns the tracks played in the room between start and end,
+// oldest first. Either bound can be nil to leave that side of the window open.
+func GetPlayedTracks(ctx context.Context, dbtx db.DBTX, roomID string, start *time.Time, end *time.Time, excludeSkipped bool) ([]PlayedTrack, error) {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("parse room UUID: %w", err)
+	}
+
+	rows, err := db.New(dbtx).RoomPlayLogGetTracks(ctx, db.RoomPlayLogGetTracksParams{
+		RoomID:         roomUUID,
+		StartTime:      start,
+		EndTime:        end,
+		ExcludeSkipped: excludeSkipped,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := make([]PlayedTrack, 0, len(rows))
+	for _, row := range rows {
+		tracks = append(tracks, PlayedTrack{
+			TrackID:   row.TrackID,
+			StartedAt: row.StartedAt,
+			Score:     int(row.Score),
+		})
+	}
+	return tracks, nil
+}
+
+// PlaylistTrackIDs orders played tracks for a playlist, listing each track once.
+// Tracks are ordered by when they first played, or by their highest vote score
+// when ordering by votes.
+func PlaylistTrackIDs(tracks []PlayedTrack, orderBy string) []string {
+	ids := []string{}
+	scores := map[string]int{}
+	for _, track := range tracks {
+		score, seen := scores[track.TrackID]
+		if !seen {
+			ids = append(ids, track.TrackID)
+			scores[track.TrackID] = track.Score
+		} else if track.Score > score {
+			scores[track.TrackID] = track.Score
+		}
+	}
+
+	if orderBy == OrderByVotes {
+		sort.SliceStable(ids, func(i, j int) bool {
+			return scores[ids[i]] > scores[ids[j]]
+		})
+	}
+	return ids
+}
+
+// isNewPlay returns false if the track playing is the one already at the top of
+// the play log. The same track starting again after it finished is a new play.
+func isNewPlay(latest *db.RoomPlayLog, playing NowPlaying) bool {
+	if latest.TrackID != playing.TrackID {
+		return true
+	}
+	return !playing.StartedAt.Before(latest.StartedAt.Add(time.Duration(latest.DurationMs) * time.Millisecond))
+}
+
+func endedEarly(latest *db.RoomPlayLog, nextStartedAt time.Time) bool {
+	end := latest.StartedAt.Add(time.Duration(latest.DurationMs)*time.Millisecond - play_log_skip_grace)
+	return nextStartedAt.Before(end)
+}
+
+func optionalUUID(id string) (*uuid.UUID, error) {
+	if id == "" {
+		return nil, nil
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,102 @@
+package room
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/db/dbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlayLogTransitions(t *testing.T) {
+	start := time.Date(2026, 10, 17, 20, 0, 0, 0, time.UTC)
+	latest := &db.RoomPlayLog{TrackID: "track", StartedAt: start, DurationMs: 180000}
+
+	t.Run("same play", func(t *testing.T) {
+		assert.False(t, isNewPlay(latest, NowPlaying{TrackID: "track", StartedAt: start.Add(time.Second)}))
+	})
+
+	t.Run("different track", func(t *testing.T) {
+		assert.True(t, isNewPlay(latest, NowPlaying{TrackID: "other", StartedAt: start.Add(time.Minute)}))
+	})
+
+	t.Run("replayed after finishing", func(t *testing.T) {
+		assert.True(t, isNewPlay(latest, NowPlaying{TrackID: "track", StartedAt: start.Add(time.Minute * 3)}))
+	})
+
+	t.Run("replaced early", func(t *testing.T) {
+		assert.True(t, endedEarly(latest, start.Add(time.Minute)))
+	})
+
+	t.Run("finished", func(t *testing.T) {
+		assert.False(t, endedEarly(latest, start.Add(time.Minute*3-time.Second*2)))
+	})
+}
+
+func TestPlaylistTrackIDs(t *testing.T) {
+	start := time.Date(2026, 10, 17, 20, 0, 0, 0, time.UTC)
+	tracks := []PlayedTrack{
+		{TrackID: "a", StartedAt: start, Score: 0},
+		{TrackID: "b", StartedAt: start.Add(time.Minute * 3), Score: 2},
+		{TrackID: "c", StartedAt: start.Add(time.Minute * 6), Score: 1},
+		{TrackID: "a", StartedAt: start.Add(time.Minute * 9), Score: 3},
+		{TrackID: "d", StartedAt: start.Add(time.Minute * 12), Score: 1},
+	}
+
+	t.Run("by play time", func(t *testing.T) {
+		assert.Equal(t, []string{"a", "b", "c", "d"}, PlaylistTrackIDs(tracks, OrderByPlayed))
+	})
+
+	t.Run("by votes", func(t *testing.T) {
+		assert.Equal(t, []string{"b", "c", "a"}, PlaylistTrackIDs(tracks[:3], OrderByVotes))
+	})
+
+	t.Run("by highest score of replayed track", func(t *testing.T) {
+		assert.Equal(t, []string{"a", "b", "c", "d"}, PlaylistTrackIDs(tracks, OrderByVotes))
+	})
+}
+
+func TestRecordNowPlaying(t *testing.T) {
+	pool := dbtest.New(t)
+	dbtest.Seed(t, pool)
+	ctx := context.Background()
+	roomID := dbtest.RoomID.String()
+	start := time.Date(2026, 10, 17, 20, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, SetQueueTrackUser(ctx, pool, dbtest.RoomCode, "qstrack000000000000000", dbtest.BobID.String()))
+	assert.NoError(t, SetQueueTrackUser(ctx, pool, dbtest.RoomCode, "qstrack000000000000001", dbtest.AliceID.String()))
+	pending, err := GetPendingQueue(ctx, pool, roomID, "")
+	assert.NoError(t, err)
+	for _, track := range pending {
+		assert.NoError(t, SetQueueTrackPushed(ctx, pool, track.ID))
+	}
+
+	err = RecordNowPlaying(ctx, pool, roomID, NowPlaying{TrackID: "qstrack000000000000000", StartedAt: start, DurationMS: 180000})
+	assert.NoError(t, err)
+
+	latest, err := db.New(pool).RoomPlayLogGetLatest(ctx, dbtest.RoomID)
+	assert.NoError(t, err)
+	assert.Equal(t, "qstrack000000000000000", latest.TrackID)
+	if assert.NotNil(t, latest.UserID) {
+		assert.Equal(t, dbtest.BobID, *latest.UserID)
+	}
+
+	queued, err := GetQueueTrackAddedBy(ctx, pool, roomID)
+	assert.NoError(t, err)
+	played := map[string]bool{}
+	for _, track := range queued {
+		played[track.TrackID] = track.Played
+	}
+	assert.Equal(t, map[string]bool{"qstrack000000000000000": true, "qstrack000000000000001": false}, played)
+
+	t.Run("still playing", func(t *testing.T) {
+		err := RecordNowPlaying(ctx, pool, roomID, NowPlaying{TrackID: "qstrack000000000000000", StartedAt: start.Add(time.Second), DurationMS: 180000})
+		assert.NoError(t, err)
+
+		count, err := db.New(pool).RoomPlayLogCount(ctx, db.RoomPlayLogCountParams{RoomID: dbtest.RoomID})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+}
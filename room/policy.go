@@ -0,0 +1,201 @@
+package room
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/google/uuid"
+)
+
+const (
+	RuleMaxPending     = "max_pending"
+	RuleCooldown       = "cooldown"
+	RuleRecentlyPlayed = "recently_played"
+	RuleExplicit       = "explicit"
+	RuleMaxDuration    = "max_duration"
+)
+
+// QueuePolicy limits what guests and members can add to a room's queue and how
+// many of them need to vote to skip a track. Nil limits are not enforced.
+type QueuePolicy struct {
+	MaxPendingPerParticipant *int `json:"max_pending_per_participant"`
+	CooldownSeconds          *int `json:"cooldown_seconds"`
+	ReplayBlockMinutes       *int `json:"replay_block_minutes"`
+	BlockExplicit            bool `json:"block_explicit"`
+	MaxDurationMS            *int `json:"max_duration_ms"`
+	SkipVoteCount            *int `json:"skip_vote_count"`
+	SkipVotePercent          *int `json:"skip_vote_percent"`
+}
+
+type PolicyViolation struct {
+	Rule       string
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (v *PolicyViolation) Error() string {
+	return v.Message
+}
+
+// ParticipantQueueStats describes a guest or member's recent activity in a room's queue
+type ParticipantQueueStats struct {
+	// PendingCount includes tracks pushed to Spotify that haven't played yet
+	PendingCount   int
+	LastAdded      time.Time
+	PlayedRecently bool
+}
+
+func GetQueuePolicy(ctx context.Context, dbtx db.DBTX, roomID string) (QueuePolicy, error) {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return QueuePolicy{}, fmt.Errorf("parse room UUID: %w", err)
+	}
+
+	row, err := db.New(dbtx).RoomGetQueuePolicy(ctx, roomUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return QueuePolicy{}, nil
+	}
+	if err != nil {
+		return QueuePolicy{}, err
+	}
+
+	return QueuePolicy{
+		MaxPendingPerParticipant: intPtrFromInt32(row.MaxPendingPerParticipant),
+		CooldownSeconds:          intPtrFromInt32(row.CooldownSeconds),
+		ReplayBlockMinutes:       intPtrFromInt32(row.ReplayBlockMinutes),
+		BlockExplicit:            row.BlockExplicit,
+		MaxDurationMS:            intPtrFromInt32(row.MaxDurationMs),
+		SkipVoteCount:            intPtrFromInt32(row.SkipVoteCount),
+		SkipVotePercent:          intPtrFromInt32(row.SkipVotePercent),
+	}, nil
+}
+
+func SetQueuePolicy(ctx context.Context, dbtx db.DBTX, roomID string, policy QueuePolicy) error {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return fmt.Errorf("parse room UUID: %w", err)
+	}
+
+	return db.New(dbtx).RoomSetQueuePolicy(ctx, db.RoomSetQueuePolicyParams{
+		RoomID:                   roomUUID,
+		MaxPendingPerParticipant: int32PtrFromInt(policy.MaxPendingPerParticipant),
+		CooldownSeconds:          int32PtrFromInt(policy.CooldownSeconds),
+		ReplayBlockMinutes:       int32PtrFromInt(policy.ReplayBlockMinutes),
+		BlockExplicit:            policy.BlockExplicit,
+		MaxDurationMs:            int32PtrFromInt(policy.MaxDurationMS),
+		SkipVoteCount:            int32PtrFromInt(policy.SkipVoteCount),
+		SkipVotePercent:          int32PtrFromInt(policy.SkipVotePercent),
+	})
+}
+
+// CheckQueuePolicy returns the first rule in the room's policy that the participant
+// would break by adding the track, or nil if the track can be added
+func CheckQueuePolicy(ctx context.Context, dbtx db.DBTX, roomID string, participantID string, track db.TrackData) (*PolicyViolation, error) {
+	policy, err := GetQueuePolicy(ctx, dbtx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("get queue policy: %w", err)
+	}
+
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("parse room UUID: %w", err)
+	}
+	participantUUID, err := uuid.Parse(participantID)
+	if err != nil {
+		return nil, fmt.Errorf("parse participant UUID: %w", err)
+	}
+
+	row, err := db.New(dbtx).RoomQueueGetParticipantStats(ctx, db.RoomQueueGetParticipantStatsParams{
+		RoomID:        roomUUID,
+		ParticipantID: participantUUID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get participant stats: %w", err)
+	}
+	stats := ParticipantQueueStats{
+		PendingCount: int(row.PendingCount),
+		LastAdded:    row.LastAdded,
+	}
+
+	if policy.ReplayBlockMinutes != nil {
+		stats.PlayedRecently, err = db.New(dbtx).RoomQueueTrackPlayedSince(ctx, db.RoomQueueTrackPlayedSinceParams{
+			RoomID:  roomUUID,
+			TrackID: track.ID,
+			Since:   time.Now().Add(-time.Duration(*policy.ReplayBlockMinutes) * time.Minute),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("check recently played: %w", err)
+		}
+	}
+
+	return policy.Check(track, stats, time.Now()), nil
+}
+
+// Check returns the first rule that adding the track would break, or nil
+func (p QueuePolicy) Check(track db.TrackData, stats ParticipantQueueStats, now time.Time) *PolicyViolation {
+	if p.BlockExplicit && track.Explicit {
+		return &PolicyViolation{
+			Rule:    RuleExplicit,
+			Message: "Explicit tracks are not allowed in this room",
+		}
+	}
+
+	if p.MaxDurationMS != nil && int(track.DurationMs) > *p.MaxDurationMS {
+		return &PolicyViolation{
+			Rule:    RuleMaxDuration,
+			Message: fmt.Sprintf("Tracks longer than %s are not allowed in this room", formatDuration(*p.MaxDurationMS)),
+		}
+	}
+
+	if p.ReplayBlockMinutes != nil && stats.PlayedRecently {
+		return &PolicyViolation{
+			Rule:    RuleRecentlyPlayed,
+			Message: fmt.Sprintf("This track has been played in the last %d minutes", *p.ReplayBlockMinutes),
+		}
+	}
+
+	if p.MaxPendingPerParticipant != nil && stats.PendingCount >= *p.MaxPendingPerParticipant {
+		return &PolicyViolation{
+			Rule:    RuleMaxPending,
+			Message: fmt.Sprintf("You can only have %d tracks waiting in the queue", *p.MaxPendingPerParticipant),
+		}
+	}
+
+	if p.CooldownSeconds != nil {
+		nextAllowed := stats.LastAdded.Add(time.Duration(*p.CooldownSeconds) * time.Second)
+		if now.Before(nextAllowed) {
+			retryAfter := nextAllowed.Sub(now).Round(time.Second)
+			return &PolicyViolation{
+				Rule:       RuleCooldown,
+				Message:    fmt.Sprintf("You can add another track in %s", retryAfter),
+				RetryAfter: retryAfter,
+			}
+		}
+	}
+
+	return nil
+}
+
+func formatDuration(ms int) string {
+	return (time.Duration(ms) * time.Millisecond).Round(time.Second).String()
+}
+
+func intPtrFromInt32(i *int32) *int {
+	if i == nil {
+		return nil
+	}
+	val := int(*i)
+	return &val
+}
+
+func int32PtrFromInt(i *int) *int32 {
+	if i == nil {
+		return nil
+	}
+	val := int32(*i)
+	return &val
+}
@@ -0,0 +1,148 @@
+package room
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/db/dbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestQueuePolicyCheck(t *testing.T) {
+	now := time.Now()
+	track := db.TrackData{ID: "track", DurationMs: 240000, Explicit: true}
+
+	tests := []struct {
+		name   string
+		policy QueuePolicy
+		stats  ParticipantQueueStats
+		rule   string
+	}{
+		{
+			name:   "no limits",
+			policy: QueuePolicy{},
+			stats:  ParticipantQueueStats{PendingCount: 100, LastAdded: now, PlayedRecently: true},
+		},
+		{
+			name:   "explicit",
+			policy: QueuePolicy{BlockExplicit: true},
+			rule:   RuleExplicit,
+		},
+		{
+			name:   "too long",
+			policy: QueuePolicy{MaxDurationMS: intPtr(180000)},
+			rule:   RuleMaxDuration,
+		},
+		{
+			name:   "played recently",
+			policy: QueuePolicy{ReplayBlockMinutes: intPtr(30)},
+			stats:  ParticipantQueueStats{PlayedRecently: true},
+			rule:   RuleRecentlyPlayed,
+		},
+		{
+			name:   "too many pending",
+			policy: QueuePolicy{MaxPendingPerParticipant: intPtr(3)},
+			stats:  ParticipantQueueStats{PendingCount: 3},
+			rule:   RuleMaxPending,
+		},
+		{
+			name:   "under pending limit",
+			policy: QueuePolicy{MaxPendingPerParticipant: intPtr(3)},
+			stats:  ParticipantQueueStats{PendingCount: 2},
+		},
+		{
+			name:   "cooling down",
+			policy: QueuePolicy{CooldownSeconds: intPtr(60)},
+			stats:  ParticipantQueueStats{LastAdded: now.Add(-time.Second * 15)},
+			rule:   RuleCooldown,
+		},
+		{
+			name:   "cooldown over",
+			policy: QueuePolicy{CooldownSeconds: intPtr(60)},
+			stats:  ParticipantQueueStats{LastAdded: now.Add(-time.Minute * 2)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			violation := test.policy.Check(track, test.stats, now)
+			if test.rule == "" {
+				assert.Nil(t, violation)
+				return
+			}
+			if assert.NotNil(t, violation) {
+				assert.Equal(t, test.rule, violation.Rule)
+			}
+		})
+	}
+
+	t.Run("cooldown retry after", func(t *testing.T) {
+		policy := QueuePolicy{CooldownSeconds: intPtr(60)}
+		violation := policy.Check(track, ParticipantQueueStats{LastAdded: now.Add(-time.Second * 15)}, now)
+		assert.Equal(t, time.Second*45, violation.RetryAfter)
+	})
+}
+
+func TestSkipVotesRequired(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   QueuePolicy
+		active   int
+		required int
+		enabled  bool
+	}{
+		{name: "disabled", policy: QueuePolicy{}, active: 5},
+		{name: "count", policy: QueuePolicy{SkipVoteCount: intPtr(3)}, active: 10, required: 3, enabled: true},
+		{name: "percent rounds up", policy: QueuePolicy{SkipVotePercent: intPtr(50)}, active: 3, required: 2, enabled: true},
+		{name: "lower of both", policy: QueuePolicy{SkipVoteCount: intPtr(4), SkipVotePercent: intPtr(50)}, active: 4, required: 2, enabled: true},
+		{name: "at least one vote", policy: QueuePolicy{SkipVoteCount: intPtr(0)}, active: 4, required: 1, enabled: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			required, enabled := test.policy.SkipVotesRequired(test.active)
+			assert.Equal(t, test.enabled, enabled)
+			assert.Equal(t, test.required, required)
+		})
+	}
+}
+
+func TestCheckQueuePolicy(t *testing.T) {
+	pool := dbtest.New(t)
+	dbtest.Seed(t, pool)
+	ctx := context.Background()
+	roomID := dbtest.RoomID.String()
+	bobID := dbtest.BobID.String()
+	track := db.TrackData{ID: "qstrack000000000000001", DurationMs: 180000}
+
+	assert.NoError(t, SetQueuePolicy(ctx, pool, roomID, QueuePolicy{MaxPendingPerParticipant: intPtr(1)}))
+	assert.NoError(t, SetQueueTrackUser(ctx, pool, dbtest.RoomCode, "qstrack000000000000000", bobID))
+
+	// pushing the track to Spotify doesn't make room for another
+	pending, err := GetPendingQueue(ctx, pool, roomID, "")
+	assert.NoError(t, err)
+	if !assert.Len(t, pending, 1) {
+		return
+	}
+	assert.NoError(t, SetQueueTrackPushed(ctx, pool, pending[0].ID))
+
+	violation, err := CheckQueuePolicy(ctx, pool, roomID, bobID, track)
+	assert.NoError(t, err)
+	if assert.NotNil(t, violation) {
+		assert.Equal(t, RuleMaxPending, violation.Rule)
+	}
+
+	t.Run("played", func(t *testing.T) {
+		assert.NoError(t, MarkTracksAsPlayedSince(ctx, pool, roomID, time.Now()))
+
+		violation, err := CheckQueuePolicy(ctx, pool, roomID, bobID, track)
+		assert.NoError(t, err)
+		assert.Nil(t, violation)
+	})
+}
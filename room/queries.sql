@@ -42,7 +42,7 @@ new_pass AS (
         encrypted_password)
     SELECT
         id,
-        crypt(@room_pass, gen_salt('bf'))
+        @password_hash::text
     FROM
         new_room
 )
@@ -55,13 +55,14 @@ FROM
 UPDATE
     room_passwords
 SET
-    encrypted_password = crypt(@room_pass, gen_salt('bf'))
+    encrypted_password = @password_hash::text
 WHERE
     room_id = $1;
 
--- name: RoomValidatePassword :one
+-- name: RoomGetPasswordHash :one
 SELECT
-    (encrypted_password = crypt(@room_pass::text, encrypted_password::text))
+    rp.room_id,
+    rp.encrypted_password
 FROM
     room_passwords AS rp
     JOIN rooms r ON r.id = rp.room_id
@@ -183,19 +184,164 @@ WHERE
 
 -- name: RoomGetQueueTracks :many
 SELECT
+    t.id,
     track_id,
+    t.guest_id,
+    t.user_id,
     g.name AS guest_name,
     u.display_name AS member_name,
-    timestamp,
-    played
+    t.pushed_at::timestamptz AS timestamp,
+    played,
+    COALESCE((
+        SELECT
+            SUM(v.vote)
+        FROM room_queue_votes v
+        WHERE
+            v.queue_track_id = t.id), 0)::integer AS score
 FROM
     room_queue_tracks t
     LEFT JOIN room_guests g ON g.id = t.guest_id
     LEFT JOIN users u ON u.id = t.user_id
 WHERE
     t.room_id = $1
+    AND t.pushed_at IS NOT NULL
 ORDER BY
-    timestamp DESC;
+    t.pushed_at DESC;
+
+-- name: RoomQueueGetPending :many
+SELECT
+    t.id,
+    t.track_id,
+    g.name AS guest_name,
+    u.display_name AS member_name,
+    t.timestamp,
+    t.priority,
+    COALESCE(SUM(v.vote), 0)::integer AS score,
+    COALESCE(SUM(v.vote) FILTER (WHERE v.voter_id = @voter_id::uuid), 0)::integer AS voter_vote
+FROM
+    room_queue_tracks t
+    LEFT JOIN room_guests g ON g.id = t.guest_id
+    LEFT JOIN users u ON u.id = t.user_id
+    LEFT JOIN room_queue_votes v ON v.queue_track_id = t.id
+WHERE
+    t.room_id = $1
+    AND t.pushed_at IS NULL
+    AND NOT t.played
+GROUP BY
+    t.id,
+    g.name,
+    u.display_name
+ORDER BY
+    t.priority DESC,
+    score DESC,
+    t.timestamp ASC;
+
+-- name: RoomQueueGetPushedTrackIDs :many
+SELECT
+    track_id
+FROM
+    room_queue_tracks
+WHERE
+    room_id = $1
+    AND pushed_at IS NOT NULL
+    AND NOT played;
+
+-- name: RoomQueueGetRoomsWithPending :many
+SELECT DISTINCT
+    r.id,
+    r.code
+FROM
+    rooms r
+    JOIN room_queue_tracks t ON t.room_id = r.id
+        AND t.pushed_at IS NULL
+        AND NOT t.played
+WHERE
+    r.is_open;
+
+-- name: RoomGetRecentlyActive :many
+SELECT
+    r.id,
+    r.code
+FROM
+    rooms r
+WHERE
+    r.is_open
+    AND EXISTS (
+        SELECT
+            1
+        FROM
+            room_queue_tracks t
+        WHERE
+            t.room_id = r.id
+            AND (NOT t.played
+                OR t.timestamp > @since
+                OR t.pushed_at > @since));
+
+-- name: RoomQueueLockFill :exec
+SELECT
+    pg_advisory_xact_lock(hashtext('room_queue_fill'), hashtext(@room_id::text));
+
+-- name: RoomQueueSetPushed :exec
+UPDATE
+    room_queue_tracks
+SET
+    pushed_at = now()
+WHERE
+    id = $1;
+
+-- name: RoomQueueResetPriorities :exec
+UPDATE
+    room_queue_tracks
+SET
+    priority = 0
+WHERE
+    room_id = $1
+    AND pushed_at IS NULL;
+
+-- name: RoomQueueSetPriorities :exec
+UPDATE
+    room_queue_tracks t
+SET
+    priority = cardinality(@queue_track_ids::uuid[]) - o.idx + 1
+FROM
+    unnest(@queue_track_ids::uuid[])
+    WITH ORDINALITY AS o(id, idx)
+WHERE
+    t.id = o.id
+    AND t.room_id = @room_id
+    AND t.pushed_at IS NULL;
+
+-- name: RoomQueueDeleteTrack :execrows
+DELETE FROM room_queue_tracks t
+WHERE t.id = $1
+    AND t.room_id = $2
+    AND t.pushed_at IS NULL;
+
+-- name: RoomQueueVote :execrows
+INSERT INTO room_queue_votes(
+    queue_track_id,
+    voter_id,
+    vote)
+SELECT
+    t.id,
+    @voter_id::uuid,
+    @vote::integer
+FROM
+    room_queue_tracks t
+WHERE
+    t.id = @queue_track_id::uuid
+    AND t.room_id = @room_id::uuid
+    AND t.pushed_at IS NULL
+ON CONFLICT (queue_track_id,
+    voter_id)
+    DO UPDATE SET
+        vote = EXCLUDED.vote,
+        timestamp = now();
+
+-- name: RoomQueueDeleteVote :exec
+DELETE FROM room_queue_votes v
+WHERE v.queue_track_id = $1
+    AND v.voter_id = $2;
 
 -- name: RoomGetHostID :one
 SELECT
@@ -297,5 +443,494 @@ SET
     played = TRUE
 WHERE
     room_id = $1
-    AND timestamp <= $2;
+    AND pushed_at <= @since::timestamptz;
+
+
+-- name: RoomGetQueuePolicy :one
+SELECT
+    *
+FROM
+    room_queue_policies
+WHERE
+    room_id = $1;
+
+-- name: RoomSetQueuePolicy :exec
+INSERT INTO room_queue_policies(
+    room_id,
+    max_pending_per_participant,
+    cooldown_seconds,
+    replay_block_minutes,
+    block_explicit,
+    max_duration_ms,
+    skip_vote_count,
+    skip_vote_percent)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8)
+ON CONFLICT (room_id)
+    DO UPDATE SET
+        max_pending_per_participant = EXCLUDED.max_pending_per_participant,
+        cooldown_seconds = EXCLUDED.cooldown_seconds,
+        replay_block_minutes = EXCLUDED.replay_block_minutes,
+        block_explicit = EXCLUDED.block_explicit,
+        max_duration_ms = EXCLUDED.max_duration_ms,
+        skip_vote_count = EXCLUDED.skip_vote_count,
+        skip_vote_percent = EXCLUDED.skip_vote_percent,
+        updated = now();
+
+-- name: RoomGetSchedule :one
+SELECT
+    *
+FROM
+    room_schedules
+WHERE
+    room_id = $1;
+
+-- name: RoomSetSchedule :exec
+INSERT INTO room_schedules(
+    room_id,
+    starts_at,
+    ends_at,
+    idle_timeout_minutes,
+    recurrence,
+    last_opened)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6)
+ON CONFLICT (room_id)
+    DO UPDATE SET
+        starts_at = EXCLUDED.starts_at,
+        ends_at = EXCLUDED.ends_at,
+        idle_timeout_minutes = EXCLUDED.idle_timeout_minutes,
+        recurrence = EXCLUDED.recurrence,
+        last_opened = EXCLUDED.last_opened,
+        updated = now();
+
+-- name: RoomDeleteSchedule :exec
+DELETE FROM room_schedules
+WHERE room_id = $1;
+
+-- name: RoomScheduleGetAll :many
+SELECT
+    r.id,
+    r.code,
+    r.is_open,
+    s.starts_at,
+    s.ends_at,
+    s.idle_timeout_minutes,
+    s.recurrence,
+    s.last_opened,
+    COALESCE((
+        SELECT
+            MAX(t.timestamp)
+        FROM room_queue_tracks t
+        WHERE
+            t.room_id = r.id), r.created)::timestamptz AS last_activity
+FROM
+    room_schedules s
+    JOIN rooms r ON r.id = s.room_id;
+
+-- name: RoomScheduleUpdateState :exec
+UPDATE
+    room_schedules
+SET
+    starts_at = $2,
+    ends_at = $3,
+    last_opened = $4
+WHERE
+    room_id = $1;
+
+-- name: RoomQueueGetParticipantStats :one
+SELECT
+    COUNT(*) FILTER (WHERE NOT played)::integer AS pending_count,
+    COALESCE(MAX(timestamp), 'epoch'::timestamptz)::timestamptz AS last_added
+FROM
+    room_queue_tracks
+WHERE
+    room_id = $1
+    AND (user_id = @participant_id::uuid
+        OR guest_id = @participant_id::uuid);
+
+-- name: RoomQueueTrackPlayedSince :one
+SELECT
+    EXISTS (
+        SELECT
+            1
+        FROM
+            room_queue_tracks
+        WHERE
+            room_id = $1
+            AND track_id = $2
+            AND pushed_at >= @since::timestamptz);
 
+-- name: RoomBlocklistGetAll :many
+SELECT
+    *
+FROM
+    room_blocklist
+WHERE
+    room_id = $1
+ORDER BY
+    created DESC;
+
+-- name: RoomBlocklistInsert :one
+INSERT INTO room_blocklist(
+    room_id,
+    kind,
+    value,
+    added_by)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4)
+RETURNING
+    *;
+
+-- name: RoomBlocklistDelete :execrows
+DELETE FROM room_blocklist
+WHERE id = $1
+    AND room_id = $2;
+
+-- name: RoomSkipVoteResetStale :exec
+DELETE FROM room_skip_votes
+WHERE room_id = $1
+    AND track_id <> $2;
+
+-- name: RoomSkipVoteInsert :exec
+INSERT INTO room_skip_votes(
+    room_id,
+    voter_id,
+    track_id)
+VALUES (
+    $1,
+    $2,
+    $3)
+ON CONFLICT (room_id,
+    voter_id)
+    DO UPDATE SET
+        track_id = EXCLUDED.track_id,
+        timestamp = now();
+
+-- name: RoomSkipVoteDelete :exec
+DELETE FROM room_skip_votes
+WHERE room_id = $1
+    AND voter_id = $2;
+
+-- name: RoomSkipVoteGetVoters :many
+SELECT
+    voter_id
+FROM
+    room_skip_votes
+WHERE
+    room_id = $1
+    AND track_id = $2;
+
+-- name: RoomSkipVoteClear :exec
+DELETE FROM room_skip_votes
+WHERE room_id = $1;
+
+-- name: RoomCountActiveParticipants :one
+SELECT
+    COUNT(DISTINCT participant_id)::integer AS active_count
+FROM (
+    SELECT
+        COALESCE(user_id, guest_id) AS participant_id
+    FROM
+        room_queue_tracks
+    WHERE
+        room_id = $1
+        AND timestamp >= @since::timestamptz
+    UNION
+    SELECT
+        v.voter_id
+    FROM
+        room_queue_votes v
+        JOIN room_queue_tracks t ON t.id = v.queue_track_id
+    WHERE
+        t.room_id = $1
+        AND v.timestamp >= @since::timestamptz
+    UNION
+    SELECT
+        voter_id
+    FROM
+        room_skip_votes
+    WHERE
+        room_id = $1
+        AND timestamp >= @since::timestamptz
+    UNION
+    SELECT
+        host_id
+    FROM
+        rooms
+    WHERE
+        id = $1) participants
+WHERE
+    participant_id IS NOT NULL;
+
+-- name: RoomSkipInsert :one
+INSERT INTO room_skips(
+    room_id,
+    track_id,
+    vote_count,
+    required_votes,
+    played_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5)
+ON CONFLICT (room_id,
+    track_id,
+    played_at)
+    DO NOTHING
+RETURNING
+    id;
+
+-- name: RoomLockForUpdate :exec
+SELECT
+    id
+FROM
+    rooms
+WHERE
+    id = $1
+FOR UPDATE;
+
+-- name: RoomSkipInsertVoters :exec
+INSERT INTO room_skip_voters(
+    skip_id,
+    voter_id)
+SELECT
+    $1,
+    voter_id
+FROM
+    room_skip_votes
+WHERE
+    room_id = $2
+    AND track_id = $3;
+
+-- name: RoomSkipGetAll :many
+SELECT
+    *
+FROM
+    room_skips
+WHERE
+    room_id = $1
+ORDER BY
+    timestamp DESC;
+
+-- name: RoomSkipGetVoters :many
+SELECT
+    sv.skip_id,
+    sv.voter_id,
+    g.name AS guest_name,
+    u.display_name AS member_name
+FROM
+    room_skip_voters sv
+    JOIN room_skips s ON s.id = sv.skip_id
+    LEFT JOIN room_guests g ON g.id = sv.voter_id
+    LEFT JOIN users u ON u.id = sv.voter_id
+WHERE
+    s.room_id = $1;
+
+-- name: RoomPlayLogGetLatest :one
+SELECT
+    *
+FROM
+    room_play_log
+WHERE
+    room_id = $1
+ORDER BY
+    started_at DESC
+LIMIT 1;
+
+-- name: RoomPlayLogInsert :exec
+INSERT INTO room_play_log(
+    room_id,
+    track_id,
+    started_at,
+    duration_ms,
+    queue_track_id,
+    guest_id,
+    user_id,
+    score)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8);
+
+-- name: RoomPlayLogMarkSkipped :exec
+UPDATE
+    room_play_log
+SET
+    skipped = TRUE,
+    skip_votes = $2
+WHERE
+    id = $1;
+
+-- name: RoomPlayLogGetPage :many
+SELECT
+    l.id,
+    l.track_id,
+    l.started_at,
+    l.duration_ms,
+    l.guest_id,
+    l.user_id,
+    g.name AS guest_name,
+    u.display_name AS member_name,
+    l.score,
+    l.skipped,
+    l.skip_votes
+FROM
+    room_play_log l
+    LEFT JOIN room_guests g ON g.id = l.guest_id
+    LEFT JOIN users u ON u.id = l.user_id
+WHERE
+    l.room_id = $1
+    AND (sqlc.narg(participant_id)::uuid IS NULL
+        OR l.guest_id = sqlc.narg(participant_id)::uuid
+        OR l.user_id = sqlc.narg(participant_id)::uuid)
+ORDER BY
+    l.started_at DESC
+LIMIT @max_count OFFSET @page_offset;
+
+-- name: RoomPlayLogCount :one
+SELECT
+    COUNT(*)
+FROM
+    room_play_log
+WHERE
+    room_id = $1
+    AND (sqlc.narg(participant_id)::uuid IS NULL
+        OR guest_id = sqlc.narg(participant_id)::uuid
+        OR user_id = sqlc.narg(participant_id)::uuid);
+
+-- name: RoomPlayLogGetTracks :many
+SELECT
+    track_id,
+    started_at,
+    score,
+    skipped
+FROM
+    room_play_log
+WHERE
+    room_id = $1
+    AND (sqlc.narg(start_time)::timestamptz IS NULL
+        OR started_at >= sqlc.narg(start_time)::timestamptz)
+    AND (sqlc.narg(end_time)::timestamptz IS NULL
+        OR started_at < sqlc.narg(end_time)::timestamptz)
+    AND NOT (@exclude_skipped::boolean
+        AND skipped)
+ORDER BY
+    started_at ASC;
+
+-- name: RoomInviteInsert :one
+INSERT INTO room_invites(
+    room_id,
+    role,
+    created_by,
+    expires_at,
+    max_uses)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5)
+RETURNING
+    *;
+
+-- name: RoomInviteGetActive :many
+SELECT
+    *
+FROM
+    room_invites
+WHERE
+    room_id = $1
+    AND revoked_at IS NULL
+    AND (expires_at IS NULL
+        OR expires_at > now())
+    AND (max_uses IS NULL
+        OR uses < max_uses)
+ORDER BY
+    created DESC;
+
+-- name: RoomInviteUse :execrows
+UPDATE
+    room_invites
+SET
+    uses = uses + 1
+WHERE
+    id = $1
+    AND room_id = $2
+    AND revoked_at IS NULL
+    AND (expires_at IS NULL
+        OR expires_at > now())
+    AND (max_uses IS NULL
+        OR uses < max_uses);
+
+-- name: RoomInviteRevoke :execrows
+UPDATE
+    room_invites
+SET
+    revoked_at = now()
+WHERE
+    id = $1
+    AND room_id = $2
+    AND revoked_at IS NULL;
+
+-- name: RoomGuestSetInvite :exec
+UPDATE
+    room_guests
+SET
+    invite_id = $3
+WHERE
+    room_id = $1
+    AND id = $2;
+
+-- name: RoomGuestHasInvite :one
+SELECT
+    EXISTS (
+        SELECT
+            1
+        FROM
+            room_guests g
+            JOIN room_invites i ON i.id = g.invite_id
+        WHERE
+            g.room_id = $1
+            AND g.id = @guest_id::uuid
+            AND i.id = @invite_id::uuid
+            AND i.revoked_at IS NULL
+            AND (i.expires_at IS NULL
+                OR i.expires_at > now()));
+
+-- name: RoomAddMemberWithRole :exec
+INSERT INTO room_members(
+    user_id,
+    room_id,
+    is_moderator)
+VALUES (
+    $1,
+    $2,
+    $3)
+ON CONFLICT (user_id,
+    room_id)
+    DO UPDATE SET
+        is_moderator = room_members.is_moderator
+        OR EXCLUDED.is_moderator;
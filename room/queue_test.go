@@ -0,0 +1,157 @@
+package room
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db/dbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func pendingTrackIDs(pending []PendingTrack) []string {
+	ids := []string{}
+	for _, track := range pending {
+		ids = append(ids, track.TrackID)
+	}
+	return ids
+}
+
+func TestFindPendingTrack(t *testing.T) {
+	pending := []PendingTrack{{ID: "a", TrackID: "track0"}, {ID: "b", TrackID: "track1"}}
+
+	track, ok := FindPendingTrack(pending, "track1")
+	assert.True(t, ok)
+	assert.Equal(t, "b", track.ID)
+
+	_, ok = FindPendingTrack(pending, "track2")
+	assert.False(t, ok)
+}
+
+func TestPendingQueue(t *testing.T) {
+	pool := dbtest.New(t)
+	dbtest.Seed(t, pool)
+	ctx := context.Background()
+	roomID := dbtest.RoomID.String()
+	aliceID := dbtest.AliceID.String()
+	bobID := dbtest.BobID.String()
+
+	guest, err := InsertGuest(ctx, pool, dbtest.RoomCode, "Guest")
+	assert.NoError(t, err)
+
+	// added oldest first, so with no votes they're pending in this order
+	assert.NoError(t, SetQueueTrackUser(ctx, pool, dbtest.RoomCode, "qstrack000000000000000", aliceID))
+	assert.NoError(t, SetQueueTrackGuest(ctx, pool, dbtest.RoomCode, "qstrack000000000000001", guest.ID))
+	assert.NoError(t, SetQueueTrackUser(ctx, pool, dbtest.RoomCode, "qstrack000000000000002", bobID))
+
+	pending, err := GetPendingQueue(ctx, pool, roomID, "")
+	assert.NoError(t, err)
+	if !assert.Len(t, pending, 3) {
+		return
+	}
+	assert.Equal(t, []string{"qstrack000000000000000", "qstrack000000000000001", "qstrack000000000000002"}, pendingTrackIDs(pending))
+	assert.Equal(t, "Guest", pending[1].AddedBy)
+	track0, track1, track2 := pending[0].ID, pending[1].ID, pending[2].ID
+
+	t.Run("votes", func(t *testing.T) {
+		assert.NoError(t, VoteOnQueueTrack(ctx, pool, roomID, track2, aliceID, 1))
+		assert.NoError(t, VoteOnQueueTrack(ctx, pool, roomID, track2, guest.ID, 1))
+		assert.NoError(t, VoteOnQueueTrack(ctx, pool, roomID, track0, bobID, -1))
+
+		pending, err := GetPendingQueue(ctx, pool, roomID, aliceID)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"qstrack000000000000002", "qstrack000000000000001", "qstrack000000000000000"}, pendingTrackIDs(pending))
+		assert.Equal(t, 2, pending[0].Score)
+		assert.Equal(t, 1, pending[0].Vote)
+		assert.Equal(t, -1, pending[2].Score)
+		assert.Equal(t, 0, pending[2].Vote)
+
+		// voting again replaces the vote, and a vote of 0 removes it
+		assert.NoError(t, VoteOnQueueTrack(ctx, pool, roomID, track2, aliceID, -1))
+		assert.NoError(t, VoteOnQueueTrack(ctx, pool, roomID, track0, bobID, 0))
+		pending, err = GetPendingQueue(ctx, pool, roomID, "")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"qstrack000000000000000", "qstrack000000000000001", "qstrack000000000000002"}, pendingTrackIDs(pending))
+		assert.Equal(t, 0, pending[2].Score)
+	})
+
+	t.Run("upvote instead of duplicate", func(t *testing.T) {
+		pending, err := GetPendingQueue(ctx, pool, roomID, "")
+		assert.NoError(t, err)
+
+		existing, ok := FindPendingTrack(pending, "qstrack000000000000001")
+		if !assert.True(t, ok) {
+			return
+		}
+		assert.NoError(t, VoteOnQueueTrack(ctx, pool, roomID, existing.ID, bobID, 1))
+
+		pending, err = GetPendingQueue(ctx, pool, roomID, "")
+		assert.NoError(t, err)
+		assert.Len(t, pending, 3)
+		assert.Equal(t, "qstrack000000000000001", pending[0].TrackID)
+		assert.Equal(t, 1, pending[0].Score)
+	})
+
+	t.Run("reorder", func(t *testing.T) {
+		assert.NoError(t, ReorderPendingQueue(ctx, pool, roomID, []string{track2, track0}))
+
+		pending, err := GetPendingQueue(ctx, pool, roomID, "")
+		assert.NoError(t, err)
+		// unlisted tracks fall back to being ordered by votes, after listed ones
+		assert.Equal(t, []string{"qstrack000000000000002", "qstrack000000000000000", "qstrack000000000000001"}, pendingTrackIDs(pending))
+
+		// reordering again replaces the previous order
+		assert.NoError(t, ReorderPendingQueue(ctx, pool, roomID, []string{track0}))
+		pending, err = GetPendingQueue(ctx, pool, roomID, "")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"qstrack000000000000000", "qstrack000000000000001", "qstrack000000000000002"}, pendingTrackIDs(pending))
+	})
+
+	t.Run("pushed tracks", func(t *testing.T) {
+		assert.NoError(t, SetQueueTrackPushed(ctx, pool, track0))
+
+		pending, err := GetPendingQueue(ctx, pool, roomID, "")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"qstrack000000000000001", "qstrack000000000000002"}, pendingTrackIDs(pending))
+
+		pushed, err := GetPushedTrackIDs(ctx, pool, roomID)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"qstrack000000000000000"}, pushed)
+
+		// pushed tracks can't be voted on or removed
+		assert.Error(t, VoteOnQueueTrack(ctx, pool, roomID, track0, aliceID, 1))
+		assert.Error(t, RemovePendingTrack(ctx, pool, roomID, track0))
+		assert.NoError(t, RemovePendingTrack(ctx, pool, roomID, track1))
+	})
+}
+
+func TestLockQueueFill(t *testing.T) {
+	pool := dbtest.New(t)
+	dbtest.Seed(t, pool)
+	ctx := context.Background()
+	roomID := dbtest.RoomID.String()
+
+	tx, err := pool.Begin(ctx)
+	assert.NoError(t, err)
+	defer tx.Rollback(ctx)
+	assert.NoError(t, LockQueueFill(ctx, tx, roomID))
+
+	t.Run("waits for the other fill", func(t *testing.T) {
+		other, err := pool.Begin(ctx)
+		assert.NoError(t, err)
+		defer other.Rollback(ctx)
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*200)
+		defer cancel()
+		assert.Error(t, LockQueueFill(timeoutCtx, other, roomID))
+	})
+
+	t.Run("released on commit", func(t *testing.T) {
+		assert.NoError(t, tx.Commit(ctx))
+
+		other, err := pool.Begin(ctx)
+		assert.NoError(t, err)
+		defer other.Rollback(ctx)
+		assert.NoError(t, LockQueueFill(ctx, other, roomID))
+	})
+}
@@ -7,11 +7,14 @@ import (
 )
 
 type Room struct {
-	ID      string    `json:"id"`
-	Code    string    `json:"code"`
-	Name    string    `json:"name"`
-	Host    user.User `json:"host"`
-	Created time.Time `json:"created"`
+	ID               string     `json:"id"`
+	Code             string     `json:"code"`
+	Name             string     `json:"name"`
+	Host             user.User  `json:"host"`
+	Created          time.Time  `json:"created"`
+	Schedule         *Schedule  `json:"schedule,omitempty"`
+	ClosesAt         *time.Time `json:"closes_at,omitempty"`
+	SecondsRemaining *int       `json:"seconds_remaining,omitempty"`
 }
 
 type InsertRoomParams struct {
@@ -36,8 +39,22 @@ type RoomResponse struct {
 }
 
 type QueuedTrack struct {
+	ID        string
 	TrackID   string
+	GuestID   string
+	UserID    string
 	AddedBy   string
 	Timestamp time.Time
 	Played    bool
+	Score     int
+}
+
+type PendingTrack struct {
+	ID        string    `json:"id"`
+	TrackID   string    `json:"track_id"`
+	AddedBy   string    `json:"added_by"`
+	Timestamp time.Time `json:"timestamp"`
+	Priority  int       `json:"priority"`
+	Score     int       `json:"score"`
+	Vote      int       `json:"vote"`
 }
@@ -0,0 +1,290 @@
+package room
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/google/uuid"
+)
+
+const (
+	RecurrenceDaily  = "daily"
+	RecurrenceWeekly = "weekly"
+)
+
+// Schedule opens a room when StartsAt arrives and closes it at EndsAt or after
+// it has gone IdleTimeoutMinutes without a track being queued. Recurring rooms
+// open again for the same window on the next day or week.
+type Schedule struct {
+	StartsAt           *time.Time `json:"starts_at"`
+	EndsAt             *time.Time `json:"ends_at"`
+	IdleTimeoutMinutes *int       `json:"idle_timeout_minutes"`
+	Recurrence         *string    `json:"recurrence"`
+}
+
+// ScheduleState is a scheduled room's schedule along with what the engine needs
+// to decide whether to open or close it
+type ScheduleState struct {
+	Schedule
+	IsOpen       bool
+	LastOpened   *time.Time
+	LastActivity time.Time
+}
+
+type ScheduledRoom struct {
+	ID    string
+	Code  string
+	State ScheduleState
+}
+
+func (s Schedule) Validate() error {
+	if s.StartsAt != nil && s.EndsAt != nil && !s.EndsAt.After(*s.StartsAt) {
+		return errors.New("end must be after start")
+	}
+	if s.IdleTimeoutMinutes != nil && *s.IdleTimeoutMinutes <= 0 {
+		return errors.New("idle timeout must be positive")
+	}
+	if s.Recurrence == nil {
+		return nil
+	}
+	if *s.Recurrence != RecurrenceDaily && *s.Recurrence != RecurrenceWeekly {
+		return fmt.Errorf("recurrence must be '%s' or '%s'", RecurrenceDaily, RecurrenceWeekly)
+	}
+	if s.StartsAt == nil || s.EndsAt == nil {
+		return errors.New("recurring schedules need a start and an end")
+	}
+	if s.EndsAt.After(s.StartsAt.AddDate(0, 0, s.recurrenceDays())) {
+		return fmt.Errorf("a %s schedule cannot overlap its next occurrence", *s.Recurrence)
+	}
+	return nil
+}
+
+func (s Schedule) recurrenceDays() int {
+	if s.Recurrence == nil {
+		return 0
+	}
+	switch *s.Recurrence {
+	case RecurrenceDaily:
+		return 1
+	case RecurrenceWeekly:
+		return 7
+	}
+	return 0
+}
+
+// withinWindow returns whether now is between the schedule's start and end,
+// treating a missing start or end as open-ended
+func (s Schedule) withinWindow(now time.Time) bool {
+	if s.StartsAt != nil && now.Before(*s.StartsAt) {
+		return false
+	}
+	return s.EndsAt == nil || now.Before(*s.EndsAt)
+}
+
+// shiftWindow moves a recurring schedule forward a day or week at a time until
+// the time returned by from is after now. It returns whether the window moved.
+func (s *Schedule) shiftWindow(now time.Time, from func(Schedule) time.Time) bool {
+	days := s.recurrenceDays()
+	if days == 0 || s.StartsAt == nil || s.EndsAt == nil {
+		return false
+	}
+	shifted := false
+	for !from(*s).After(now) {
+		start := s.StartsAt.AddDate(0, 0, days)
+		end := s.EndsAt.AddDate(0, 0, days)
+		s.StartsAt, s.EndsAt = &start, &end
+		shifted = true
+	}
+	return shifted
+}
+
+func scheduleStart(s Schedule) time.Time {
+	return *s.StartsAt
+}
+
+func scheduleEnd(s Schedule) time.Time {
+	return *s.EndsAt
+}
+
+// ClosesAt returns when an open room will be closed, or nil if nothing in its
+// schedule will close it
+func (s ScheduleState) ClosesAt() *time.Time {
+	if !s.IsOpen {
+		return nil
+	}
+	closesAt := s.EndsAt
+	if s.IdleTimeoutMinutes != nil {
+		lastActive := s.LastActivity
+		if s.LastOpened != nil && s.LastOpened.After(lastActive) {
+			lastActive = *s.LastOpened
+		}
+		idleAt := lastActive.Add(time.Duration(*s.IdleTimeoutMinutes) * time.Minute)
+		if closesAt == nil || idleAt.Before(*closesAt) {
+			closesAt = &idleAt
+		}
+	}
+	return closesAt
+}
+
+// Next returns the state the room should be in at now, and whether that differs
+// from its current state. Open rooms are closed once their window ends or they
+// have been idle too long, and closed rooms are opened once per window when it
+// starts. Recurring windows move to their next occurrence once they are over.
+func (s ScheduleState) Next(now time.Time) (ScheduleState, bool) {
+	next := s
+	if next.IsOpen {
+		closesAt := next.ClosesAt()
+		if closesAt == nil || now.Before(*closesAt) {
+			return next, false
+		}
+		next.IsOpen = false
+		next.shiftWindow(now, scheduleStart)
+		return next, true
+	}
+
+	shifted := next.shiftWindow(now, scheduleEnd)
+	if next.StartsAt == nil || !next.withinWindow(now) {
+		return next, shifted
+	}
+	// a room closed during its window stays closed until the next one
+	if next.LastOpened != nil && !next.LastOpened.Before(*next.StartsAt) {
+		return next, shifted
+	}
+	next.IsOpen = true
+	next.LastOpened = &now
+	return next, true
+}
+
+func GetSchedule(ctx context.Context, dbtx db.DBTX, roomID string) (*Schedule, error) {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("parse room UUID: %w", err)
+	}
+
+	row, err := db.New(dbtx).RoomGetSchedule(ctx, roomUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Schedule{
+		StartsAt:           row.StartsAt,
+		EndsAt:             row.EndsAt,
+		IdleTimeoutMinutes: intPtrFromInt32(row.IdleTimeoutMinutes),
+		Recurrence:         row.Recurrence,
+	}, nil
+}
+
+// SetSchedule saves the room's schedule and opens or closes the room depending
+// on whether its window has started
+func SetSchedule(ctx context.Context, dbtx db.DBTX, roomID string, schedule Schedule, now time.Time) error {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return fmt.Errorf("parse room UUID: %w", err)
+	}
+
+	schedule.shiftWindow(now, scheduleEnd)
+	isOpen := schedule.withinWindow(now)
+	var lastOpened *time.Time
+	if isOpen {
+		lastOpened = &now
+	}
+
+	err = db.New(dbtx).RoomSetSchedule(ctx, db.RoomSetScheduleParams{
+		RoomID:             roomUUID,
+		StartsAt:           schedule.StartsAt,
+		EndsAt:             schedule.EndsAt,
+		IdleTimeoutMinutes: int32PtrFromInt(schedule.IdleTimeoutMinutes),
+		Recurrence:         schedule.Recurrence,
+		LastOpened:         lastOpened,
+	})
+	if err != nil {
+		return err
+	}
+
+	return SetIsOpen(ctx, dbtx, roomID, isOpen)
+}
+
+// DeleteSchedule removes the room's schedule and reopens it
+func DeleteSchedule(ctx context.Context, dbtx db.DBTX, roomID string) error {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return fmt.Errorf("parse room UUID: %w", err)
+	}
+
+	err = db.New(dbtx).RoomDeleteSchedule(ctx, roomUUID)
+	if err != nil {
+		return err
+	}
+
+	return SetIsOpen(ctx, dbtx, roomID, true)
+}
+
+func GetScheduledRooms(ctx context.Context, dbtx db.DBTX) ([]ScheduledRoom, error) {
+	rows, err := db.New(dbtx).RoomScheduleGetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rooms := make([]ScheduledRoom, 0, len(rows))
+	for _, row := range rows {
+		rooms = append(rooms, ScheduledRoom{
+			ID:   row.ID.String(),
+			Code: row.Code,
+			State: ScheduleState{
+				Schedule: Schedule{
+					StartsAt:           row.StartsAt,
+					EndsAt:             row.EndsAt,
+					IdleTimeoutMinutes: intPtrFromInt32(row.IdleTimeoutMinutes),
+					Recurrence:         row.Recurrence,
+				},
+				IsOpen:       row.IsOpen,
+				LastOpened:   row.LastOpened,
+				LastActivity: row.LastActivity,
+			},
+		})
+	}
+	return rooms, nil
+}
+
+// UpdateScheduleState saves the room's window and when it was last opened, and
+// opens or closes it
+func UpdateScheduleState(ctx context.Context, dbtx db.DBTX, roomID string, state ScheduleState) error {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return fmt.Errorf("parse room UUID: %w", err)
+	}
+
+	err = db.New(dbtx).RoomScheduleUpdateState(ctx, db.RoomScheduleUpdateStateParams{
+		RoomID:     roomUUID,
+		StartsAt:   state.StartsAt,
+		EndsAt:     state.EndsAt,
+		LastOpened: state.LastOpened,
+	})
+	if err != nil {
+		return err
+	}
+
+	return SetIsOpen(ctx, dbtx, roomID, state.IsOpen)
+}
+
+// addSchedule adds the room's schedule and the time left until it closes to a
+// hosted or joined room listing
+func (r *Room) addSchedule(state ScheduleState, now time.Time) {
+	if state.StartsAt == nil && state.EndsAt == nil && state.IdleTimeoutMinutes == nil {
+		return
+	}
+	schedule := state.Schedule
+	r.Schedule = &schedule
+	r.ClosesAt = state.ClosesAt()
+	if r.ClosesAt != nil {
+		remaining := max(int(r.ClosesAt.Sub(now).Seconds()), 0)
+		r.SecondsRemaining = &remaining
+	}
+}
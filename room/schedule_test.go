@@ -0,0 +1,99 @@
+package room
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleNext(t *testing.T) {
+	start := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour * 8)
+	weekly := RecurrenceWeekly
+	idleMinutes := 30
+
+	t.Run("opens when window starts", func(t *testing.T) {
+		state := ScheduleState{Schedule: Schedule{StartsAt: &start, EndsAt: &end}}
+		next, changed := state.Next(start.Add(time.Minute))
+		assert.True(t, changed)
+		assert.True(t, next.IsOpen)
+	})
+
+	t.Run("stays closed before window", func(t *testing.T) {
+		state := ScheduleState{Schedule: Schedule{StartsAt: &start, EndsAt: &end}}
+		_, changed := state.Next(start.Add(-time.Minute))
+		assert.False(t, changed)
+	})
+
+	t.Run("closes when window ends", func(t *testing.T) {
+		state := ScheduleState{Schedule: Schedule{StartsAt: &start, EndsAt: &end}, IsOpen: true, LastOpened: &start}
+		next, changed := state.Next(end)
+		assert.True(t, changed)
+		assert.False(t, next.IsOpen)
+		assert.Equal(t, start, *next.StartsAt)
+	})
+
+	t.Run("closes when idle", func(t *testing.T) {
+		state := ScheduleState{
+			Schedule:     Schedule{IdleTimeoutMinutes: &idleMinutes},
+			IsOpen:       true,
+			LastActivity: start,
+		}
+		_, changed := state.Next(start.Add(time.Minute * 29))
+		assert.False(t, changed)
+		next, changed := state.Next(start.Add(time.Minute * 30))
+		assert.True(t, changed)
+		assert.False(t, next.IsOpen)
+	})
+
+	t.Run("idle time counts from opening", func(t *testing.T) {
+		opened := start.Add(time.Hour)
+		state := ScheduleState{
+			Schedule:     Schedule{IdleTimeoutMinutes: &idleMinutes},
+			IsOpen:       true,
+			LastOpened:   &opened,
+			LastActivity: start,
+		}
+		assert.Equal(t, opened.Add(time.Minute*30), *state.ClosesAt())
+	})
+
+	t.Run("recurring room moves to next week after closing", func(t *testing.T) {
+		state := ScheduleState{
+			Schedule:   Schedule{StartsAt: &start, EndsAt: &end, Recurrence: &weekly},
+			IsOpen:     true,
+			LastOpened: &start,
+		}
+		next, changed := state.Next(end.Add(time.Minute))
+		assert.True(t, changed)
+		assert.False(t, next.IsOpen)
+		assert.Equal(t, start.AddDate(0, 0, 7), *next.StartsAt)
+		assert.Equal(t, end.AddDate(0, 0, 7), *next.EndsAt)
+
+		reopened, changed := next.Next(start.AddDate(0, 0, 7))
+		assert.True(t, changed)
+		assert.True(t, reopened.IsOpen)
+	})
+
+	t.Run("room closed early stays closed until next window", func(t *testing.T) {
+		opened := start.Add(time.Minute)
+		state := ScheduleState{Schedule: Schedule{StartsAt: &start, EndsAt: &end}, LastOpened: &opened}
+		_, changed := state.Next(start.Add(time.Hour))
+		assert.False(t, changed)
+	})
+}
+
+func TestScheduleValidate(t *testing.T) {
+	start := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour * 8)
+	daily := RecurrenceDaily
+	monthly := "monthly"
+
+	assert.NoError(t, Schedule{StartsAt: &start, EndsAt: &end, Recurrence: &daily}.Validate())
+	assert.Error(t, Schedule{StartsAt: &end, EndsAt: &start}.Validate())
+	assert.Error(t, Schedule{StartsAt: &start, Recurrence: &daily}.Validate())
+	assert.Error(t, Schedule{StartsAt: &start, EndsAt: &end, Recurrence: &monthly}.Validate())
+
+	tooLong := start.AddDate(0, 0, 2)
+	assert.Error(t, Schedule{StartsAt: &start, EndsAt: &tooLong, Recurrence: &daily}.Validate())
+}
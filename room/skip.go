@@ -0,0 +1,250 @@
+package room
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+const (
+	// guests and members who added or voted on a track this recently count
+	// towards a percentage skip threshold
+	active_participant_window = time.Minute * 30
+)
+
+type SkipVoteStatus struct {
+	TrackID  string `json:"track_id"`
+	Enabled  bool   `json:"enabled"`
+	Votes    int    `json:"votes"`
+	Required int    `json:"required"`
+	Voted    bool   `json:"voted"`
+	Skipped  bool   `json:"skipped"`
+}
+
+type Skip struct {
+	ID            string    `json:"id"`
+	TrackID       string    `json:"track_id"`
+	VoteCount     int       `json:"vote_count"`
+	RequiredVotes int       `json:"required_votes"`
+	Timestamp     time.Time `json:"timestamp"`
+	Voters        []string  `json:"voters"`
+}
+
+// SkipVotesRequired returns the number of votes needed to skip a track. If both
+// a count and a percentage are set, whichever is reached first skips the track.
+func (p QueuePolicy) SkipVotesRequired(activeParticipants int) (int, bool) {
+	if p.SkipVoteCount == nil && p.SkipVotePercent == nil {
+		return 0, false
+	}
+
+	required := -1
+	if p.SkipVoteCount != nil {
+		required = *p.SkipVoteCount
+	}
+	if p.SkipVotePercent != nil {
+		// round up so that 50% of 3 participants needs 2 votes
+		percent := *p.SkipVotePercent
+		fromPercent := (activeParticipants*percent + 99) / 100
+		if required < 0 || fromPercent < required {
+			required = fromPercent
+		}
+	}
+
+	return max(required, 1), true
+}
+
+// GetSkipVoteStatus returns the votes to skip the track that is currently playing
+// in the room
+func GetSkipVoteStatus(ctx context.Context, dbtx db.DBTX, roomID string, trackID string, voterID string) (*SkipVoteStatus, error) {
+	policy, err := GetQueuePolicy(ctx, dbtx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("get queue policy: %w", err)
+	}
+
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("parse room UUID: %w", err)
+	}
+
+	voters, err := db.New(dbtx).RoomSkipVoteGetVoters(ctx, db.RoomSkipVoteGetVotersParams{
+		RoomID:  roomUUID,
+		TrackID: trackID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get skip voters: %w", err)
+	}
+
+	activeCount, err := db.New(dbtx).RoomCountActiveParticipants(ctx, db.RoomCountActiveParticipantsParams{
+		RoomID: roomUUID,
+		Since:  time.Now().Add(-active_participant_window),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("count active participants: %w", err)
+	}
+
+	required, enabled := policy.SkipVotesRequired(int(activeCount))
+	return &SkipVoteStatus{
+		TrackID:  trackID,
+		Enabled:  enabled,
+		Votes:    len(voters),
+		Required: required,
+		Voted: lo.ContainsBy(voters, func(id uuid.UUID) bool {
+			return id.String() == voterID
+		}),
+	}, nil
+}
+
+// AddSkipVote records a vote to skip the track, discarding any votes left over
+// from tracks that have stopped playing
+func AddSkipVote(ctx context.Context, dbtx db.DBTX, roomID string, trackID string, voterID string) error {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return fmt.Errorf("parse room UUID: %w", err)
+	}
+	voterUUID, err := uuid.Parse(voterID)
+	if err != nil {
+		return fmt.Errorf("parse voter UUID: %w", err)
+	}
+
+	err = db.New(dbtx).RoomSkipVoteResetStale(ctx, db.RoomSkipVoteResetStaleParams{
+		RoomID:  roomUUID,
+		TrackID: trackID,
+	})
+	if err != nil {
+		return fmt.Errorf("reset skip votes: %w", err)
+	}
+
+	return db.New(dbtx).RoomSkipVoteInsert(ctx, db.RoomSkipVoteInsertParams{
+		RoomID:  roomUUID,
+		VoterID: voterUUID,
+		TrackID: trackID,
+	})
+}
+
+func RemoveSkipVote(ctx context.Context, dbtx db.DBTX, roomID string, voterID string) error {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return fmt.Errorf("parse room UUID: %w", err)
+	}
+	voterUUID, err := uuid.Parse(voterID)
+	if err != nil {
+		return fmt.Errorf("parse voter UUID: %w", err)
+	}
+
+	return db.New(dbtx).RoomSkipVoteDelete(ctx, db.RoomSkipVoteDeleteParams{
+		RoomID:  roomUUID,
+		VoterID: voterUUID,
+	})
+}
+
+// RecordSkip saves the skip and the guests and members who voted for it, marks
+// the track as skipped in the play log, then clears the room's votes for the
+// next track. startedAt is when the track started playing, used if the play
+// hasn't been logged yet. Each play of a track is only recorded once, and false
+// is returned if it was already skipped.
+func RecordSkip(ctx context.Context, dbtx db.DBTX, roomID string, status SkipVoteStatus, startedAt time.Time) (bool, error) {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return false, fmt.Errorf("parse room UUID: %w", err)
+	}
+
+	playedAt, err := skippedPlayStart(ctx, dbtx, roomUUID, status.TrackID, startedAt)
+	if err != nil {
+		return false, fmt.Errorf("get skipped play: %w", err)
+	}
+
+	skipID, err := db.New(dbtx).RoomSkipInsert(ctx, db.RoomSkipInsertParams{
+		RoomID:        roomUUID,
+		TrackID:       status.TrackID,
+		VoteCount:     int32(status.Votes),
+		RequiredVotes: int32(status.Required),
+		PlayedAt:      playedAt,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("insert skip: %w", err)
+	}
+
+	err = db.New(dbtx).RoomSkipInsertVoters(ctx, db.RoomSkipInsertVotersParams{
+		SkipID:  skipID,
+		RoomID:  roomUUID,
+		TrackID: status.TrackID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("insert skip voters: %w", err)
+	}
+
+	err = markSkippedByVote(ctx, dbtx, roomUUID, status.TrackID, status.Votes)
+	if err != nil {
+		return false, fmt.Errorf("mark play skipped: %w", err)
+	}
+
+	err = db.New(dbtx).RoomSkipVoteClear(ctx, roomUUID)
+	if err != nil {
+		return false, fmt.Errorf("clear skip votes: %w", err)
+	}
+
+	return true, nil
+}
+
+// skippedPlayStart returns when the skipped play started, preferring the play
+// log so that every vote on the same play agrees
+func skippedPlayStart(ctx context.Context, dbtx db.DBTX, roomUUID uuid.UUID, trackID string, startedAt time.Time) (time.Time, error) {
+	latest, err := db.New(dbtx).RoomPlayLogGetLatest(ctx, roomUUID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, err
+	}
+	if err == nil && !isNewPlay(latest, NowPlaying{TrackID: trackID, StartedAt: startedAt}) {
+		return latest.StartedAt, nil
+	}
+	return startedAt.Truncate(time.Second), nil
+}
+
+func GetSkips(ctx context.Context, dbtx db.DBTX, roomID string) ([]Skip, error) {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("parse room UUID: %w", err)
+	}
+
+	rows, err := db.New(dbtx).RoomSkipGetAll(ctx, roomUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	voterRows, err := db.New(dbtx).RoomSkipGetVoters(ctx, roomUUID)
+	if err != nil {
+		return nil, fmt.Errorf("get skip voters: %w", err)
+	}
+	votersBySkip := map[uuid.UUID][]string{}
+	for _, voter := range voterRows {
+		name := ""
+		if voter.GuestName != nil {
+			name = *voter.GuestName
+		} else if voter.MemberName != nil {
+			name = *voter.MemberName
+		}
+		votersBySkip[voter.SkipID] = append(votersBySkip[voter.SkipID], name)
+	}
+
+	skips := make([]Skip, 0, len(rows))
+	for _, row := range rows {
+		skips = append(skips, Skip{
+			ID:            row.ID.String(),
+			TrackID:       row.TrackID,
+			VoteCount:     int(row.VoteCount),
+			RequiredVotes: int(row.RequiredVotes),
+			Timestamp:     row.Timestamp,
+			Voters:        votersBySkip[row.ID],
+		})
+	}
+
+	return skips, nil
+}
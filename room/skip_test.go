@@ -0,0 +1,49 @@
+package room
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db/dbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordSkip(t *testing.T) {
+	pool := dbtest.New(t)
+	dbtest.Seed(t, pool)
+	ctx := context.Background()
+	roomID := dbtest.RoomID.String()
+	trackID := "qstrack000000000000000"
+	startedAt := time.Date(2026, 10, 17, 20, 0, 0, 0, time.UTC)
+
+	err := LogNowPlaying(ctx, pool, roomID, NowPlaying{TrackID: trackID, StartedAt: startedAt, DurationMS: 180000}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, AddSkipVote(ctx, pool, roomID, trackID, dbtest.AliceID.String()))
+
+	status := SkipVoteStatus{TrackID: trackID, Enabled: true, Votes: 1, Required: 1}
+	recorded, err := RecordSkip(ctx, pool, roomID, status, startedAt.Add(time.Second))
+	assert.NoError(t, err)
+	assert.True(t, recorded)
+
+	t.Run("same play skipped again", func(t *testing.T) {
+		// the start time from the player drifts, but the play log is the same
+		recorded, err := RecordSkip(ctx, pool, roomID, status, startedAt.Add(time.Second*2))
+		assert.NoError(t, err)
+		assert.False(t, recorded)
+
+		skips, err := GetSkips(ctx, pool, roomID)
+		assert.NoError(t, err)
+		assert.Len(t, skips, 1)
+	})
+
+	t.Run("replayed", func(t *testing.T) {
+		recorded, err := RecordSkip(ctx, pool, roomID, status, startedAt.Add(time.Minute*10))
+		assert.NoError(t, err)
+		assert.True(t, recorded)
+
+		skips, err := GetSkips(ctx, pool, roomID)
+		assert.NoError(t, err)
+		assert.Len(t, skips, 2)
+	})
+}
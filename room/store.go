@@ -12,6 +12,7 @@ import (
 	"github.com/andrewbenington/queue-share-api/user"
 	"github.com/andrewbenington/queue-share-api/util"
 	"github.com/google/uuid"
+	"github.com/samber/lo"
 	"golang.org/x/oauth2"
 )
 
@@ -74,12 +75,16 @@ func Insert(ctx context.Context, dbtx db.DBTX, insertParams InsertRoomParams) (R
 	if err != nil {
 		return Room{}, fmt.Errorf("parse user UUID: %w", err)
 	}
+	passwordHash, err := auth.HashPassword(insertParams.Password)
+	if err != nil {
+		return Room{}, fmt.Errorf("hash password: %w", err)
+	}
 	row, err := db.New(dbtx).RoomInsertWithPassword(
 		ctx,
 		db.RoomInsertWithPasswordParams{
-			Name:     insertParams.Name,
-			HostID:   hostUUID,
-			RoomPass: insertParams.Password,
+			Name:         insertParams.Name,
+			HostID:       hostUUID,
+			PasswordHash: passwordHash,
 		},
 	)
 	if err != nil {
@@ -113,11 +118,29 @@ func UpdateSpotifyToken(ctx context.Context, dbtx db.DBTX, code string, oauthTok
 	})
 }
 
+// ValidatePassword checks the password against the room's argon2id or legacy
+// bcrypt hash. Passwords with a legacy or outdated hash are rehashed when they
+// match.
 func ValidatePassword(ctx context.Context, dbtx db.DBTX, code string, password string) (bool, error) {
-	return db.New(dbtx).RoomValidatePassword(ctx, db.RoomValidatePasswordParams{
-		Code:     strings.ToUpper(code),
-		RoomPass: password,
-	})
+	row, err := db.New(dbtx).RoomGetPasswordHash(ctx, strings.ToUpper(code))
+	if err != nil {
+		return false, err
+	}
+	if row.EncryptedPassword == nil {
+		return false, nil
+	}
+
+	matches, needsRehash, err := auth.VerifyPassword(*row.EncryptedPassword, password)
+	if err != nil {
+		return false, fmt.Errorf("verify password: %w", err)
+	}
+	if matches && needsRehash {
+		err = UpdatePassword(ctx, dbtx, row.RoomID.String(), password)
+		if err != nil {
+			return false, fmt.Errorf("rehash password: %w", err)
+		}
+	}
+	return matches, nil
 }
 
 func AddMember(ctx context.Context, dbtx db.DBTX, roomID string, userID string) error {
@@ -344,12 +367,21 @@ func GetQueueTrackAddedBy(ctx context.Context, dbtx db.DBTX, roomID string) (tra
 		} else if row.MemberName != nil {
 			addedBy = *row.MemberName
 		}
-		tracks = append(tracks, QueuedTrack{
+		track := QueuedTrack{
+			ID:        row.ID.String(),
 			TrackID:   row.TrackID,
 			AddedBy:   addedBy,
 			Timestamp: row.Timestamp,
 			Played:    row.Played,
-		})
+			Score:     int(row.Score),
+		}
+		if row.GuestID != nil {
+			track.GuestID = row.GuestID.String()
+		}
+		if row.UserID != nil {
+			track.UserID = row.UserID.String()
+		}
+		tracks = append(tracks, track)
 	}
 
 	return
@@ -365,9 +397,14 @@ func UpdatePassword(ctx context.Context, dbtx db.DBTX, roomID string, newPasswor
 		return fmt.Errorf("parse room UUID: %w", err)
 	}
 
+	passwordHash, err := auth.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+
 	return db.New(dbtx).RoomUpdatePassword(ctx, db.RoomUpdatePasswordParams{
-		RoomID:   roomUUID,
-		RoomPass: newPassword,
+		RoomID:       roomUUID,
+		PasswordHash: passwordHash,
 	})
 }
 
@@ -384,6 +421,7 @@ func GetUserHostedRooms(ctx context.Context, dbtx db.DBTX, userID string, isOpen
 		return nil, err
 	}
 
+	now := time.Now()
 	rooms := make([]Room, len(rows))
 	for i, row := range rows {
 		rooms[i] = Room{
@@ -398,6 +436,17 @@ func GetUserHostedRooms(ctx context.Context, dbtx db.DBTX, userID string, isOpen
 			},
 			Created: row.Created,
 		}
+		rooms[i].addSchedule(ScheduleState{
+			Schedule: Schedule{
+				StartsAt:           row.StartsAt,
+				EndsAt:             row.EndsAt,
+				IdleTimeoutMinutes: intPtrFromInt32(row.IdleTimeoutMinutes),
+				Recurrence:         row.Recurrence,
+			},
+			IsOpen:       isOpen,
+			LastOpened:   row.LastOpened,
+			LastActivity: row.LastActivity,
+		}, now)
 	}
 	return rooms, nil
 }
@@ -415,6 +464,7 @@ func GetUserJoinedRooms(ctx context.Context, dbtx db.DBTX, userID string, isOpen
 		return nil, err
 	}
 
+	now := time.Now()
 	rooms := make([]Room, len(rows))
 	for i, row := range rows {
 		rooms[i] = Room{
@@ -429,6 +479,17 @@ func GetUserJoinedRooms(ctx context.Context, dbtx db.DBTX, userID string, isOpen
 			},
 			Created: row.Created,
 		}
+		rooms[i].addSchedule(ScheduleState{
+			Schedule: Schedule{
+				StartsAt:           row.StartsAt,
+				EndsAt:             row.EndsAt,
+				IdleTimeoutMinutes: intPtrFromInt32(row.IdleTimeoutMinutes),
+				Recurrence:         row.Recurrence,
+			},
+			IsOpen:       isOpen,
+			LastOpened:   row.LastOpened,
+			LastActivity: row.LastActivity,
+		}, now)
 	}
 	return rooms, nil
 }
@@ -451,7 +512,188 @@ func MarkTracksAsPlayedSince(ctx context.Context, dbtx db.DBTX, roomID string, s
 	}
 
 	return db.New(dbtx).RoomMarkTracksAsPlayed(ctx, db.RoomMarkTracksAsPlayedParams{
-		RoomID:    roomUUID,
-		Timestamp: since,
+		RoomID: roomUUID,
+		Since:  since,
+	})
+}
+
+// LockRoom locks the room's row until the transaction ends, so that concurrent
+// requests changing the room's state are handled one at a time
+func LockRoom(ctx context.Context, dbtx db.DBTX, roomID string) error {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return fmt.Errorf("parse room UUID: %w", err)
+	}
+
+	return db.New(dbtx).RoomLockForUpdate(ctx, roomUUID)
+}
+
+// LockQueueFill waits until no other transaction, on any server, is pushing the
+// room's pending tracks to Spotify, and holds the lock until the transaction
+// ends. The room's row isn't locked, so votes and adds aren't held up while
+// Spotify is called.
+func LockQueueFill(ctx context.Context, dbtx db.DBTX, roomID string) error {
+	return db.New(dbtx).RoomQueueLockFill(ctx, roomID)
+}
+
+// GetPendingQueue returns the tracks that have been added to the room but not yet
+// pushed to the host's Spotify queue, in the order they will be pushed. If voterID
+// is not empty, each track includes that participant's vote.
+func GetPendingQueue(ctx context.Context, dbtx db.DBTX, roomID string, voterID string) ([]PendingTrack, error) {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("parse room UUID: %w", err)
+	}
+	voterUUID := uuid.Nil
+	if voterID != "" {
+		voterUUID, err = uuid.Parse(voterID)
+		if err != nil {
+			return nil, fmt.Errorf("parse voter UUID: %w", err)
+		}
+	}
+
+	rows, err := db.New(dbtx).RoomQueueGetPending(ctx, db.RoomQueueGetPendingParams{
+		RoomID:  roomUUID,
+		VoterID: voterUUID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := make([]PendingTrack, 0, len(rows))
+	for _, row := range rows {
+		addedBy := ""
+		if row.GuestName != nil {
+			addedBy = *row.GuestName
+		} else if row.MemberName != nil {
+			addedBy = *row.MemberName
+		}
+		tracks = append(tracks, PendingTrack{
+			ID:        row.ID.String(),
+			TrackID:   row.TrackID,
+			AddedBy:   addedBy,
+			Timestamp: row.Timestamp,
+			Priority:  int(row.Priority),
+			Score:     int(row.Score),
+			Vote:      int(row.VoterVote),
+		})
+	}
+
+	return tracks, nil
+}
+
+// FindPendingTrack returns the entry of a track that is already waiting in the
+// pending queue, which is upvoted instead of being added again
+func FindPendingTrack(pending []PendingTrack, trackID string) (PendingTrack, bool) {
+	return lo.Find(pending, func(track PendingTrack) bool {
+		return track.TrackID == trackID
+	})
+}
+
+func GetPushedTrackIDs(ctx context.Context, dbtx db.DBTX, roomID string) ([]string, error) {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("parse room UUID: %w", err)
+	}
+	return db.New(dbtx).RoomQueueGetPushedTrackIDs(ctx, roomUUID)
+}
+
+func SetQueueTrackPushed(ctx context.Context, dbtx db.DBTX, queueTrackID string) error {
+	queueTrackUUID, err := uuid.Parse(queueTrackID)
+	if err != nil {
+		return fmt.Errorf("parse queue track UUID: %w", err)
+	}
+	return db.New(dbtx).RoomQueueSetPushed(ctx, queueTrackUUID)
+}
+
+// VoteOnQueueTrack records a participant's vote on a pending track. A vote of 0
+// removes any existing vote. sql.ErrNoRows is returned if the track is not pending
+// in the room.
+func VoteOnQueueTrack(ctx context.Context, dbtx db.DBTX, roomID string, queueTrackID string, voterID string, vote int) error {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return fmt.Errorf("parse room UUID: %w", err)
+	}
+	queueTrackUUID, err := uuid.Parse(queueTrackID)
+	if err != nil {
+		return fmt.Errorf("parse queue track UUID: %w", err)
+	}
+	voterUUID, err := uuid.Parse(voterID)
+	if err != nil {
+		return fmt.Errorf("parse voter UUID: %w", err)
+	}
+
+	if vote == 0 {
+		return db.New(dbtx).RoomQueueDeleteVote(ctx, db.RoomQueueDeleteVoteParams{
+			QueueTrackID: queueTrackUUID,
+			VoterID:      voterUUID,
+		})
+	}
+
+	count, err := db.New(dbtx).RoomQueueVote(ctx, db.RoomQueueVoteParams{
+		VoterID:      voterUUID,
+		Vote:         int32(vote),
+		QueueTrackID: queueTrackUUID,
+		RoomID:       roomUUID,
+	})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ReorderPendingQueue moves the given pending tracks to the front of the room's
+// queue in the given order. Pending tracks that are not listed fall back to being
+// ordered by votes.
+func ReorderPendingQueue(ctx context.Context, dbtx db.DBTX, roomID string, queueTrackIDs []string) error {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return fmt.Errorf("parse room UUID: %w", err)
+	}
+	queueTrackUUIDs := make([]uuid.UUID, 0, len(queueTrackIDs))
+	for _, id := range queueTrackIDs {
+		queueTrackUUID, err := uuid.Parse(id)
+		if err != nil {
+			return fmt.Errorf("parse queue track UUID: %w", err)
+		}
+		queueTrackUUIDs = append(queueTrackUUIDs, queueTrackUUID)
+	}
+
+	err = db.New(dbtx).RoomQueueResetPriorities(ctx, roomUUID)
+	if err != nil {
+		return err
+	}
+
+	return db.New(dbtx).RoomQueueSetPriorities(ctx, db.RoomQueueSetPrioritiesParams{
+		QueueTrackIds: queueTrackUUIDs,
+		RoomID:        roomUUID,
 	})
 }
+
+// RemovePendingTrack removes a track from the room's queue before it is pushed to
+// Spotify. sql.ErrNoRows is returned if the track is not pending in the room.
+func RemovePendingTrack(ctx context.Context, dbtx db.DBTX, roomID string, queueTrackID string) error {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return fmt.Errorf("parse room UUID: %w", err)
+	}
+	queueTrackUUID, err := uuid.Parse(queueTrackID)
+	if err != nil {
+		return fmt.Errorf("parse queue track UUID: %w", err)
+	}
+
+	count, err := db.New(dbtx).RoomQueueDeleteTrack(ctx, db.RoomQueueDeleteTrackParams{
+		ID:     queueTrackUUID,
+		RoomID: roomUUID,
+	})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -15,3 +15,9 @@ func ParametersFromRequest(r *http.Request) (code string, guest_id string, passw
 	password = r.URL.Query().Get("password")
 	return
 }
+
+// InviteTokenFromRequest returns the room invite token from the X-Room-Invite
+// header. It isn't read from the URL so that it never ends up in request logs.
+func InviteTokenFromRequest(r *http.Request) string {
+	return r.Header.Get("X-Room-Invite")
+}
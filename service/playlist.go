@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	"github.com/zmb3/spotify/v2"
+)
+
+type Playlist struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// CreatePlaylist creates a playlist on the client user's account and adds the
+// tracks to it in order
+func CreatePlaylist(ctx context.Context, spClient MusicProvider, name string, description string, public bool, trackIDs []string) (*Playlist, error) {
+	user, err := spClient.CurrentUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+
+	playlist, err := spClient.CreatePlaylistForUser(ctx, user.ID, name, description, public, false)
+	if err != nil {
+		return nil, fmt.Errorf("create playlist: %w", err)
+	}
+
+	ids := lo.Map(trackIDs, func(id string, _ int) spotify.ID {
+		return spotify.ID(id)
+	})
+
+	// spotify accepts at most 100 tracks per request
+	for start := 0; start < len(ids); start += 100 {
+		end := start + 100
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		_, err = spClient.AddTracksToPlaylist(ctx, playlist.ID, ids[start:end]...)
+		if err != nil {
+			return nil, fmt.Errorf("add tracks to playlist: %w", err)
+		}
+	}
+
+	return &Playlist{
+		ID:  playlist.ID.String(),
+		URL: playlist.ExternalURLs["spotify"],
+	}, nil
+}
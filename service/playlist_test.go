@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andrewbenington/queue-share-api/service/spotifytest"
+	"github.com/stretchr/testify/assert"
+	"github.com/zmb3/spotify/v2"
+)
+
+func TestCreatePlaylist(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("adds tracks in batches", func(t *testing.T) {
+		server := spotifytest.NewServer(t)
+		client := server.SpotifyClient(nil)
+
+		trackIDs := []string{}
+		for i := 0; i < 250; i++ {
+			trackIDs = append(trackIDs, string(spotifytest.TrackID(i%len(server.Catalog.Tracks))))
+		}
+
+		playlist, err := CreatePlaylist(ctx, client, "Mix", "made by a test", false, trackIDs)
+		assert.NoError(t, err)
+
+		created, err := client.GetPlaylist(ctx, spotify.ID(playlist.ID))
+		assert.NoError(t, err)
+		assert.Len(t, created.Tracks.Tracks, 250)
+		assert.Equal(t, trackIDs[101], created.Tracks.Tracks[101].Track.ID.String())
+		assert.Equal(t, created.ExternalURLs["spotify"], playlist.URL)
+	})
+
+	t.Run("spotify error", func(t *testing.T) {
+		server := spotifytest.NewServer(t)
+		server.FailNext("GET /v1/me", 503)
+
+		_, err := CreatePlaylist(ctx, server.SpotifyClient(nil), "Mix", "", false, nil)
+		assert.ErrorContains(t, err, "get user")
+	})
+}
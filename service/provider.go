@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// MusicProvider is the part of the Spotify Web API the app uses. It is satisfied
+// by *spotify.Client, and by a client for spotifytest.Server in tests.
+type MusicProvider interface {
+	CurrentUser(ctx context.Context) (*spotify.PrivateUser, error)
+
+	// queue and playback
+	GetQueue(ctx context.Context) (*spotify.Queue, error)
+	QueueSong(ctx context.Context, trackID spotify.ID) error
+	PlayerState(ctx context.Context, opts ...spotify.RequestOption) (*spotify.PlayerState, error)
+	PlayerCurrentlyPlaying(ctx context.Context, opts ...spotify.RequestOption) (*spotify.CurrentlyPlaying, error)
+	PlayerDevices(ctx context.Context) ([]spotify.PlayerDevice, error)
+	PlayOpt(ctx context.Context, opt *spotify.PlayOptions) error
+	Pause(ctx context.Context) error
+	Next(ctx context.Context) error
+	Previous(ctx context.Context) error
+	Volume(ctx context.Context, percent int) error
+
+	// catalog
+	Search(ctx context.Context, query string, t spotify.SearchType, opts ...spotify.RequestOption) (*spotify.SearchResult, error)
+	GetTrack(ctx context.Context, id spotify.ID, opts ...spotify.RequestOption) (*spotify.FullTrack, error)
+	GetTracks(ctx context.Context, ids []spotify.ID, opts ...spotify.RequestOption) ([]*spotify.FullTrack, error)
+	GetAlbum(ctx context.Context, id spotify.ID, opts ...spotify.RequestOption) (*spotify.FullAlbum, error)
+	GetAlbums(ctx context.Context, ids []spotify.ID, opts ...spotify.RequestOption) ([]*spotify.FullAlbum, error)
+	GetArtist(ctx context.Context, id spotify.ID) (*spotify.FullArtist, error)
+	GetArtists(ctx context.Context, ids ...spotify.ID) ([]*spotify.FullArtist, error)
+
+	// playlists
+	GetPlaylist(ctx context.Context, playlistID spotify.ID, opts ...spotify.RequestOption) (*spotify.FullPlaylist, error)
+	CurrentUsersPlaylists(ctx context.Context, opts ...spotify.RequestOption) (*spotify.SimplePlaylistPage, error)
+	CreatePlaylistForUser(ctx context.Context, userID, playlistName, description string, public bool, collaborative bool) (*spotify.FullPlaylist, error)
+	AddTracksToPlaylist(ctx context.Context, playlistID spotify.ID, trackIDs ...spotify.ID) (snapshotID string, err error)
+
+	// listening history
+	CurrentUsersTopTracks(ctx context.Context, opts ...spotify.RequestOption) (*spotify.FullTrackPage, error)
+	PlayerRecentlyPlayedOpt(ctx context.Context, opt *spotify.RecentlyPlayedOptions) ([]spotify.RecentlyPlayedItem, error)
+}
+
+var _ MusicProvider = (*spotify.Client)(nil)
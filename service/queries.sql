@@ -6,6 +6,22 @@ FROM
 WHERE
     id = ANY (@track_ids::text[]);
 
+-- name: TrackCacheGetByISRC :many
+SELECT
+    *
+FROM
+    SPOTIFY_TRACK_CACHE
+WHERE
+    isrc = ANY (@isrcs::text[]);
+
+-- name: TrackCacheGetByArtistNames :many
+SELECT
+    *
+FROM
+    SPOTIFY_TRACK_CACHE
+WHERE
+    lower(artist_name) = ANY (@artist_names::text[]);
+
 -- name: TrackCacheInsertBulk :exec
 INSERT INTO SPOTIFY_TRACK_CACHE(
     id,
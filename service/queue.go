@@ -25,7 +25,7 @@ type CurrentQueue struct {
 	Queue            []TrackInfo `json:"queue"`
 }
 
-func UpdateUserPlayback(ctx context.Context, client *spotify.Client, playbackStatus *CurrentQueue) error {
+func UpdateUserPlayback(ctx context.Context, client MusicProvider, playbackStatus *CurrentQueue) error {
 	playback, err := client.PlayerState(ctx)
 	if err != nil {
 		return fmt.Errorf("could not get playback: %w", err)
@@ -39,7 +39,7 @@ func UpdateUserPlayback(ctx context.Context, client *spotify.Client, playbackSta
 	return nil
 }
 
-func GetUserQueue(ctx context.Context, client *spotify.Client) (*CurrentQueue, error) {
+func GetUserQueue(ctx context.Context, client MusicProvider) (*CurrentQueue, error) {
 	queue, err := client.GetQueue(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("could not get queue: %w", err)
@@ -67,7 +67,7 @@ func GetUserQueue(ctx context.Context, client *spotify.Client) (*CurrentQueue, e
 	return cq, nil
 }
 
-func PushToUserQueue(ctx context.Context, client *spotify.Client, songID string) error {
+func PushToUserQueue(ctx context.Context, client MusicProvider, songID string) error {
 	err := client.QueueSong(ctx, spotify.ID(songID))
 	if err != nil {
 		return fmt.Errorf("push to queue: %w", err)
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andrewbenington/queue-share-api/service/spotifytest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserQueue(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("nothing playing", func(t *testing.T) {
+		client := spotifytest.NewServer(t).SpotifyClient(nil)
+
+		queue, err := GetUserQueue(ctx, client)
+		assert.NoError(t, err)
+		assert.Equal(t, "", queue.CurrentlyPlaying.ID)
+		assert.Empty(t, queue.Queue)
+
+		err = UpdateUserPlayback(ctx, client, queue)
+		assert.NoError(t, err)
+		assert.Nil(t, queue.CurrentlyPlaying.StartedPlayingEpochMilis)
+	})
+
+	t.Run("push and read queue", func(t *testing.T) {
+		server := spotifytest.NewServer(t)
+		client := server.SpotifyClient(nil)
+		err := server.SetPlaying(spotifytest.TrackID(0))
+		assert.NoError(t, err)
+
+		err = PushToUserQueue(ctx, client, string(spotifytest.TrackID(7)))
+		assert.NoError(t, err)
+
+		queue, err := GetUserQueue(ctx, client)
+		assert.NoError(t, err)
+		assert.Equal(t, "Porch Light", queue.CurrentlyPlaying.Name)
+		assert.GreaterOrEqual(t, int(queue.CurrentlyPlaying.Image.Height), 300)
+		assert.Len(t, queue.Queue, 1)
+		assert.Equal(t, "Medianoche", queue.Queue[0].Name)
+
+		err = UpdateUserPlayback(ctx, client, queue)
+		assert.NoError(t, err)
+		assert.NotNil(t, queue.CurrentlyPlaying.StartedPlayingEpochMilis)
+		assert.False(t, queue.CurrentlyPlaying.Paused)
+	})
+
+	t.Run("unknown track", func(t *testing.T) {
+		client := spotifytest.NewServer(t).SpotifyClient(nil)
+
+		err := PushToUserQueue(ctx, client, "missing")
+		assert.Error(t, err)
+	})
+}
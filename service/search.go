@@ -11,7 +11,7 @@ import (
 	"github.com/zmb3/spotify/v2"
 )
 
-func SearchTracks(ctx context.Context, spClient *spotify.Client, text string) ([]db.TrackData, error) {
+func SearchTracks(ctx context.Context, spClient MusicProvider, text string) ([]db.TrackData, error) {
 	results, err := spClient.Search(ctx, text, spotify.SearchTypeTrack)
 	if err != nil {
 		return nil, fmt.Errorf("search: %w", err)
@@ -19,7 +19,7 @@ func SearchTracks(ctx context.Context, spClient *spotify.Client, text string) ([
 	return lo.Map(results.Tracks.Tracks, TrackDataFromFullTrackIdx), nil
 }
 
-func SearchArtists(ctx context.Context, spClient *spotify.Client, text string) ([]db.ArtistData, error) {
+func SearchArtists(ctx context.Context, spClient MusicProvider, text string) ([]db.ArtistData, error) {
 	results, err := spClient.Search(ctx, text, spotify.SearchTypeArtist)
 	if err != nil {
 		return nil, fmt.Errorf("search: %w", err)
@@ -38,7 +38,7 @@ func SearchArtists(ctx context.Context, spClient *spotify.Client, text string) (
 	return lo.Map(results.Artists.Artists, ArtistDataFromFullArtistIdx), nil
 }
 
-func SearchAlbums(ctx context.Context, spClient *spotify.Client, text string) ([]SimpleAlbumData, error) {
+func SearchAlbums(ctx context.Context, spClient MusicProvider, text string) ([]SimpleAlbumData, error) {
 	results, err := spClient.Search(ctx, text, spotify.SearchTypeAlbum)
 	if err != nil {
 		return nil, fmt.Errorf("search: %w", err)
@@ -57,7 +57,7 @@ func SearchAlbums(ctx context.Context, spClient *spotify.Client, text string) ([
 	return simpleAlbums, nil
 }
 
-func GetTrack(ctx context.Context, spClient *spotify.Client, id string) (*db.TrackData, error) {
+func GetTrack(ctx context.Context, spClient MusicProvider, id string) (*db.TrackData, error) {
 	tx, err := db.Service().BeginTx(ctx)
 	if err != nil {
 		return nil, err
@@ -86,7 +86,7 @@ func GetTrack(ctx context.Context, spClient *spotify.Client, id string) (*db.Tra
 	return &trackData, nil
 }
 
-func GetTracks(ctx context.Context, spClient *spotify.Client, ids []string) (map[string]db.TrackData, error) {
+func GetTracks(ctx context.Context, spClient MusicProvider, ids []string) (map[string]db.TrackData, error) {
 	tx, err := db.Service().BeginTx(ctx)
 	if err != nil {
 		return nil, err
@@ -136,7 +136,7 @@ func GetTracks(ctx context.Context, spClient *spotify.Client, ids []string) (map
 	return tracks, nil
 }
 
-func GetArtist(ctx context.Context, spClient *spotify.Client, id string) (*db.ArtistData, error) {
+func GetArtist(ctx context.Context, spClient MusicProvider, id string) (*db.ArtistData, error) {
 	tx, err := db.Service().BeginTx(ctx)
 	if err != nil {
 		return nil, err
@@ -164,7 +164,7 @@ func GetArtist(ctx context.Context, spClient *spotify.Client, id string) (*db.Ar
 	return &artist, nil
 }
 
-func GetArtists(ctx context.Context, spClient *spotify.Client, ids []string) (map[string]db.ArtistData, error) {
+func GetArtists(ctx context.Context, spClient MusicProvider, ids []string) (map[string]db.ArtistData, error) {
 	tx, err := db.Service().BeginTx(ctx)
 	if err != nil {
 		return nil, err
@@ -208,7 +208,7 @@ func GetArtists(ctx context.Context, spClient *spotify.Client, ids []string) (ma
 	return artists, nil
 }
 
-func GetAlbum(ctx context.Context, spClient *spotify.Client, id string) (*db.AlbumData, error) {
+func GetAlbum(ctx context.Context, spClient MusicProvider, id string) (*db.AlbumData, error) {
 
 	tx, err := db.Service().BeginTx(ctx)
 	if err != nil {
@@ -240,7 +240,7 @@ func GetAlbum(ctx context.Context, spClient *spotify.Client, id string) (*db.Alb
 	return AlbumDataFromSpotifyAlbum(*album)
 }
 
-func GetAlbums(ctx context.Context, spClient *spotify.Client, ids []string) (map[string]db.AlbumData, error) {
+func GetAlbums(ctx context.Context, spClient MusicProvider, ids []string) (map[string]db.AlbumData, error) {
 	tx, err := db.Service().BeginTx(ctx)
 	if err != nil {
 		return nil, err
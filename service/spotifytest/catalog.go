@@ -0,0 +1,225 @@
+package spotifytest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// Catalog is the music the fake Spotify API knows about. Every ID is
+// deterministic, so tests can refer to seeded items directly.
+type Catalog struct {
+	User      spotify.PrivateUser
+	Artists   []spotify.FullArtist
+	Albums    []spotify.FullAlbum
+	Tracks    []spotify.FullTrack
+	Playlists []spotify.FullPlaylist
+}
+
+type seedAlbum struct {
+	artist      int
+	name        string
+	releaseDate string
+	tracks      []string
+}
+
+var (
+	seedArtists = []struct {
+		name   string
+		genres []string
+	}{
+		{name: "The Paper Lanterns", genres: []string{"indie pop"}},
+		{name: "Marisol Vega", genres: []string{"latin pop", "dance pop"}},
+		{name: "Northbound Static", genres: []string{"post-rock"}},
+	}
+
+	seedAlbums = []seedAlbum{
+		{artist: 0, name: "Lights Out Early", releaseDate: "2019-04-12", tracks: []string{"Porch Light", "Kite Season", "Last Ferry Home"}},
+		{artist: 0, name: "Second Story", releaseDate: "2022-09-30", tracks: []string{"Stairwell", "Open Window", "Paper Moon"}},
+		{artist: 1, name: "Calor", releaseDate: "2021-06-18", tracks: []string{"Calor", "Medianoche", "Brisa"}},
+		{artist: 2, name: "Signal Loss", releaseDate: "2017-11-03", tracks: []string{"Carrier Wave", "Dead Air", "Static Bloom"}},
+	}
+
+	// SeedTime is when the seeded playlist tracks were added, and when the
+	// seeded listening history ends
+	SeedTime = time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func ArtistID(n int) spotify.ID {
+	return spotify.ID(fmt.Sprintf("qsartist%014d", n))
+}
+
+func AlbumID(n int) spotify.ID {
+	return spotify.ID(fmt.Sprintf("qsalbum%015d", n))
+}
+
+func TrackID(n int) spotify.ID {
+	return spotify.ID(fmt.Sprintf("qstrack%015d", n))
+}
+
+func PlaylistID(n int) spotify.ID {
+	return spotify.ID(fmt.Sprintf("qsplaylist%012d", n))
+}
+
+func uri(kind string, id spotify.ID) spotify.URI {
+	return spotify.URI(fmt.Sprintf("spotify:%s:%s", kind, id))
+}
+
+func images(url string) []spotify.Image {
+	return []spotify.Image{
+		{Height: 640, Width: 640, URL: url + "/640"},
+		{Height: 300, Width: 300, URL: url + "/300"},
+		{Height: 64, Width: 64, URL: url + "/64"},
+	}
+}
+
+// SeedCatalog builds the catalog a new Server starts with: three artists, four
+// albums of three tracks each, and one playlist owned by the user
+func SeedCatalog() *Catalog {
+	c := &Catalog{
+		User: spotify.PrivateUser{
+			User: spotify.User{
+				ID:          "qs-test-user",
+				DisplayName: "Queue Share Tester",
+				URI:         "spotify:user:qs-test-user",
+				Images: []spotify.Image{
+					{Height: 300, Width: 300, URL: "https://i.scdn.test/user/300"},
+					{Height: 64, Width: 64, URL: "https://i.scdn.test/user/64"},
+				},
+			},
+			Country: "US",
+			Email:   "tester@queueshare.test",
+			Product: "premium",
+		},
+	}
+
+	for i, a := range seedArtists {
+		id := ArtistID(i)
+		c.Artists = append(c.Artists, spotify.FullArtist{
+			SimpleArtist: spotify.SimpleArtist{
+				ID:           id,
+				Name:         a.name,
+				URI:          uri("artist", id),
+				ExternalURLs: map[string]string{"spotify": "https://open.spotify.com/artist/" + string(id)},
+			},
+			Genres:     a.genres,
+			Popularity: spotify.Numeric(60 - i*10),
+			Followers:  spotify.Followers{Count: spotify.Numeric(10000 * (i + 1))},
+			Images:     images("https://i.scdn.test/artist/" + string(id)),
+		})
+	}
+
+	for i, a := range seedAlbums {
+		artist := c.Artists[a.artist].SimpleArtist
+		album := spotify.FullAlbum{
+			SimpleAlbum: spotify.SimpleAlbum{
+				ID:                   AlbumID(i),
+				Name:                 a.name,
+				Artists:              []spotify.SimpleArtist{artist},
+				AlbumType:            "album",
+				URI:                  uri("album", AlbumID(i)),
+				Images:               images("https://i.scdn.test/album/" + string(AlbumID(i))),
+				ExternalURLs:         map[string]string{"spotify": "https://open.spotify.com/album/" + string(AlbumID(i))},
+				ReleaseDate:          a.releaseDate,
+				ReleaseDatePrecision: "day",
+				TotalTracks:          spotify.Numeric(len(a.tracks)),
+			},
+			Genres:     c.Artists[a.artist].Genres,
+			Popularity: spotify.Numeric(50 + i),
+		}
+
+		for j, name := range a.tracks {
+			id := TrackID(len(c.Tracks))
+			track := spotify.FullTrack{
+				SimpleTrack: spotify.SimpleTrack{
+					ID:           id,
+					Name:         name,
+					Artists:      []spotify.SimpleArtist{artist},
+					DiscNumber:   1,
+					Duration:     spotify.Numeric(180000 + 15000*j),
+					ExternalURLs: map[string]string{"spotify": "https://open.spotify.com/track/" + string(id)},
+					TrackNumber:  spotify.Numeric(j + 1),
+					URI:          uri("track", id),
+					Type:         "track",
+				},
+				Album:       album.SimpleAlbum,
+				ExternalIDs: map[string]string{"isrc": fmt.Sprintf("QSTEST%06d", len(c.Tracks))},
+				Popularity:  spotify.Numeric(70 - len(c.Tracks)),
+			}
+			c.Tracks = append(c.Tracks, track)
+			album.Tracks.Tracks = append(album.Tracks.Tracks, track.SimpleTrack)
+		}
+		album.Tracks.Total = spotify.Numeric(len(album.Tracks.Tracks))
+		album.Tracks.Limit = album.Tracks.Total
+
+		c.Albums = append(c.Albums, album)
+	}
+
+	playlistTracks := []int{0, 3, 6, 9, 1, 4}
+	c.Playlists = append(c.Playlists, c.newPlaylist(PlaylistID(0), "Road Trip", "Songs for the drive", true))
+	for i, n := range playlistTracks {
+		addedAt := SeedTime.Add(time.Duration(i-len(playlistTracks)) * time.Hour)
+		c.Playlists[0].Tracks.Tracks = append(c.Playlists[0].Tracks.Tracks, spotify.PlaylistTrack{
+			AddedAt: addedAt.Format(time.RFC3339),
+			AddedBy: c.User.User,
+			Track:   c.Tracks[n],
+		})
+	}
+	c.Playlists[0].Tracks.Total = spotify.Numeric(len(playlistTracks))
+	c.Playlists[0].SimplePlaylist.Tracks.Total = spotify.Numeric(len(playlistTracks))
+
+	return c
+}
+
+func (c *Catalog) newPlaylist(id spotify.ID, name string, description string, public bool) spotify.FullPlaylist {
+	return spotify.FullPlaylist{
+		SimplePlaylist: spotify.SimplePlaylist{
+			ID:           id,
+			Name:         name,
+			Description:  description,
+			IsPublic:     public,
+			Owner:        c.User.User,
+			URI:          uri("playlist", id),
+			Images:       images("https://i.scdn.test/playlist/" + string(id)),
+			ExternalURLs: map[string]string{"spotify": "https://open.spotify.com/playlist/" + string(id)},
+			SnapshotID:   "snapshot-0",
+		},
+	}
+}
+
+func (c *Catalog) Artist(id spotify.ID) (*spotify.FullArtist, bool) {
+	for i := range c.Artists {
+		if c.Artists[i].ID == id {
+			return &c.Artists[i], true
+		}
+	}
+	return nil, false
+}
+
+func (c *Catalog) Album(id spotify.ID) (*spotify.FullAlbum, bool) {
+	for i := range c.Albums {
+		if c.Albums[i].ID == id {
+			return &c.Albums[i], true
+		}
+	}
+	return nil, false
+}
+
+func (c *Catalog) Track(id spotify.ID) (*spotify.FullTrack, bool) {
+	for i := range c.Tracks {
+		if c.Tracks[i].ID == id {
+			return &c.Tracks[i], true
+		}
+	}
+	return nil, false
+}
+
+func (c *Catalog) Playlist(id spotify.ID) (*spotify.FullPlaylist, bool) {
+	for i := range c.Playlists {
+		if c.Playlists[i].ID == id {
+			return &c.Playlists[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,747 @@
+// Package spotifytest runs a fake Spotify Web API in process, so code that talks
+// to Spotify can be tested offline. It serves the seeded Catalog and keeps a
+// single user's player state.
+package spotifytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+const (
+	DeviceID = "qs-test-device"
+
+	default_limit = 20
+	max_limit     = 50
+)
+
+// Server is a fake Spotify Web API. Its player starts paused with nothing
+// playing, and every seeded track in its listening history.
+type Server struct {
+	*httptest.Server
+	Catalog *Catalog
+
+	mu        sync.Mutex
+	devices   []spotify.PlayerDevice
+	current   *spotify.FullTrack
+	queue     []spotify.FullTrack
+	playing   bool
+	progress  time.Duration
+	resumedAt time.Time
+	history   []spotify.RecentlyPlayedItem
+	failures  map[string]int
+}
+
+// NewServer starts a fake Spotify API with the seeded catalog. It is closed
+// when the test finishes.
+func NewServer(t testing.TB) *Server {
+	s := &Server{
+		Catalog: SeedCatalog(),
+		devices: []spotify.PlayerDevice{
+			{ID: DeviceID, Active: true, Name: "Test Speaker", Type: "Speaker", Volume: 50},
+		},
+		failures: map[string]int{},
+	}
+
+	// one play of every track, a minute apart, ending at SeedTime
+	for i := len(s.Catalog.Tracks) - 1; i >= 0; i-- {
+		s.history = append(s.history, recentlyPlayed(s.Catalog.Tracks[i], SeedTime.Add(-time.Duration(len(s.Catalog.Tracks)-1-i)*time.Minute)))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/me", s.getCurrentUser)
+	mux.HandleFunc("GET /v1/me/player", s.getPlayerState)
+	mux.HandleFunc("GET /v1/me/player/currently-playing", s.getPlayerState)
+	mux.HandleFunc("GET /v1/me/player/devices", s.getDevices)
+	mux.HandleFunc("GET /v1/me/player/queue", s.getQueue)
+	mux.HandleFunc("POST /v1/me/player/queue", s.addToQueue)
+	mux.HandleFunc("GET /v1/me/player/recently-played", s.getRecentlyPlayed)
+	mux.HandleFunc("PUT /v1/me/player/play", s.play)
+	mux.HandleFunc("PUT /v1/me/player/pause", s.pause)
+	mux.HandleFunc("POST /v1/me/player/next", s.next)
+	mux.HandleFunc("POST /v1/me/player/previous", s.previous)
+	mux.HandleFunc("PUT /v1/me/player/volume", s.setVolume)
+	mux.HandleFunc("GET /v1/me/playlists", s.getCurrentUsersPlaylists)
+	mux.HandleFunc("GET /v1/me/top/tracks", s.getTopTracks)
+	mux.HandleFunc("GET /v1/search", s.search)
+	mux.HandleFunc("GET /v1/tracks", s.getTracks)
+	mux.HandleFunc("GET /v1/tracks/{id}", s.getTrack)
+	mux.HandleFunc("GET /v1/albums", s.getAlbums)
+	mux.HandleFunc("GET /v1/albums/{id}", s.getAlbum)
+	mux.HandleFunc("GET /v1/artists", s.getArtists)
+	mux.HandleFunc("GET /v1/artists/{id}", s.getArtist)
+	mux.HandleFunc("GET /v1/playlists/{id}", s.getPlaylist)
+	mux.HandleFunc("POST /v1/playlists/{id}/tracks", s.addTracksToPlaylist)
+	mux.HandleFunc("POST /v1/users/{id}/playlists", s.createPlaylist)
+
+	s.Server = httptest.NewServer(s.withFailures(mux))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// BaseURL is the URL to pass to spotify.WithBaseURL
+func (s *Server) BaseURL() string {
+	return s.URL + "/v1/"
+}
+
+// SpotifyClient returns a Spotify client that sends its requests to the fake
+// API. If httpClient is nil, the server's own client is used.
+func (s *Server) SpotifyClient(httpClient *http.Client) *spotify.Client {
+	if httpClient == nil {
+		httpClient = s.Client()
+	}
+	return spotify.New(httpClient, spotify.WithBaseURL(s.BaseURL()))
+}
+
+// FailNext makes the next request for the method and path, such as
+// "GET /v1/me/player/queue", fail with the status code
+func (s *Server) FailNext(pattern string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[pattern] = status
+}
+
+// SetPlaying starts playing the track, replacing the queue with the ones after it
+func (s *Server) SetPlaying(trackID spotify.ID, queue ...spotify.ID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tracks, err := s.lookupTracks(append([]spotify.ID{trackID}, queue...))
+	if err != nil {
+		return err
+	}
+	s.startTrack(&tracks[0])
+	s.queue = tracks[1:]
+	s.playing = true
+	s.resumedAt = time.Now()
+	return nil
+}
+
+// CurrentlyPlaying returns the track that is playing, or "" if there is none
+func (s *Server) CurrentlyPlaying() (trackID spotify.ID, playing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil {
+		return "", false
+	}
+	return s.current.ID, s.playing
+}
+
+// QueuedIDs returns the IDs of the tracks waiting to play, in order
+func (s *Server) QueuedIDs() []spotify.ID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]spotify.ID, 0, len(s.queue))
+	for _, track := range s.queue {
+		ids = append(ids, track.ID)
+	}
+	return ids
+}
+
+// Volume returns the active device's volume
+func (s *Server) Volume() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int(s.devices[0].Volume)
+}
+
+func (s *Server) withFailures(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pattern := r.Method + " " + r.URL.Path
+		s.mu.Lock()
+		status, ok := s.failures[pattern]
+		delete(s.failures, pattern)
+		s.mu.Unlock()
+
+		if ok {
+			respondError(w, status, http.StatusText(status))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func respond(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// respondError writes an error in the format the Spotify client decodes
+func respondError(w http.ResponseWriter, status int, message string) {
+	respond(w, status, map[string]spotify.Error{
+		"error": {Status: status, Message: message},
+	})
+}
+
+func recentlyPlayed(track spotify.FullTrack, playedAt time.Time) spotify.RecentlyPlayedItem {
+	simple := track.SimpleTrack
+	simple.Album = track.Album
+	return spotify.RecentlyPlayedItem{
+		Track:    simple,
+		PlayedAt: playedAt,
+	}
+}
+
+func limitParam(r *http.Request) (int, error) {
+	limit := default_limit
+	if param := r.URL.Query().Get("limit"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil || parsed < 1 || parsed > max_limit {
+			return 0, fmt.Errorf("Invalid limit")
+		}
+		limit = parsed
+	}
+	return limit, nil
+}
+
+func offsetParam(r *http.Request) (int, error) {
+	param := r.URL.Query().Get("offset")
+	if param == "" {
+		return 0, nil
+	}
+	offset, err := strconv.Atoi(param)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("Invalid offset")
+	}
+	return offset, nil
+}
+
+// idsParam splits the ids query parameter, which Spotify caps at max IDs
+func idsParam(r *http.Request, max int) ([]spotify.ID, error) {
+	ids := []spotify.ID{}
+	for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		if id != "" {
+			ids = append(ids, spotify.ID(id))
+		}
+	}
+	if len(ids) == 0 || len(ids) > max {
+		return nil, fmt.Errorf("Invalid ids")
+	}
+	return ids, nil
+}
+
+func (s *Server) lookupTracks(ids []spotify.ID) ([]spotify.FullTrack, error) {
+	tracks := make([]spotify.FullTrack, 0, len(ids))
+	for _, id := range ids {
+		track, ok := s.Catalog.Track(id)
+		if !ok {
+			return nil, fmt.Errorf("unknown track %s", id)
+		}
+		tracks = append(tracks, *track)
+	}
+	return tracks, nil
+}
+
+// trackFromURI looks up a track from a spotify:track:<id> URI
+func (s *Server) trackFromURI(uri string) (*spotify.FullTrack, bool) {
+	id, ok := strings.CutPrefix(uri, "spotify:track:")
+	if !ok {
+		return nil, false
+	}
+	return s.Catalog.Track(spotify.ID(id))
+}
+
+// startTrack must be called with s.mu held
+func (s *Server) startTrack(track *spotify.FullTrack) {
+	if s.current != nil {
+		s.history = append([]spotify.RecentlyPlayedItem{recentlyPlayed(*s.current, time.Now())}, s.history...)
+	}
+	s.current = track
+	s.progress = 0
+	s.resumedAt = time.Now()
+}
+
+// elapsed must be called with s.mu held
+func (s *Server) elapsed() time.Duration {
+	if !s.playing {
+		return s.progress
+	}
+	return s.progress + time.Since(s.resumedAt)
+}
+
+func (s *Server) getCurrentUser(w http.ResponseWriter, r *http.Request) {
+	respond(w, http.StatusOK, s.Catalog.User)
+}
+
+func (s *Server) getPlayerState(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	item := *s.current
+	respond(w, http.StatusOK, spotify.PlayerState{
+		CurrentlyPlaying: spotify.CurrentlyPlaying{
+			Timestamp: time.Now().UnixMilli(),
+			Progress:  spotify.Numeric(s.elapsed().Milliseconds()),
+			Playing:   s.playing,
+			Item:      &item,
+		},
+		Device:      s.devices[0],
+		RepeatState: "off",
+	})
+}
+
+func (s *Server) getDevices(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	respond(w, http.StatusOK, map[string][]spotify.PlayerDevice{"devices": s.devices})
+}
+
+func (s *Server) getQueue(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	respond(w, http.StatusOK, struct {
+		CurrentlyPlaying *spotify.FullTrack  `json:"currently_playing"`
+		Queue            []spotify.FullTrack `json:"queue"`
+	}{
+		CurrentlyPlaying: s.current,
+		Queue:            append([]spotify.FullTrack{}, s.queue...),
+	})
+}
+
+func (s *Server) addToQueue(w http.ResponseWriter, r *http.Request) {
+	track, ok := s.trackFromURI(r.URL.Query().Get("uri"))
+	if !ok {
+		respondError(w, http.StatusBadRequest, "Invalid track uri")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, *track)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getRecentlyPlayed(w http.ResponseWriter, r *http.Request) {
+	limit, err := limitParam(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var before, after int64
+	if param := r.URL.Query().Get("before"); param != "" {
+		before, _ = strconv.ParseInt(param, 10, 64)
+	}
+	if param := r.URL.Query().Get("after"); param != "" {
+		after, _ = strconv.ParseInt(param, 10, 64)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := []spotify.RecentlyPlayedItem{}
+	for _, item := range s.history {
+		playedAt := item.PlayedAt.UnixMilli()
+		if before != 0 && playedAt >= before || after != 0 && playedAt <= after {
+			continue
+		}
+		items = append(items, item)
+		if len(items) == limit {
+			break
+		}
+	}
+	respond(w, http.StatusOK, spotify.RecentlyPlayedResult{Items: items})
+}
+
+func (s *Server) play(w http.ResponseWriter, r *http.Request) {
+	var opts spotify.PlayOptions
+	if r.ContentLength != 0 {
+		err := json.NewDecoder(r.Body).Decode(&opts)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Malformed json")
+			return
+		}
+	}
+	if deviceID := r.URL.Query().Get("device_id"); deviceID != "" && deviceID != DeviceID {
+		respondError(w, http.StatusNotFound, "Device not found")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tracks := []spotify.FullTrack{}
+	if opts.PlaybackContext != nil {
+		id, ok := strings.CutPrefix(string(*opts.PlaybackContext), "spotify:playlist:")
+		playlist, found := s.Catalog.Playlist(spotify.ID(id))
+		if !ok || !found {
+			respondError(w, http.StatusNotFound, "Context not found")
+			return
+		}
+		for _, item := range playlist.Tracks.Tracks {
+			tracks = append(tracks, item.Track)
+		}
+	}
+	for _, uri := range opts.URIs {
+		track, ok := s.trackFromURI(string(uri))
+		if !ok {
+			respondError(w, http.StatusBadRequest, "Invalid track uri")
+			return
+		}
+		tracks = append(tracks, *track)
+	}
+
+	if len(tracks) > 0 {
+		s.startTrack(&tracks[0])
+		s.queue = tracks[1:]
+	} else if s.current == nil {
+		respondError(w, http.StatusNotFound, "Player command failed: No active device found")
+		return
+	} else if !s.playing {
+		s.resumedAt = time.Now()
+	}
+	s.playing = true
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) pause(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.playing {
+		respondError(w, http.StatusForbidden, "Player command failed: Restriction violated")
+		return
+	}
+	s.progress = s.elapsed()
+	s.playing = false
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) next(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) == 0 {
+		if s.current != nil {
+			s.history = append([]spotify.RecentlyPlayedItem{recentlyPlayed(*s.current, time.Now())}, s.history...)
+		}
+		s.current = nil
+		s.playing = false
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	track := s.queue[0]
+	s.startTrack(&track)
+	s.queue = s.queue[1:]
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// previous restarts the current track
+func (s *Server) previous(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.progress = 0
+	s.resumedAt = time.Now()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) setVolume(w http.ResponseWriter, r *http.Request) {
+	percent, err := strconv.Atoi(r.URL.Query().Get("volume_percent"))
+	if err != nil || percent < 0 || percent > 100 {
+		respondError(w, http.StatusBadRequest, "Invalid volume_percent")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.devices[0].Volume = spotify.Numeric(percent)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getCurrentUsersPlaylists(w http.ResponseWriter, r *http.Request) {
+	limit, err := limitParam(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	offset, err := offsetParam(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	page := spotify.SimplePlaylistPage{Playlists: []spotify.SimplePlaylist{}}
+	for i := offset; i < len(s.Catalog.Playlists) && i < offset+limit; i++ {
+		page.Playlists = append(page.Playlists, s.Catalog.Playlists[i].SimplePlaylist)
+	}
+	page.Limit = spotify.Numeric(limit)
+	page.Offset = spotify.Numeric(offset)
+	page.Total = spotify.Numeric(len(s.Catalog.Playlists))
+	if offset+limit < len(s.Catalog.Playlists) {
+		page.Next = fmt.Sprintf("%sme/playlists?offset=%d&limit=%d", s.BaseURL(), offset+limit, limit)
+	}
+	respond(w, http.StatusOK, page)
+}
+
+// getTopTracks ranks tracks by how many times they appear in the history
+func (s *Server) getTopTracks(w http.ResponseWriter, r *http.Request) {
+	limit, err := limitParam(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plays := map[spotify.ID]int{}
+	ranked := []spotify.FullTrack{}
+	for _, item := range s.history {
+		if plays[item.Track.ID] == 0 {
+			track, _ := s.Catalog.Track(item.Track.ID)
+			ranked = append(ranked, *track)
+		}
+		plays[item.Track.ID]++
+	}
+	// stable, so ties stay in order of most recently played
+	for i := 1; i < len(ranked); i++ {
+		for j := i; j > 0 && plays[ranked[j].ID] > plays[ranked[j-1].ID]; j-- {
+			ranked[j], ranked[j-1] = ranked[j-1], ranked[j]
+		}
+	}
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	page := spotify.FullTrackPage{Tracks: ranked}
+	page.Limit = spotify.Numeric(limit)
+	page.Total = spotify.Numeric(len(plays))
+	respond(w, http.StatusOK, page)
+}
+
+// search matches names containing the query, ignoring case. Tracks and albums
+// also match on their artists' names.
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	query := strings.ToLower(r.URL.Query().Get("q"))
+	if query == "" {
+		respondError(w, http.StatusBadRequest, "No search query")
+		return
+	}
+	limit, err := limitParam(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	matches := func(name string, artists []spotify.SimpleArtist) bool {
+		if strings.Contains(strings.ToLower(name), query) {
+			return true
+		}
+		for _, artist := range artists {
+			if strings.Contains(strings.ToLower(artist.Name), query) {
+				return true
+			}
+		}
+		return false
+	}
+
+	result := spotify.SearchResult{}
+	for _, searchType := range strings.Split(r.URL.Query().Get("type"), ",") {
+		switch searchType {
+		case "track":
+			result.Tracks = &spotify.FullTrackPage{Tracks: []spotify.FullTrack{}}
+			for _, track := range s.Catalog.Tracks {
+				if matches(track.Name, track.Artists) && len(result.Tracks.Tracks) < limit {
+					result.Tracks.Tracks = append(result.Tracks.Tracks, track)
+				}
+			}
+			result.Tracks.Limit = spotify.Numeric(limit)
+			result.Tracks.Total = spotify.Numeric(len(result.Tracks.Tracks))
+		case "artist":
+			result.Artists = &spotify.FullArtistPage{Artists: []spotify.FullArtist{}}
+			for _, artist := range s.Catalog.Artists {
+				if matches(artist.Name, nil) && len(result.Artists.Artists) < limit {
+					result.Artists.Artists = append(result.Artists.Artists, artist)
+				}
+			}
+			result.Artists.Limit = spotify.Numeric(limit)
+			result.Artists.Total = spotify.Numeric(len(result.Artists.Artists))
+		case "album":
+			result.Albums = &spotify.SimpleAlbumPage{Albums: []spotify.SimpleAlbum{}}
+			for _, album := range s.Catalog.Albums {
+				if matches(album.Name, album.Artists) && len(result.Albums.Albums) < limit {
+					result.Albums.Albums = append(result.Albums.Albums, album.SimpleAlbum)
+				}
+			}
+			result.Albums.Limit = spotify.Numeric(limit)
+			result.Albums.Total = spotify.Numeric(len(result.Albums.Albums))
+		default:
+			respondError(w, http.StatusBadRequest, "Unsupported search type")
+			return
+		}
+	}
+	respond(w, http.StatusOK, result)
+}
+
+func (s *Server) getTrack(w http.ResponseWriter, r *http.Request) {
+	track, ok := s.Catalog.Track(spotify.ID(r.PathValue("id")))
+	if !ok {
+		respondError(w, http.StatusNotFound, "Non existing id")
+		return
+	}
+	respond(w, http.StatusOK, track)
+}
+
+// getTracks returns null in place of tracks that aren't found, like Spotify
+func (s *Server) getTracks(w http.ResponseWriter, r *http.Request) {
+	ids, err := idsParam(r, 50)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	tracks := make([]*spotify.FullTrack, 0, len(ids))
+	for _, id := range ids {
+		track, _ := s.Catalog.Track(id)
+		tracks = append(tracks, track)
+	}
+	respond(w, http.StatusOK, map[string][]*spotify.FullTrack{"tracks": tracks})
+}
+
+func (s *Server) getAlbum(w http.ResponseWriter, r *http.Request) {
+	album, ok := s.Catalog.Album(spotify.ID(r.PathValue("id")))
+	if !ok {
+		respondError(w, http.StatusNotFound, "Non existing id")
+		return
+	}
+	respond(w, http.StatusOK, album)
+}
+
+func (s *Server) getAlbums(w http.ResponseWriter, r *http.Request) {
+	ids, err := idsParam(r, 20)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	albums := make([]*spotify.FullAlbum, 0, len(ids))
+	for _, id := range ids {
+		album, _ := s.Catalog.Album(id)
+		albums = append(albums, album)
+	}
+	respond(w, http.StatusOK, map[string][]*spotify.FullAlbum{"albums": albums})
+}
+
+func (s *Server) getArtist(w http.ResponseWriter, r *http.Request) {
+	artist, ok := s.Catalog.Artist(spotify.ID(r.PathValue("id")))
+	if !ok {
+		respondError(w, http.StatusNotFound, "Non existing id")
+		return
+	}
+	respond(w, http.StatusOK, artist)
+}
+
+func (s *Server) getArtists(w http.ResponseWriter, r *http.Request) {
+	ids, err := idsParam(r, 50)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	artists := make([]*spotify.FullArtist, 0, len(ids))
+	for _, id := range ids {
+		artist, _ := s.Catalog.Artist(id)
+		artists = append(artists, artist)
+	}
+	respond(w, http.StatusOK, map[string][]*spotify.FullArtist{"artists": artists})
+}
+
+func (s *Server) getPlaylist(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	playlist, ok := s.Catalog.Playlist(spotify.ID(r.PathValue("id")))
+	if !ok {
+		respondError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	respond(w, http.StatusOK, playlist)
+}
+
+func (s *Server) createPlaylist(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("id") != s.Catalog.User.ID {
+		respondError(w, http.StatusForbidden, "You cannot create a playlist for another user")
+		return
+	}
+
+	var body struct {
+		Name          string `json:"name"`
+		Public        bool   `json:"public"`
+		Description   string `json:"description"`
+		Collaborative bool   `json:"collaborative"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil || body.Name == "" {
+		respondError(w, http.StatusBadRequest, "Missing required field: name")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	playlist := s.Catalog.newPlaylist(PlaylistID(len(s.Catalog.Playlists)), body.Name, body.Description, body.Public)
+	playlist.Collaborative = body.Collaborative
+	s.Catalog.Playlists = append(s.Catalog.Playlists, playlist)
+	respond(w, http.StatusCreated, playlist)
+}
+
+func (s *Server) addTracksToPlaylist(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		URIs []string `json:"uris"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Malformed json")
+		return
+	}
+	if len(body.URIs) > 100 {
+		respondError(w, http.StatusBadRequest, "You can add a maximum of 100 tracks per request.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	playlist, ok := s.Catalog.Playlist(spotify.ID(r.PathValue("id")))
+	if !ok {
+		respondError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	added := []spotify.PlaylistTrack{}
+	for _, uri := range body.URIs {
+		track, ok := s.trackFromURI(uri)
+		if !ok {
+			respondError(w, http.StatusBadRequest, "Invalid track uri: "+uri)
+			return
+		}
+		added = append(added, spotify.PlaylistTrack{
+			AddedAt: time.Now().UTC().Format(time.RFC3339),
+			AddedBy: s.Catalog.User.User,
+			Track:   *track,
+		})
+	}
+
+	playlist.Tracks.Tracks = append(playlist.Tracks.Tracks, added...)
+	playlist.Tracks.Total = spotify.Numeric(len(playlist.Tracks.Tracks))
+	playlist.SimplePlaylist.Tracks.Total = playlist.Tracks.Total
+	playlist.SnapshotID = fmt.Sprintf("snapshot-%d", len(playlist.Tracks.Tracks))
+	respond(w, http.StatusCreated, map[string]string{"snapshot_id": playlist.SnapshotID})
+}
@@ -0,0 +1,157 @@
+package spotifytest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zmb3/spotify/v2"
+)
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("catalog", func(t *testing.T) {
+		s := NewServer(t)
+		client := s.SpotifyClient(nil)
+
+		user, err := client.CurrentUser(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "qs-test-user", user.ID)
+
+		track, err := client.GetTrack(ctx, TrackID(4))
+		assert.NoError(t, err)
+		assert.Equal(t, "Open Window", track.Name)
+		assert.Equal(t, AlbumID(1), track.Album.ID)
+		assert.Equal(t, ArtistID(0), track.Artists[0].ID)
+
+		tracks, err := client.GetTracks(ctx, []spotify.ID{TrackID(0), "missing"})
+		assert.NoError(t, err)
+		assert.Len(t, tracks, 2)
+		assert.Nil(t, tracks[1])
+
+		album, err := client.GetAlbum(ctx, AlbumID(2))
+		assert.NoError(t, err)
+		assert.Len(t, album.Tracks.Tracks, 3)
+
+		_, err = client.GetArtist(ctx, "missing")
+		assert.Equal(t, http.StatusNotFound, err.(spotify.Error).Status)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		s := NewServer(t)
+		client := s.SpotifyClient(nil)
+
+		results, err := client.Search(ctx, "paper", spotify.SearchTypeTrack|spotify.SearchTypeArtist)
+		assert.NoError(t, err)
+		// every track by The Paper Lanterns, including Paper Moon
+		assert.Len(t, results.Tracks.Tracks, 6)
+		assert.Len(t, results.Artists.Artists, 1)
+		assert.Nil(t, results.Albums)
+	})
+
+	t.Run("playback", func(t *testing.T) {
+		s := NewServer(t)
+		client := s.SpotifyClient(nil)
+
+		state, err := client.PlayerState(ctx)
+		assert.NoError(t, err)
+		assert.Nil(t, state.Item)
+
+		err = s.SetPlaying(TrackID(0), TrackID(1))
+		assert.NoError(t, err)
+		err = client.QueueSong(ctx, TrackID(2))
+		assert.NoError(t, err)
+		assert.Equal(t, []spotify.ID{TrackID(1), TrackID(2)}, s.QueuedIDs())
+
+		err = client.Next(ctx)
+		assert.NoError(t, err)
+		queue, err := client.GetQueue(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, TrackID(1), queue.CurrentlyPlaying.ID)
+		assert.Len(t, queue.Items, 1)
+
+		err = client.Pause(ctx)
+		assert.NoError(t, err)
+		_, playing := s.CurrentlyPlaying()
+		assert.False(t, playing)
+
+		err = client.Volume(ctx, 80)
+		assert.NoError(t, err)
+		assert.Equal(t, 80, s.Volume())
+
+		playlist := spotify.URI("spotify:playlist:" + string(PlaylistID(0)))
+		err = client.PlayOpt(ctx, &spotify.PlayOptions{PlaybackContext: &playlist})
+		assert.NoError(t, err)
+		current, playing := s.CurrentlyPlaying()
+		assert.Equal(t, TrackID(0), current)
+		assert.True(t, playing)
+
+		// skipped tracks are added to the history
+		recent, err := client.PlayerRecentlyPlayedOpt(ctx, &spotify.RecentlyPlayedOptions{Limit: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, TrackID(1), recent[0].Track.ID)
+		assert.Equal(t, TrackID(0), recent[1].Track.ID)
+	})
+
+	t.Run("recently played pages", func(t *testing.T) {
+		s := NewServer(t)
+		client := s.SpotifyClient(nil)
+
+		seen := 0
+		opts := spotify.RecentlyPlayedOptions{Limit: 5}
+		for {
+			items, err := client.PlayerRecentlyPlayedOpt(ctx, &opts)
+			assert.NoError(t, err)
+			if len(items) == 0 {
+				break
+			}
+			seen += len(items)
+			opts.BeforeEpochMs = items[len(items)-1].PlayedAt.UnixMilli()
+		}
+		assert.Equal(t, len(s.Catalog.Tracks), seen)
+
+		items, err := client.PlayerRecentlyPlayedOpt(ctx, &spotify.RecentlyPlayedOptions{
+			AfterEpochMs: SeedTime.Add(-2 * time.Minute).UnixMilli(),
+		})
+		assert.NoError(t, err)
+		assert.Len(t, items, 2)
+	})
+
+	t.Run("playlists", func(t *testing.T) {
+		s := NewServer(t)
+		client := s.SpotifyClient(nil)
+
+		created, err := client.CreatePlaylistForUser(ctx, "qs-test-user", "New Mix", "", false, false)
+		assert.NoError(t, err)
+		_, err = client.AddTracksToPlaylist(ctx, created.ID, TrackID(5), TrackID(6))
+		assert.NoError(t, err)
+
+		playlist, err := client.GetPlaylist(ctx, created.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "New Mix", playlist.Name)
+		assert.Len(t, playlist.Tracks.Tracks, 2)
+
+		page, err := client.CurrentUsersPlaylists(ctx, spotify.Limit(1))
+		assert.NoError(t, err)
+		assert.Len(t, page.Playlists, 1)
+		assert.NotEmpty(t, page.Next)
+
+		_, err = client.CreatePlaylistForUser(ctx, "someone-else", "New Mix", "", false, false)
+		assert.Error(t, err)
+	})
+
+	t.Run("injected failures", func(t *testing.T) {
+		s := NewServer(t)
+		client := s.SpotifyClient(nil)
+
+		s.FailNext("GET /v1/me/player/queue", http.StatusTooManyRequests)
+		_, err := client.GetQueue(ctx)
+		assert.Equal(t, http.StatusTooManyRequests, err.(spotify.Error).Status)
+
+		_, err = client.GetQueue(ctx)
+		assert.NoError(t, err)
+	})
+}
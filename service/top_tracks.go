@@ -3,11 +3,9 @@ package service
 import (
 	"context"
 	"fmt"
-
-	"github.com/zmb3/spotify/v2"
 )
 
-func TopTracks(ctx context.Context, spClient *spotify.Client) ([]TrackInfo, error) {
+func TopTracks(ctx context.Context, spClient MusicProvider) ([]TrackInfo, error) {
 	results, err := spClient.CurrentUsersTopTracks(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("search: %w", err)
@@ -13,7 +13,7 @@ type SpotifyUser struct {
 	ImageURL string
 }
 
-func GetUser(ctx context.Context, client *spotify.Client) (*SpotifyUser, error) {
+func GetUser(ctx context.Context, client MusicProvider) (*SpotifyUser, error) {
 	user, err := client.CurrentUser(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("could not get user: %w", err)
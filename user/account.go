@@ -0,0 +1,245 @@
+package user
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/util"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+)
+
+// UpdateNames changes the user's username and display name. Usernames are unique
+// regardless of case, so a username another user has fails with a unique
+// violation.
+func UpdateNames(ctx context.Context, dbtx db.DBTX, userID string, username string, displayName string) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("parse user UUID: %w", err)
+	}
+	return db.New(dbtx).UserUpdateNames(ctx, db.UserUpdateNamesParams{
+		Username:    username,
+		DisplayName: displayName,
+		ID:          userUUID,
+	})
+}
+
+// exportPageSize is how many history entries are read at a time
+var exportPageSize int32 = 5000
+
+// WriteDataArchive writes a zip archive of the user's profile, uploaded
+// streaming history, podcast and audiobook history, friends and hosted rooms,
+// one JSON file each. History is read and written a page at a time, so it is
+// never all in memory and no transaction is held open while it's sent.
+func WriteDataArchive(ctx context.Context, dbtx db.DBTX, userID string, w io.Writer) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("parse user UUID: %w", err)
+	}
+
+	profile, err := GetByID(ctx, dbtx, userID)
+	if err != nil {
+		return fmt.Errorf("get user: %w", err)
+	}
+
+	friendRows, err := db.New(dbtx).UserGetFriends(ctx, userUUID)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("get friends: %w", err)
+	}
+	friends := lo.Map(friendRows, func(row *db.User, _ int) User {
+		return User{
+			ID:           row.ID.String(),
+			Username:     row.Username,
+			DisplayName:  row.DisplayName,
+			SpotifyName:  util.StringFromPointer(row.SpotifyName),
+			SpotifyImage: row.SpotifyImageUrl,
+			Role:         row.Role,
+		}
+	})
+
+	roomRows, err := db.New(dbtx).UserExportHostedRooms(ctx, userUUID)
+	if err != nil {
+		return fmt.Errorf("get hosted rooms: %w", err)
+	}
+	rooms := lo.Map(roomRows, func(row *db.UserExportHostedRoomsRow, _ int) HostedRoom {
+		return HostedRoom{
+			ID:      row.ID.String(),
+			Code:    row.Code,
+			Name:    row.Name,
+			IsOpen:  row.IsOpen,
+			Created: row.Created,
+		}
+	})
+
+	archive := zip.NewWriter(w)
+	err = writeArchiveJSON(archive, "profile.json", profile)
+	if err != nil {
+		return err
+	}
+
+	err = writeArchivePages(archive, "history.json", func(after time.Time) ([]*db.SpotifyHistory, time.Time, error) {
+		rows, err := db.New(dbtx).UserExportHistory(ctx, db.UserExportHistoryParams{
+			UserID:   userUUID,
+			After:    after,
+			PageSize: exportPageSize,
+		})
+		if err != nil || len(rows) == 0 {
+			return rows, after, err
+		}
+		return rows, rows[len(rows)-1].Timestamp, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeArchivePages(archive, "episode_history.json", func(after time.Time) ([]*db.SpotifyEpisodeHistory, time.Time, error) {
+		rows, err := db.New(dbtx).UserExportEpisodeHistory(ctx, db.UserExportEpisodeHistoryParams{
+			UserID:   userUUID,
+			After:    after,
+			PageSize: exportPageSize,
+		})
+		if err != nil || len(rows) == 0 {
+			return rows, after, err
+		}
+		return rows, rows[len(rows)-1].Timestamp, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeArchiveJSON(archive, "friends.json", friends)
+	if err != nil {
+		return err
+	}
+	err = writeArchiveJSON(archive, "hosted_rooms.json", rooms)
+	if err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func writeArchiveJSON(archive *zip.Writer, name string, data any) error {
+	fileWriter, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", name, err)
+	}
+	encoder := json.NewEncoder(fileWriter)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(data)
+	if err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+// writeArchivePages writes a JSON array to the archive one page of rows at a
+// time. getPage returns the rows after the given timestamp, oldest first, and
+// the timestamp of the last one. History is unique by user and timestamp.
+func writeArchivePages[T any](archive *zip.Writer, name string, getPage func(after time.Time) ([]T, time.Time, error)) error {
+	fileWriter, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", name, err)
+	}
+
+	written := 0
+	after := time.Time{}
+	for {
+		rows, last, err := getPage(after)
+		if err != nil {
+			return fmt.Errorf("get %s: %w", name, err)
+		}
+
+		for _, row := range rows {
+			data, err := json.MarshalIndent(row, "  ", "  ")
+			if err != nil {
+				return fmt.Errorf("write %s: %w", name, err)
+			}
+			separator := ",\n  "
+			if written == 0 {
+				separator = "[\n  "
+			}
+			_, err = fmt.Fprintf(fileWriter, "%s%s", separator, data)
+			if err != nil {
+				return fmt.Errorf("write %s: %w", name, err)
+			}
+			written++
+		}
+
+		if len(rows) < int(exportPageSize) {
+			break
+		}
+		after = last
+	}
+
+	closing := "\n]\n"
+	if written == 0 {
+		closing = "[]\n"
+	}
+	_, err = io.WriteString(fileWriter, closing)
+	if err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+// SetDataExported records that the user downloaded an archive of their data
+func SetDataExported(ctx context.Context, dbtx db.DBTX, userID string) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("parse user UUID: %w", err)
+	}
+	return db.New(dbtx).UserDataExportSet(ctx, userUUID)
+}
+
+// ExportedDataSince returns true if the user downloaded an archive of their data
+// after the given time
+func ExportedDataSince(ctx context.Context, dbtx db.DBTX, userID string, since time.Time) (bool, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return false, fmt.Errorf("parse user UUID: %w", err)
+	}
+
+	exportedAt, err := db.New(dbtx).UserDataExportGetTime(ctx, userUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return exportedAt.After(since), nil
+}
+
+// Delete deletes the user's account and uploaded streaming history. Their
+// password, sessions, Spotify tokens, friends and hosted rooms are removed with
+// the account. It returns sql.ErrNoRows if there is no such user.
+func Delete(ctx context.Context, dbtx db.DBTX, userID string) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("parse user UUID: %w", err)
+	}
+
+	historyCount, err := db.New(dbtx).UserDeleteHistory(ctx, userUUID)
+	if err != nil {
+		return fmt.Errorf("delete history: %w", err)
+	}
+
+	count, err := db.New(dbtx).UserDelete(ctx, userUUID)
+	if err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+	if count == 0 {
+		return sql.ErrNoRows
+	}
+
+	log.Printf("Deleted user %s and %d history entries", userID, historyCount)
+	return nil
+}
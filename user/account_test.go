@@ -0,0 +1,76 @@
+package user
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/andrewbenington/queue-share-api/db/dbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteDataArchive(t *testing.T) {
+	pool := dbtest.New(t)
+	dbtest.Seed(t, pool)
+	ctx := context.Background()
+	aliceID := dbtest.AliceID.String()
+
+	var historyCount int
+	err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM spotify_history WHERE user_id = $1", dbtest.AliceID).Scan(&historyCount)
+	assert.NoError(t, err)
+
+	// small pages so the history is written across several
+	defaultPageSize := exportPageSize
+	exportPageSize = 3
+	defer func() { exportPageSize = defaultPageSize }()
+
+	archive := bytes.Buffer{}
+	assert.NoError(t, WriteDataArchive(ctx, pool, aliceID, &archive))
+
+	reader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	files := map[string]*zip.File{}
+	for _, file := range reader.File {
+		files[file.Name] = file
+	}
+
+	t.Run("history", func(t *testing.T) {
+		f, err := files["history.json"].Open()
+		assert.NoError(t, err)
+		defer f.Close()
+
+		history := []db.SpotifyHistory{}
+		assert.NoError(t, json.NewDecoder(f).Decode(&history))
+		assert.Len(t, history, historyCount)
+		for i := 1; i < len(history); i++ {
+			assert.True(t, history[i].Timestamp.After(history[i-1].Timestamp))
+		}
+	})
+
+	t.Run("empty episode history", func(t *testing.T) {
+		f, err := files["episode_history.json"].Open()
+		assert.NoError(t, err)
+		defer f.Close()
+
+		episodes := []db.SpotifyEpisodeHistory{}
+		assert.NoError(t, json.NewDecoder(f).Decode(&episodes))
+		assert.Empty(t, episodes)
+	})
+
+	t.Run("export recorded", func(t *testing.T) {
+		exported, err := ExportedDataSince(ctx, pool, aliceID, time.Now().Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.False(t, exported)
+
+		assert.NoError(t, SetDataExported(ctx, pool, aliceID))
+		exported, err = ExportedDataSince(ctx, pool, aliceID, time.Now().Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.True(t, exported)
+	})
+}
@@ -47,7 +47,7 @@ INSERT INTO user_passwords(
   encrypted_password)
 SELECT
   id,
-  crypt(@user_pass, gen_salt('bf'))
+  @password_hash::text
 FROM
   new_user
 RETURNING (
@@ -57,16 +57,25 @@ RETURNING (
     new_user);
 
 -- name: UserUpdatePassword :exec
+UPDATE
+  user_passwords
+SET
+  encrypted_password = @password_hash::text
+WHERE
+  user_id = @user_id;
+
+-- name: UserSetRole :execrows
 UPDATE
   users
 SET
-  encrypted_password = crypt(@user_pass, gen_salt('bf'))
+  role = @role
 WHERE
-  id = @user_id;
+  UPPER(username) = UPPER(@username::text);
 
--- name: UserValidatePassword :one
+-- name: UserGetPasswordHash :one
 SELECT
-  (encrypted_password = crypt(@user_pass, encrypted_password))
+  up.user_id,
+  up.encrypted_password
 FROM
   user_passwords AS up
   JOIN users u ON u.id = up.user_id
@@ -81,12 +90,24 @@ SELECT
   u.id AS host_id,
   u.username AS host_username,
   u.display_name AS host_display_name,
-  u.spotify_image_url AS host_spotify_image_url
+  u.spotify_image_url AS host_spotify_image_url,
+  s.starts_at,
+  s.ends_at,
+  s.idle_timeout_minutes,
+  s.recurrence,
+  s.last_opened,
+  COALESCE((
+    SELECT
+      MAX(t.timestamp)
+    FROM room_queue_tracks t
+    WHERE
+      t.room_id = r.id), r.created)::timestamptz AS last_activity
 FROM
   rooms r
   JOIN users u ON r.host_id = u.id
     AND u.id = $1
-    AND r.is_open = $2;
+    AND r.is_open = $2
+  LEFT JOIN room_schedules s ON s.room_id = r.id;
 
 -- name: UserGetByUsername :one
 SELECT
@@ -95,7 +116,8 @@ SELECT
   display_name,
   spotify_account,
   spotify_name,
-  spotify_image_url
+  spotify_image_url,
+  role
 FROM
   users u
 WHERE
@@ -108,7 +130,8 @@ SELECT
   display_name,
   spotify_account,
   spotify_name,
-  spotify_image_url
+  spotify_image_url,
+  role
 FROM
   users u
 WHERE
@@ -147,13 +170,25 @@ SELECT
   u.id AS host_id,
   u.username AS host_username,
   u.display_name AS host_display_name,
-  u.spotify_image_url AS host_spotify_image_url
+  u.spotify_image_url AS host_spotify_image_url,
+  s.starts_at,
+  s.ends_at,
+  s.idle_timeout_minutes,
+  s.recurrence,
+  s.last_opened,
+  COALESCE((
+    SELECT
+      MAX(t.timestamp)
+    FROM room_queue_tracks t
+    WHERE
+      t.room_id = r.id), r.created)::timestamptz AS last_activity
 FROM
   rooms r
   JOIN room_members rm ON rm.user_id = $1
     AND r.id = rm.room_id
     AND r.is_open = $2
-  JOIN users u ON r.host_id = u.id;
+  JOIN users u ON r.host_id = u.id
+  LEFT JOIN room_schedules s ON s.room_id = r.id;
 
 -- name: UserGetSpotifyTokens :one
 SELECT
@@ -165,6 +200,26 @@ FROM
 WHERE
   st.user_id = $1;
 
+-- name: UserGetSpotifyPermissionsVersion :one
+SELECT
+  permissions_version
+FROM
+  spotify_tokens
+WHERE
+  user_id = $1;
+
+-- name: UserSetLatestSpotifyPermissions :exec
+UPDATE
+  spotify_tokens
+SET
+  permissions_version = (
+    SELECT
+      MAX(id)
+    FROM
+      spotify_permissions_versions)
+WHERE
+  user_id = $1;
+
 -- name: UserHasSpotifyHistory :one
 SELECT
   EXISTS (
@@ -302,3 +357,207 @@ FROM
   JOIN spotify_tokens st ON u.id = st.user_id
     AND st.permissions_version >= 3;
 
+
+-- name: UserSessionInsert :one
+INSERT INTO user_sessions(
+  user_id,
+  refresh_token_hash,
+  user_agent,
+  ip_address,
+  expires_at)
+VALUES (
+  @user_id,
+  @refresh_token_hash,
+  @user_agent,
+  @ip_address,
+  @expires_at)
+RETURNING
+  id;
+
+-- name: UserSessionRotate :one
+WITH rotated AS (
+  UPDATE
+    user_sessions
+  SET
+    refresh_token_hash = @new_refresh_token_hash,
+    last_used = NOW(),
+    expires_at = @expires_at
+  WHERE
+    refresh_token_hash = @refresh_token_hash
+    AND revoked_at IS NULL
+    AND expires_at > NOW()
+  RETURNING
+    id,
+    user_id
+),
+used AS (
+  INSERT INTO user_session_used_tokens(
+    refresh_token_hash,
+    session_id)
+  SELECT
+    @refresh_token_hash,
+    id
+  FROM
+    rotated)
+SELECT
+  id,
+  user_id
+FROM
+  rotated;
+
+-- name: UserSessionRevokeReused :execrows
+UPDATE
+  user_sessions s
+SET
+  revoked_at = NOW()
+FROM
+  user_session_used_tokens t
+WHERE
+  t.refresh_token_hash = @refresh_token_hash
+  AND s.id = t.session_id
+  AND s.revoked_at IS NULL;
+
+-- name: UserSessionRevoke :execrows
+UPDATE
+  user_sessions
+SET
+  revoked_at = NOW()
+WHERE
+  id = @id
+  AND user_id = @user_id
+  AND revoked_at IS NULL;
+
+-- name: UserSessionGetActive :many
+SELECT
+  id,
+  user_agent,
+  ip_address,
+  created,
+  last_used,
+  expires_at
+FROM
+  user_sessions
+WHERE
+  user_id = @user_id
+  AND revoked_at IS NULL
+  AND expires_at > NOW()
+ORDER BY
+  last_used DESC;
+
+-- name: UserSessionIsActive :one
+SELECT
+  EXISTS (
+    SELECT
+      *
+    FROM
+      user_sessions
+    WHERE
+      id = @id
+      AND user_id = @user_id
+      AND revoked_at IS NULL
+      AND expires_at > NOW());
+
+-- name: UserGetSpotifyTokensToReencrypt :many
+SELECT
+  id,
+  encrypted_access_token,
+  encrypted_refresh_token
+FROM
+  spotify_tokens
+WHERE (substring(encrypted_access_token FROM 1 FOR length(@key_prefix::bytea)) <> @key_prefix::bytea
+  OR substring(encrypted_refresh_token FROM 1 FOR length(@key_prefix::bytea)) <> @key_prefix::bytea)
+  AND id > @after::uuid
+ORDER BY
+  id
+LIMIT @batch_size::int;
+
+-- name: UserReencryptSpotifyTokens :execrows
+UPDATE
+  spotify_tokens
+SET
+  encrypted_access_token = @encrypted_access_token,
+  encrypted_refresh_token = @encrypted_refresh_token
+WHERE
+  id = @id
+  AND encrypted_access_token = @previous_access_token
+  AND encrypted_refresh_token = @previous_refresh_token;
+
+-- name: UserUpdateNames :exec
+UPDATE
+  users
+SET
+  username = @username,
+  display_name = @display_name
+WHERE
+  id = @id;
+
+-- name: UserSessionRevokeOthers :execrows
+UPDATE
+  user_sessions
+SET
+  revoked_at = NOW()
+WHERE
+  user_id = @user_id
+  AND id != @current_session_id
+  AND revoked_at IS NULL;
+
+-- name: UserExportHistory :many
+SELECT
+  *
+FROM
+  spotify_history
+WHERE
+  user_id = @user_id
+  AND timestamp > @after
+ORDER BY
+  timestamp
+LIMIT @page_size;
+
+-- name: UserExportEpisodeHistory :many
+SELECT
+  *
+FROM
+  spotify_episode_history
+WHERE
+  user_id = @user_id
+  AND timestamp > @after
+ORDER BY
+  timestamp
+LIMIT @page_size;
+
+-- name: UserExportHostedRooms :many
+SELECT
+  id,
+  code,
+  name,
+  is_open,
+  created
+FROM
+  rooms
+WHERE
+  host_id = $1
+ORDER BY
+  created;
+
+-- name: UserDataExportSet :exec
+INSERT INTO user_data_exports(user_id)
+  VALUES ($1)
+ON CONFLICT (user_id)
+  DO UPDATE SET
+    exported_at = now();
+
+-- name: UserDataExportGetTime :one
+SELECT
+  exported_at
+FROM
+  user_data_exports
+WHERE
+  user_id = $1;
+
+-- name: UserDeleteHistory :execrows
+DELETE FROM spotify_history
+WHERE user_id = $1;
+
+-- name: UserDelete :execrows
+DELETE FROM users
+WHERE id = $1;
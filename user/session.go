@@ -0,0 +1,216 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/db"
+	"github.com/google/uuid"
+)
+
+const (
+	// RefreshTokenLifetime is how long a session lasts without being refreshed.
+	// Each refresh extends it.
+	RefreshTokenLifetime = 30 * 24 * time.Hour
+)
+
+// Session is a device the user has logged in on
+type Session struct {
+	ID        string    `json:"id"`
+	UserAgent *string   `json:"user_agent"`
+	IPAddress *string   `json:"ip_address"`
+	Created   time.Time `json:"created"`
+	LastUsed  time.Time `json:"last_used"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Current   bool      `json:"current"`
+}
+
+// RefreshedSession is a session along with the refresh token that replaced the
+// one it was refreshed with
+type RefreshedSession struct {
+	ID           string
+	UserID       string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// newRefreshToken returns a random refresh token and the hash stored in place of
+// it
+func newRefreshToken() (string, []byte, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+	return token, hashRefreshToken(token), nil
+}
+
+func hashRefreshToken(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
+}
+
+func stringPtrOrNil(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// CreateSession starts a session on a new device, returning its ID and refresh
+// token
+func CreateSession(ctx context.Context, dbtx db.DBTX, userID string, userAgent string, ipAddress string) (sessionID string, refreshToken string, expiresAt time.Time, err error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("parse user UUID: %w", err)
+	}
+
+	refreshToken, refreshTokenHash, err := newRefreshToken()
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("generate refresh token: %w", err)
+	}
+	expiresAt = time.Now().Add(RefreshTokenLifetime)
+
+	sessionUUID, err := db.New(dbtx).UserSessionInsert(ctx, db.UserSessionInsertParams{
+		UserID:           userUUID,
+		RefreshTokenHash: refreshTokenHash,
+		UserAgent:        stringPtrOrNil(userAgent),
+		IpAddress:        stringPtrOrNil(ipAddress),
+		ExpiresAt:        expiresAt,
+	})
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	return sessionUUID.String(), refreshToken, expiresAt, nil
+}
+
+// RefreshSession replaces the refresh token with a new one and extends the
+// session. It returns sql.ErrNoRows if the token is unknown, expired or revoked.
+// Every token a session has been refreshed with is kept, and one that has
+// already been replaced was likely stolen, so presenting it revokes the session
+// it belonged to.
+func RefreshSession(ctx context.Context, dbtx db.DBTX, refreshToken string) (*RefreshedSession, error) {
+	newToken, newTokenHash, err := newRefreshToken()
+	if err != nil {
+		return nil, fmt.Errorf("generate refresh token: %w", err)
+	}
+	expiresAt := time.Now().Add(RefreshTokenLifetime)
+	tokenHash := hashRefreshToken(refreshToken)
+
+	row, err := db.New(dbtx).UserSessionRotate(ctx, db.UserSessionRotateParams{
+		NewRefreshTokenHash: newTokenHash,
+		ExpiresAt:           expiresAt,
+		RefreshTokenHash:    tokenHash,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = db.New(dbtx).UserSessionRevokeReused(ctx, tokenHash)
+		if err != nil {
+			return nil, err
+		}
+		return nil, sql.ErrNoRows
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &RefreshedSession{
+		ID:           row.ID.String(),
+		UserID:       row.UserID.String(),
+		RefreshToken: newToken,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+// RevokeSession logs the user out of the session, returning sql.ErrNoRows if it
+// is not one of the user's active sessions
+func RevokeSession(ctx context.Context, dbtx db.DBTX, userID string, sessionID string) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("parse user UUID: %w", err)
+	}
+	sessionUUID, err := uuid.Parse(sessionID)
+	if err != nil {
+		return fmt.Errorf("parse session UUID: %w", err)
+	}
+
+	count, err := db.New(dbtx).UserSessionRevoke(ctx, db.UserSessionRevokeParams{
+		ID:     sessionUUID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetActiveSessions returns the user's sessions that have not expired or been
+// revoked, most recently used first
+func GetActiveSessions(ctx context.Context, dbtx db.DBTX, userID string, currentSessionID string) ([]Session, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("parse user UUID: %w", err)
+	}
+
+	rows, err := db.New(dbtx).UserSessionGetActive(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, len(rows))
+	for i, row := range rows {
+		sessions[i] = Session{
+			ID:        row.ID.String(),
+			UserAgent: row.UserAgent,
+			IPAddress: row.IpAddress,
+			Created:   row.Created,
+			LastUsed:  row.LastUsed,
+			ExpiresAt: row.ExpiresAt,
+			Current:   row.ID.String() == currentSessionID,
+		}
+	}
+	return sessions, nil
+}
+
+func SessionIsActive(ctx context.Context, dbtx db.DBTX, userID string, sessionID string) (bool, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return false, nil
+	}
+	sessionUUID, err := uuid.Parse(sessionID)
+	if err != nil {
+		return false, nil
+	}
+
+	return db.New(dbtx).UserSessionIsActive(ctx, db.UserSessionIsActiveParams{
+		ID:     sessionUUID,
+		UserID: userUUID,
+	})
+}
+
+// RevokeOtherSessions logs the user out of every session except the current one
+func RevokeOtherSessions(ctx context.Context, dbtx db.DBTX, userID string, currentSessionID string) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("parse user UUID: %w", err)
+	}
+	sessionUUID, err := uuid.Parse(currentSessionID)
+	if err != nil {
+		return fmt.Errorf("parse session UUID: %w", err)
+	}
+
+	_, err = db.New(dbtx).UserSessionRevokeOthers(ctx, db.UserSessionRevokeOthersParams{
+		UserID:           userUUID,
+		CurrentSessionID: sessionUUID,
+	})
+	return err
+}
@@ -0,0 +1,74 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/andrewbenington/queue-share-api/db/dbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshSession(t *testing.T) {
+	pool := dbtest.New(t)
+	dbtest.Seed(t, pool)
+	ctx := context.Background()
+	aliceID := dbtest.AliceID.String()
+
+	refresh := func(t *testing.T, token string) string {
+		session, err := RefreshSession(ctx, pool, token)
+		assert.NoError(t, err)
+		if session == nil {
+			return ""
+		}
+		return session.RefreshToken
+	}
+
+	t.Run("rotates", func(t *testing.T) {
+		sessionID, first, _, err := CreateSession(ctx, pool, aliceID, "test", "")
+		assert.NoError(t, err)
+
+		second := refresh(t, first)
+		assert.NotEqual(t, first, second)
+		third := refresh(t, second)
+		assert.NotEmpty(t, refresh(t, third))
+
+		active, err := SessionIsActive(ctx, pool, aliceID, sessionID)
+		assert.NoError(t, err)
+		assert.True(t, active)
+	})
+
+	t.Run("reusing the previous token revokes the session", func(t *testing.T) {
+		sessionID, first, _, err := CreateSession(ctx, pool, aliceID, "test", "")
+		assert.NoError(t, err)
+		second := refresh(t, first)
+
+		_, err = RefreshSession(ctx, pool, first)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+
+		active, err := SessionIsActive(ctx, pool, aliceID, sessionID)
+		assert.NoError(t, err)
+		assert.False(t, active)
+		_, err = RefreshSession(ctx, pool, second)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("reusing an older token revokes the session", func(t *testing.T) {
+		sessionID, first, _, err := CreateSession(ctx, pool, aliceID, "test", "")
+		assert.NoError(t, err)
+		second := refresh(t, first)
+		refresh(t, second)
+
+		_, err = RefreshSession(ctx, pool, first)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+
+		active, err := SessionIsActive(ctx, pool, aliceID, sessionID)
+		assert.NoError(t, err)
+		assert.False(t, active)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		_, err := RefreshSession(ctx, pool, "unknown")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
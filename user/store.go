@@ -3,7 +3,9 @@ package user
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/andrewbenington/queue-share-api/auth"
@@ -25,10 +27,14 @@ func NewStore(db *sql.DB) *Store {
 }
 
 func InsertUser(ctx context.Context, dbtx db.DBTX, username string, displayName string, password string) (newUserID string, err error) {
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		return "", fmt.Errorf("hash password: %w", err)
+	}
 	userUUID, err := db.New(dbtx).UserInsertWithPassword(ctx, db.UserInsertWithPasswordParams{
-		Username:    username,
-		DisplayName: displayName,
-		UserPass:    password,
+		Username:     username,
+		DisplayName:  displayName,
+		PasswordHash: passwordHash,
 	})
 	if err != nil {
 		return "", err
@@ -59,6 +65,81 @@ func UpdateSpotifyToken(ctx context.Context, dbtx db.DBTX, userID string, oauthT
 	})
 }
 
+// ReencryptSpotifyTokens moves up to batchSize users' Spotify tokens that aren't
+// encrypted with the active encryption key onto it, starting after the tokens
+// with ID after. It returns the ID of the last tokens in the batch, to start the
+// next batch after, along with how many tokens needed moving and how many were
+// moved. Tokens that can't be decrypted are logged and left alone.
+func ReencryptSpotifyTokens(ctx context.Context, dbtx db.DBTX, after uuid.UUID, batchSize int) (last uuid.UUID, found int, moved int, err error) {
+	keyring := auth.EncryptionKeys()
+	rows, err := db.New(dbtx).UserGetSpotifyTokensToReencrypt(ctx, db.UserGetSpotifyTokensToReencryptParams{
+		KeyPrefix: keyring.ActivePrefix(),
+		After:     after,
+		BatchSize: int32(batchSize),
+	})
+	if err != nil {
+		return after, 0, 0, err
+	}
+	if len(rows) > 0 {
+		last = rows[len(rows)-1].ID
+	}
+
+	for _, row := range rows {
+		encryptedAccessToken, err := keyring.Rewrap(row.EncryptedAccessToken)
+		if err != nil {
+			log.Printf("re-encrypt access token %s: %s", row.ID, err)
+			continue
+		}
+		encryptedRefreshToken, err := keyring.Rewrap(row.EncryptedRefreshToken)
+		if err != nil {
+			log.Printf("re-encrypt refresh token %s: %s", row.ID, err)
+			continue
+		}
+
+		// the tokens aren't updated if they were refreshed in the meantime
+		count, err := db.New(dbtx).UserReencryptSpotifyTokens(ctx, db.UserReencryptSpotifyTokensParams{
+			EncryptedAccessToken:  encryptedAccessToken,
+			EncryptedRefreshToken: encryptedRefreshToken,
+			ID:                    row.ID,
+			PreviousAccessToken:   row.EncryptedAccessToken,
+			PreviousRefreshToken:  row.EncryptedRefreshToken,
+		})
+		if err != nil {
+			return last, len(rows), moved, err
+		}
+		moved += int(count)
+	}
+
+	return last, len(rows), moved, nil
+}
+
+// SetLatestSpotifyPermissions records that the user's Spotify token was granted
+// with the current scopes, after the user authorizes the app again
+func SetLatestSpotifyPermissions(ctx context.Context, dbtx db.DBTX, userID string) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("parse user UUID: %w", err)
+	}
+	return db.New(dbtx).UserSetLatestSpotifyPermissions(ctx, userUUID)
+}
+
+// HasSpotifyPermissions returns whether the user's Spotify token was granted with
+// the scopes added in the given permissions version
+func HasSpotifyPermissions(ctx context.Context, dbtx db.DBTX, userID string, version int64) (bool, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return false, fmt.Errorf("parse user UUID: %w", err)
+	}
+	userVersion, err := db.New(dbtx).UserGetSpotifyPermissionsVersion(ctx, userUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return userVersion >= version, nil
+}
+
 func UpdateSpotifyInfo(ctx context.Context, dbtx db.DBTX, userID string, info *service.SpotifyUser) error {
 	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
@@ -76,10 +157,40 @@ func UpdateSpotifyInfo(ctx context.Context, dbtx db.DBTX, userID string, info *s
 	})
 }
 
+// Authenticate checks the password against the user's argon2id or legacy bcrypt
+// hash. Passwords with a legacy or outdated hash are rehashed when they match.
 func Authenticate(ctx context.Context, dbtx db.DBTX, username string, password string) (bool, error) {
-	return db.New(dbtx).UserValidatePassword(ctx, db.UserValidatePasswordParams{
-		Username: username,
-		UserPass: password,
+	row, err := db.New(dbtx).UserGetPasswordHash(ctx, username)
+	if err != nil {
+		return false, err
+	}
+
+	matches, needsRehash, err := auth.VerifyPassword(row.EncryptedPassword, password)
+	if err != nil {
+		return false, fmt.Errorf("verify password: %w", err)
+	}
+	if matches && needsRehash {
+		err = UpdatePassword(ctx, dbtx, row.UserID.String(), password)
+		if err != nil {
+			return false, fmt.Errorf("rehash password: %w", err)
+		}
+	}
+	return matches, nil
+}
+
+func UpdatePassword(ctx context.Context, dbtx db.DBTX, userID string, newPassword string) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("parse user UUID: %w", err)
+	}
+	passwordHash, err := auth.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+
+	return db.New(dbtx).UserUpdatePassword(ctx, db.UserUpdatePasswordParams{
+		UserID:       userUUID,
+		PasswordHash: passwordHash,
 	})
 }
 
@@ -87,6 +198,7 @@ type HostedRoom struct {
 	ID      string    `json:"id"`
 	Code    string    `json:"code"`
 	Name    string    `json:"name"`
+	IsOpen  bool      `json:"is_open"`
 	Created time.Time `json:"created"`
 }
 
@@ -104,6 +216,7 @@ func GetByUsername(ctx context.Context, dbtx db.DBTX, username string) (*User, e
 		DisplayName:  row.DisplayName,
 		SpotifyName:  util.StringFromPointer(row.SpotifyName),
 		SpotifyImage: row.SpotifyImageUrl,
+		Role:         row.Role,
 	}, nil
 }
 
@@ -124,6 +237,7 @@ func GetByID(ctx context.Context, dbtx db.DBTX, userID string) (*User, error) {
 		DisplayName:  row.DisplayName,
 		SpotifyName:  util.StringFromPointer(row.SpotifyName),
 		SpotifyImage: row.SpotifyImageUrl,
+		Role:         row.Role,
 	}, nil
 }
 
@@ -142,3 +256,19 @@ func UnlinkSpotify(ctx context.Context, dbtx db.DBTX, userID string) error {
 	}
 	return nil
 }
+
+// SetRole changes the user's role, returning sql.ErrNoRows if there is no user
+// with the username
+func SetRole(ctx context.Context, dbtx db.DBTX, username string, role string) error {
+	count, err := db.New(dbtx).UserSetRole(ctx, db.UserSetRoleParams{
+		Role:     role,
+		Username: username,
+	})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
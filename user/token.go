@@ -4,44 +4,69 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/andrewbenington/queue-share-api/config"
+	"github.com/andrewbenington/queue-share-api/auth"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func (u *User) GetJWT() (string, time.Time, error) {
+const (
+	AccessTokenLifetime = 15 * time.Minute
+)
+
+type TokenClaims struct {
+	UserID    string
+	SessionID string
+	Role      string
+}
+
+// GetJWT issues a short-lived access token for the session. The session ID is
+// the token's jti, so the token stops working once the session is revoked.
+func (u *User) GetJWT(sessionID string) (string, time.Time, error) {
 	now := time.Now()
-	expiry := now.Add(7 * 24 * time.Hour)
-	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":  u.ID,
-		"iat": now.Unix(),
-		"exp": expiry.Unix(),
-	}).SignedString(config.GetSigningSecret())
+	expiry := now.Add(AccessTokenLifetime)
+	token, err := auth.SigningKeys().Sign(jwt.MapClaims{
+		"id":   u.ID,
+		"jti":  sessionID,
+		"role": u.Role,
+		"iat":  now.Unix(),
+		"exp":  expiry.Unix(),
+	})
 	if err != nil {
 		return "", time.Time{}, err
 	}
 	return token, expiry, nil
 }
 
-func GetTokenID(tokenStr string) (string, error) {
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-		}
-
-		return config.GetSigningSecret(), nil
-	})
+// ParseToken checks the token's signature against the key named by its kid and
+// its expiry, and returns its claims.
+// Whether its session is still active is checked against the database.
+func ParseToken(tokenStr string) (*TokenClaims, error) {
+	token, err := jwt.Parse(tokenStr, auth.SigningKeys().Keyfunc)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || !token.Valid {
-		return "", fmt.Errorf("Invalid token")
+		return nil, fmt.Errorf("Invalid token")
 	}
 	id, ok := claims["id"].(string)
 	if !ok {
-		return "", fmt.Errorf("Invalid token")
+		return nil, fmt.Errorf("Invalid token")
+	}
+	// tokens issued before sessions were added have no jti and can't be revoked
+	sessionID, ok := claims["jti"].(string)
+	if !ok {
+		return nil, fmt.Errorf("Invalid token")
+	}
+
+	role, ok := claims["role"].(string)
+	if !ok {
+		role = RoleUser
 	}
 
-	return id, nil
+	return &TokenClaims{
+		UserID:    id,
+		SessionID: sessionID,
+		Role:      role,
+	}, nil
 }
@@ -0,0 +1,63 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andrewbenington/queue-share-api/auth/authtest"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToken(t *testing.T) {
+	authtest.UseKeys(t)
+	u := User{ID: "4b9c7a55-3f7e-4d52-9a3e-8c1b9f2d6e10", Role: RoleUser}
+	sessionID := "0f1e2d3c-4b5a-4968-8776-a5b4c3d2e1f0"
+
+	t.Run("sign -> parse", func(t *testing.T) {
+		token, expiry, err := u.GetJWT(sessionID)
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(AccessTokenLifetime), expiry, time.Second)
+
+		claims, err := ParseToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, u.ID, claims.UserID)
+		assert.Equal(t, sessionID, claims.SessionID)
+		assert.Equal(t, RoleUser, claims.Role)
+	})
+
+	t.Run("admin role", func(t *testing.T) {
+		admin := User{ID: u.ID, Role: RoleAdmin}
+		token, _, err := admin.GetJWT(sessionID)
+		assert.NoError(t, err)
+
+		claims, err := ParseToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, RoleAdmin, claims.Role)
+	})
+
+	t.Run("token without session", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"id":  u.ID,
+			"iat": time.Now().Unix(),
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}).SignedString(authtest.SigningSecret)
+		assert.NoError(t, err)
+
+		_, err = ParseToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"id":  u.ID,
+			"jti": sessionID,
+			"iat": time.Now().Add(-time.Hour).Unix(),
+			"exp": time.Now().Add(-time.Minute).Unix(),
+		}).SignedString(authtest.SigningSecret)
+		assert.NoError(t, err)
+
+		_, err = ParseToken(token)
+		assert.Error(t, err)
+	})
+}
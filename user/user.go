@@ -1,9 +1,15 @@
 package user
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	ID           string  `json:"id"`
 	Username     string  `json:"username"`
 	DisplayName  string  `json:"display_name"`
 	SpotifyName  string  `json:"spotify_name"`
 	SpotifyImage *string `json:"spotify_image_url"`
+	Role         string  `json:"role"`
 }
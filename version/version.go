@@ -17,11 +17,22 @@ type GoMetadata struct {
 	OS      string `yaml:"os" json:"os"`
 }
 
+// SchemaVersion compares the database schema the server was built for with the
+// one it is connected to
+type SchemaVersion struct {
+	// Expected is the version of the newest embedded migration
+	Expected int64 `yaml:"expected" json:"expected"`
+	// Actual is nil if the database couldn't be reached
+	Actual *int64 `yaml:"actual" json:"actual"`
+	Dirty  bool   `yaml:"dirty" json:"dirty"`
+}
+
 type Version struct {
-	Git      GitVersion `yaml:"git" json:"git"`
-	Database string     `yaml:"database" json:"database"`
-	Go       GoMetadata `yaml:"go" json:"go"`
-	Date     string     `yaml:"build_date" json:"build_date"`
+	Git      GitVersion     `yaml:"git" json:"git"`
+	Database string         `yaml:"database" json:"database"`
+	Schema   *SchemaVersion `yaml:"schema,omitempty" json:"schema,omitempty"`
+	Go       GoMetadata     `yaml:"go" json:"go"`
+	Date     string         `yaml:"build_date" json:"build_date"`
 }
 
 type WorkingTreeState string